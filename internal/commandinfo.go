@@ -23,6 +23,7 @@ const (
 	MultiState
 	SubscribeState
 	MonitorState
+	ShardSubscribeState
 )
 
 type CommandInfo struct {
@@ -37,6 +38,7 @@ var commandInfos = map[string]CommandInfo{
 	"DISCARD":    {Clear: WatchState | MultiState},
 	"PSUBSCRIBE": {Set: SubscribeState},
 	"SUBSCRIBE":  {Set: SubscribeState},
+	"SSUBSCRIBE": {Set: SubscribeState | ShardSubscribeState},
 	"MONITOR":    {Set: MonitorState},
 }
 
@@ -0,0 +1,64 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"math/big"
+)
+
+// BitmapBools is a helper that converts a bitmap returned by the GET or
+// GETRANGE command to a []bool. Element i of the result is the bit at offset
+// i as set by SETBIT. The length of the result is eight times the length of
+// the bitmap. BitmapBools returns nil, ErrNil if the key does not exist.
+func BitmapBools(reply interface{}, err error) ([]bool, error) {
+	p, err := Bytes(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	bools := make([]bool, 8*len(p))
+	for i, b := range p {
+		for j := 0; j < 8; j++ {
+			bools[8*i+j] = b&(0x80>>uint(j)) != 0
+		}
+	}
+	return bools, nil
+}
+
+// BitmapInt is a helper that converts a bitmap returned by the GET or
+// GETRANGE command to a big.Int. Bit i of the result, as reported by the
+// big.Int Bit method, is the bit at offset i as set by SETBIT. BitmapInt
+// returns nil, ErrNil if the key does not exist.
+func BitmapInt(reply interface{}, err error) (*big.Int, error) {
+	p, err := Bytes(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	// Redis stores offset 0 in the most significant bit of the first byte.
+	// Reverse the bits in each byte and the order of the bytes to get the
+	// big-endian representation with offset 0 in the least significant
+	// bit.
+	q := make([]byte, len(p))
+	for i, b := range p {
+		q[len(p)-1-i] = reverseBits(b)
+	}
+	return new(big.Int).SetBytes(q), nil
+}
+
+func reverseBits(b byte) byte {
+	b = b>>4 | b<<4
+	b = (b&0xcc)>>2 | (b&0x33)<<2
+	b = (b&0xaa)>>1 | (b&0x55)<<1
+	return b
+}
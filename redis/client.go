@@ -0,0 +1,123 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClientInfo represents a client connection in the reply to the CLIENT LIST
+// and CLIENT INFO commands.
+type ClientInfo struct {
+	ID        int64
+	Addr      string
+	LocalAddr string
+	Name      string
+	User      string
+	DB        int
+
+	// The time since the connection was created and the time since the
+	// last command on the connection.
+	Age  time.Duration
+	Idle time.Duration
+
+	// The client flags. See the CLIENT LIST command documentation for the
+	// meaning of the flags.
+	Flags string
+
+	// The last command run by the client.
+	Cmd string
+
+	// The protocol version used by the client. Resp is zero if the server
+	// does not report the version.
+	Resp int
+
+	// Fields holds all fields reported for the client, including fields
+	// not decoded above.
+	Fields map[string]string
+}
+
+// ClientInfos is a helper that converts the reply to the CLIENT LIST or
+// CLIENT INFO command to a slice of ClientInfo. The reply to CLIENT INFO is
+// converted to a slice with one element.
+func ClientInfos(reply interface{}, err error) ([]ClientInfo, error) {
+	s, err := String(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	var result []ClientInfo
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		ci, err := parseClientInfo(line)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ci)
+	}
+	return result, nil
+}
+
+func parseClientInfo(line string) (ClientInfo, error) {
+	ci := ClientInfo{Fields: make(map[string]string)}
+	for _, kv := range strings.Fields(line) {
+		i := strings.IndexByte(kv, '=')
+		if i < 0 {
+			continue
+		}
+		k, v := kv[:i], kv[i+1:]
+		ci.Fields[k] = v
+
+		var err error
+		switch k {
+		case "id":
+			ci.ID, err = strconv.ParseInt(v, 10, 64)
+		case "addr":
+			ci.Addr = v
+		case "laddr":
+			ci.LocalAddr = v
+		case "name":
+			ci.Name = v
+		case "user":
+			ci.User = v
+		case "db":
+			ci.DB, err = strconv.Atoi(v)
+		case "age":
+			ci.Age, err = parseSeconds(v)
+		case "idle":
+			ci.Idle, err = parseSeconds(v)
+		case "flags":
+			ci.Flags = v
+		case "cmd":
+			ci.Cmd = v
+		case "resp":
+			ci.Resp, err = strconv.Atoi(v)
+		}
+		if err != nil {
+			return ci, fmt.Errorf("redigo: bad client field %q", kv)
+		}
+	}
+	return ci, nil
+}
+
+func parseSeconds(s string) (time.Duration, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	return time.Duration(n) * time.Second, err
+}
@@ -0,0 +1,324 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+)
+
+// CommandInfo represents a command in the reply to the COMMAND and COMMAND
+// INFO commands.
+type CommandInfo struct {
+	Name string
+
+	// The number of arguments including the command name. A negative arity
+	// means that the command takes at least -Arity arguments.
+	Arity int
+
+	Flags []string
+
+	// The positions of the first and last key arguments and the step
+	// between key arguments. A negative LastKey counts from the end of the
+	// argument list.
+	FirstKey int
+	LastKey  int
+	Step     int
+
+	// The following fields are set by Redis 6.0 and later.
+	ACLCategories []string
+	Tips          []string
+	KeySpecs      []CommandKeySpec
+	Subcommands   []CommandInfo
+}
+
+// CommandKeySpec represents a key specification in the reply to the COMMAND
+// INFO command.
+type CommandKeySpec struct {
+	Notes       string
+	Flags       []string
+	BeginSearch CommandKeySearch
+	FindKeys    CommandKeySearch
+}
+
+// CommandKeySearch represents the begin_search or find_keys step of a key
+// specification. Type is the search type such as "index", "keyword" or
+// "range". Spec holds the type specific parameters. The values in Spec are
+// int64 or string.
+type CommandKeySearch struct {
+	Type string
+	Spec map[string]interface{}
+}
+
+// CommandDoc represents a command in the reply to the COMMAND DOCS command.
+type CommandDoc struct {
+	Summary     string
+	Since       string
+	Group       string
+	Complexity  string
+	DocFlags    []string
+	Arguments   []CommandArg
+	Subcommands map[string]CommandDoc
+}
+
+// CommandArg represents an argument in the reply to the COMMAND DOCS
+// command. Arguments of type "block" and "oneof" contain nested arguments.
+type CommandArg struct {
+	Name        string
+	Type        string
+	DisplayText string
+
+	// The index of the argument's key specification in CommandInfo
+	// KeySpecs or -1 if the argument is not a key.
+	KeySpecIndex int
+
+	Token     string
+	Summary   string
+	Since     string
+	Flags     []string
+	Arguments []CommandArg
+}
+
+// CommandInfos is a helper that converts the reply to the COMMAND or COMMAND
+// INFO command to a slice of CommandInfo. The element for an unknown command
+// is the zero value.
+func CommandInfos(reply interface{}, err error) ([]CommandInfo, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]CommandInfo, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if result[i], err = commandInfo(v); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func commandInfo(v interface{}) (CommandInfo, error) {
+	var ci CommandInfo
+	fields, err := Values(v, nil)
+	if err != nil {
+		return ci, err
+	}
+	if len(fields) < 6 {
+		return ci, fmt.Errorf("redigo: CommandInfos expects at least 6 fields, got %d", len(fields))
+	}
+	if ci.Name, err = String(fields[0], nil); err != nil {
+		return ci, err
+	}
+	if ci.Arity, err = Int(fields[1], nil); err != nil {
+		return ci, err
+	}
+	if ci.Flags, err = statusStrings(fields[2]); err != nil {
+		return ci, err
+	}
+	if ci.FirstKey, err = Int(fields[3], nil); err != nil {
+		return ci, err
+	}
+	if ci.LastKey, err = Int(fields[4], nil); err != nil {
+		return ci, err
+	}
+	if ci.Step, err = Int(fields[5], nil); err != nil {
+		return ci, err
+	}
+	if len(fields) > 6 {
+		if ci.ACLCategories, err = statusStrings(fields[6]); err != nil {
+			return ci, err
+		}
+	}
+	if len(fields) > 7 {
+		if ci.Tips, err = statusStrings(fields[7]); err != nil {
+			return ci, err
+		}
+	}
+	if len(fields) > 8 {
+		specs, err := Values(fields[8], nil)
+		if err != nil {
+			return ci, err
+		}
+		ci.KeySpecs = make([]CommandKeySpec, len(specs))
+		for i, spec := range specs {
+			if ci.KeySpecs[i], err = commandKeySpec(spec); err != nil {
+				return ci, err
+			}
+		}
+	}
+	if len(fields) > 9 {
+		if ci.Subcommands, err = CommandInfos(fields[9], nil); err != nil {
+			return ci, err
+		}
+	}
+	return ci, nil
+}
+
+func commandKeySpec(v interface{}) (CommandKeySpec, error) {
+	var ks CommandKeySpec
+	fields, err := Values(v, nil)
+	if err != nil {
+		return ks, err
+	}
+	err = forEachPair(fields, func(name string, value interface{}) (err error) {
+		switch name {
+		case "notes":
+			ks.Notes, err = String(value, nil)
+		case "flags":
+			ks.Flags, err = statusStrings(value)
+		case "begin_search":
+			ks.BeginSearch, err = commandKeySearch(value)
+		case "find_keys":
+			ks.FindKeys, err = commandKeySearch(value)
+		}
+		return err
+	})
+	return ks, err
+}
+
+func commandKeySearch(v interface{}) (CommandKeySearch, error) {
+	var s CommandKeySearch
+	fields, err := Values(v, nil)
+	if err != nil {
+		return s, err
+	}
+	err = forEachPair(fields, func(name string, value interface{}) (err error) {
+		switch name {
+		case "type":
+			s.Type, err = String(value, nil)
+		case "spec":
+			var spec []interface{}
+			if spec, err = Values(value, nil); err != nil {
+				return err
+			}
+			s.Spec = make(map[string]interface{}, len(spec)/2)
+			err = forEachPair(spec, func(name string, value interface{}) error {
+				if p, ok := value.([]byte); ok {
+					value = string(p)
+				}
+				s.Spec[name] = value
+				return nil
+			})
+		}
+		return err
+	})
+	return s, err
+}
+
+// CommandDocs is a helper that converts the reply to the COMMAND DOCS
+// command to a map of command names to CommandDoc.
+func CommandDocs(reply interface{}, err error) (map[string]CommandDoc, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]CommandDoc, len(values)/2)
+	err = forEachPair(values, func(name string, value interface{}) error {
+		doc, err := commandDoc(value)
+		result[name] = doc
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func commandDoc(v interface{}) (CommandDoc, error) {
+	var doc CommandDoc
+	fields, err := Values(v, nil)
+	if err != nil {
+		return doc, err
+	}
+	err = forEachPair(fields, func(name string, value interface{}) (err error) {
+		switch name {
+		case "summary":
+			doc.Summary, err = String(value, nil)
+		case "since":
+			doc.Since, err = String(value, nil)
+		case "group":
+			doc.Group, err = String(value, nil)
+		case "complexity":
+			doc.Complexity, err = String(value, nil)
+		case "doc_flags":
+			doc.DocFlags, err = statusStrings(value)
+		case "arguments":
+			doc.Arguments, err = commandArgs(value)
+		case "subcommands":
+			doc.Subcommands, err = CommandDocs(value, nil)
+		}
+		return err
+	})
+	return doc, err
+}
+
+func commandArgs(v interface{}) ([]CommandArg, error) {
+	values, err := Values(v, nil)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]CommandArg, len(values))
+	for i, value := range values {
+		fields, err := Values(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		arg := &args[i]
+		arg.KeySpecIndex = -1
+		err = forEachPair(fields, func(name string, value interface{}) (err error) {
+			switch name {
+			case "name":
+				arg.Name, err = String(value, nil)
+			case "type":
+				arg.Type, err = String(value, nil)
+			case "display_text":
+				arg.DisplayText, err = String(value, nil)
+			case "key_spec_index":
+				arg.KeySpecIndex, err = Int(value, nil)
+			case "token":
+				arg.Token, err = String(value, nil)
+			case "summary":
+				arg.Summary, err = String(value, nil)
+			case "since":
+				arg.Since, err = String(value, nil)
+			case "flags":
+				arg.Flags, err = statusStrings(value)
+			case "arguments":
+				arg.Arguments, err = commandArgs(value)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// statusStrings converts an array of status or bulk strings to a []string.
+func statusStrings(v interface{}) ([]string, error) {
+	values, err := Values(v, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		if result[i], err = String(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerConfig represents server configuration parameters as returned by
+// the CONFIG GET command. The map keys are parameter names.
+type ServerConfig map[string]string
+
+// ServerConfigReply is a helper that converts the reply to the CONFIG GET
+// command to a ServerConfig.
+//
+//  cfg, err := redis.ServerConfigReply(c.Do("CONFIG", "GET", "*"))
+//  if err != nil {
+//      // handle error
+//  }
+//  maxmemory, err := cfg.MemorySize("maxmemory")
+func ServerConfigReply(reply interface{}, err error) (ServerConfig, error) {
+	m, err := StringMap(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	return ServerConfig(m), nil
+}
+
+// Int returns the value of the named parameter as an integer. The getter
+// methods return ErrNil if the parameter is not in the configuration.
+func (cfg ServerConfig) Int(name string) (int64, error) {
+	s, ok := cfg[name]
+	if !ok {
+		return 0, ErrNil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, configError(name, s)
+	}
+	return n, nil
+}
+
+// Bool returns the value of the named parameter as a boolean. The values
+// "yes" and "no" are converted to true and false.
+func (cfg ServerConfig) Bool(name string) (bool, error) {
+	s, ok := cfg[name]
+	if !ok {
+		return false, ErrNil
+	}
+	switch strings.ToLower(s) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return false, configError(name, s)
+}
+
+// Duration returns the value of the named parameter as a duration. The
+// parameter value is an integer count of unit. For example, the timeout
+// parameter is in seconds and the slowlog-log-slower-than parameter is in
+// microseconds.
+func (cfg ServerConfig) Duration(name string, unit time.Duration) (time.Duration, error) {
+	n, err := cfg.Int(name)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// MemorySize returns the value of the named parameter as a number of bytes.
+// The value may have one of the unit suffixes accepted by the server: k, kb,
+// m, mb, g and gb. The suffixes k, m and g are powers of 1000. The suffixes
+// kb, mb and gb are powers of 1024.
+func (cfg ServerConfig) MemorySize(name string) (int64, error) {
+	s, ok := cfg[name]
+	if !ok {
+		return 0, ErrNil
+	}
+	n, err := parseMemorySize(s)
+	if err != nil {
+		return 0, configError(name, s)
+	}
+	return n, nil
+}
+
+var memoryUnits = []struct {
+	suffix string
+	mul    int64
+}{
+	// Two letter suffixes are listed first so that "kb" is not matched
+	// as "b".
+	{"kb", 1 << 10},
+	{"mb", 1 << 20},
+	{"gb", 1 << 30},
+	{"k", 1000},
+	{"m", 1000 * 1000},
+	{"g", 1000 * 1000 * 1000},
+	{"b", 1},
+}
+
+func parseMemorySize(s string) (int64, error) {
+	s = strings.ToLower(s)
+	mul := int64(1)
+	for _, u := range memoryUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s = s[:len(s)-len(u.suffix)]
+			mul = u.mul
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n * mul, err
+}
+
+func configError(name, value string) error {
+	return fmt.Errorf("redigo: bad value %q for config parameter %s", value, name)
+}
+
+// ConfigChange represents a difference between two ServerConfig values. Old
+// is empty if the parameter was added and New is empty if the parameter was
+// removed.
+type ConfigChange struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// Diff returns the changes from cfg to other, sorted by parameter name.
+func (cfg ServerConfig) Diff(other ServerConfig) []ConfigChange {
+	var changes []ConfigChange
+	for name, old := range cfg {
+		if v, ok := other[name]; !ok || v != old {
+			changes = append(changes, ConfigChange{Name: name, Old: old, New: v})
+		}
+	}
+	for name, v := range other {
+		if _, ok := cfg[name]; !ok {
+			changes = append(changes, ConfigChange{Name: name, New: v})
+		}
+	}
+	sort.Sort(configChangesByName(changes))
+	return changes
+}
+
+type configChangesByName []ConfigChange
+
+func (c configChangesByName) Len() int           { return len(c) }
+func (c configChangesByName) Less(i, j int) bool { return c[i].Name < c[j].Name }
+func (c configChangesByName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
@@ -25,6 +25,7 @@ import (
 	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -46,6 +47,12 @@ type conn struct {
 	writeTimeout time.Duration
 	bw           *bufio.Writer
 
+	// pushHandler is called with RESP3 push messages.
+	pushHandler func(push []interface{})
+
+	// nativeTypes enables decoding of RESP3 replies to native types.
+	nativeTypes bool
+
 	// Scratch space for formatting argument length.
 	// '*' or '$', length, "\r\n"
 	lenScratch [32]byte
@@ -79,6 +86,17 @@ type dialOptions struct {
 	dialTLS      bool
 	skipVerify   bool
 	tlsConfig    *tls.Config
+	protocol     int
+	nativeTypes  bool
+	pushHandler  func(push []interface{})
+
+	// wrappers are applied to the connection returned from Dial. The
+	// address argument is the address passed to Dial.
+	wrappers []func(c Conn, address string) Conn
+
+	// redaction is the policy for commands reported by the logger and slow
+	// command log wrappers.
+	redaction *RedactionPolicy
 }
 
 // DialReadTimeout specifies the timeout for reading a single command reply.
@@ -127,6 +145,58 @@ func DialPassword(password string) DialOption {
 	}}
 }
 
+// DialProtocol specifies the protocol version to negotiate with the HELLO
+// command when dialing a connection. Use version 3 to enable RESP3. The
+// server's default protocol is used if this option is not specified.
+//
+// RESP3 maps, sets and attributes are returned as flattened []interface{}
+// values, doubles and big numbers are returned as []byte, booleans are
+// returned as int64 1 or 0 and verbatim strings are returned as []byte
+// without the format prefix. This mapping allows the reply helpers to work
+// with RESP3 replies. Use the DialNativeTypes option to decode RESP3 replies
+// to native Go types instead.
+func DialProtocol(version int) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.protocol = version
+	}}
+}
+
+// DialNativeTypes specifies whether RESP3 replies are decoded to native Go
+// types. When enabled, maps are returned as map[string]interface{},
+// booleans are returned as bool and doubles are returned as float64. Map
+// keys that are not strings are formatted as strings. Other reply types
+// are returned as described in DialProtocol.
+//
+// The reply helpers in this package expect the default representation of
+// maps, booleans and doubles. Use the DecodeReply helper or a type switch to
+// consume native replies.
+func DialNativeTypes(enabled bool) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.nativeTypes = enabled
+	}}
+}
+
+// DialPushHandler specifies a function to call with RESP3 push messages
+// received on the connection. When a push handler is specified, push
+// messages are not returned from Do or Receive and the application can
+// subscribe to channels and execute other commands on the same connection.
+// The replies to SUBSCRIBE and related commands are push messages. Do
+// returns a nil reply for these commands.
+//
+// The handler is called from the goroutine reading the connection and must
+// not call methods on the connection.
+//
+// Push messages are passed to the handler only while the connection reads
+// the reply to a command. Receive blocks until the reply to a command
+// arrives, so a connection that only has subscriptions does not call the
+// handler. Send a command such as PING periodically and receive the reply to
+// process the push messages on such a connection.
+func DialPushHandler(handler func(push []interface{})) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.pushHandler = handler
+	}}
+}
+
 // DialTLSConfig specifies the config to use when a TLS connection is dialed.
 //  Has no effect when not dialing a TLS connection.
 func DialTLSConfig(c *tls.Config) DialOption {
@@ -183,6 +253,8 @@ func Dial(network, address string, options ...DialOption) (Conn, error) {
 		br:           bufio.NewReader(netConn),
 		readTimeout:  do.readTimeout,
 		writeTimeout: do.writeTimeout,
+		pushHandler:  do.pushHandler,
+		nativeTypes:  do.nativeTypes,
 	}
 
 	if do.password != "" {
@@ -192,6 +264,13 @@ func Dial(network, address string, options ...DialOption) (Conn, error) {
 		}
 	}
 
+	if do.protocol != 0 {
+		if _, err := c.Do("HELLO", do.protocol); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
 	if do.db != 0 {
 		if _, err := c.Do("SELECT", do.db); err != nil {
 			netConn.Close()
@@ -199,7 +278,11 @@ func Dial(network, address string, options ...DialOption) (Conn, error) {
 		}
 	}
 
-	return c, nil
+	var conn Conn = c
+	for _, wrap := range do.wrappers {
+		conn = wrap(conn, address)
+	}
+	return conn, nil
 }
 
 func dialTLS(do *dialOptions) {
@@ -344,39 +427,50 @@ func (c *conn) writeFloat64(n float64) error {
 	return c.writeBytes(strconv.AppendFloat(c.numScratch[:0], n, 'g', -1, 64))
 }
 
-func (c *conn) writeCommand(cmd string, args []interface{}) (err error) {
+func (c *conn) writeCommand(cmd string, args []interface{}) error {
 	c.writeLen('*', 1+len(args))
-	err = c.writeString(cmd)
+	if err := c.writeString(cmd); err != nil {
+		return err
+	}
 	for _, arg := range args {
-		if err != nil {
-			break
+		if err := c.writeArg(arg, true); err != nil {
+			return err
 		}
-		switch arg := arg.(type) {
-		case string:
-			err = c.writeString(arg)
-		case []byte:
-			err = c.writeBytes(arg)
-		case int:
-			err = c.writeInt64(int64(arg))
-		case int64:
-			err = c.writeInt64(arg)
-		case float64:
-			err = c.writeFloat64(arg)
-		case bool:
-			if arg {
-				err = c.writeString("1")
-			} else {
-				err = c.writeString("0")
-			}
-		case nil:
-			err = c.writeString("")
-		default:
-			var buf bytes.Buffer
-			fmt.Fprint(&buf, arg)
-			err = c.writeBytes(buf.Bytes())
+	}
+	return nil
+}
+
+// writeArg writes a command argument. Argument values are expanded only when
+// argumentTypeOK is true to prevent infinite recursion.
+func (c *conn) writeArg(arg interface{}, argumentTypeOK bool) error {
+	switch arg := arg.(type) {
+	case string:
+		return c.writeString(arg)
+	case []byte:
+		return c.writeBytes(arg)
+	case int:
+		return c.writeInt64(int64(arg))
+	case int64:
+		return c.writeInt64(arg)
+	case float64:
+		return c.writeFloat64(arg)
+	case bool:
+		if arg {
+			return c.writeString("1")
+		}
+		return c.writeString("0")
+	case nil:
+		return c.writeString("")
+	case time.Time:
+		return c.writeBytes(arg.AppendFormat(c.numScratch[:0], time.RFC3339Nano))
+	case Argument:
+		if argumentTypeOK {
+			return c.writeArg(arg.RedisArg(), false)
 		}
 	}
-	return err
+	var buf bytes.Buffer
+	fmt.Fprint(&buf, arg)
+	return c.writeBytes(buf.Bytes())
 }
 
 type protocolError string
@@ -458,7 +552,24 @@ var (
 	pongReply interface{} = "PONG"
 )
 
+// pushFrame is a push message read for a connection with a push handler.
+type pushFrame []interface{}
+
+// readReply reads a reply. Push messages are passed to the push handler
+// until a reply is read.
 func (c *conn) readReply() (interface{}, error) {
+	for {
+		r, err := c.readFrame()
+		if p, ok := r.(pushFrame); ok {
+			c.pushHandler(p)
+			continue
+		}
+		return r, err
+	}
+}
+
+// readFrame reads a reply or a push message.
+func (c *conn) readFrame() (interface{}, error) {
 	line, err := c.readLine()
 	if err != nil {
 		return nil, err
@@ -483,42 +594,168 @@ func (c *conn) readReply() (interface{}, error) {
 	case ':':
 		return parseInt(line[1:])
 	case '$':
-		n, err := parseLen(line[1:])
-		if n < 0 || err != nil {
+		p, err := c.readBulk(line[1:])
+		if p == nil || err != nil {
 			return nil, err
 		}
-		p := make([]byte, n)
-		_, err = io.ReadFull(c.br, p)
-		if err != nil {
+		return p, nil
+	case '*':
+		return c.readArray(line[1:], 1)
+	case '_':
+		return nil, nil
+	case '#':
+		if len(line) != 2 || (line[1] != 't' && line[1] != 'f') {
+			return nil, protocolError("malformed boolean")
+		}
+		if c.nativeTypes {
+			return line[1] == 't', nil
+		}
+		if line[1] == 't' {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case ',':
+		if c.nativeTypes {
+			f, err := strconv.ParseFloat(string(line[1:]), 64)
+			if err != nil {
+				return nil, protocolError("malformed double")
+			}
+			return f, nil
+		}
+		return append([]byte(nil), line[1:]...), nil
+	case '(':
+		return append([]byte(nil), line[1:]...), nil
+	case '!':
+		p, err := c.readBulk(line[1:])
+		if p == nil || err != nil {
 			return nil, err
 		}
-		if line, err := c.readLine(); err != nil {
+		return Error(p), nil
+	case '=':
+		p, err := c.readBulk(line[1:])
+		if p == nil || err != nil {
 			return nil, err
-		} else if len(line) != 0 {
-			return nil, protocolError("bad bulk string format")
 		}
-		return p, nil
-	case '*':
-		n, err := parseLen(line[1:])
-		if n < 0 || err != nil {
+		if len(p) < 4 || p[3] != ':' {
+			return nil, protocolError("bad verbatim string format")
+		}
+		return p[4:], nil
+	case '~':
+		return c.readArray(line[1:], 1)
+	case '%':
+		if c.nativeTypes {
+			return c.readMap(line[1:])
+		}
+		return c.readArray(line[1:], 2)
+	case '|':
+		// Discard attributes and return the reply that follows.
+		if _, err := c.readArray(line[1:], 2); err != nil {
 			return nil, err
 		}
-		r := make([]interface{}, n)
-		for i := range r {
-			r[i], err = c.readReply()
-			if err != nil {
-				return nil, err
-			}
+		return c.readReply()
+	case '>':
+		r, err := c.readArray(line[1:], 1)
+		if err != nil {
+			return nil, err
+		}
+		if c.pushHandler == nil {
+			return r, nil
 		}
-		return r, nil
+		return pushFrame(r.([]interface{})), nil
 	}
 	return nil, protocolError("unexpected response line")
 }
 
+// readBulk reads a bulk string with the length in p. A nil slice is returned
+// for the null bulk string.
+func (c *conn) readBulk(p []byte) ([]byte, error) {
+	n, err := parseLen(p)
+	if n < 0 || err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(c.br, b)
+	if err != nil {
+		return nil, err
+	}
+	if line, err := c.readLine(); err != nil {
+		return nil, err
+	} else if len(line) != 0 {
+		return nil, protocolError("bad bulk string format")
+	}
+	return b, nil
+}
+
+// readArray reads an aggregate reply with the element count in p. Each
+// element is made of size values.
+func (c *conn) readArray(p []byte, size int) (interface{}, error) {
+	n, err := parseLen(p)
+	if n < 0 || err != nil {
+		return nil, err
+	}
+	r := make([]interface{}, n*size)
+	for i := range r {
+		r[i], err = c.readReply()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// readMap reads a RESP3 map with the pair count in p to a
+// map[string]interface{}.
+func (c *conn) readMap(p []byte) (interface{}, error) {
+	n, err := parseLen(p)
+	if n < 0 || err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := c.readReply()
+		if err != nil {
+			return nil, err
+		}
+		v, err := c.readReply()
+		if err != nil {
+			return nil, err
+		}
+		m[mapKey(k)] = v
+	}
+	return m, nil
+}
+
+func mapKey(k interface{}) string {
+	switch k := k.(type) {
+	case string:
+		return k
+	case []byte:
+		return string(k)
+	case int64:
+		return strconv.FormatInt(k, 10)
+	}
+	return fmt.Sprint(k)
+}
+
+// isPushCommand returns true if the reply to the command is a push message
+// when the connection has a push handler.
+func (c *conn) isPushCommand(cmd string) bool {
+	if c.pushHandler == nil {
+		return false
+	}
+	switch strings.ToUpper(cmd) {
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE":
+		return true
+	}
+	return false
+}
+
 func (c *conn) Send(cmd string, args ...interface{}) error {
-	c.mu.Lock()
-	c.pending += 1
-	c.mu.Unlock()
+	if !c.isPushCommand(cmd) {
+		c.mu.Lock()
+		c.pending += 1
+		c.mu.Unlock()
+	}
 	if c.writeTimeout != 0 {
 		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
 	}
@@ -538,10 +775,57 @@ func (c *conn) Flush() error {
 	return nil
 }
 
-func (c *conn) Receive() (reply interface{}, err error) {
+func (c *conn) Receive() (interface{}, error) {
 	if c.readTimeout != 0 {
 		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
 	}
+	return c.receive()
+}
+
+// ErrReceiveTimeout is returned from ReceiveWithTimeout when a reply does not
+// arrive before the timeout expires.
+var ErrReceiveTimeout = errors.New("redigo: receive timeout")
+
+var errTimeoutNotSupported = errors.New("redigo: connection does not support ConnWithTimeout")
+
+// ReceiveWithTimeout receives a reply from c with the specified timeout. If
+// the connection does not implement ConnWithTimeout, then an error is
+// returned.
+func ReceiveWithTimeout(c Conn, timeout time.Duration) (interface{}, error) {
+	cwt, ok := c.(ConnWithTimeout)
+	if !ok {
+		return nil, errTimeoutNotSupported
+	}
+	return cwt.ReceiveWithTimeout(timeout)
+}
+
+func (c *conn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	var deadline time.Time
+	if timeout != 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	c.conn.SetReadDeadline(deadline)
+
+	// Wait for the start of the reply. If the timeout expires first, then no
+	// part of the reply is consumed and the connection remains usable.
+	if _, err := c.br.Peek(1); err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return nil, ErrReceiveTimeout
+		}
+		return nil, c.fatal(err)
+	}
+
+	// Read the rest of the reply with the connection's read timeout.
+	if c.readTimeout != 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	} else {
+		c.conn.SetReadDeadline(time.Time{})
+	}
+	return c.receive()
+}
+
+// receive reads a reply using the read deadline set by the caller.
+func (c *conn) receive() (reply interface{}, err error) {
 	if reply, err = c.readReply(); err != nil {
 		return nil, c.fatal(err)
 	}
@@ -591,6 +875,16 @@ func (c *conn) Do(cmd string, args ...interface{}) (interface{}, error) {
 		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
 	}
 
+	if c.isPushCommand(cmd) {
+		// The reply to the command is delivered to the push handler.
+		for i := 0; i < pending; i++ {
+			if _, e := c.readReply(); e != nil {
+				return nil, c.fatal(e)
+			}
+		}
+		return nil, nil
+	}
+
 	if cmd == "" {
 		reply := make([]interface{}, pending)
 		for i := range reply {
@@ -16,6 +16,7 @@ package redis_test
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"math"
 	"net"
@@ -74,6 +75,10 @@ var writeTests = []struct {
 		[]interface{}{"SET", "key", float64(1349673917.939762)},
 		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$21\r\n1.349673917939762e+09\r\n",
 	},
+	{
+		[]interface{}{"SET", "key", time.Date(2017, 1, 2, 3, 4, 5, 6, time.UTC)},
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$30\r\n2017-01-02T03:04:05.000000006Z\r\n",
+	},
 	{
 		[]interface{}{"SET", "key", ""},
 		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$0\r\n\r\n",
@@ -86,6 +91,10 @@ var writeTests = []struct {
 		[]interface{}{"ECHO", true, false},
 		"*3\r\n$4\r\nECHO\r\n$1\r\n1\r\n$1\r\n0\r\n",
 	},
+	{
+		[]interface{}{"SET", "key", millis(1500 * time.Millisecond)},
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$4\r\n1500\r\n",
+	},
 }
 
 func TestWrite(t *testing.T) {
@@ -155,6 +164,42 @@ var readTests = []struct {
 		"*3\r\n$3\r\nfoo\r\n$-1\r\n$3\r\nbar\r\n",
 		[]interface{}{[]byte("foo"), nil, []byte("bar")},
 	},
+	{
+		"_\r\n",
+		nil,
+	},
+	{
+		"#t\r\n",
+		int64(1),
+	},
+	{
+		",3.14\r\n",
+		[]byte("3.14"),
+	},
+	{
+		"(3492890328409238509324850943850943825024385\r\n",
+		[]byte("3492890328409238509324850943850943825024385"),
+	},
+	{
+		"=15\r\ntxt:Some string\r\n",
+		[]byte("Some string"),
+	},
+	{
+		"%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n",
+		[]interface{}{"first", int64(1), "second", int64(2)},
+	},
+	{
+		"~2\r\n+orange\r\n+apple\r\n",
+		[]interface{}{"orange", "apple"},
+	},
+	{
+		"|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.19\r\n*1\r\n:2039123\r\n",
+		[]interface{}{int64(2039123)},
+	},
+	{
+		">2\r\n$7\r\nmessage\r\n$3\r\nfoo\r\n",
+		[]interface{}{[]byte("message"), []byte("foo")},
+	},
 
 	{
 		// "x" is not a valid length
@@ -186,6 +231,16 @@ var readTests = []struct {
 		"$6\r\nfoobarx\r\n",
 		errorSentinel,
 	},
+	{
+		// "x" is not a valid boolean
+		"#x\r\n",
+		errorSentinel,
+	},
+	{
+		// missing verbatim string format
+		"=3\r\nabc\r\n",
+		errorSentinel,
+	},
 }
 
 func TestRead(t *testing.T) {
@@ -208,6 +263,97 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestPushHandler(t *testing.T) {
+	var buf bytes.Buffer
+	r := strings.NewReader("" +
+		"%1\r\n+proto\r\n:3\r\n" +
+		">3\r\n$9\r\nsubscribe\r\n$1\r\nc\r\n:1\r\n" +
+		"+OK\r\n" +
+		">3\r\n$7\r\nmessage\r\n$1\r\nc\r\n$5\r\nhello\r\n" +
+		"$3\r\nbar\r\n")
+	var pushes [][]interface{}
+	c, err := redis.Dial("", "",
+		dialTestConn(r, &buf),
+		redis.DialProtocol(3),
+		redis.DialPushHandler(func(push []interface{}) { pushes = append(pushes, push) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := c.Do("SUBSCRIBE", "c"); v != nil || err != nil {
+		t.Fatalf("Do(SUBSCRIBE) = %v, %v, want nil, nil", v, err)
+	}
+	if v, err := redis.String(c.Do("SET", "k", "bar")); v != "OK" || err != nil {
+		t.Fatalf("Do(SET) = %v, %v, want OK, nil", v, err)
+	}
+	if v, err := redis.String(c.Do("GET", "k")); v != "bar" || err != nil {
+		t.Fatalf("Do(GET) = %v, %v, want bar, nil", v, err)
+	}
+
+	expected := [][]interface{}{
+		{[]byte("subscribe"), []byte("c"), int64(1)},
+		{[]byte("message"), []byte("c"), []byte("hello")},
+	}
+	if !reflect.DeepEqual(pushes, expected) {
+		t.Errorf("pushes = %v, want %v", pushes, expected)
+	}
+	if !strings.HasPrefix(buf.String(), "*2\r\n$5\r\nHELLO\r\n$1\r\n3\r\n") {
+		t.Errorf("commands = %q, want HELLO 3 first", buf.String())
+	}
+}
+
+func TestPushHandlerBurst(t *testing.T) {
+	const n = 100000
+	var buf bytes.Buffer
+	buf.WriteString("%1\r\n+proto\r\n:3\r\n")
+	for i := 0; i < n; i++ {
+		buf.WriteString(">3\r\n$7\r\nmessage\r\n$1\r\nc\r\n$1\r\nx\r\n")
+	}
+	buf.WriteString("+PONG\r\n")
+	count := 0
+	c, err := redis.Dial("", "",
+		dialTestConn(&buf, &bytes.Buffer{}),
+		redis.DialProtocol(3),
+		redis.DialPushHandler(func(push []interface{}) { count++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := redis.String(c.Do("PING")); v != "PONG" || err != nil {
+		t.Fatalf("Do(PING) = %v, %v, want PONG, nil", v, err)
+	}
+	if count != n {
+		t.Errorf("handler called %d times, want %d", count, n)
+	}
+}
+
+func TestNativeTypes(t *testing.T) {
+	var buf bytes.Buffer
+	r := strings.NewReader("" +
+		"%1\r\n+proto\r\n:3\r\n" +
+		"%3\r\n$4\r\nname\r\n$1\r\nx\r\n:1\r\n#t\r\n$4\r\nsubs\r\n%1\r\n$1\r\na\r\n*2\r\n,1.5\r\n_\r\n" +
+		"#f\r\n")
+	c, err := redis.Dial("", "", dialTestConn(r, &buf), redis.DialProtocol(3), redis.DialNativeTypes(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := redis.DecodeReply(c.Receive())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"name": "x",
+		"1":    true,
+		"subs": map[string]interface{}{"a": []interface{}{1.5, nil}},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("DecodeReply() = %#v, want %#v", v, expected)
+	}
+	if v, err := c.Receive(); v != false || err != nil {
+		t.Errorf("Receive() = %v, %v, want false, nil", v, err)
+	}
+}
+
 var testCommands = []struct {
 	args     []interface{}
 	expected interface{}
@@ -383,6 +529,62 @@ func TestError(t *testing.T) {
 	}
 }
 
+var errorCodeTests = []struct {
+	err     redis.Error
+	code    string
+	message string
+}{
+	{"WRONGTYPE Operation against a key holding the wrong kind of value", "WRONGTYPE", "Operation against a key holding the wrong kind of value"},
+	{"ERR unknown command", "ERR", "unknown command"},
+	{"BUSYGROUP", "BUSYGROUP", ""},
+	{"Error without code", "", "Error without code"},
+	{"", "", ""},
+}
+
+func TestErrorCode(t *testing.T) {
+	for _, tt := range errorCodeTests {
+		if code := tt.err.Code(); code != tt.code {
+			t.Errorf("Error(%q).Code() = %q, want %q", tt.err, code, tt.code)
+		}
+		if message := tt.err.Message(); message != tt.message {
+			t.Errorf("Error(%q).Message() = %q, want %q", tt.err, message, tt.message)
+		}
+		if code := redis.ErrorCode(tt.err); code != tt.code {
+			t.Errorf("ErrorCode(%q) = %q, want %q", tt.err, code, tt.code)
+		}
+	}
+	if code := redis.ErrorCode(errors.New("ERR not a reply")); code != "" {
+		t.Errorf("ErrorCode(non-reply error) = %q, want \"\"", code)
+	}
+}
+
+func TestFunction(t *testing.T) {
+	var buf bytes.Buffer
+	r := strings.NewReader("-ERR Function not found\r\n+mylib\r\n:1\r\n:2\r\n")
+	c, err := redis.Dial("", "", dialTestConn(r, &buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib := redis.NewLibrary("#!lua name=mylib\nredis.register_function('f', function() return 1 end)")
+	if lib.Name() != "mylib" {
+		t.Errorf("Name() = %q, want mylib", lib.Name())
+	}
+	f := lib.Function(1, "f")
+	if v, err := redis.Int(f.Do(c, "k", "a")); err != nil || v != 1 {
+		t.Errorf("Do() = %v, %v, want 1, nil", v, err)
+	}
+	if v, err := redis.Int(redis.NewFunction(-1, "g").DoRO(c, 0)); err != nil || v != 2 {
+		t.Errorf("DoRO() = %v, %v, want 2, nil", v, err)
+	}
+	expected := "*5\r\n$5\r\nFCALL\r\n$1\r\nf\r\n$1\r\n1\r\n$1\r\nk\r\n$1\r\na\r\n" +
+		"*3\r\n$8\r\nFUNCTION\r\n$4\r\nLOAD\r\n$70\r\n#!lua name=mylib\nredis.register_function('f', function() return 1 end)\r\n" +
+		"*5\r\n$5\r\nFCALL\r\n$1\r\nf\r\n$1\r\n1\r\n$1\r\nk\r\n$1\r\na\r\n" +
+		"*3\r\n$8\r\nFCALL_RO\r\n$1\r\ng\r\n$1\r\n0\r\n"
+	if buf.String() != expected {
+		t.Errorf("commands = %q, want %q", buf.String(), expected)
+	}
+}
+
 func TestReadTimeout(t *testing.T) {
 	l, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
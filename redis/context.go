@@ -0,0 +1,185 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/internal"
+)
+
+// ConnWithContext is an optional interface that allows the caller to abandon
+// a call when a context is done.
+type ConnWithContext interface {
+	Conn
+
+	// ReceiveContext receives a single reply from the Redis server. If the
+	// context is done before the reply starts to arrive, then ReceiveContext
+	// returns the context's error and the connection remains usable.
+	ReceiveContext(ctx context.Context) (reply interface{}, err error)
+
+	// DoContext sends a command to the server and returns the received
+	// reply. If the context is done before the reply is received, then
+	// DoContext closes the connection and returns the context's error.
+	DoContext(ctx context.Context, commandName string, args ...interface{}) (reply interface{}, err error)
+}
+
+var errContextNotSupported = errors.New("redigo: connection does not support ConnWithContext")
+
+// aLongTimeAgo is a read deadline used to unblock a pending read.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// ReceiveContext receives a reply from c using the specified context. If the
+// connection does not implement ConnWithContext, then an error is returned.
+func ReceiveContext(c Conn, ctx context.Context) (interface{}, error) {
+	cwc, ok := c.(ConnWithContext)
+	if !ok {
+		return nil, errContextNotSupported
+	}
+	return cwc.ReceiveContext(ctx)
+}
+
+// DoContext sends a command to c and returns the received reply using the
+// specified context. If the connection does not implement ConnWithContext,
+// then an error is returned.
+func DoContext(c Conn, ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	cwc, ok := c.(ConnWithContext)
+	if !ok {
+		return nil, errContextNotSupported
+	}
+	return cwc.DoContext(ctx, commandName, args...)
+}
+
+func (c *conn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// The reply to an abandoned command is not read, so the connection is
+	// closed when the context is done.
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			c.fatal(ctx.Err())
+		case <-done:
+		}
+	}()
+	reply, err := c.Do(commandName, args...)
+	close(done)
+	<-exited
+
+	if err != nil && ctx.Err() != nil && c.Err() == ctx.Err() {
+		return nil, ctx.Err()
+	}
+	return reply, err
+}
+
+func (c *conn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	var deadline time.Time
+	if c.readTimeout != 0 {
+		deadline = time.Now().Add(c.readTimeout)
+	}
+	c.conn.SetReadDeadline(deadline)
+
+	// Wait for the start of the reply. If the context is done first, then
+	// no part of the reply is consumed and the connection remains usable.
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			c.conn.SetReadDeadline(aLongTimeAgo)
+		case <-done:
+		}
+	}()
+	_, err := c.br.Peek(1)
+	close(done)
+	<-exited
+
+	if err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() && ctx.Err() != nil {
+			c.conn.SetReadDeadline(deadline)
+			return nil, ctx.Err()
+		}
+		return nil, c.fatal(err)
+	}
+
+	// Restore the deadline in case the context was done after the start of
+	// the reply arrived.
+	c.conn.SetReadDeadline(deadline)
+	return c.receive()
+}
+
+func (pc *pooledConnection) ReceiveContext(ctx context.Context) (interface{}, error) {
+	reply, err := ReceiveContext(pc.c, ctx)
+	return reply, pc.checkError(err)
+}
+
+func (pc *pooledConnection) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	ci := internal.LookupCommandInfo(commandName)
+	pc.state = (pc.state | ci.Set) &^ ci.Clear
+	reply, err := DoContext(pc.c, ctx, commandName, args...)
+	return reply, pc.checkError(err)
+}
+
+func (ec errorConnection) ReceiveContext(context.Context) (interface{}, error) {
+	return nil, ec.err
+}
+
+func (ec errorConnection) DoContext(context.Context, string, ...interface{}) (interface{}, error) {
+	return nil, ec.err
+}
+
+func (c *loggingConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	reply, err := ReceiveContext(c.Conn, ctx)
+	c.print("ReceiveContext", "", nil, reply, err)
+	return reply, err
+}
+
+func (c *loggingConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := DoContext(c.Conn, ctx, commandName, args...)
+	c.print("DoContext", commandName, args, reply, err)
+	return reply, err
+}
+
+// DoContext is like Do, but it uses the DoContext function to evaluate the
+// script. There is no context variant of Send because Send does not wait
+// for the server.
+func (s *Script) DoContext(c Conn, ctx context.Context, keysAndArgs ...interface{}) (interface{}, error) {
+	eval, evalsha := s.commands()
+	v, err := DoContext(c, ctx, evalsha, s.args(s.hash, keysAndArgs)...)
+	if e, ok := err.(Error); ok && strings.HasPrefix(string(e), "NOSCRIPT ") {
+		v, err = DoContext(c, ctx, eval, s.args(s.src, keysAndArgs)...)
+	}
+	return v, err
+}
+
+// ReceiveContext is like Receive, but it returns the context's error if the
+// context is done before a message arrives. The connection remains usable
+// after the context is done, so the application can continue to receive
+// messages or unsubscribe.
+func (c PubSubConn) ReceiveContext(ctx context.Context) interface{} {
+	return c.receiveInternal(ReceiveContext(c.Conn, ctx))
+}
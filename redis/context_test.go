@@ -0,0 +1,115 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestPubSubReceiveContext(t *testing.T) {
+	pc, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	sc, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer sc.Close()
+
+	c := redis.PubSubConn{Conn: sc}
+
+	c.Subscribe("c1")
+	expectPushed(t, c, "Subscribe(c1)", redis.Subscription{Kind: "subscribe", Channel: "c1", Count: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err, ok := c.ReceiveContext(ctx).(error); !ok || err != context.DeadlineExceeded {
+		t.Fatalf("ReceiveContext() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The connection is usable after the context is done.
+	pc.Do("PUBLISH", "c1", "hello")
+	expectPushed(t, c, "PUBLISH c1 hello", redis.Message{Channel: "c1", Data: []byte("hello")})
+
+	pc.Do("PUBLISH", "c1", "world")
+	v := c.ReceiveContext(context.Background())
+	if m, ok := v.(redis.Message); !ok || string(m.Data) != "world" {
+		t.Fatalf("ReceiveContext() returned %v, want message world", v)
+	}
+}
+
+func TestPubSubReceiveContextNoReadTimeout(t *testing.T) {
+	pc, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	sc, err := redis.DialDefaultServerOptions()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer sc.Close()
+
+	c := redis.PubSubConn{Conn: sc}
+
+	c.Subscribe("c1")
+	expectPushed(t, c, "Subscribe(c1)", redis.Subscription{Kind: "subscribe", Channel: "c1", Count: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err, ok := c.ReceiveContext(ctx).(error); !ok || err != context.Canceled {
+		t.Fatalf("ReceiveContext() returned %v, want %v", err, context.Canceled)
+	}
+
+	// The read deadline set to abandon the receive must not leak into the
+	// next receive on a connection without a read timeout.
+	pc.Do("PUBLISH", "c1", "hello")
+	expectPushed(t, c, "PUBLISH c1 hello", redis.Message{Channel: "c1", Data: []byte("hello")})
+}
+
+func TestDoContext(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	s := redis.NewScript(0, "return 1")
+	if v, err := redis.Int(s.DoContext(c, context.Background())); err != nil || v != 1 {
+		t.Fatalf("Script.DoContext() = %v, %v, want 1, nil", v, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := redis.DoContext(c, ctx, "DEBUG", "SLEEP", "0.2"); err != context.DeadlineExceeded {
+		t.Fatalf("DoContext() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if c.Err() == nil {
+		t.Fatal("connection not closed after context done")
+	}
+	if _, err := s.DoContext(c, ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Script.DoContext() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -127,7 +127,7 @@
 // send and flush a subscription management command. The receive method
 // converts a pushed message to convenient types for use in a type switch.
 //
-//  psc := redis.PubSubConn{c}
+//  psc := redis.NewPubSubConn(c)
 //  psc.Subscribe("example")
 //  for {
 //      switch v := psc.Receive().(type) {
@@ -0,0 +1,204 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"strings"
+)
+
+// Library encapsulates the source of a Redis Functions library. See
+// https://redis.io/docs/manual/programmability/functions-intro/ for
+// information on functions in Redis.
+type Library struct {
+	name string
+	src  string
+}
+
+// NewLibrary returns a new library object. The library name is read from
+// the name parameter in the shebang line at the start of the source.
+func NewLibrary(src string) *Library {
+	l := &Library{src: src}
+	line := src
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if strings.HasPrefix(line, "#!") {
+		for _, f := range strings.Fields(line[2:]) {
+			if strings.HasPrefix(f, "name=") {
+				l.name = f[len("name="):]
+			}
+		}
+	}
+	return l
+}
+
+// Name returns the library name.
+func (l *Library) Name() string {
+	return l.name
+}
+
+// Load loads the library with the FUNCTION LOAD command. If replace is
+// true, then an existing library with the same name is replaced.
+func (l *Library) Load(c Conn, replace bool) error {
+	args := Args{"LOAD"}
+	if replace {
+		args = append(args, "REPLACE")
+	}
+	_, err := c.Do("FUNCTION", append(args, l.src)...)
+	return err
+}
+
+// Function returns a function in the library. See NewFunction for the
+// meaning of keyCount.
+//
+// The Do and DoRO methods of the returned function load the library when
+// the function is not found on the server.
+func (l *Library) Function(keyCount int, name string) *Function {
+	return &Function{keyCount: keyCount, name: name, lib: l}
+}
+
+// Function represents a function called with the FCALL and FCALL_RO
+// commands.
+type Function struct {
+	keyCount int
+	name     string
+	lib      *Library
+}
+
+// NewFunction returns a new function object. If keyCount is greater than or
+// equal to zero, then the count is automatically inserted in the FCALL
+// command argument list. If keyCount is less than zero, then the
+// application supplies the count as the first value in the keysAndArgs
+// argument to the Do, DoRO and Send methods.
+func NewFunction(keyCount int, name string) *Function {
+	return &Function{keyCount: keyCount, name: name}
+}
+
+func (f *Function) args(keysAndArgs []interface{}) []interface{} {
+	return evalArgs(f.name, f.keyCount, keysAndArgs)
+}
+
+func (f *Function) do(c Conn, cmd string, keysAndArgs []interface{}) (interface{}, error) {
+	v, err := c.Do(cmd, f.args(keysAndArgs)...)
+	if e, ok := err.(Error); ok && f.lib != nil && strings.HasPrefix(string(e), "ERR Function not found") {
+		if err := f.lib.Load(c, false); err != nil {
+			return nil, err
+		}
+		v, err = c.Do(cmd, f.args(keysAndArgs)...)
+	}
+	return v, err
+}
+
+// Do calls the function with the FCALL command.
+func (f *Function) Do(c Conn, keysAndArgs ...interface{}) (interface{}, error) {
+	return f.do(c, "FCALL", keysAndArgs)
+}
+
+// DoRO calls the function with the FCALL_RO command. The function must be
+// registered with the no-writes flag.
+func (f *Function) DoRO(c Conn, keysAndArgs ...interface{}) (interface{}, error) {
+	return f.do(c, "FCALL_RO", keysAndArgs)
+}
+
+// Send calls the function with the FCALL command without waiting for the
+// reply.
+func (f *Function) Send(c Conn, keysAndArgs ...interface{}) error {
+	return c.Send("FCALL", f.args(keysAndArgs)...)
+}
+
+// FunctionLibrary represents a library in the reply to the FUNCTION LIST
+// command.
+type FunctionLibrary struct {
+	Name      string
+	Engine    string
+	Functions []FunctionInfo
+
+	// Code is set when the WITHCODE option is used.
+	Code string
+}
+
+// FunctionInfo represents a function in the reply to the FUNCTION LIST
+// command.
+type FunctionInfo struct {
+	Name        string
+	Description string
+	Flags       []string
+}
+
+// FunctionLibraries is a helper that converts the reply to the FUNCTION
+// LIST command to a []FunctionLibrary.
+func FunctionLibraries(reply interface{}, err error) ([]FunctionLibrary, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	libs := make([]FunctionLibrary, len(values))
+	for i, v := range values {
+		fields, err := Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		lib := &libs[i]
+		err = forEachPair(fields, func(name string, value interface{}) (err error) {
+			switch name {
+			case "library_name":
+				lib.Name, err = String(value, nil)
+			case "engine":
+				lib.Engine, err = String(value, nil)
+			case "library_code":
+				lib.Code, err = String(value, nil)
+			case "functions":
+				lib.Functions, err = functionInfos(value)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return libs, nil
+}
+
+func functionInfos(reply interface{}) ([]FunctionInfo, error) {
+	values, err := Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	functions := make([]FunctionInfo, len(values))
+	for i, v := range values {
+		fields, err := Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		f := &functions[i]
+		err = forEachPair(fields, func(name string, value interface{}) (err error) {
+			switch name {
+			case "name":
+				f.Name, err = String(value, nil)
+			case "description":
+				if value != nil {
+					f.Description, err = String(value, nil)
+				}
+			case "flags":
+				f.Flags, err = statusStrings(value)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return functions, nil
+}
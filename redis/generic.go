@@ -0,0 +1,91 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.18
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reply is a helper that converts a command reply to a value of type T. If
+// err is not equal to nil, then Reply returns the zero value of T and err.
+// Reply returns ErrNil for a nil reply and the error for an error reply.
+//
+// The reply is converted using the rules of the Scan function. Types whose
+// pointer implements Scanner control their own conversion.
+//
+//  n, err := redis.Reply[int64](c.Do("INCR", "counter"))
+func Reply[T any](reply interface{}, err error) (T, error) {
+	var v T
+	if err != nil {
+		return v, err
+	}
+	switch reply := reply.(type) {
+	case nil:
+		return v, ErrNil
+	case Error:
+		return v, reply
+	}
+	if err := convertAssign(&v, reply); err != nil {
+		return v, fmt.Errorf("redigo: cannot convert reply to %T: %v", v, err)
+	}
+	return v, nil
+}
+
+// Slice is a helper that converts an array command reply to a []T. If err
+// is not equal to nil, then Slice returns nil, err. Nil array items are
+// converted to the zero value of T. The items are converted using the rules
+// of the Reply function.
+func Slice[T any](reply interface{}, err error) ([]T, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]T, len(values))
+	for i, value := range values {
+		if err := convertAssign(&result[i], value); err != nil {
+			return nil, fmt.Errorf("redigo: cannot convert element %d to %T: %v", i, result[i], err)
+		}
+	}
+	return result, nil
+}
+
+// Map is a helper that converts an array of alternating keys and values to
+// a map[string]T. The HGETALL and CONFIG GET commands return replies in this
+// format. The values are converted using the rules of the Reply function.
+func Map[T any](reply interface{}, err error) (map[string]T, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, errors.New("redigo: Map expects even number of values result")
+	}
+	m := make(map[string]T, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].([]byte)
+		if !ok {
+			return nil, errors.New("redigo: Map key not a bulk string value")
+		}
+		var value T
+		if err := convertAssign(&value, values[i+1]); err != nil {
+			return nil, fmt.Errorf("redigo: cannot convert value for %s to %T: %v", key, value, err)
+		}
+		m[string(key)] = value
+	}
+	return m, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.18
+
+package redis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// upper is a Scanner that converts bulk strings to upper case.
+type upper string
+
+func (u *upper) RedisScan(src interface{}) error {
+	p, _ := src.([]byte)
+	*u = upper(strings.ToUpper(string(p)))
+	return nil
+}
+
+var genericReplyTests = []struct {
+	name     interface{}
+	actual   valueError
+	expected valueError
+}{
+	{
+		"Reply[int64](1)",
+		ve(redis.Reply[int64](int64(1), nil)),
+		ve(int64(1), nil),
+	},
+	{
+		"Reply[float64](1.5)",
+		ve(redis.Reply[float64]([]byte("1.5"), nil)),
+		ve(float64(1.5), nil),
+	},
+	{
+		"Reply[string](OK)",
+		ve(redis.Reply[string]("OK", nil)),
+		ve("OK", nil),
+	},
+	{
+		"Reply[string](nil)",
+		ve(redis.Reply[string](nil, nil)),
+		ve("", redis.ErrNil),
+	},
+	{
+		"Reply[upper](abc)",
+		ve(redis.Reply[upper]([]byte("abc"), nil)),
+		ve(upper("ABC"), nil),
+	},
+	{
+		"Slice[int]([1, nil, 3])",
+		ve(redis.Slice[int]([]interface{}{[]byte("1"), nil, int64(3)}, nil)),
+		ve([]int{1, 0, 3}, nil),
+	},
+	{
+		"Slice[upper]([a, b])",
+		ve(redis.Slice[upper]([]interface{}{[]byte("a"), []byte("b")}, nil)),
+		ve([]upper{"A", "B"}, nil),
+	},
+	{
+		"Slice[int](nil)",
+		ve(redis.Slice[int](nil, nil)),
+		ve([]int(nil), redis.ErrNil),
+	},
+	{
+		"Map[float64]([a, 1.5])",
+		ve(redis.Map[float64]([]interface{}{[]byte("a"), []byte("1.5")}, nil)),
+		ve(map[string]float64{"a": 1.5}, nil),
+	},
+}
+
+func TestGenericReply(t *testing.T) {
+	for _, rt := range genericReplyTests {
+		if rt.actual.err != rt.expected.err {
+			t.Errorf("%s returned err %v, want %v", rt.name, rt.actual.err, rt.expected.err)
+			continue
+		}
+		if !reflect.DeepEqual(rt.actual.v, rt.expected.v) {
+			t.Errorf("%s=%+v, want %+v", rt.name, rt.actual.v, rt.expected.v)
+		}
+	}
+
+	if _, err := redis.Reply[int]([]byte("x"), nil); err == nil {
+		t.Error("Reply[int](x) did not return error")
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+)
+
+// GeoPos represents the position of a member in a geospatial index.
+type GeoPos struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// GeoLocation represents a member in the reply to the GEOSEARCH, GEORADIUS
+// and GEORADIUSBYMEMBER commands. The Dist, Hash and Pos fields are set only
+// when requested with the WITHDIST, WITHHASH and WITHCOORD options.
+type GeoLocation struct {
+	Name string
+	Dist float64
+	Hash int64
+	Pos  *GeoPos
+}
+
+// GeoPositions is a helper that converts the reply to the GEOPOS command to
+// a slice of *GeoPos. The element for a missing member is nil.
+func GeoPositions(reply interface{}, err error) ([]*GeoPos, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]*GeoPos, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if positions[i], err = geoPos(v); err != nil {
+			return nil, err
+		}
+	}
+	return positions, nil
+}
+
+// GeoLocations is a helper that converts the reply to the GEOSEARCH,
+// GEORADIUS or GEORADIUSBYMEMBER command to a slice of GeoLocation. The
+// helper accepts replies for any combination of the WITHDIST, WITHHASH and
+// WITHCOORD options.
+func GeoLocations(reply interface{}, err error) ([]GeoLocation, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]GeoLocation, len(values))
+	for i, v := range values {
+		loc := &locations[i]
+		fields, ok := v.([]interface{})
+		if !ok {
+			// The reply is the member name when no options are given.
+			if loc.Name, err = String(v, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("redigo: GeoLocations expects member name, got empty array")
+		}
+		if loc.Name, err = String(fields[0], nil); err != nil {
+			return nil, err
+		}
+		// The optional fields are returned in the order dist, hash,
+		// coord. Each has a distinct reply type.
+		for _, f := range fields[1:] {
+			switch f := f.(type) {
+			case []byte:
+				loc.Dist, err = Float64(f, nil)
+			case int64:
+				loc.Hash = f
+			case []interface{}:
+				loc.Pos, err = geoPos(f)
+			default:
+				err = fmt.Errorf("redigo: unexpected element type for GeoLocations, got type %T", f)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return locations, nil
+}
+
+func geoPos(v interface{}) (*GeoPos, error) {
+	coord, err := Float64s(v, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(coord) != 2 {
+		return nil, fmt.Errorf("redigo: GeoPos expects two coordinates, got %d", len(coord))
+	}
+	return &GeoPos{Longitude: coord[0], Latitude: coord[1]}, nil
+}
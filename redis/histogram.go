@@ -0,0 +1,196 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyRecorder is a Hook that records command latencies in histograms.
+// Use a LatencyRecorder to get latency percentiles without a metrics
+// system. The zero value is ready to use.
+//
+// The histograms have a resolution of one microsecond and a relative error
+// of about three percent.
+type LatencyRecorder struct {
+	// Family specifies an optional function for grouping commands in
+	// histograms. If nil, then commands are grouped by the upper case
+	// command name.
+	Family func(commandName string) string
+
+	mu    sync.Mutex
+	hists map[string]*histogram
+}
+
+// LatencySnapshot is the latency distribution for a command family.
+type LatencySnapshot struct {
+	Family string
+	Count  int64
+	Min    time.Duration
+	Max    time.Duration
+	Mean   time.Duration
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+}
+
+var _ Hook = (*LatencyRecorder)(nil)
+
+// BeforeCommand implements the Hook interface.
+func (r *LatencyRecorder) BeforeCommand(ctx context.Context, commandName string, args []interface{}) context.Context {
+	return ctx
+}
+
+// AfterCommand implements the Hook interface.
+func (r *LatencyRecorder) AfterCommand(ctx context.Context, commandName string, d time.Duration, reply interface{}, err error) {
+	r.Record(commandName, d)
+}
+
+// Record records a command latency.
+func (r *LatencyRecorder) Record(commandName string, d time.Duration) {
+	var family string
+	if r.Family != nil {
+		family = r.Family(commandName)
+	} else {
+		family = strings.ToUpper(commandName)
+	}
+	r.mu.Lock()
+	if r.hists == nil {
+		r.hists = make(map[string]*histogram)
+	}
+	h := r.hists[family]
+	if h == nil {
+		h = &histogram{}
+		r.hists[family] = h
+	}
+	h.record(d)
+	r.mu.Unlock()
+}
+
+// Snapshot returns the latency distributions sorted by family.
+func (r *LatencyRecorder) Snapshot() []LatencySnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]LatencySnapshot, 0, len(r.hists))
+	for family, h := range r.hists {
+		result = append(result, LatencySnapshot{
+			Family: family,
+			Count:  h.count,
+			Min:    h.min,
+			Max:    h.max,
+			Mean:   time.Duration(int64(h.sum) / h.count),
+			P50:    h.percentile(0.50),
+			P95:    h.percentile(0.95),
+			P99:    h.percentile(0.99),
+		})
+	}
+	sort.Sort(latencySnapshotsByFamily(result))
+	return result
+}
+
+// Reset discards the recorded latencies.
+func (r *LatencyRecorder) Reset() {
+	r.mu.Lock()
+	r.hists = nil
+	r.mu.Unlock()
+}
+
+type latencySnapshotsByFamily []LatencySnapshot
+
+func (s latencySnapshotsByFamily) Len() int           { return len(s) }
+func (s latencySnapshotsByFamily) Less(i, j int) bool { return s[i].Family < s[j].Family }
+func (s latencySnapshotsByFamily) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// The histogram buckets are log-linear in microseconds. Values less than
+// 2*histSub are counted exactly. Larger values are counted in histSub
+// buckets for each power of two.
+const (
+	histSubBits = 4
+	histSub     = 1 << histSubBits
+	histBuckets = (64 - histSubBits) * histSub
+)
+
+type histogram struct {
+	counts   [histBuckets]int64
+	count    int64
+	sum      time.Duration
+	min, max time.Duration
+}
+
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+	h.counts[histIndex(uint64(d/time.Microsecond))]++
+}
+
+// percentile returns the value at quantile q, clamped to the recorded range.
+func (h *histogram) percentile(q float64) time.Duration {
+	rank := int64(q*float64(h.count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var n int64
+	for i, count := range h.counts {
+		n += count
+		if n >= rank {
+			d := time.Duration(histValue(i)) * time.Microsecond
+			if d < h.min {
+				d = h.min
+			}
+			if d > h.max {
+				d = h.max
+			}
+			return d
+		}
+	}
+	return h.max
+}
+
+func histIndex(v uint64) int {
+	if v < 2*histSub {
+		return int(v)
+	}
+	n := 0
+	for x := v; x != 0; x >>= 1 {
+		n++
+	}
+	shift := uint(n - histSubBits - 1)
+	return int(shift)*histSub + int(v>>shift)
+}
+
+// histValue returns the midpoint of the values counted in bucket i.
+func histValue(i int) uint64 {
+	if i < 2*histSub {
+		return uint64(i)
+	}
+	shift := uint(i/histSub - 1)
+	sub := uint64(i - int(shift)*histSub)
+	return sub<<shift + (1<<shift)/2
+}
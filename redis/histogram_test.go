@@ -0,0 +1,63 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestLatencyRecorder(t *testing.T) {
+	var r redis.LatencyRecorder
+	for i := 1; i <= 1000; i++ {
+		r.Record("get", time.Duration(i)*time.Millisecond)
+	}
+	r.Record("SET", 5*time.Microsecond)
+
+	snapshots := r.Snapshot()
+	if len(snapshots) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snapshots))
+	}
+	s := snapshots[0]
+	if s.Family != "GET" || s.Count != 1000 || s.Min != time.Millisecond || s.Max != time.Second {
+		t.Errorf("snapshot = %+v", s)
+	}
+	for _, p := range []struct {
+		name     string
+		value    time.Duration
+		expected time.Duration
+	}{
+		{"P50", s.P50, 500 * time.Millisecond},
+		{"P95", s.P95, 950 * time.Millisecond},
+		{"P99", s.P99, 990 * time.Millisecond},
+		{"Mean", s.Mean, 500500 * time.Microsecond},
+	} {
+		if diff := p.value - p.expected; diff < -p.expected/30 || diff > p.expected/30 {
+			t.Errorf("%s = %v, want %v", p.name, p.value, p.expected)
+		}
+	}
+	if s := snapshots[1]; s.Family != "SET" || s.P50 != 5*time.Microsecond {
+		t.Errorf("snapshot = %+v", s)
+	}
+
+	r.Reset()
+	if n := len(r.Snapshot()); n != 0 {
+		t.Errorf("got %d snapshots after reset, want 0", n)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis
+
+import (
+	"context"
+	"time"
+)
+
+// Hook is called before and after commands executed on a connection. Use
+// hooks to add tracing, metrics and auditing to connections.
+type Hook interface {
+	// BeforeCommand is called before a command is sent to the server. The
+	// returned context is passed to AfterCommand for the command.
+	BeforeCommand(ctx context.Context, commandName string, args []interface{}) context.Context
+
+	// AfterCommand is called with the reply to the command and the time
+	// between the call to BeforeCommand and the arrival of the reply.
+	AfterCommand(ctx context.Context, commandName string, d time.Duration, reply interface{}, err error)
+}
+
+// DialHook specifies a hook for commands executed on the connection. The
+// option can be specified more than once. The hooks are called in the
+// order that the options are specified for BeforeCommand and in reverse
+// order for AfterCommand.
+//
+// Commands executed with Do and DoContext are reported when the reply
+// arrives. Commands sent with Send are reported when the reply is read with
+// Receive. The context for Do and Send is context.Background().
+//
+// To attach a hook to all connections in a pool, use the option in the
+// pool's Dial function.
+func DialHook(h Hook) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.addHook(func(address string) Hook { return h })
+	}}
+}
+
+// addHook adds a wrapper that calls the hook returned from newHook. The
+// function newHook is called after all options are applied.
+func (do *dialOptions) addHook(newHook func(address string) Hook) {
+	// Wrap in reverse so that the first hook is the outermost.
+	wrap := func(c Conn, address string) Conn { return &hookConn{Conn: c, hook: newHook(address)} }
+	do.wrappers = append([]func(Conn, string) Conn{wrap}, do.wrappers...)
+}
+
+type hookCall struct {
+	ctx         context.Context
+	commandName string
+	start       time.Time
+}
+
+// hookConn calls a hook for commands executed on the wrapped connection.
+type hookConn struct {
+	Conn
+	hook    Hook
+	pending []hookCall
+}
+
+func (c *hookConn) before(ctx context.Context, commandName string, args []interface{}) hookCall {
+	ctx = c.hook.BeforeCommand(ctx, commandName, args)
+	return hookCall{ctx: ctx, commandName: commandName, start: time.Now()}
+}
+
+func (c *hookConn) after(call hookCall, reply interface{}, err error) {
+	c.hook.AfterCommand(call.ctx, call.commandName, time.Since(call.start), reply, err)
+}
+
+// flushPending reports commands sent with Send that were not received before
+// a call to Do. The replies to these commands are read by Do and are not
+// available.
+func (c *hookConn) flushPending(err error) {
+	for _, call := range c.pending {
+		c.after(call, nil, err)
+	}
+	c.pending = nil
+}
+
+func (c *hookConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	return c.do(context.Background(), commandName, args, func() (interface{}, error) {
+		return c.Conn.Do(commandName, args...)
+	})
+}
+
+func (c *hookConn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	return c.do(ctx, commandName, args, func() (interface{}, error) {
+		return DoContext(c.Conn, ctx, commandName, args...)
+	})
+}
+
+func (c *hookConn) do(ctx context.Context, commandName string, args []interface{}, fn func() (interface{}, error)) (interface{}, error) {
+	if commandName == "" {
+		// Do("") flushes and returns the replies to pending commands.
+		reply, err := fn()
+		if replies, ok := reply.([]interface{}); ok && err == nil && len(replies) == len(c.pending) {
+			for i, call := range c.pending {
+				if e, ok := replies[i].(Error); ok {
+					c.after(call, nil, e)
+				} else {
+					c.after(call, replies[i], nil)
+				}
+			}
+			c.pending = nil
+		}
+		c.flushPending(err)
+		return reply, err
+	}
+	call := c.before(ctx, commandName, args)
+	reply, err := fn()
+	c.flushPending(err)
+	c.after(call, reply, err)
+	return reply, err
+}
+
+// Close closes the connection. Commands that are waiting for a reply are
+// reported with an error.
+func (c *hookConn) Close() error {
+	err := c.Conn.Close()
+	c.flushPending(errConnClosed)
+	return err
+}
+
+func (c *hookConn) Send(commandName string, args ...interface{}) error {
+	call := c.before(context.Background(), commandName, args)
+	if err := c.Conn.Send(commandName, args...); err != nil {
+		c.after(call, nil, err)
+		return err
+	}
+	c.pending = append(c.pending, call)
+	return nil
+}
+
+func (c *hookConn) Receive() (interface{}, error) {
+	return c.receive(c.Conn.Receive())
+}
+
+func (c *hookConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return c.receive(ReceiveWithTimeout(c.Conn, timeout))
+}
+
+func (c *hookConn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	return c.receive(ReceiveContext(c.Conn, ctx))
+}
+
+func (c *hookConn) receive(reply interface{}, err error) (interface{}, error) {
+	if len(c.pending) == 0 {
+		// Pub/sub messages and replies read after Do are not reported.
+		return reply, err
+	}
+	if _, ok := err.(Error); err == nil || ok {
+		call := c.pending[0]
+		c.pending = c.pending[1:]
+		c.after(call, reply, err)
+	} else if c.Conn.Err() != nil {
+		c.flushPending(err)
+	}
+	return reply, err
+}
+
+// DialLogger specifies a logger for commands executed on the connection.
+// Commands are logged at LogDebug level with the keys cmd, args, duration and
+// err. The arguments are redacted with the policy specified by the
+// DialRedaction option.
+func DialLogger(l Logger) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.addHook(func(address string) Hook { return loggerHook{l, do.redaction} })
+	}}
+}
+
+type loggerHook struct {
+	l         Logger
+	redaction *RedactionPolicy
+}
+
+type loggerArgs struct{}
+
+func (h loggerHook) BeforeCommand(ctx context.Context, commandName string, args []interface{}) context.Context {
+	return context.WithValue(ctx, loggerArgs{}, args)
+}
+
+func (h loggerHook) AfterCommand(ctx context.Context, commandName string, d time.Duration, reply interface{}, err error) {
+	args, _ := ctx.Value(loggerArgs{}).([]interface{})
+	h.l.Log(LogDebug, "command", "cmd", commandName, "args", h.redaction.Args(commandName, args), "duration", d, "err", err)
+}
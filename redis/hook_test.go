@@ -0,0 +1,97 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+type recordingHook struct {
+	events []string
+}
+
+type hookKey struct{}
+
+func (h *recordingHook) BeforeCommand(ctx context.Context, commandName string, args []interface{}) context.Context {
+	h.events = append(h.events, fmt.Sprintf("before %s %v", commandName, args))
+	return context.WithValue(ctx, hookKey{}, commandName)
+}
+
+func (h *recordingHook) AfterCommand(ctx context.Context, commandName string, d time.Duration, reply interface{}, err error) {
+	if ctx.Value(hookKey{}) != commandName {
+		h.events = append(h.events, "bad context for "+commandName)
+	}
+	if b, ok := reply.([]byte); ok {
+		reply = string(b)
+	}
+	h.events = append(h.events, fmt.Sprintf("after %s %v %v", commandName, reply, err))
+}
+
+func TestDialHook(t *testing.T) {
+	var h recordingHook
+	r := strings.NewReader("+OK\r\n$3\r\nbar\r\n-ERR bad\r\n:1\r\n")
+	c, err := redis.Dial("", "", dialTestConn(r, &bytes.Buffer{}), redis.DialHook(&h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Do("SET", "foo", "bar")
+	c.Send("GET", "foo")
+	c.Send("BAD")
+	c.Flush()
+	c.Receive()
+	c.Receive()
+	redis.DoContext(c, context.Background(), "INCR", "n")
+
+	expected := []string{
+		"before SET [foo bar]",
+		"after SET OK <nil>",
+		"before GET [foo]",
+		"before BAD []",
+		"after GET bar <nil>",
+		"after BAD <nil> ERR bad",
+		"before INCR [n]",
+		"after INCR 1 <nil>",
+	}
+	if !reflect.DeepEqual(h.events, expected) {
+		t.Errorf("events =\n%s\nwant\n%s", strings.Join(h.events, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestDialLogger(t *testing.T) {
+	var l recordingLogger
+	r := strings.NewReader("+OK\r\n")
+	c, err := redis.Dial("", "", dialTestConn(r, &bytes.Buffer{}), redis.DialLogger(&l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Do("SET", "foo", "secret")
+	if len(l.events) != 1 || !strings.HasPrefix(l.events[0], "DEBUG command [cmd SET args [foo ?] duration ") {
+		t.Errorf("events = %q", l.events)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// InFlightCommand represents a command that is waiting for a reply.
+type InFlightCommand struct {
+	// ConnID identifies the connection in the registry.
+	ConnID uint64
+
+	// The address of the server.
+	Target string
+
+	CommandName string
+
+	// The time that the command was sent.
+	Start time.Time
+
+	// The ID of the goroutine that sent the command.
+	GoroutineID uint64
+}
+
+// InFlightRegistry tracks the commands that are waiting for a reply on
+// connections dialed with the DialInFlightRegistry option. Use the registry
+// to find stuck commands. The zero value is ready to use.
+type InFlightRegistry struct {
+	mu       sync.Mutex
+	lastID   uint64
+	commands map[*InFlightCommand]struct{}
+}
+
+// DialInFlightRegistry specifies a registry for the commands executed on the
+// connection. The registry records the ID of the goroutine that sends each
+// command. Finding the goroutine ID adds overhead to each command.
+func DialInFlightRegistry(r *InFlightRegistry) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.addHook(func(address string) Hook {
+			r.mu.Lock()
+			r.lastID++
+			h := inFlightHook{r: r, connID: r.lastID, target: address}
+			r.mu.Unlock()
+			return h
+		})
+	}}
+}
+
+// Commands returns the commands waiting for a reply, oldest first.
+func (r *InFlightRegistry) Commands() []InFlightCommand {
+	r.mu.Lock()
+	result := make([]InFlightCommand, 0, len(r.commands))
+	for cmd := range r.commands {
+		result = append(result, *cmd)
+	}
+	r.mu.Unlock()
+	sort.Sort(inFlightByStart(result))
+	return result
+}
+
+type inFlightByStart []InFlightCommand
+
+func (c inFlightByStart) Len() int           { return len(c) }
+func (c inFlightByStart) Less(i, j int) bool { return c[i].Start.Before(c[j].Start) }
+func (c inFlightByStart) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+type inFlightKey struct{}
+
+type inFlightHook struct {
+	r      *InFlightRegistry
+	connID uint64
+	target string
+}
+
+func (h inFlightHook) BeforeCommand(ctx context.Context, commandName string, args []interface{}) context.Context {
+	cmd := &InFlightCommand{
+		ConnID:      h.connID,
+		Target:      h.target,
+		CommandName: commandName,
+		Start:       time.Now(),
+		GoroutineID: goroutineID(),
+	}
+	h.r.mu.Lock()
+	if h.r.commands == nil {
+		h.r.commands = make(map[*InFlightCommand]struct{})
+	}
+	h.r.commands[cmd] = struct{}{}
+	h.r.mu.Unlock()
+	return context.WithValue(ctx, inFlightKey{}, cmd)
+}
+
+func (h inFlightHook) AfterCommand(ctx context.Context, commandName string, d time.Duration, reply interface{}, err error) {
+	if cmd, ok := ctx.Value(inFlightKey{}).(*InFlightCommand); ok {
+		h.r.mu.Lock()
+		delete(h.r.commands, cmd)
+		h.r.mu.Unlock()
+	}
+}
+
+// goroutineID returns the ID of the calling goroutine. The ID is parsed from
+// the first line of the goroutine's stack trace, "goroutine 123 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
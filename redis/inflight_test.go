@@ -0,0 +1,56 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestInFlightRegistry(t *testing.T) {
+	var r redis.InFlightRegistry
+	c, err := redis.Dial("", "example.com:6379", dialTestConn(strings.NewReader("+OK\r\n+OK\r\n"), &bytes.Buffer{}), redis.DialInFlightRegistry(&r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Send("SET", "a", "1")
+	c.Send("GET", "a")
+	commands := r.Commands()
+	if len(commands) != 2 {
+		t.Fatalf("got %d commands, want 2", len(commands))
+	}
+	cmd := commands[0]
+	if cmd.CommandName != "SET" || cmd.ConnID != 1 || cmd.Target != "example.com:6379" || cmd.GoroutineID == 0 {
+		t.Errorf("commands[0] = %+v", cmd)
+	}
+
+	c.Receive()
+	if commands := r.Commands(); len(commands) != 1 || commands[0].CommandName != "GET" {
+		t.Errorf("commands after receive = %+v, want GET", commands)
+	}
+
+	c.Send("GET", "b")
+	c.Close()
+	if commands := r.Commands(); len(commands) != 0 {
+		t.Errorf("got %d commands after close, want 0", len(commands))
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Info represents the reply to the INFO command.
+type Info struct {
+	Server      InfoServer
+	Clients     InfoClients
+	Memory      InfoMemory
+	Persistence InfoPersistence
+	Replication InfoReplication
+
+	// Keyspace maps database numbers to key statistics.
+	Keyspace map[int]InfoKeyspace
+
+	// Sections holds the raw fields of every section in the reply, keyed
+	// by the lower case section name and then by the field name. Use
+	// Sections to access fields that are not decoded to the typed fields
+	// above.
+	Sections map[string]map[string]string
+}
+
+// InfoServer represents the server section of the INFO reply.
+type InfoServer struct {
+	Version         string `redis:"redis_version"`
+	Mode            string `redis:"redis_mode"`
+	OS              string `redis:"os"`
+	ProcessID       int    `redis:"process_id"`
+	RunID           string `redis:"run_id"`
+	TCPPort         int    `redis:"tcp_port"`
+	UptimeInSeconds int64  `redis:"uptime_in_seconds"`
+	ConfigFile      string `redis:"config_file"`
+}
+
+// InfoClients represents the clients section of the INFO reply.
+type InfoClients struct {
+	ConnectedClients int `redis:"connected_clients"`
+	BlockedClients   int `redis:"blocked_clients"`
+	MaxClients       int `redis:"maxclients"`
+}
+
+// InfoMemory represents the memory section of the INFO reply.
+type InfoMemory struct {
+	UsedMemory            int64   `redis:"used_memory"`
+	UsedMemoryRSS         int64   `redis:"used_memory_rss"`
+	UsedMemoryPeak        int64   `redis:"used_memory_peak"`
+	UsedMemoryLua         int64   `redis:"used_memory_lua"`
+	MaxMemory             int64   `redis:"maxmemory"`
+	MaxMemoryPolicy       string  `redis:"maxmemory_policy"`
+	MemFragmentationRatio float64 `redis:"mem_fragmentation_ratio"`
+}
+
+// InfoPersistence represents the persistence section of the INFO reply.
+type InfoPersistence struct {
+	Loading                 bool   `redis:"loading"`
+	RDBChangesSinceLastSave int64  `redis:"rdb_changes_since_last_save"`
+	RDBBgsaveInProgress     bool   `redis:"rdb_bgsave_in_progress"`
+	RDBLastSaveTime         int64  `redis:"rdb_last_save_time"`
+	RDBLastBgsaveStatus     string `redis:"rdb_last_bgsave_status"`
+	AOFEnabled              bool   `redis:"aof_enabled"`
+	AOFRewriteInProgress    bool   `redis:"aof_rewrite_in_progress"`
+	AOFLastWriteStatus      string `redis:"aof_last_write_status"`
+}
+
+// InfoReplication represents the replication section of the INFO reply.
+type InfoReplication struct {
+	Role             string `redis:"role"`
+	ConnectedSlaves  int    `redis:"connected_slaves"`
+	MasterHost       string `redis:"master_host"`
+	MasterPort       int    `redis:"master_port"`
+	MasterLinkStatus string `redis:"master_link_status"`
+	MasterReplOffset int64  `redis:"master_repl_offset"`
+}
+
+// InfoKeyspace represents a database entry in the keyspace section of the
+// INFO reply.
+type InfoKeyspace struct {
+	Keys    int64 `redis:"keys"`
+	Expires int64 `redis:"expires"`
+	AvgTTL  int64 `redis:"avg_ttl"`
+}
+
+// InfoReply is a helper that converts the reply to the INFO command to an
+// Info. Fields missing from the reply are left at their zero value.
+//
+//  info, err := redis.InfoReply(c.Do("INFO"))
+//  if err != nil {
+//      // handle error
+//  }
+//  fmt.Println(info.Server.Version, info.Memory.UsedMemory)
+func InfoReply(reply interface{}, err error) (Info, error) {
+	var info Info
+	s, err := String(reply, err)
+	if err != nil {
+		return info, err
+	}
+
+	info.Sections = make(map[string]map[string]string)
+	var fields map[string]string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case line == "":
+			continue
+		case line[0] == '#':
+			fields = make(map[string]string)
+			info.Sections[strings.ToLower(strings.TrimSpace(line[1:]))] = fields
+			continue
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+			info.Sections[""] = fields
+		}
+		fields[line[:i]] = line[i+1:]
+	}
+
+	for _, section := range []struct {
+		name string
+		dest interface{}
+	}{
+		{"server", &info.Server},
+		{"clients", &info.Clients},
+		{"memory", &info.Memory},
+		{"persistence", &info.Persistence},
+		{"replication", &info.Replication},
+	} {
+		if err := scanInfoFields(info.Sections[section.name], section.dest); err != nil {
+			return info, err
+		}
+	}
+
+	if keyspace := info.Sections["keyspace"]; len(keyspace) > 0 {
+		info.Keyspace = make(map[int]InfoKeyspace, len(keyspace))
+		for name, value := range keyspace {
+			if !strings.HasPrefix(name, "db") {
+				continue
+			}
+			db, err := strconv.Atoi(name[2:])
+			if err != nil {
+				return info, errors.New("redigo: bad keyspace database " + name)
+			}
+			var ks InfoKeyspace
+			if err := scanInfoFields(parseInfoValue(value), &ks); err != nil {
+				return info, err
+			}
+			info.Keyspace[db] = ks
+		}
+	}
+	return info, nil
+}
+
+// parseInfoValue parses an INFO field value of the form k1=v1,k2=v2.
+func parseInfoValue(value string) map[string]string {
+	m := make(map[string]string)
+	for _, kv := range strings.Split(value, ",") {
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			m[kv[:i]] = kv[i+1:]
+		}
+	}
+	return m
+}
+
+// scanInfoFields scans fields to the struct pointed to by dest.
+func scanInfoFields(fields map[string]string, dest interface{}) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	src := make([]interface{}, 0, 2*len(fields))
+	for k, v := range fields {
+		src = append(src, []byte(k), []byte(v))
+	}
+	return ScanStruct(src, dest)
+}
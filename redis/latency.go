@@ -0,0 +1,104 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+	"time"
+)
+
+// LatencyEvent represents an event in the reply to the LATENCY LATEST
+// command.
+type LatencyEvent struct {
+	Name string
+
+	// The time of the latest latency spike for the event.
+	Time time.Time
+
+	// The latency of the latest spike and the maximum latency recorded for
+	// the event.
+	Latest time.Duration
+	Max    time.Duration
+}
+
+// LatencySample represents a sample in the reply to the LATENCY HISTORY
+// command.
+type LatencySample struct {
+	Time    time.Time
+	Latency time.Duration
+}
+
+// LatencyEvents is a helper that converts the reply to the LATENCY LATEST
+// command to a slice of LatencyEvent.
+func LatencyEvents(reply interface{}, err error) ([]LatencyEvent, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]LatencyEvent, len(values))
+	for i, v := range values {
+		fields, err := Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("redigo: LatencyEvents expects 4 fields in event, got %d", len(fields))
+		}
+		e := &events[i]
+		if e.Name, err = String(fields[0], nil); err != nil {
+			return nil, err
+		}
+		if e.Time, err = Time(fields[1], nil); err != nil {
+			return nil, err
+		}
+		if e.Latest, err = DurationMillis(fields[2], nil); err != nil {
+			return nil, err
+		}
+		if e.Max, err = DurationMillis(fields[3], nil); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// LatencySamples is a helper that converts the reply to the LATENCY HISTORY
+// command to a slice of LatencySample.
+//
+// The reply to the LATENCY RESET command is the number of event series
+// reset. Use the Int helper to convert the reply.
+func LatencySamples(reply interface{}, err error) ([]LatencySample, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]LatencySample, len(values))
+	for i, v := range values {
+		fields, err := Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("redigo: LatencySamples expects 2 fields in sample, got %d", len(fields))
+		}
+		s := &samples[i]
+		if s.Time, err = Time(fields[0], nil); err != nil {
+			return nil, err
+		}
+		if s.Latency, err = DurationMillis(fields[1], nil); err != nil {
+			return nil, err
+		}
+	}
+	return samples, nil
+}
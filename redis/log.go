@@ -18,20 +18,61 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"time"
 )
 
 // NewLoggingConn returns a logging wrapper around a connection.
+// The DialLogger option logs commands to a structured Logger.
 func NewLoggingConn(conn Conn, logger *log.Logger, prefix string) Conn {
+	return newLoggingConn(conn, logger, prefix, nil)
+}
+
+// NewLoggingConnWithPolicy is like NewLoggingConn, but it applies the
+// redaction policy to logged command arguments and replaces reply values
+// with "?". If policy is nil, then DefaultRedactionPolicy is used.
+func NewLoggingConnWithPolicy(conn Conn, logger *log.Logger, prefix string, policy *RedactionPolicy) Conn {
+	if policy == nil {
+		policy = DefaultRedactionPolicy
+	}
+	return newLoggingConn(conn, logger, prefix, policy)
+}
+
+func newLoggingConn(conn Conn, logger *log.Logger, prefix string, policy *RedactionPolicy) Conn {
 	if prefix != "" {
 		prefix = prefix + "."
 	}
-	return &loggingConn{conn, logger, prefix}
+	return &loggingConn{conn, logger, prefix, policy}
 }
 
 type loggingConn struct {
 	Conn
 	logger *log.Logger
 	prefix string
+
+	// redaction is the policy applied to logged commands. Commands are
+	// logged as is when redaction is nil.
+	redaction *RedactionPolicy
+}
+
+// redactedValue is logged in place of a reply value.
+type redactedValue struct{}
+
+func (redactedValue) String() string { return "?" }
+
+// redactReply replaces the strings in reply with redactedValue. Integers,
+// nil and errors are logged as is.
+func redactReply(reply interface{}) interface{} {
+	switch reply := reply.(type) {
+	case []byte, string:
+		return redactedValue{}
+	case []interface{}:
+		result := make([]interface{}, len(reply))
+		for i, v := range reply {
+			result[i] = redactReply(v)
+		}
+		return result
+	}
+	return reply
 }
 
 func (c *loggingConn) Close() error {
@@ -79,11 +120,27 @@ func (c *loggingConn) printValue(buf *bytes.Buffer, v interface{}) {
 	}
 }
 
+// redactArgs applies the redaction policy to the command arguments.
+func (c *loggingConn) redactArgs(commandName string, args []interface{}) []interface{} {
+	result := make([]interface{}, len(args))
+	for i, arg := range c.redaction.Args(commandName, args) {
+		if arg == "?" {
+			result[i] = redactedValue{}
+		} else {
+			result[i] = arg
+		}
+	}
+	return result
+}
+
 func (c *loggingConn) print(method, commandName string, args []interface{}, reply interface{}, err error) {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "%s%s(", c.prefix, method)
 	if method != "Receive" {
 		buf.WriteString(commandName)
+		if c.redaction != nil {
+			args = c.redactArgs(commandName, args)
+		}
 		for _, arg := range args {
 			buf.WriteString(", ")
 			c.printValue(&buf, arg)
@@ -91,6 +148,9 @@ func (c *loggingConn) print(method, commandName string, args []interface{}, repl
 	}
 	buf.WriteString(") -> (")
 	if method != "Send" {
+		if c.redaction != nil {
+			reply = redactReply(reply)
+		}
 		c.printValue(&buf, reply)
 		buf.WriteString(", ")
 	}
@@ -115,3 +175,9 @@ func (c *loggingConn) Receive() (interface{}, error) {
 	c.print("Receive", "", nil, reply, err)
 	return reply, err
 }
+
+func (c *loggingConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	reply, err := ReceiveWithTimeout(c.Conn, timeout)
+	c.print("ReceiveWithTimeout", "", nil, reply, err)
+	return reply, err
+}
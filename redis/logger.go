@@ -0,0 +1,81 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+)
+
+// LogLevel is the severity of a logged event.
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+func (l LogLevel) String() string {
+	if l >= 0 && int(l) < len(logLevelNames) {
+		return logLevelNames[l]
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// Logger is the interface for logging events from the package. Pool,
+// Sentinel and connections dialed with the DialLogger option report events
+// such as dial failures, evicted connections and failovers to a Logger.
+//
+// The keyvals argument is a list of alternating keys and values. The keys
+// are strings. Adapters for structured logging packages pass the list
+// through. For example, an adapter for log/slog is:
+//
+//  type slogLogger struct{ l *slog.Logger }
+//
+//  func (s slogLogger) Log(level redis.LogLevel, msg string, keyvals ...interface{}) {
+//      s.l.Log(context.Background(), slog.Level(4*(int(level)-1)), msg, keyvals...)
+//  }
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// NewStdLogger returns a Logger that writes events to l in the format
+//
+//  LEVEL msg key=value key=value
+func NewStdLogger(l *log.Logger) Logger {
+	return stdLogger{l}
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s stdLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s", level, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var v interface{} = "MISSING"
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		fmt.Fprintf(&buf, " %v=%v", keyvals[i], v)
+	}
+	s.l.Output(2, buf.String())
+}
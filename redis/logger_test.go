@@ -0,0 +1,64 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+type recordingLogger struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *recordingLogger) Log(level redis.LogLevel, msg string, keyvals ...interface{}) {
+	l.mu.Lock()
+	l.events = append(l.events, fmt.Sprint(level, " ", msg, " ", keyvals))
+	l.mu.Unlock()
+}
+
+func TestPoolLogger(t *testing.T) {
+	var l recordingLogger
+	p := &redis.Pool{
+		Dial:   func() (redis.Conn, error) { return nil, errors.New("dial error") },
+		Logger: &l,
+	}
+	defer p.Close()
+
+	c := p.Get()
+	c.Close()
+
+	expected := []string{"WARN dial failed [err dial error]"}
+	if !reflect.DeepEqual(l.events, expected) {
+		t.Errorf("events = %q, want %q", l.events, expected)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := redis.NewStdLogger(log.New(&buf, "", 0))
+	l.Log(redis.LogInfo, "hello", "a", 1, "b")
+	if s, expected := buf.String(), "INFO hello a=1 b=MISSING\n"; s != expected {
+		t.Errorf("output = %q, want %q", s, expected)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MemoryStats represents the reply to the MEMORY STATS command. All sizes
+// are in bytes.
+type MemoryStats struct {
+	PeakAllocated      int64   `redis:"peak.allocated"`
+	TotalAllocated     int64   `redis:"total.allocated"`
+	StartupAllocated   int64   `redis:"startup.allocated"`
+	ReplicationBacklog int64   `redis:"replication.backlog"`
+	ClientsSlaves      int64   `redis:"clients.slaves"`
+	ClientsNormal      int64   `redis:"clients.normal"`
+	AOFBuffer          int64   `redis:"aof.buffer"`
+	LuaCaches          int64   `redis:"lua.caches"`
+	OverheadTotal      int64   `redis:"overhead.total"`
+	KeysCount          int64   `redis:"keys.count"`
+	KeysBytesPerKey    int64   `redis:"keys.bytes-per-key"`
+	DatasetBytes       int64   `redis:"dataset.bytes"`
+	DatasetPercentage  float64 `redis:"dataset.percentage"`
+	PeakPercentage     float64 `redis:"peak.percentage"`
+	Fragmentation      float64 `redis:"fragmentation"`
+
+	// DB maps database numbers to the overhead of the database hash
+	// tables.
+	DB map[int]MemoryStatsDB
+}
+
+// MemoryStatsDB represents the hash table overhead of a database in the
+// reply to the MEMORY STATS command.
+type MemoryStatsDB struct {
+	OverheadMain    int64 `redis:"overhead.hashtable.main"`
+	OverheadExpires int64 `redis:"overhead.hashtable.expires"`
+}
+
+// MemoryStatsReply is a helper that converts the reply to the MEMORY STATS
+// command to a MemoryStats. Fields missing from the reply are left at their
+// zero value.
+func MemoryStatsReply(reply interface{}, err error) (MemoryStats, error) {
+	var ms MemoryStats
+	values, err := Values(reply, err)
+	if err != nil {
+		return ms, err
+	}
+	if err := ScanStruct(values, &ms); err != nil {
+		return ms, err
+	}
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := values[i].([]byte)
+		if !strings.HasPrefix(string(name), "db.") {
+			continue
+		}
+		db, err := strconv.Atoi(string(name[3:]))
+		if err != nil {
+			continue
+		}
+		fields, err := Values(values[i+1], nil)
+		if err != nil {
+			return ms, err
+		}
+		var mdb MemoryStatsDB
+		if err := ScanStruct(fields, &mdb); err != nil {
+			return ms, err
+		}
+		if ms.DB == nil {
+			ms.DB = make(map[int]MemoryStatsDB)
+		}
+		ms.DB[db] = mdb
+	}
+	return ms, nil
+}
+
+// MemoryUsage returns the number of bytes used by key and its value. If
+// samples is greater than zero, then the server samples that many nested
+// values to estimate the size of aggregate types. Otherwise, the server
+// default is used. MemoryUsage returns ErrNil if the key does not exist.
+func MemoryUsage(c Conn, key string, samples int) (int64, error) {
+	if samples > 0 {
+		return Int64(c.Do("MEMORY", "USAGE", key, "SAMPLES", samples))
+	}
+	return Int64(c.Do("MEMORY", "USAGE", key))
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+// NullString represents a string reply that may be nil. NullString
+// implements the Scanner interface so it can be used as a Scan destination.
+type NullString struct {
+	String string
+	Valid  bool // Valid is true if the reply is not nil.
+}
+
+// RedisScan implements the Scanner interface.
+func (ns *NullString) RedisScan(src interface{}) (err error) {
+	if src == nil {
+		*ns = NullString{}
+		return nil
+	}
+	ns.String, err = String(src, nil)
+	ns.Valid = err == nil
+	return err
+}
+
+// NullInt64 represents an integer reply that may be nil. NullInt64
+// implements the Scanner interface so it can be used as a Scan destination.
+type NullInt64 struct {
+	Int64 int64
+	Valid bool // Valid is true if the reply is not nil.
+}
+
+// RedisScan implements the Scanner interface.
+func (ni *NullInt64) RedisScan(src interface{}) (err error) {
+	if src == nil {
+		*ni = NullInt64{}
+		return nil
+	}
+	ni.Int64, err = Int64(src, nil)
+	ni.Valid = err == nil
+	return err
+}
+
+// NullFloat64 represents a float reply that may be nil. NullFloat64
+// implements the Scanner interface so it can be used as a Scan destination.
+type NullFloat64 struct {
+	Float64 float64
+	Valid   bool // Valid is true if the reply is not nil.
+}
+
+// RedisScan implements the Scanner interface.
+func (nf *NullFloat64) RedisScan(src interface{}) (err error) {
+	if src == nil {
+		*nf = NullFloat64{}
+		return nil
+	}
+	nf.Float64, err = Float64(src, nil)
+	nf.Valid = err == nil
+	return err
+}
+
+// NullStringReply is a helper that converts a command reply to a NullString.
+// If err is not equal to nil, then NullStringReply returns the zero value and
+// err. A nil reply is returned as a NullString with Valid set to false.
+//
+//  name, err := redis.NullStringReply(c.Do("GET", "name"))
+//  if err != nil {
+//      // handle error
+//  }
+//  if !name.Valid {
+//      // handle missing key
+//  }
+func NullStringReply(reply interface{}, err error) (NullString, error) {
+	var ns NullString
+	if err != nil {
+		return ns, err
+	}
+	err = ns.RedisScan(reply)
+	return ns, err
+}
+
+// NullInt64Reply is a helper that converts a command reply to a NullInt64.
+// See NullStringReply for details.
+func NullInt64Reply(reply interface{}, err error) (NullInt64, error) {
+	var ni NullInt64
+	if err != nil {
+		return ni, err
+	}
+	err = ni.RedisScan(reply)
+	return ni, err
+}
+
+// NullFloat64Reply is a helper that converts a command reply to a
+// NullFloat64. See NullStringReply for details.
+func NullFloat64Reply(reply interface{}, err error) (NullFloat64, error) {
+	var nf NullFloat64
+	if err != nil {
+		return nf, err
+	}
+	err = nf.RedisScan(reply)
+	return nf, err
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+// Pipeline sends commands to the server without waiting for replies and
+// then receives the replies for all commands. Each command sent to the
+// pipeline has a Result that is set by the Exec method.
+//
+//  p := redis.NewPipeline(c)
+//  a := p.Send("GET", "a")
+//  p.Send("INCR", "n")
+//  if err := p.Exec(); err != nil {
+//      // handle error
+//  }
+//  v, err := a.String()
+//  n, err := p.Result(1).Int()
+type Pipeline struct {
+	c        Conn
+	results  []*Result
+	received int
+}
+
+// NewPipeline returns a pipeline for the connection.
+func NewPipeline(c Conn) *Pipeline {
+	return &Pipeline{c: c}
+}
+
+// Send writes a command to the connection's output buffer and returns the
+// result for the command.
+func (p *Pipeline) Send(commandName string, args ...interface{}) *Result {
+	r := &Result{}
+	p.results = append(p.results, r)
+	if err := p.c.Send(commandName, args...); err != nil {
+		r.Err = err
+	}
+	return r
+}
+
+// Exec flushes the output buffer and receives the replies for the commands
+// sent since the previous call to Exec. Exec returns an error if the
+// connection fails. The results for the commands that were not received
+// are set to the error.
+func (p *Pipeline) Exec() error {
+	pending := p.results[p.received:]
+	p.received = len(p.results)
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := p.c.Flush(); err != nil {
+		return p.fail(pending, err)
+	}
+	for i, r := range pending {
+		if r.Err != nil {
+			// The command was not sent.
+			continue
+		}
+		v, err := p.c.Receive()
+		if _, ok := err.(Error); err != nil && !ok {
+			return p.fail(pending[i:], err)
+		}
+		r.Value, r.Err = v, err
+	}
+	return nil
+}
+
+func (p *Pipeline) fail(results []*Result, err error) error {
+	for _, r := range results {
+		if r.Err == nil {
+			r.Err = err
+		}
+	}
+	return err
+}
+
+// Len returns the number of commands sent to the pipeline.
+func (p *Pipeline) Len() int {
+	return len(p.results)
+}
+
+// Results returns the results for the commands sent to the pipeline in the
+// order that the commands were sent.
+func (p *Pipeline) Results() []Result {
+	results := make([]Result, len(p.results))
+	for i, r := range p.results {
+		results[i] = *r
+	}
+	return results
+}
+
+// Result returns the result for the i-th command sent to the pipeline.
+func (p *Pipeline) Result(i int) *Result {
+	return p.results[i]
+}
+
+// Command is a command name and arguments for DoMulti.
+type Command struct {
+	Name string
+	Args []interface{}
+}
+
+// DoMulti sends commands to the server, flushes the output buffer once and
+// receives the replies. The results are in the same order as the commands.
+// DoMulti returns an error if the connection fails. Error replies from the
+// server are returned in the results.
+//
+//  results, err := redis.DoMulti(c, []redis.Command{
+//      {"SET", redis.Args{"a", 1}},
+//      {"INCR", redis.Args{"a"}},
+//  })
+func DoMulti(c Conn, commands []Command) ([]Result, error) {
+	p := NewPipeline(c)
+	for _, cmd := range commands {
+		p.Send(cmd.Name, cmd.Args...)
+	}
+	err := p.Exec()
+	return p.Results(), err
+}
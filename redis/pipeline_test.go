@@ -0,0 +1,137 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestPipeline(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := redis.NewPipeline(c)
+	p.Send("SET", "a", "hello")
+	a := p.Send("GET", "a")
+	p.Send("INCR", "n")
+	bad := p.Send("INCR", "a")
+	if err := p.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := a.String(); err != nil || v != "hello" {
+		t.Errorf("GET a = %q, %v, want hello", v, err)
+	}
+	if n, err := p.Result(2).Int(); err != nil || n != 1 {
+		t.Errorf("INCR n = %d, %v, want 1", n, err)
+	}
+	if _, ok := bad.Err.(redis.Error); !ok {
+		t.Errorf("INCR a err = %v, want server error", bad.Err)
+	}
+
+	// The pipeline can be used again after Exec.
+	n := p.Send("INCR", "n")
+	if err := p.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := n.Int(); err != nil || v != 2 || p.Len() != 5 {
+		t.Errorf("INCR n = %d, %v, Len() = %d, want 2, nil, 5", v, err, p.Len())
+	}
+}
+
+func TestPipelineConnError(t *testing.T) {
+	var buf bytes.Buffer
+	r := strings.NewReader("+OK\r\n-ERR bad\r\n")
+	c, err := redis.Dial("", "", dialTestConn(r, &buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := redis.NewPipeline(c)
+	p.Send("SET", "a", "b")
+	p.Send("BAD")
+	p.Send("GET", "a")
+	if err := p.Exec(); err == nil {
+		t.Fatal("Exec() returned nil error")
+	}
+	results := p.Results()
+	if results[0].Value != "OK" || results[0].Err != nil {
+		t.Errorf("results[0] = %+v, want OK", results[0])
+	}
+	if _, ok := results[1].Err.(redis.Error); !ok {
+		t.Errorf("results[1].Err = %v, want server error", results[1].Err)
+	}
+	if _, ok := results[2].Err.(redis.Error); ok || results[2].Err == nil {
+		t.Errorf("results[2].Err = %v, want connection error", results[2].Err)
+	}
+}
+
+func TestTxResults(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	tx := redis.NewTx(c)
+	tx.Int("INCR", "n")
+	tx.Do("SET", "s", "v")
+	tx.Int("INCR", "s")
+	if err := tx.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	results := tx.Results()
+	if len(results) != 3 || results[0].Value != int64(1) || results[1].Value != "OK" {
+		t.Errorf("Results() = %+v", results)
+	}
+	if _, ok := results[2].Err.(redis.Error); !ok {
+		t.Errorf("results[2].Err = %v, want server error", results[2].Err)
+	}
+}
+
+func TestDoMulti(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	results, err := redis.DoMulti(c, []redis.Command{
+		{"SET", redis.Args{"a", 1}},
+		{"INCR", redis.Args{"a"}},
+		{"HGET", redis.Args{"a", "f"}},
+		{"GET", redis.Args{"a"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("len(results) = %d, want 4", len(results))
+	}
+	if n, err := results[1].Int(); err != nil || n != 2 {
+		t.Errorf("INCR = %d, %v, want 2", n, err)
+	}
+	if _, ok := results[2].Err.(redis.Error); !ok {
+		t.Errorf("HGET err = %v, want server error", results[2].Err)
+	}
+	if s, err := results[3].String(); err != nil || s != "2" {
+		t.Errorf("GET = %q, %v, want 2", s, err)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"crypto/sha1"
 	"errors"
 	"io"
+	"net"
 	"strconv"
 	"sync"
 	"time"
@@ -30,6 +31,13 @@ import (
 
 var nowFunc = time.Now // for testing
 
+// Clock is the interface for reading the current time. Applications set
+// the Pool Clock field to a fake clock to test timeout logic without
+// sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
 // ErrPoolExhausted is returned from a pool connection method (Do, Send,
 // Receive, Flush, Err) when the maximum number of database connections in the
 // pool has been reached.
@@ -146,11 +154,24 @@ type Pool struct {
 	// for a connection to be returned to the pool before returning.
 	Wait bool
 
+	// Logger specifies an optional logger for pool events such as dial
+	// failures and closed connections.
+	Logger Logger
+
+	// Clock specifies an optional clock for idle timeouts. If Clock is nil,
+	// then the system clock is used.
+	Clock Clock
+
+	// If CountErrors is true, then the pool counts errors on the pool's
+	// connections by class. The counts are reported in PoolStats.Errors.
+	CountErrors bool
+
 	// mu protects fields defined below.
 	mu     sync.Mutex
 	cond   *sync.Cond
 	closed bool
 	active int
+	errors PoolErrorStats
 
 	// Stack of idleConn with most recently used at the front.
 	idle list.List
@@ -189,6 +210,93 @@ func (p *Pool) ActiveCount() int {
 	return active
 }
 
+// PoolStats contains pool statistics.
+type PoolStats struct {
+	// ActiveCount is the number of connections in the pool. The count
+	// includes idle connections and connections in use.
+	ActiveCount int
+
+	// IdleCount is the number of idle connections in the pool.
+	IdleCount int
+
+	// Errors is the count of errors by class. The counts are zero unless
+	// the pool's CountErrors field is true.
+	Errors PoolErrorStats
+}
+
+// PoolErrorStats contains counts of errors on connections from a pool.
+// Connection errors are counted once per connection.
+type PoolErrorStats struct {
+	// Dial is the number of errors returned from the pool's Dial function.
+	Dial int64
+
+	// ReadTimeout and WriteTimeout are the number of network timeouts.
+	ReadTimeout  int64
+	WriteTimeout int64
+
+	// Protocol is the number of malformed replies from the server.
+	Protocol int64
+
+	// Connection is the number of other errors that broke a connection,
+	// such as a connection reset by the server.
+	Connection int64
+
+	// Server is the number of error replies from the server by error code.
+	Server map[string]int64
+}
+
+// Stats returns pool statistics.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	stats := PoolStats{
+		ActiveCount: p.active,
+		IdleCount:   p.idle.Len(),
+		Errors:      p.errors,
+	}
+	if p.errors.Server != nil {
+		stats.Errors.Server = make(map[string]int64, len(p.errors.Server))
+		for code, n := range p.errors.Server {
+			stats.Errors.Server[code] = n
+		}
+	}
+	p.mu.Unlock()
+	return stats
+}
+
+// countError adds err to the error counts.
+func (p *Pool) countError(err error) {
+	if !p.CountErrors {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch err := err.(type) {
+	case Error:
+		if p.errors.Server == nil {
+			p.errors.Server = make(map[string]int64)
+		}
+		p.errors.Server[err.Code()]++
+	case protocolError:
+		p.errors.Protocol++
+	case net.Error:
+		switch {
+		case !err.Timeout():
+			p.errors.Connection++
+		case isWriteError(err):
+			p.errors.WriteTimeout++
+		default:
+			p.errors.ReadTimeout++
+		}
+	default:
+		p.errors.Connection++
+	}
+}
+
+func isWriteError(err error) bool {
+	op, ok := err.(*net.OpError)
+	return ok && op.Op == "write"
+}
+
 // Close releases the resources used by the pool.
 func (p *Pool) Close() error {
 	p.mu.Lock()
@@ -229,13 +337,14 @@ func (p *Pool) get() (Conn, error) {
 				break
 			}
 			ic := e.Value.(idleConn)
-			if ic.t.Add(timeout).After(nowFunc()) {
+			if ic.t.Add(timeout).After(p.now()) {
 				break
 			}
 			p.idle.Remove(e)
 			p.release()
 			p.mu.Unlock()
 			ic.c.Close()
+			p.log(LogDebug, "closed idle connection", "idle", p.now().Sub(ic.t))
 			p.mu.Lock()
 		}
 	}
@@ -253,10 +362,15 @@ func (p *Pool) get() (Conn, error) {
 			p.idle.Remove(e)
 			test := p.TestOnBorrow
 			p.mu.Unlock()
-			if test == nil || test(ic.c, ic.t) == nil {
+			if test == nil {
+				return ic.c, nil
+			}
+			err := test(ic.c, ic.t)
+			if err == nil {
 				return ic.c, nil
 			}
 			ic.c.Close()
+			p.log(LogInfo, "closed connection that failed borrow test", "err", err)
 			p.mu.Lock()
 			p.release()
 		}
@@ -280,12 +394,19 @@ func (p *Pool) get() (Conn, error) {
 				p.release()
 				p.mu.Unlock()
 				c = nil
+				p.log(LogWarn, "dial failed", "err", err)
+				if p.CountErrors {
+					p.mu.Lock()
+					p.errors.Dial++
+					p.mu.Unlock()
+				}
 			}
 			return c, err
 		}
 
 		if !p.Wait {
 			p.mu.Unlock()
+			p.log(LogWarn, "pool exhausted", "maxActive", p.MaxActive)
 			return nil, ErrPoolExhausted
 		}
 
@@ -300,7 +421,7 @@ func (p *Pool) put(c Conn, forceClose bool) error {
 	err := c.Err()
 	p.mu.Lock()
 	if !p.closed && err == nil && !forceClose {
-		p.idle.PushFront(idleConn{t: nowFunc(), c: c})
+		p.idle.PushFront(idleConn{t: p.now(), c: c})
 		if p.idle.Len() > p.MaxIdle {
 			c = p.idle.Remove(p.idle.Back()).(idleConn).c
 		} else {
@@ -318,13 +439,46 @@ func (p *Pool) put(c Conn, forceClose bool) error {
 
 	p.release()
 	p.mu.Unlock()
+	if err != nil {
+		p.log(LogDebug, "closed broken connection", "err", err)
+	}
 	return c.Close()
 }
 
+func (p *Pool) now() time.Time {
+	if p.Clock != nil {
+		return p.Clock.Now()
+	}
+	return nowFunc()
+}
+
+func (p *Pool) log(level LogLevel, msg string, keyvals ...interface{}) {
+	if p.Logger != nil {
+		p.Logger.Log(level, msg, keyvals...)
+	}
+}
+
 type pooledConnection struct {
 	p     *Pool
 	c     Conn
 	state int
+
+	// connErrCounted is set when a connection error is counted.
+	connErrCounted bool
+}
+
+// checkError counts errors returned from the underlying connection.
+func (pc *pooledConnection) checkError(err error) error {
+	if err == nil || !pc.p.CountErrors {
+		return err
+	}
+	if _, ok := err.(Error); ok {
+		pc.p.countError(err)
+	} else if !pc.connErrCounted && pc.c.Err() != nil {
+		pc.connErrCounted = true
+		pc.p.countError(err)
+	}
+	return err
 }
 
 var (
@@ -361,6 +515,9 @@ func (pc *pooledConnection) Close() error {
 	if pc.state&internal.SubscribeState != 0 {
 		c.Send("UNSUBSCRIBE")
 		c.Send("PUNSUBSCRIBE")
+		if pc.state&internal.ShardSubscribeState != 0 {
+			c.Send("SUNSUBSCRIBE")
+		}
 		// To detect the end of the message stream, ask the server to echo
 		// a sentinel value and read until we see that value.
 		sentinelOnce.Do(initSentinel)
@@ -372,7 +529,7 @@ func (pc *pooledConnection) Close() error {
 				break
 			}
 			if p, ok := p.([]byte); ok && bytes.Equal(p, sentinel) {
-				pc.state &^= internal.SubscribeState
+				pc.state &^= internal.SubscribeState | internal.ShardSubscribeState
 				break
 			}
 		}
@@ -389,21 +546,28 @@ func (pc *pooledConnection) Err() error {
 func (pc *pooledConnection) Do(commandName string, args ...interface{}) (reply interface{}, err error) {
 	ci := internal.LookupCommandInfo(commandName)
 	pc.state = (pc.state | ci.Set) &^ ci.Clear
-	return pc.c.Do(commandName, args...)
+	reply, err = pc.c.Do(commandName, args...)
+	return reply, pc.checkError(err)
 }
 
 func (pc *pooledConnection) Send(commandName string, args ...interface{}) error {
 	ci := internal.LookupCommandInfo(commandName)
 	pc.state = (pc.state | ci.Set) &^ ci.Clear
-	return pc.c.Send(commandName, args...)
+	return pc.checkError(pc.c.Send(commandName, args...))
 }
 
 func (pc *pooledConnection) Flush() error {
-	return pc.c.Flush()
+	return pc.checkError(pc.c.Flush())
 }
 
 func (pc *pooledConnection) Receive() (reply interface{}, err error) {
-	return pc.c.Receive()
+	reply, err = pc.c.Receive()
+	return reply, pc.checkError(err)
+}
+
+func (pc *pooledConnection) ReceiveWithTimeout(timeout time.Duration) (reply interface{}, err error) {
+	reply, err = ReceiveWithTimeout(pc.c, timeout)
+	return reply, pc.checkError(err)
 }
 
 type errorConnection struct{ err error }
@@ -414,3 +578,7 @@ func (ec errorConnection) Err() error                                     { retu
 func (ec errorConnection) Close() error                                   { return ec.err }
 func (ec errorConnection) Flush() error                                   { return ec.err }
 func (ec errorConnection) Receive() (interface{}, error)                  { return nil, ec.err }
+
+func (ec errorConnection) ReceiveWithTimeout(time.Duration) (interface{}, error) {
+	return nil, ec.err
+}
@@ -15,14 +15,17 @@
 package redis_test
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
 )
 
 type poolTestConn struct {
@@ -118,6 +121,69 @@ func TestPoolReuse(t *testing.T) {
 	d.check("after close", p, 2, 0)
 }
 
+func TestPoolStats(t *testing.T) {
+	d := poolDialer{t: t}
+	p := &redis.Pool{
+		MaxIdle: 2,
+		Dial:    d.dial,
+	}
+	defer p.Close()
+
+	c1 := p.Get()
+	c1.Do("PING")
+	c2 := p.Get()
+	c2.Do("PING")
+	if stats := p.Stats(); stats.ActiveCount != 2 || stats.IdleCount != 0 {
+		t.Errorf("Stats() = %+v, want 2 active and 0 idle", stats)
+	}
+
+	c1.Close()
+	c2.Close()
+	expected := redis.PoolStats{ActiveCount: 2, IdleCount: 2}
+	if stats := p.Stats(); !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Stats() = %+v, want %+v", stats, expected)
+	}
+}
+
+func TestPoolCountErrors(t *testing.T) {
+	dialErr := errors.New("dial error")
+	replies := []string{"-ERR unknown command\r\n-WRONGTYPE bad type\r\n", "?garbage\r\n"}
+	p := &redis.Pool{
+		CountErrors: true,
+		Dial: func() (redis.Conn, error) {
+			if len(replies) == 0 {
+				return nil, dialErr
+			}
+			r := strings.NewReader(replies[0])
+			replies = replies[1:]
+			return redis.Dial("", "", dialTestConn(r, &bytes.Buffer{}))
+		},
+	}
+	defer p.Close()
+
+	c := p.Get()
+	c.Do("NOSUCHCOMMAND")
+	c.Do("INCR", "s")
+	c.Close()
+
+	c = p.Get()
+	c.Do("GET", "a")
+	c.Do("GET", "a")
+	c.Close()
+
+	c = p.Get()
+	c.Close()
+
+	expected := redis.PoolErrorStats{
+		Dial:     1,
+		Protocol: 1,
+		Server:   map[string]int64{"ERR": 1, "WRONGTYPE": 1},
+	}
+	if stats := p.Stats(); !reflect.DeepEqual(stats.Errors, expected) {
+		t.Errorf("Errors = %+v, want %+v", stats.Errors, expected)
+	}
+}
+
 func TestPoolMaxIdle(t *testing.T) {
 	d := poolDialer{t: t}
 	p := &redis.Pool{
@@ -233,6 +299,35 @@ func TestPoolTimeout(t *testing.T) {
 	d.check("2", p, 2, 1)
 }
 
+func TestPoolClock(t *testing.T) {
+	d := poolDialer{t: t}
+	clock := redistest.NewClock(time.Unix(1000, 0))
+	p := &redis.Pool{
+		MaxIdle:     2,
+		IdleTimeout: 300 * time.Second,
+		Dial:        d.dial,
+		Clock:       clock,
+	}
+	defer p.Close()
+
+	c := p.Get()
+	c.Do("PING")
+	c.Close()
+	d.check("1", p, 1, 1)
+
+	clock.Advance(p.IdleTimeout - time.Second)
+	c = p.Get()
+	c.Do("PING")
+	c.Close()
+	d.check("2", p, 1, 1)
+
+	clock.Advance(p.IdleTimeout)
+	c = p.Get()
+	c.Do("PING")
+	c.Close()
+	d.check("3", p, 2, 1)
+}
+
 func TestPoolConcurrenSendReceive(t *testing.T) {
 	p := &redis.Pool{
 		Dial: redis.DialDefaultServer,
@@ -14,12 +14,18 @@
 
 package redis
 
-import "errors"
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
 
 // Subscription represents a subscribe or unsubscribe notification.
 type Subscription struct {
 
-	// Kind is "subscribe", "unsubscribe", "psubscribe" or "punsubscribe"
+	// Kind is "subscribe", "unsubscribe", "psubscribe", "punsubscribe",
+	// "ssubscribe" or "sunsubscribe"
 	Kind string
 
 	// The channel that was changed.
@@ -37,6 +43,9 @@ type Message struct {
 
 	// The message data.
 	Data []byte
+
+	// Sharded is true if the message was published to a shard channel.
+	Sharded bool
 }
 
 // PMessage represents a pmessage notification.
@@ -52,14 +61,82 @@ type PMessage struct {
 	Data []byte
 }
 
+// Timeout is returned from the PubSubConn ReceiveWithTimeout method when no
+// message arrives before the timeout expires. The connection remains usable.
+type Timeout struct{}
+
 // Pong represents a pubsub pong notification.
 type Pong struct {
 	Data string
 }
 
 // PubSubConn wraps a Conn with convenience methods for subscribers.
+//
+// The Channels, Patterns and ShardChannels methods require a PubSubConn
+// created with NewPubSubConn. These methods return nil for a PubSubConn
+// created with a composite literal such as PubSubConn{Conn: c}.
 type PubSubConn struct {
 	Conn Conn
+
+	// subs is the subscription state confirmed by the server. The field is
+	// nil if the PubSubConn was not created with NewPubSubConn.
+	subs *subscriptions
+}
+
+// NewPubSubConn returns a PubSubConn for c. The returned PubSubConn tracks
+// the subscriptions confirmed by the server for the Channels, Patterns and
+// ShardChannels methods.
+func NewPubSubConn(c Conn) PubSubConn {
+	return PubSubConn{Conn: c, subs: &subscriptions{}}
+}
+
+// subscriptions tracks the subscriptions on a connection.
+type subscriptions struct {
+	mu            sync.Mutex
+	channels      map[string]bool
+	patterns      map[string]bool
+	shardChannels map[string]bool
+}
+
+// update applies a subscription confirmation to the state.
+func (s *subscriptions) update(kind, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var set *map[string]bool
+	switch kind {
+	case "subscribe", "unsubscribe":
+		set = &s.channels
+	case "psubscribe", "punsubscribe":
+		set = &s.patterns
+	case "ssubscribe", "sunsubscribe":
+		set = &s.shardChannels
+	default:
+		return
+	}
+	switch kind {
+	case "subscribe", "psubscribe", "ssubscribe":
+		if *set == nil {
+			*set = make(map[string]bool)
+		}
+		(*set)[name] = true
+	default:
+		delete(*set, name)
+	}
+}
+
+// names returns the sorted names in *set.
+func (s *subscriptions) names(set *map[string]bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(*set) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(*set))
+	for name := range *set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // Close closes the connection.
@@ -93,6 +170,64 @@ func (c PubSubConn) PUnsubscribe(channel ...interface{}) error {
 	return c.Conn.Flush()
 }
 
+// SSubscribe subscribes the connection to the specified shard channels.
+func (c PubSubConn) SSubscribe(channel ...interface{}) error {
+	c.Conn.Send("SSUBSCRIBE", channel...)
+	return c.Conn.Flush()
+}
+
+// SUnsubscribe unsubscribes the connection from the given shard channels, or
+// from all of them if none is given.
+func (c PubSubConn) SUnsubscribe(channel ...interface{}) error {
+	c.Conn.Send("SUNSUBSCRIBE", channel...)
+	return c.Conn.Flush()
+}
+
+// UnsubscribeAll unsubscribes the connection from all channels and patterns.
+// Shard channels are also unsubscribed if the PubSubConn was created with
+// NewPubSubConn and has shard channel subscriptions.
+func (c PubSubConn) UnsubscribeAll() error {
+	c.Conn.Send("UNSUBSCRIBE")
+	c.Conn.Send("PUNSUBSCRIBE")
+	if len(c.ShardChannels()) > 0 {
+		c.Conn.Send("SUNSUBSCRIBE")
+	}
+	return c.Conn.Flush()
+}
+
+// Channels returns the sorted names of the channels that the connection is
+// subscribed to. Subscriptions are tracked from the confirmations returned by
+// Receive, so a subscription is not reported until its confirmation is
+// received. Channels returns nil if the PubSubConn was not created with
+// NewPubSubConn.
+func (c PubSubConn) Channels() []string {
+	if c.subs == nil {
+		return nil
+	}
+	return c.subs.names(&c.subs.channels)
+}
+
+// Patterns returns the sorted patterns that the connection is subscribed to.
+// See the Channels method for how subscriptions are tracked. Patterns
+// returns nil if the PubSubConn was not created with NewPubSubConn.
+func (c PubSubConn) Patterns() []string {
+	if c.subs == nil {
+		return nil
+	}
+	return c.subs.names(&c.subs.patterns)
+}
+
+// ShardChannels returns the sorted names of the shard channels that the
+// connection is subscribed to. See the Channels method for how subscriptions
+// are tracked. ShardChannels returns nil if the PubSubConn was not created
+// with NewPubSubConn.
+func (c PubSubConn) ShardChannels() []string {
+	if c.subs == nil {
+		return nil
+	}
+	return c.subs.names(&c.subs.shardChannels)
+}
+
 // Ping sends a PING to the server with the specified data.
 func (c PubSubConn) Ping(data string) error {
 	c.Conn.Send("PING", data)
@@ -103,7 +238,22 @@ func (c PubSubConn) Ping(data string) error {
 // or error. The return value is intended to be used directly in a type switch
 // as illustrated in the PubSubConn example.
 func (c PubSubConn) Receive() interface{} {
-	reply, err := Values(c.Conn.Receive())
+	return c.receiveInternal(c.Conn.Receive())
+}
+
+// ReceiveWithTimeout is like Receive, but it returns Timeout if no message
+// arrives before the timeout expires. Use ReceiveWithTimeout to interleave
+// periodic work with receiving messages.
+func (c PubSubConn) ReceiveWithTimeout(timeout time.Duration) interface{} {
+	reply, err := ReceiveWithTimeout(c.Conn, timeout)
+	if err == ErrReceiveTimeout {
+		return Timeout{}
+	}
+	return c.receiveInternal(reply, err)
+}
+
+func (c PubSubConn) receiveInternal(replyArg interface{}, errArg error) interface{} {
+	reply, err := Values(replyArg, errArg)
 	if err != nil {
 		return err
 	}
@@ -115,8 +265,8 @@ func (c PubSubConn) Receive() interface{} {
 	}
 
 	switch kind {
-	case "message":
-		var m Message
+	case "message", "smessage":
+		m := Message{Sharded: kind == "smessage"}
 		if _, err := Scan(reply, &m.Channel, &m.Data); err != nil {
 			return err
 		}
@@ -127,11 +277,14 @@ func (c PubSubConn) Receive() interface{} {
 			return err
 		}
 		return pm
-	case "subscribe", "psubscribe", "unsubscribe", "punsubscribe":
+	case "subscribe", "psubscribe", "unsubscribe", "punsubscribe", "ssubscribe", "sunsubscribe":
 		s := Subscription{Kind: kind}
 		if _, err := Scan(reply, &s.Channel, &s.Count); err != nil {
 			return err
 		}
+		if c.subs != nil {
+			c.subs.update(s.Kind, s.Channel)
+		}
 		return s
 	case "pong":
 		var p Pong
@@ -15,10 +15,13 @@
 package redis_test
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/garyburd/redigo/redis"
 )
@@ -146,3 +149,93 @@ func TestPushed(t *testing.T) {
 	c.Conn.Flush()
 	expectPushed(t, c, `Send("PING")`, redis.Pong{})
 }
+
+func TestPushedShard(t *testing.T) {
+	var buf bytes.Buffer
+	r := strings.NewReader("" +
+		"*3\r\n$10\r\nssubscribe\r\n$2\r\ns1\r\n:1\r\n" +
+		"*3\r\n$8\r\nsmessage\r\n$2\r\ns1\r\n$5\r\nhello\r\n" +
+		"*3\r\n$12\r\nsunsubscribe\r\n$2\r\ns1\r\n:0\r\n")
+	sc, _ := redis.Dial("", "", dialTestConn(r, &buf))
+	c := redis.PubSubConn{Conn: sc}
+
+	c.SSubscribe("s1")
+	expectPushed(t, c, "SSubscribe(s1)", redis.Subscription{Kind: "ssubscribe", Channel: "s1", Count: 1})
+	expectPushed(t, c, "SPUBLISH s1 hello", redis.Message{Channel: "s1", Data: []byte("hello"), Sharded: true})
+	c.SUnsubscribe("s1")
+	expectPushed(t, c, "SUnsubscribe(s1)", redis.Subscription{Kind: "sunsubscribe", Channel: "s1", Count: 0})
+
+	expected := "*2\r\n$10\r\nSSUBSCRIBE\r\n$2\r\ns1\r\n*2\r\n$12\r\nSUNSUBSCRIBE\r\n$2\r\ns1\r\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("commands = %q, want %q", actual, expected)
+	}
+}
+
+func TestPubSubReceiveWithTimeout(t *testing.T) {
+	pc, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	sc, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer sc.Close()
+
+	c := redis.PubSubConn{Conn: sc}
+
+	c.Subscribe("c1")
+	expectPushed(t, c, "Subscribe(c1)", redis.Subscription{Kind: "subscribe", Channel: "c1", Count: 1})
+
+	if v := c.ReceiveWithTimeout(50 * time.Millisecond); v != (redis.Timeout{}) {
+		t.Fatalf("ReceiveWithTimeout() returned %v, want Timeout", v)
+	}
+
+	// The connection is usable after the timeout.
+	pc.Do("PUBLISH", "c1", "hello")
+	v := c.ReceiveWithTimeout(time.Second)
+	if m, ok := v.(redis.Message); !ok || string(m.Data) != "hello" {
+		t.Fatalf("ReceiveWithTimeout() returned %v, want message hello", v)
+	}
+}
+
+func TestPubSubSubscriptions(t *testing.T) {
+	var buf bytes.Buffer
+	r := strings.NewReader("" +
+		"*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n" +
+		"*3\r\n$9\r\nsubscribe\r\n$1\r\nb\r\n:2\r\n" +
+		"*3\r\n$10\r\npsubscribe\r\n$2\r\np*\r\n:3\r\n" +
+		"*3\r\n$11\r\nunsubscribe\r\n$1\r\na\r\n:2\r\n" +
+		"*3\r\n$11\r\nunsubscribe\r\n$1\r\nb\r\n:1\r\n" +
+		"*3\r\n$12\r\npunsubscribe\r\n$2\r\np*\r\n:0\r\n")
+	sc, _ := redis.Dial("", "", dialTestConn(r, &buf))
+	c := redis.NewPubSubConn(sc)
+
+	c.Subscribe("a", "b")
+	c.PSubscribe("p*")
+	for i := 0; i < 3; i++ {
+		c.Receive()
+	}
+	if channels := c.Channels(); !reflect.DeepEqual(channels, []string{"a", "b"}) {
+		t.Errorf("Channels() = %v, want [a b]", channels)
+	}
+	if patterns := c.Patterns(); !reflect.DeepEqual(patterns, []string{"p*"}) {
+		t.Errorf("Patterns() = %v, want [p*]", patterns)
+	}
+
+	buf.Reset()
+	c.UnsubscribeAll()
+	for i := 0; i < 3; i++ {
+		c.Receive()
+	}
+	if channels, patterns := c.Channels(), c.Patterns(); channels != nil || patterns != nil {
+		t.Errorf("Channels(), Patterns() = %v, %v, want nil, nil", channels, patterns)
+	}
+
+	expected := "*1\r\n$11\r\nUNSUBSCRIBE\r\n*1\r\n$12\r\nPUNSUBSCRIBE\r\n"
+	if actual := buf.String(); actual != expected {
+		t.Errorf("commands = %q, want %q", actual, expected)
+	}
+}
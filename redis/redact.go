@@ -0,0 +1,111 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// KeyRedaction specifies how keys are reported by a RedactionPolicy.
+type KeyRedaction int
+
+const (
+	// KeysVisible reports keys as is.
+	KeysVisible KeyRedaction = iota
+
+	// KeysHashed replaces keys with a hash of the key. The hash is stable,
+	// so all commands on a key can be found without revealing the key.
+	KeysHashed
+
+	// KeysHidden replaces keys with "?".
+	KeysHidden
+)
+
+// RedactionPolicy specifies how command arguments are reported in logs,
+// traces and the slow command log. Argument values other than keys are
+// always replaced with "?". The zero value reports keys as is.
+//
+// Keys are located using a table of common commands. All arguments to other
+// commands are replaced with "?".
+//
+// Use the DialRedaction option to set the policy for the DialLogger and
+// DialSlowCommandLog options and NewLoggingConnWithPolicy to set the policy
+// for a logging connection.
+type RedactionPolicy struct {
+	Keys KeyRedaction
+
+	// HashKey is an optional secret used to hash keys with HMAC-SHA256.
+	// Without a secret, short keys can be recovered from the hash by a
+	// brute force search.
+	HashKey []byte
+}
+
+// DefaultRedactionPolicy is the policy used when no policy is specified.
+var DefaultRedactionPolicy = &RedactionPolicy{}
+
+// Args returns the command arguments as strings with the policy applied. A
+// nil policy is equivalent to DefaultRedactionPolicy.
+func (p *RedactionPolicy) Args(commandName string, args []interface{}) []string {
+	if p == nil {
+		p = DefaultRedactionPolicy
+	}
+	result := make([]string, len(args))
+	for i := range result {
+		result[i] = "?"
+	}
+	indexes, _ := keyIndexes(commandName, args)
+	for _, i := range indexes {
+		result[i] = p.key(argString(args[i]))
+	}
+	return result
+}
+
+// Statement returns the command name followed by the keys with the policy
+// applied. The statement is suitable for the db.statement attribute of a
+// trace span.
+func (p *RedactionPolicy) Statement(commandName string, args []interface{}) string {
+	if p == nil {
+		p = DefaultRedactionPolicy
+	}
+	parts := []string{commandName}
+	indexes, _ := keyIndexes(commandName, args)
+	for _, i := range indexes {
+		parts = append(parts, p.key(argString(args[i])))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (p *RedactionPolicy) key(key string) string {
+	switch p.Keys {
+	case KeysHashed:
+		h := hmac.New(sha256.New, p.HashKey)
+		h.Write([]byte(key))
+		return "#" + hex.EncodeToString(h.Sum(nil)[:8])
+	case KeysHidden:
+		return "?"
+	}
+	return key
+}
+
+// DialRedaction specifies the redaction policy for commands reported by the
+// DialLogger and DialSlowCommandLog options.
+func DialRedaction(p *RedactionPolicy) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.redaction = p
+	}}
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var redactionPolicyTests = []struct {
+	policy      *redis.RedactionPolicy
+	commandName string
+	args        []interface{}
+	expected    []string
+	statement   string
+}{
+	{nil, "SET", []interface{}{"k", "v"}, []string{"k", "?"}, "SET k"},
+	{&redis.RedactionPolicy{Keys: redis.KeysHidden}, "MSET", []interface{}{"a", 1, "b", 2}, []string{"?", "?", "?", "?"}, "MSET ? ?"},
+	{&redis.RedactionPolicy{Keys: redis.KeysHashed}, "GET", []interface{}{"k"}, []string{"#40fc241f23ecd341"}, "GET #40fc241f23ecd341"},
+	{nil, "EVALSHA", []interface{}{"sha", 1, []byte("k"), "v"}, []string{"?", "?", "k", "?"}, "EVALSHA k"},
+}
+
+func TestRedactionPolicy(t *testing.T) {
+	for _, tt := range redactionPolicyTests {
+		if args := tt.policy.Args(tt.commandName, tt.args); !reflect.DeepEqual(args, tt.expected) {
+			t.Errorf("Args(%s, %v) = %q, want %q", tt.commandName, tt.args, args, tt.expected)
+		}
+		if s := tt.policy.Statement(tt.commandName, tt.args); s != tt.statement {
+			t.Errorf("Statement(%s, %v) = %q, want %q", tt.commandName, tt.args, s, tt.statement)
+		}
+	}
+}
+
+func TestRedactionPolicyCredentials(t *testing.T) {
+	const password = "hunter2"
+	for _, tt := range []struct {
+		commandName string
+		args        []interface{}
+	}{
+		{"AUTH", []interface{}{password}},
+		{"AUTH", []interface{}{"user", password}},
+		{"HELLO", []interface{}{3, "AUTH", "user", password}},
+		{"ACL", []interface{}{"SETUSER", "user", ">" + password}},
+		{"CONFIG", []interface{}{"SET", "requirepass", password}},
+		{"MIGRATE", []interface{}{"host", 6379, "k", 0, 1000, "AUTH", password}},
+		{"NOSUCHCOMMAND", []interface{}{password}},
+	} {
+		policy := &redis.RedactionPolicy{}
+		args := strings.Join(policy.Args(tt.commandName, tt.args), " ")
+		if strings.Contains(args, "user") || strings.Contains(args, password) {
+			t.Errorf("Args(%s, %v) = %q", tt.commandName, tt.args, args)
+		}
+		if s := policy.Statement(tt.commandName, tt.args); s != tt.commandName {
+			t.Errorf("Statement(%s, %v) = %q, want %q", tt.commandName, tt.args, s, tt.commandName)
+		}
+	}
+}
+
+func TestLoggingConn(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	var buf bytes.Buffer
+	lc := redis.NewLoggingConn(c, log.New(&buf, "", 0), "")
+	lc.Do("SET", "k", "v")
+	lc.Do("GET", "k")
+
+	const expected = "Do(SET, \"k\", \"v\") -> (\"OK\", <nil>)\nDo(GET, \"k\") -> (\"v\", <nil>)\n"
+	if s := buf.String(); s != expected {
+		t.Errorf("log = %q, want %q", s, expected)
+	}
+}
+
+func TestLoggingConnRedaction(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	var buf bytes.Buffer
+	lc := redis.NewLoggingConnWithPolicy(c, log.New(&buf, "", 0), "", nil)
+	lc.Do("SET", "k", "secret")
+	lc.Do("GET", "k")
+
+	const expected = "Do(SET, \"k\", ?) -> (?, <nil>)\nDo(GET, \"k\") -> (?, <nil>)\n"
+	if s := buf.String(); s != expected {
+		t.Errorf("log = %q, want %q", s, expected)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Error("value logged")
+	}
+
+	buf.Reset()
+	lc = redis.NewLoggingConnWithPolicy(c, log.New(&buf, "", 0), "", &redis.RedactionPolicy{Keys: redis.KeysHidden})
+	lc.Do("GET", "k")
+	if s := buf.String(); s != "Do(GET, ?) -> (?, <nil>)\n" {
+		t.Errorf("log with KeysHidden = %q", s)
+	}
+}
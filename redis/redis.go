@@ -14,11 +14,65 @@
 
 package redis
 
+import "time"
+
 // Error represents an error returned in a command reply.
 type Error string
 
 func (err Error) Error() string { return string(err) }
 
+// Code returns the error code at the start of the error. Examples of codes
+// are "ERR", "WRONGTYPE", "BUSYGROUP", "OOM" and "NOPERM". Code returns ""
+// if the error does not start with a code.
+func (err Error) Code() string {
+	code, _ := err.split()
+	return code
+}
+
+// Message returns the error with the code removed.
+func (err Error) Message() string {
+	_, msg := err.split()
+	return msg
+}
+
+// split splits the error into the code and the message. The code is the
+// first word of the error if the word is all upper case letters.
+func (err Error) split() (string, string) {
+	s := string(err)
+	i := 0
+	for i < len(s) && 'A' <= s[i] && s[i] <= 'Z' {
+		i++
+	}
+	switch {
+	case i == 0:
+		return "", s
+	case i == len(s):
+		return s, ""
+	case s[i] == ' ':
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
+
+// ErrorCode returns the code of err if err is an Error. Otherwise,
+// ErrorCode returns "". ErrorCode is a convenience for switching on error
+// codes:
+//
+//  _, err := c.Do("XGROUP", "CREATE", "stream", "group", "$", "MKSTREAM")
+//  switch redis.ErrorCode(err) {
+//  case "":
+//  case "BUSYGROUP":
+//      // the group exists
+//  default:
+//      // handle error
+//  }
+func ErrorCode(err error) string {
+	if err, ok := err.(Error); ok {
+		return err.Code()
+	}
+	return ""
+}
+
 // Conn represents a connection to a Redis server.
 type Conn interface {
 	// Close closes the connection.
@@ -42,3 +96,15 @@ type Conn interface {
 	// Receive receives a single reply from the Redis server
 	Receive() (reply interface{}, err error)
 }
+
+// ConnWithTimeout is an optional interface that allows the caller to override
+// a connection's default read timeout.
+type ConnWithTimeout interface {
+	Conn
+
+	// ReceiveWithTimeout receives a single reply from the Redis server. If
+	// the reply does not start to arrive before the timeout expires, then
+	// ReceiveWithTimeout returns ErrReceiveTimeout and the connection
+	// remains usable. A zero timeout waits indefinitely.
+	ReceiveWithTimeout(timeout time.Duration) (reply interface{}, err error)
+}
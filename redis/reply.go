@@ -317,6 +317,224 @@ func Ints(reply interface{}, err error) ([]int, error) {
 	return ints, nil
 }
 
+// Int64s is a helper that converts an array command reply to a []int64. If
+// err is not equal to nil, then Int64s returns nil, err.
+func Int64s(reply interface{}, err error) ([]int64, error) {
+	var int64s []int64
+	values, err := Values(reply, err)
+	if err != nil {
+		return int64s, err
+	}
+	if err := ScanSlice(values, &int64s); err != nil {
+		return int64s, err
+	}
+	return int64s, nil
+}
+
+// Int32s is a helper that converts an array command reply to a []int32. If
+// err is not equal to nil, then Int32s returns nil, err.
+func Int32s(reply interface{}, err error) ([]int32, error) {
+	var int32s []int32
+	values, err := Values(reply, err)
+	if err != nil {
+		return int32s, err
+	}
+	if err := ScanSlice(values, &int32s); err != nil {
+		return int32s, err
+	}
+	return int32s, nil
+}
+
+// Int8s is a helper that converts an array command reply to a []int8. If
+// err is not equal to nil, then Int8s returns nil, err.
+func Int8s(reply interface{}, err error) ([]int8, error) {
+	var int8s []int8
+	values, err := Values(reply, err)
+	if err != nil {
+		return int8s, err
+	}
+	if err := ScanSlice(values, &int8s); err != nil {
+		return int8s, err
+	}
+	return int8s, nil
+}
+
+// Uints is a helper that converts an array command reply to a []uint. If
+// err is not equal to nil, then Uints returns nil, err.
+func Uints(reply interface{}, err error) ([]uint, error) {
+	var uints []uint
+	values, err := Values(reply, err)
+	if err != nil {
+		return uints, err
+	}
+	if err := ScanSlice(values, &uints); err != nil {
+		return uints, err
+	}
+	return uints, nil
+}
+
+// Uint64s is a helper that converts an array command reply to a []uint64. If
+// err is not equal to nil, then Uint64s returns nil, err.
+func Uint64s(reply interface{}, err error) ([]uint64, error) {
+	var uint64s []uint64
+	values, err := Values(reply, err)
+	if err != nil {
+		return uint64s, err
+	}
+	if err := ScanSlice(values, &uint64s); err != nil {
+		return uint64s, err
+	}
+	return uint64s, nil
+}
+
+// Float64s is a helper that converts an array command reply to a []float64.
+// If err is not equal to nil, then Float64s returns nil, err.
+func Float64s(reply interface{}, err error) ([]float64, error) {
+	var floats []float64
+	values, err := Values(reply, err)
+	if err != nil {
+		return floats, err
+	}
+	if err := ScanSlice(values, &floats); err != nil {
+		return floats, err
+	}
+	return floats, nil
+}
+
+// Float32s is a helper that converts an array command reply to a []float32. If
+// err is not equal to nil, then Float32s returns nil, err.
+func Float32s(reply interface{}, err error) ([]float32, error) {
+	var floats []float32
+	values, err := Values(reply, err)
+	if err != nil {
+		return floats, err
+	}
+	if err := ScanSlice(values, &floats); err != nil {
+		return floats, err
+	}
+	return floats, nil
+}
+
+// Bools is a helper that converts an array command reply to a []bool. The
+// integer replies 0 and 1 returned by commands such as SMISMEMBER and SCRIPT
+// EXISTS are converted to false and true. If err is not equal to nil, then
+// Bools returns nil, err.
+func Bools(reply interface{}, err error) ([]bool, error) {
+	var bools []bool
+	values, err := Values(reply, err)
+	if err != nil {
+		return bools, err
+	}
+	if err := ScanSlice(values, &bools); err != nil {
+		return bools, err
+	}
+	return bools, nil
+}
+
+// DecodeReply is a helper that converts a command reply to a tree of native
+// Go values. Bulk strings are converted to string and the elements of arrays
+// and maps are converted recursively. Error replies nested in the tree are
+// kept as Error values. If err is not equal to nil, then DecodeReply returns
+// nil, err. If the reply is an Error, then DecodeReply returns nil, reply.
+//
+// DecodeReply is intended for use with connections dialed with the
+// DialNativeTypes option. For these connections, the result is composed of
+// map[string]interface{}, []interface{}, string, int64, float64, bool and
+// nil values.
+func DecodeReply(reply interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := reply.(Error); ok {
+		return nil, err
+	}
+	return decodeReply(reply), nil
+}
+
+func decodeReply(reply interface{}) interface{} {
+	switch reply := reply.(type) {
+	case []byte:
+		return string(reply)
+	case []interface{}:
+		result := make([]interface{}, len(reply))
+		for i, v := range reply {
+			result[i] = decodeReply(v)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(reply))
+		for k, v := range reply {
+			result[k] = decodeReply(v)
+		}
+		return result
+	}
+	return reply
+}
+
+// CursorAndValues is a helper that converts the two element reply to the
+// SCAN, HSCAN, SSCAN and ZSCAN commands to the next cursor and the array of
+// values. If err is not equal to nil, then CursorAndValues returns 0, nil,
+// err.
+//
+//  cursor := uint64(0)
+//  for {
+//      var keys []interface{}
+//      cursor, keys, err = redis.CursorAndValues(c.Do("SCAN", cursor))
+//      if err != nil {
+//          // handle error
+//      }
+//      // process keys
+//      if cursor == 0 {
+//          break
+//      }
+//  }
+func CursorAndValues(reply interface{}, err error) (uint64, []interface{}, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(values) != 2 {
+		return 0, nil, fmt.Errorf("redigo: CursorAndValues expects two element reply, got %d", len(values))
+	}
+	cursor, err := Uint64(values[0], nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	values, err = Values(values[1], nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	return cursor, values, nil
+}
+
+// CursorAndStrings is like CursorAndValues, except that the values are
+// converted to a []string as with the Strings helper.
+func CursorAndStrings(reply interface{}, err error) (uint64, []string, error) {
+	cursor, values, err := CursorAndValues(reply, err)
+	if err != nil {
+		return 0, nil, err
+	}
+	strings, err := Strings(values, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	return cursor, strings, nil
+}
+
+// CursorAndInts is like CursorAndValues, except that the values are
+// converted to a []int as with the Ints helper.
+func CursorAndInts(reply interface{}, err error) (uint64, []int, error) {
+	cursor, values, err := CursorAndValues(reply, err)
+	if err != nil {
+		return 0, nil, err
+	}
+	ints, err := Ints(values, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	return cursor, ints, nil
+}
+
 // StringMap is a helper that converts an array of strings (alternating key, value)
 // into a map[string]string. The HGETALL and CONFIG GET commands return replies in this format.
 // Requires an even number of values in result.
@@ -333,7 +551,7 @@ func StringMap(result interface{}, err error) (map[string]string, error) {
 		key, okKey := values[i].([]byte)
 		value, okValue := values[i+1].([]byte)
 		if !okKey || !okValue {
-			return nil, errors.New("redigo: ScanMap key not a bulk string value")
+			return nil, errors.New("redigo: StringMap key not a bulk string value")
 		}
 		m[string(key)] = string(value)
 	}
@@ -355,7 +573,7 @@ func IntMap(result interface{}, err error) (map[string]int, error) {
 	for i := 0; i < len(values); i += 2 {
 		key, ok := values[i].([]byte)
 		if !ok {
-			return nil, errors.New("redigo: ScanMap key not a bulk string value")
+			return nil, errors.New("redigo: IntMap key not a bulk string value")
 		}
 		value, err := Int(values[i+1], nil)
 		if err != nil {
@@ -381,7 +599,7 @@ func Int64Map(result interface{}, err error) (map[string]int64, error) {
 	for i := 0; i < len(values); i += 2 {
 		key, ok := values[i].([]byte)
 		if !ok {
-			return nil, errors.New("redigo: ScanMap key not a bulk string value")
+			return nil, errors.New("redigo: Int64Map key not a bulk string value")
 		}
 		value, err := Int64(values[i+1], nil)
 		if err != nil {
@@ -391,3 +609,29 @@ func Int64Map(result interface{}, err error) (map[string]int64, error) {
 	}
 	return m, nil
 }
+
+// Uint64Map is a helper that converts an array of strings (alternating key, value)
+// into a map[string]uint64. The HGETALL commands return replies in this format.
+// Requires an even number of values in result.
+func Uint64Map(result interface{}, err error) (map[string]uint64, error) {
+	values, err := Values(result, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, errors.New("redigo: Uint64Map expects even number of values result")
+	}
+	m := make(map[string]uint64, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].([]byte)
+		if !ok {
+			return nil, errors.New("redigo: Uint64Map key not a bulk string value")
+		}
+		value, err := Uint64(values[i+1], nil)
+		if err != nil {
+			return nil, err
+		}
+		m[string(key)] = value
+	}
+	return m, nil
+}
@@ -16,8 +16,11 @@ package redis_test
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/garyburd/redigo/redis"
 )
@@ -96,6 +99,161 @@ var replyTests = []struct {
 		ve(redis.Uint64(int64(-1), nil)),
 		ve(uint64(0), redis.ErrNegativeInt),
 	},
+	{
+		"uint64s([v1, v2])",
+		ve(redis.Uint64s([]interface{}{[]byte("18446744073709551615"), int64(5)}, nil)),
+		ve([]uint64{18446744073709551615, 5}, nil),
+	},
+	{
+		"uint64s(nil)",
+		ve(redis.Uint64s(nil, nil)),
+		ve([]uint64(nil), redis.ErrNil),
+	},
+	{
+		"uint64map([k1, v1, k2, v2])",
+		ve(redis.Uint64Map([]interface{}{[]byte("k1"), []byte("18446744073709551615"), []byte("k2"), int64(2)}, nil)),
+		ve(map[string]uint64{"k1": 18446744073709551615, "k2": 2}, nil),
+	},
+	{
+		"duration(10)",
+		ve(redis.Duration(int64(10), nil)),
+		ve(10*time.Second, nil),
+	},
+	{
+		"duration(-1)",
+		ve(redis.Duration(int64(-1), nil)),
+		ve(redis.NoExpiry, nil),
+	},
+	{
+		"durationmillis(-2)",
+		ve(redis.DurationMillis(int64(-2), nil)),
+		ve(redis.KeyNotFound, nil),
+	},
+	{
+		"durationmillis(1500)",
+		ve(redis.DurationMillis(int64(1500), nil)),
+		ve(1500*time.Millisecond, nil),
+	},
+	{
+		"time(1500000000)",
+		ve(redis.Time([]byte("1500000000"), nil)),
+		ve(time.Unix(1500000000, 0), nil),
+	},
+	{
+		"time([1500000000, 250])",
+		ve(redis.Time([]interface{}{[]byte("1500000000"), []byte("250")}, nil)),
+		ve(time.Unix(1500000000, 250000), nil),
+	},
+	{
+		"time(-2)",
+		ve(redis.Time(int64(-2), nil)),
+		ve(time.Time{}, redis.ErrNil),
+	},
+	{
+		"timemillis(1500000000123)",
+		ve(redis.TimeMillis(int64(1500000000123), nil)),
+		ve(time.Unix(1500000000, 123000000), nil),
+	},
+	{
+		"nullstring(v)",
+		ve(redis.NullStringReply([]byte("v"), nil)),
+		ve(redis.NullString{String: "v", Valid: true}, nil),
+	},
+	{
+		"nullstring(empty)",
+		ve(redis.NullStringReply([]byte(""), nil)),
+		ve(redis.NullString{String: "", Valid: true}, nil),
+	},
+	{
+		"nullstring(nil)",
+		ve(redis.NullStringReply(nil, nil)),
+		ve(redis.NullString{}, nil),
+	},
+	{
+		"nullint64(0)",
+		ve(redis.NullInt64Reply(int64(0), nil)),
+		ve(redis.NullInt64{Int64: 0, Valid: true}, nil),
+	},
+	{
+		"nullint64(nil)",
+		ve(redis.NullInt64Reply(nil, nil)),
+		ve(redis.NullInt64{}, nil),
+	},
+	{
+		"nullfloat64(1.5)",
+		ve(redis.NullFloat64Reply([]byte("1.5"), nil)),
+		ve(redis.NullFloat64{Float64: 1.5, Valid: true}, nil),
+	},
+	{
+		"int64s([v1, v2])",
+		ve(redis.Int64s([]interface{}{[]byte("4"), int64(-5)}, nil)),
+		ve([]int64{4, -5}, nil),
+	},
+	{
+		"int32s([v1, v2])",
+		ve(redis.Int32s([]interface{}{[]byte("4"), int64(-5)}, nil)),
+		ve([]int32{4, -5}, nil),
+	},
+	{
+		"int8s([v1, v2])",
+		ve(redis.Int8s([]interface{}{[]byte("127"), int64(-128)}, nil)),
+		ve([]int8{127, -128}, nil),
+	},
+	{
+		"uints([v1, v2])",
+		ve(redis.Uints([]interface{}{[]byte("4"), int64(5)}, nil)),
+		ve([]uint{4, 5}, nil),
+	},
+	{
+		"float32s([v1, v2])",
+		ve(redis.Float32s([]interface{}{[]byte("1.5"), []byte("-2")}, nil)),
+		ve([]float32{1.5, -2}, nil),
+	},
+	{
+		"bools([v1, v2])",
+		ve(redis.Bools([]interface{}{int64(1), int64(0)}, nil)),
+		ve([]bool{true, false}, nil),
+	},
+	{
+		"bitmapbools(bitmap)",
+		ve(redis.BitmapBools([]byte{0x81, 0x40}, nil)),
+		ve([]bool{true, false, false, false, false, false, false, true, false, true, false, false, false, false, false, false}, nil),
+	},
+	{
+		"bitmapbools(nil)",
+		ve(redis.BitmapBools(nil, nil)),
+		ve([]bool(nil), redis.ErrNil),
+	},
+	{
+		"bitmapint(bitmap)",
+		ve(redis.BitmapInt([]byte{0x81, 0x40}, nil)),
+		ve(big.NewInt(1<<0|1<<7|1<<9), nil),
+	},
+	{
+		"float64s([v1, v2])",
+		ve(redis.Float64s([]interface{}{[]byte("1.5"), []byte("-2")}, nil)),
+		ve([]float64{1.5, -2}, nil),
+	},
+	{
+		"scoredmembers([m1, s1, m2, s2])",
+		ve(redis.ScoredMembers([]interface{}{[]byte("a"), []byte("1"), []byte("b"), []byte("2.5")}, nil)),
+		ve([]redis.ScoredMember{{Member: "a", Score: 1}, {Member: "b", Score: 2.5}}, nil),
+	},
+	{
+		"scoredmembers([[m1, s1]])",
+		ve(redis.ScoredMembers([]interface{}{[]interface{}{[]byte("a"), []byte("-inf")}}, nil)),
+		ve([]redis.ScoredMember{{Member: "a", Score: math.Inf(-1)}}, nil),
+	},
+	{
+		"scoredmembers([])",
+		ve(redis.ScoredMembers([]interface{}{}, nil)),
+		ve([]redis.ScoredMember{}, nil),
+	},
+	{
+		"int64map([k1, v1])",
+		ve(redis.Int64Map([]interface{}{[]byte("k1"), int64(-1)}, nil)),
+		ve(map[string]int64{"k1": -1}, nil),
+	},
 }
 
 func TestReply(t *testing.T) {
@@ -110,6 +268,594 @@ func TestReply(t *testing.T) {
 	}
 }
 
+const infoText = "# Server\r\n" +
+	"redis_version:3.2.8\r\n" +
+	"redis_mode:standalone\r\n" +
+	"process_id:42\r\n" +
+	"tcp_port:6379\r\n" +
+	"uptime_in_seconds:3600\r\n" +
+	"\r\n" +
+	"# Clients\r\n" +
+	"connected_clients:3\r\n" +
+	"blocked_clients:1\r\n" +
+	"\r\n" +
+	"# Memory\r\n" +
+	"used_memory:1048576\r\n" +
+	"used_memory_human:1.00M\r\n" +
+	"maxmemory_policy:noeviction\r\n" +
+	"mem_fragmentation_ratio:1.25\r\n" +
+	"\r\n" +
+	"# Persistence\r\n" +
+	"loading:0\r\n" +
+	"aof_enabled:1\r\n" +
+	"\r\n" +
+	"# Replication\r\n" +
+	"role:master\r\n" +
+	"connected_slaves:2\r\n" +
+	"\r\n" +
+	"# Keyspace\r\n" +
+	"db0:keys=10,expires=2,avg_ttl=5000\r\n" +
+	"db3:keys=1,expires=0,avg_ttl=0\r\n"
+
+func TestCursorAndValues(t *testing.T) {
+	reply := []interface{}{[]byte("17"), []interface{}{[]byte("1"), []byte("2")}}
+
+	cursor, values, err := redis.CursorAndValues(reply, nil)
+	if cursor != 17 || !reflect.DeepEqual(values, []interface{}{[]byte("1"), []byte("2")}) || err != nil {
+		t.Errorf("CursorAndValues() = %d, %v, %v, want 17, [1 2], nil", cursor, values, err)
+	}
+	cursor, strings, err := redis.CursorAndStrings(reply, nil)
+	if cursor != 17 || !reflect.DeepEqual(strings, []string{"1", "2"}) || err != nil {
+		t.Errorf("CursorAndStrings() = %d, %v, %v, want 17, [1 2], nil", cursor, strings, err)
+	}
+	cursor, ints, err := redis.CursorAndInts(reply, nil)
+	if cursor != 17 || !reflect.DeepEqual(ints, []int{1, 2}) || err != nil {
+		t.Errorf("CursorAndInts() = %d, %v, %v, want 17, [1 2], nil", cursor, ints, err)
+	}
+	if _, _, err := redis.CursorAndValues([]interface{}{[]byte("0")}, nil); err == nil {
+		t.Error("CursorAndValues(one element) did not return error")
+	}
+}
+
+func TestInfoReply(t *testing.T) {
+	info, err := redis.InfoReply([]byte(infoText), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := redis.Info{
+		Server:      redis.InfoServer{Version: "3.2.8", Mode: "standalone", ProcessID: 42, TCPPort: 6379, UptimeInSeconds: 3600},
+		Clients:     redis.InfoClients{ConnectedClients: 3, BlockedClients: 1},
+		Memory:      redis.InfoMemory{UsedMemory: 1048576, MaxMemoryPolicy: "noeviction", MemFragmentationRatio: 1.25},
+		Persistence: redis.InfoPersistence{AOFEnabled: true},
+		Replication: redis.InfoReplication{Role: "master", ConnectedSlaves: 2},
+		Keyspace: map[int]redis.InfoKeyspace{
+			0: {Keys: 10, Expires: 2, AvgTTL: 5000},
+			3: {Keys: 1},
+		},
+		Sections: info.Sections,
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("InfoReply() = %+v, want %+v", info, expected)
+	}
+	if v := info.Sections["memory"]["used_memory_human"]; v != "1.00M" {
+		t.Errorf("Sections[memory][used_memory_human] = %q, want 1.00M", v)
+	}
+
+	if _, err := redis.InfoReply(nil, nil); err != redis.ErrNil {
+		t.Errorf("InfoReply(nil) returned err %v, want ErrNil", err)
+	}
+}
+
+func TestClientInfos(t *testing.T) {
+	reply := []byte("id=3 addr=127.0.0.1:50188 laddr=127.0.0.1:6379 fd=8 name=worker age=120 idle=5 flags=N db=2 sub=0 cmd=client|list user=default resp=3\n" +
+		"id=4 addr=127.0.0.1:50190 fd=9 name= age=1 idle=1 flags=P db=0 cmd=subscribe\n")
+	infos, err := redis.ClientInfos(reply, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("len(infos) = %d, want 2", len(infos))
+	}
+	for _, tt := range []struct {
+		actual, expected redis.ClientInfo
+	}{
+		{infos[0], redis.ClientInfo{ID: 3, Addr: "127.0.0.1:50188", LocalAddr: "127.0.0.1:6379", Name: "worker", User: "default", DB: 2,
+			Age: 120 * time.Second, Idle: 5 * time.Second, Flags: "N", Cmd: "client|list", Resp: 3, Fields: infos[0].Fields}},
+		{infos[1], redis.ClientInfo{ID: 4, Addr: "127.0.0.1:50190", Age: time.Second, Idle: time.Second, Flags: "P", Cmd: "subscribe", Fields: infos[1].Fields}},
+	} {
+		if !reflect.DeepEqual(tt.actual, tt.expected) {
+			t.Errorf("ClientInfo = %+v, want %+v", tt.actual, tt.expected)
+		}
+	}
+	if fd := infos[1].Fields["fd"]; fd != "9" {
+		t.Errorf("Fields[fd] = %q, want 9", fd)
+	}
+
+	if _, err := redis.ClientInfos([]byte("id=x"), nil); err == nil {
+		t.Error("ClientInfos(id=x) did not return error")
+	}
+}
+
+func TestSlowLogs(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{int64(14), int64(1309448221), int64(15),
+			[]interface{}{[]byte("ping")}, []byte("127.0.0.1:58217"), []byte("worker")},
+		[]interface{}{int64(13), int64(1309448128), int64(30),
+			[]interface{}{[]byte("slowlog"), []byte("get"), []byte("100")}},
+	}
+	actual, err := redis.SlowLogs(reply, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []redis.SlowLog{
+		{ID: 14, Time: time.Unix(1309448221, 0), Duration: 15 * time.Microsecond,
+			Args: []string{"ping"}, ClientAddr: "127.0.0.1:58217", ClientName: "worker"},
+		{ID: 13, Time: time.Unix(1309448128, 0), Duration: 30 * time.Microsecond,
+			Args: []string{"slowlog", "get", "100"}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("SlowLogs() = %+v, want %+v", actual, expected)
+	}
+
+	if _, err := redis.SlowLogs([]interface{}{[]interface{}{int64(1)}}, nil); err == nil {
+		t.Error("SlowLogs(short entry) did not return error")
+	}
+}
+
+func TestMemoryStatsReply(t *testing.T) {
+	reply := []interface{}{
+		[]byte("peak.allocated"), int64(1000),
+		[]byte("total.allocated"), int64(900),
+		[]byte("db.0"), []interface{}{
+			[]byte("overhead.hashtable.main"), int64(72),
+			[]byte("overhead.hashtable.expires"), int64(32),
+		},
+		[]byte("keys.count"), int64(3),
+		[]byte("dataset.percentage"), []byte("41.5"),
+		[]byte("fragmentation"), []byte("1.25"),
+	}
+	actual, err := redis.MemoryStatsReply(reply, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := redis.MemoryStats{
+		PeakAllocated:     1000,
+		TotalAllocated:    900,
+		KeysCount:         3,
+		DatasetPercentage: 41.5,
+		Fragmentation:     1.25,
+		DB:                map[int]redis.MemoryStatsDB{0: {OverheadMain: 72, OverheadExpires: 32}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("MemoryStatsReply() = %+v, want %+v", actual, expected)
+	}
+}
+
+func TestStreamEntries(t *testing.T) {
+	actual, err := redis.StreamEntries([]interface{}{
+		[]interface{}{[]byte("1-0"), []interface{}{[]byte("a"), []byte("1")}},
+		[]interface{}{[]byte("2-0"), nil},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []redis.StreamEntry{
+		{ID: "1-0", Fields: map[string]string{"a": "1"}},
+		{ID: "2-0"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("StreamEntries() = %+v, want %+v", actual, expected)
+	}
+}
+
+func TestStreamMessages(t *testing.T) {
+	entries := func(id, n string) interface{} {
+		return []interface{}{[]interface{}{[]byte(id), []interface{}{[]byte("n"), []byte(n)}}}
+	}
+	expected := []redis.StreamMessage{
+		{Stream: "a", StreamEntry: redis.StreamEntry{ID: "1-2", Fields: map[string]string{"n": "1"}}},
+		{Stream: "b", StreamEntry: redis.StreamEntry{ID: "3-0", Fields: map[string]string{"n": "2"}}},
+	}
+	for _, reply := range []interface{}{
+		[]interface{}{
+			[]interface{}{[]byte("a"), entries("1-2", "1")},
+			[]interface{}{[]byte("b"), entries("3-0", "2")},
+		},
+		map[string]interface{}{"b": entries("3-0", "2"), "a": entries("1-2", "1")},
+	} {
+		actual, err := redis.StreamMessages(reply, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("StreamMessages(%v) = %+v, want %+v", reply, actual, expected)
+		}
+	}
+	if id, err := expected[0].StreamID(); err != nil || id != (redis.StreamID{Ms: 1, Seq: 2}) {
+		t.Errorf("StreamID() = %v, %v, want 1-2", id, err)
+	}
+	if _, err := redis.StreamMessages(nil, nil); err != redis.ErrNil {
+		t.Errorf("StreamMessages(nil) returned %v, want %v", err, redis.ErrNil)
+	}
+}
+
+func TestFunctionLibraries(t *testing.T) {
+	actual, err := redis.FunctionLibraries([]interface{}{
+		[]interface{}{
+			[]byte("library_name"), []byte("mylib"),
+			[]byte("engine"), []byte("LUA"),
+			[]byte("functions"), []interface{}{
+				[]interface{}{
+					[]byte("name"), []byte("f"),
+					[]byte("description"), nil,
+					[]byte("flags"), []interface{}{"no-writes"},
+				},
+			},
+			[]byte("library_code"), []byte("#!lua name=mylib"),
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []redis.FunctionLibrary{{
+		Name:      "mylib",
+		Engine:    "LUA",
+		Functions: []redis.FunctionInfo{{Name: "f", Flags: []string{"no-writes"}}},
+		Code:      "#!lua name=mylib",
+	}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("FunctionLibraries() = %+v, want %+v", actual, expected)
+	}
+}
+
+func TestXAddStruct(t *testing.T) {
+	c, err := dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	type event struct {
+		Kind string `redis:"kind"`
+		User string `redis:"user"`
+	}
+	var ids []redis.StreamID
+	for _, id := range []string{"1-1", "2-0", "3-0"} {
+		v, err := redis.XAddStruct(c, "s", &event{Kind: "login", User: "gary"}, redis.XAddID(id), redis.XAddMaxLen(2, false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, v)
+	}
+	if expected := (redis.StreamID{Ms: 1, Seq: 1}); ids[0] != expected {
+		t.Errorf("XAddStruct() = %v, want %v", ids[0], expected)
+	}
+	if !ids[1].Less(ids[2]) || ids[2].Less(ids[1]) {
+		t.Errorf("Less(%v, %v) not ordered", ids[1], ids[2])
+	}
+
+	entries, err := redis.StreamEntries(c.Do("XRANGE", "s", "-", "+"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []redis.StreamEntry{
+		{ID: "2-0", Fields: map[string]string{"kind": "login", "user": "gary"}},
+		{ID: "3-0", Fields: map[string]string{"kind": "login", "user": "gary"}},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("XRANGE = %+v, want %+v", entries, expected)
+	}
+
+	if _, err := redis.XAddStruct(c, "missing", &event{Kind: "x"}, redis.XAddNoMkStream()); err != redis.ErrNil {
+		t.Errorf("XAddStruct(NOMKSTREAM) returned %v, want %v", err, redis.ErrNil)
+	}
+}
+
+func TestParseStreamID(t *testing.T) {
+	for _, tt := range []struct {
+		s        string
+		expected redis.StreamID
+		ok       bool
+	}{
+		{"1526919030474-55", redis.StreamID{Ms: 1526919030474, Seq: 55}, true},
+		{"7", redis.StreamID{Ms: 7}, true},
+		{"x-1", redis.StreamID{}, false},
+		{"1-", redis.StreamID{Ms: 1}, true},
+		{"1-y", redis.StreamID{}, false},
+	} {
+		id, err := redis.ParseStreamID(tt.s)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseStreamID(%q) returned error %v", tt.s, err)
+			continue
+		}
+		if tt.ok && id != tt.expected {
+			t.Errorf("ParseStreamID(%q) = %v, want %v", tt.s, id, tt.expected)
+		}
+	}
+}
+
+func TestXInfo(t *testing.T) {
+	entry := []interface{}{[]byte("1-0"), []interface{}{[]byte("f"), []byte("v")}}
+	stream, err := redis.XInfoStreamReply([]interface{}{
+		[]byte("length"), int64(2),
+		[]byte("radix-tree-keys"), int64(1),
+		[]byte("radix-tree-nodes"), int64(2),
+		[]byte("last-generated-id"), []byte("2-0"),
+		[]byte("groups"), int64(1),
+		[]byte("first-entry"), entry,
+		[]byte("last-entry"), nil,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedStream := redis.XInfoStream{Length: 2, RadixTreeKeys: 1, RadixTreeNodes: 2, Groups: 1, LastGeneratedID: "2-0", FirstEntry: entry}
+	if !reflect.DeepEqual(stream, expectedStream) {
+		t.Errorf("XInfoStreamReply() = %+v, want %+v", stream, expectedStream)
+	}
+
+	groups, err := redis.XInfoGroups([]interface{}{
+		[]interface{}{[]byte("name"), []byte("g1"), []byte("consumers"), int64(2), []byte("pending"), int64(3),
+			[]byte("last-delivered-id"), []byte("1-0"), []byte("entries-read"), int64(1), []byte("lag"), int64(1)},
+		[]interface{}{[]byte("name"), []byte("g2"), []byte("consumers"), int64(0), []byte("pending"), int64(0),
+			[]byte("last-delivered-id"), []byte("0-0"), []byte("lag"), nil},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedGroups := []redis.XInfoGroup{
+		{Name: "g1", Consumers: 2, Pending: 3, LastDeliveredID: "1-0", EntriesRead: 1, Lag: 1},
+		{Name: "g2", LastDeliveredID: "0-0", Lag: -1},
+	}
+	if !reflect.DeepEqual(groups, expectedGroups) {
+		t.Errorf("XInfoGroups() = %+v, want %+v", groups, expectedGroups)
+	}
+
+	consumers, err := redis.XInfoConsumers([]interface{}{
+		[]interface{}{[]byte("name"), []byte("c1"), []byte("pending"), int64(1), []byte("idle"), int64(1500), []byte("inactive"), int64(500)},
+		[]interface{}{[]byte("name"), []byte("c2"), []byte("pending"), int64(0), []byte("idle"), int64(10)},
+		[]interface{}{[]byte("name"), []byte("c3"), []byte("pending"), int64(0), []byte("idle"), int64(2), []byte("inactive"), int64(-1)},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedConsumers := []redis.XInfoConsumer{
+		{Name: "c1", Pending: 1, Idle: 1500 * time.Millisecond, Inactive: 500 * time.Millisecond},
+		{Name: "c2", Idle: 10 * time.Millisecond, Inactive: -1},
+		{Name: "c3", Idle: 2 * time.Millisecond, Inactive: -1},
+	}
+	if !reflect.DeepEqual(consumers, expectedConsumers) {
+		t.Errorf("XInfoConsumers() = %+v, want %+v", consumers, expectedConsumers)
+	}
+}
+
+func TestGeo(t *testing.T) {
+	positions, err := redis.GeoPositions([]interface{}{
+		[]interface{}{[]byte("13.361389"), []byte("38.115556")},
+		nil,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPositions := []*redis.GeoPos{{Longitude: 13.361389, Latitude: 38.115556}, nil}
+	if !reflect.DeepEqual(positions, expectedPositions) {
+		t.Errorf("GeoPositions() = %v, want %v", positions, expectedPositions)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		reply    interface{}
+		expected []redis.GeoLocation
+	}{
+		{
+			"no options",
+			[]interface{}{[]byte("Palermo"), []byte("Catania")},
+			[]redis.GeoLocation{{Name: "Palermo"}, {Name: "Catania"}},
+		},
+		{
+			"all options",
+			[]interface{}{
+				[]interface{}{[]byte("Palermo"), []byte("190.4424"), int64(3479099956230698),
+					[]interface{}{[]byte("13.361389"), []byte("38.115556")}},
+			},
+			[]redis.GeoLocation{{Name: "Palermo", Dist: 190.4424, Hash: 3479099956230698,
+				Pos: &redis.GeoPos{Longitude: 13.361389, Latitude: 38.115556}}},
+		},
+		{
+			"with coord",
+			[]interface{}{
+				[]interface{}{[]byte("Catania"), []interface{}{[]byte("15.087269"), []byte("37.502669")}},
+			},
+			[]redis.GeoLocation{{Name: "Catania", Pos: &redis.GeoPos{Longitude: 15.087269, Latitude: 37.502669}}},
+		},
+	} {
+		locations, err := redis.GeoLocations(tt.reply, nil)
+		if err != nil {
+			t.Errorf("%s: GeoLocations returned error %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(locations, tt.expected) {
+			t.Errorf("%s: GeoLocations() = %+v, want %+v", tt.name, locations, tt.expected)
+		}
+	}
+}
+
+func TestKeyScoredMember(t *testing.T) {
+	key, m, err := redis.KeyScoredMember([]interface{}{[]byte("z"), []byte("a"), []byte("3")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "z" || m != (redis.ScoredMember{Member: "a", Score: 3}) {
+		t.Errorf("KeyScoredMember() = %q, %+v, want z, {a 3}", key, m)
+	}
+	if _, _, err := redis.KeyScoredMember(nil, nil); err != redis.ErrNil {
+		t.Errorf("KeyScoredMember(nil) returned err %v, want ErrNil", err)
+	}
+}
+
+func TestCommandInfos(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{
+			[]byte("get"), int64(2), []interface{}{"readonly", "fast"}, int64(1), int64(1), int64(1),
+			[]interface{}{"@read", "@string", "@fast"},
+			[]interface{}{},
+			[]interface{}{
+				[]interface{}{
+					[]byte("flags"), []interface{}{"RO", "access"},
+					[]byte("begin_search"), []interface{}{[]byte("type"), []byte("index"), []byte("spec"), []interface{}{[]byte("index"), int64(1)}},
+					[]byte("find_keys"), []interface{}{[]byte("type"), []byte("range"), []byte("spec"),
+						[]interface{}{[]byte("lastkey"), int64(0), []byte("step"), int64(1), []byte("limit"), int64(0)}},
+				},
+			},
+			[]interface{}{},
+		},
+		nil,
+		[]interface{}{[]byte("ping"), int64(-1), []interface{}{"fast"}, int64(0), int64(0), int64(0)},
+	}
+	actual, err := redis.CommandInfos(reply, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []redis.CommandInfo{
+		{
+			Name: "get", Arity: 2, Flags: []string{"readonly", "fast"}, FirstKey: 1, LastKey: 1, Step: 1,
+			ACLCategories: []string{"@read", "@string", "@fast"},
+			Tips:          []string{},
+			KeySpecs: []redis.CommandKeySpec{{
+				Flags:       []string{"RO", "access"},
+				BeginSearch: redis.CommandKeySearch{Type: "index", Spec: map[string]interface{}{"index": int64(1)}},
+				FindKeys:    redis.CommandKeySearch{Type: "range", Spec: map[string]interface{}{"lastkey": int64(0), "step": int64(1), "limit": int64(0)}},
+			}},
+			Subcommands: []redis.CommandInfo{},
+		},
+		{},
+		{Name: "ping", Arity: -1, Flags: []string{"fast"}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("CommandInfos() = %+v, want %+v", actual, expected)
+	}
+}
+
+func TestCommandDocs(t *testing.T) {
+	reply := []interface{}{
+		[]byte("get"), []interface{}{
+			[]byte("summary"), []byte("Returns the string value of a key."),
+			[]byte("since"), []byte("1.0.0"),
+			[]byte("group"), []byte("string"),
+			[]byte("complexity"), []byte("O(1)"),
+			[]byte("arguments"), []interface{}{
+				[]interface{}{[]byte("name"), []byte("key"), []byte("type"), []byte("key"), []byte("display_text"), []byte("key"), []byte("key_spec_index"), int64(0)},
+			},
+		},
+		[]byte("set"), []interface{}{
+			[]byte("arguments"), []interface{}{
+				[]interface{}{[]byte("name"), []byte("condition"), []byte("type"), []byte("oneof"), []byte("flags"), []interface{}{"optional"},
+					[]byte("arguments"), []interface{}{
+						[]interface{}{[]byte("name"), []byte("nx"), []byte("type"), []byte("pure-token"), []byte("token"), []byte("NX")},
+					}},
+			},
+		},
+	}
+	actual, err := redis.CommandDocs(reply, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]redis.CommandDoc{
+		"get": {
+			Summary: "Returns the string value of a key.", Since: "1.0.0", Group: "string", Complexity: "O(1)",
+			Arguments: []redis.CommandArg{{Name: "key", Type: "key", DisplayText: "key", KeySpecIndex: 0}},
+		},
+		"set": {
+			Arguments: []redis.CommandArg{{Name: "condition", Type: "oneof", KeySpecIndex: -1, Flags: []string{"optional"},
+				Arguments: []redis.CommandArg{{Name: "nx", Type: "pure-token", Token: "NX", KeySpecIndex: -1}}}},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("CommandDocs() = %+v, want %+v", actual, expected)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	events, err := redis.LatencyEvents([]interface{}{
+		[]interface{}{[]byte("command"), int64(1405067976), int64(251), int64(1001)},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedEvents := []redis.LatencyEvent{
+		{Name: "command", Time: time.Unix(1405067976, 0), Latest: 251 * time.Millisecond, Max: 1001 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(events, expectedEvents) {
+		t.Errorf("LatencyEvents() = %+v, want %+v", events, expectedEvents)
+	}
+
+	samples, err := redis.LatencySamples([]interface{}{
+		[]interface{}{int64(1405067822), int64(251)},
+		[]interface{}{int64(1405067941), int64(1001)},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSamples := []redis.LatencySample{
+		{Time: time.Unix(1405067822, 0), Latency: 251 * time.Millisecond},
+		{Time: time.Unix(1405067941, 0), Latency: 1001 * time.Millisecond},
+	}
+	if !reflect.DeepEqual(samples, expectedSamples) {
+		t.Errorf("LatencySamples() = %+v, want %+v", samples, expectedSamples)
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	cfg, err := redis.ServerConfigReply([]interface{}{
+		[]byte("maxmemory"), []byte("100mb"),
+		[]byte("maxclients"), []byte("10000"),
+		[]byte("appendonly"), []byte("yes"),
+		[]byte("timeout"), []byte("300"),
+		[]byte("proto-max-bulk-len"), []byte("512m"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := cfg.MemorySize("maxmemory"); v != 100<<20 || err != nil {
+		t.Errorf("MemorySize(maxmemory) = %d, %v, want %d, nil", v, err, 100<<20)
+	}
+	if v, err := cfg.MemorySize("proto-max-bulk-len"); v != 512000000 || err != nil {
+		t.Errorf("MemorySize(proto-max-bulk-len) = %d, %v, want 512000000, nil", v, err)
+	}
+	if v, err := cfg.Int("maxclients"); v != 10000 || err != nil {
+		t.Errorf("Int(maxclients) = %d, %v, want 10000, nil", v, err)
+	}
+	if v, err := cfg.Bool("appendonly"); !v || err != nil {
+		t.Errorf("Bool(appendonly) = %v, %v, want true, nil", v, err)
+	}
+	if v, err := cfg.Duration("timeout", time.Second); v != 5*time.Minute || err != nil {
+		t.Errorf("Duration(timeout) = %v, %v, want 5m, nil", v, err)
+	}
+	if _, err := cfg.Int("missing"); err != redis.ErrNil {
+		t.Errorf("Int(missing) returned err %v, want ErrNil", err)
+	}
+	if _, err := cfg.Bool("maxclients"); err == nil {
+		t.Error("Bool(maxclients) did not return error")
+	}
+
+	other := redis.ServerConfig{
+		"maxmemory":  "200mb",
+		"maxclients": "10000",
+		"appendonly": "yes",
+		"timeout":    "300",
+		"save":       "",
+		"databases":  "16",
+	}
+	expected := []redis.ConfigChange{
+		{Name: "databases", New: "16"},
+		{Name: "maxmemory", Old: "100mb", New: "200mb"},
+		{Name: "proto-max-bulk-len", Old: "512m"},
+		{Name: "save"},
+	}
+	if changes := cfg.Diff(other); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Diff() = %+v, want %+v", changes, expected)
+	}
+}
+
 // dial wraps DialDefaultServer() with a more suitable function name for examples.
 func dial() (redis.Conn, error) {
 	return redis.DialDefaultServer()
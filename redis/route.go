@@ -0,0 +1,264 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TxConstraintError is returned by the Tx Validate and Exec methods when
+// the keys of a transaction violate the transaction's routing constraint.
+type TxConstraintError struct {
+	Keys []string
+	Err  error
+}
+
+func (e *TxConstraintError) Error() string {
+	return fmt.Sprintf("redigo: transaction keys %q violate constraint: %v", e.Keys, e.Err)
+}
+
+// Constrain sets a routing constraint for the transaction. The Exec method
+// calls Validate with the constraint before sending any commands.
+func (tx *Tx) Constrain(constraint func(keys []string) error) {
+	tx.constraint = constraint
+}
+
+// Keys returns the keys used by the queued commands. The keys are found
+// using the argument layout of the command. For commands not known to
+// Keys, the first argument is assumed to be the only key.
+func (tx *Tx) Keys() []string {
+	var keys []string
+	for _, cmd := range tx.cmds {
+		keys = append(keys, commandKeys(cmd.name, cmd.args)...)
+	}
+	return keys
+}
+
+// Validate checks the keys of the queued commands with constraint. Validate
+// returns a *TxConstraintError if the constraint returns an error.
+func (tx *Tx) Validate(constraint func(keys []string) error) error {
+	keys := tx.Keys()
+	if err := constraint(keys); err != nil {
+		return &TxConstraintError{Keys: keys, Err: err}
+	}
+	return nil
+}
+
+// SameSlot is a routing constraint that requires all keys to hash to the
+// same Redis Cluster slot.
+func SameSlot(keys []string) error {
+	for _, key := range keys {
+		if KeySlot(key) != KeySlot(keys[0]) {
+			return fmt.Errorf("key %q in slot %d, key %q in slot %d", keys[0], KeySlot(keys[0]), key, KeySlot(key))
+		}
+	}
+	return nil
+}
+
+// SameShard returns a routing constraint that requires shard to return the
+// same value for all keys.
+func SameShard(shard func(key string) string) func(keys []string) error {
+	return func(keys []string) error {
+		for _, key := range keys {
+			if shard(key) != shard(keys[0]) {
+				return fmt.Errorf("key %q in shard %s, key %q in shard %s", keys[0], shard(keys[0]), key, shard(key))
+			}
+		}
+		return nil
+	}
+}
+
+// KeySlot returns the Redis Cluster hash slot for key. If the key contains
+// a hash tag, then only the tag is hashed.
+func KeySlot(key string) int {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			key = key[i+1 : i+1+j]
+		}
+	}
+	return int(crc16(key) % 16384)
+}
+
+// crc16 computes the CRC16-XMODEM checksum used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// keyLayout describes the positions of keys in command arguments.
+type keyLayout int
+
+const (
+	keysNone      keyLayout = iota
+	keysFirst               // the first argument is the only key
+	keysAll                 // all arguments are keys
+	keysAllButOne           // all arguments except the last are keys
+	keysFirstTwo            // the first two arguments are keys
+	keysEven                // arguments at even positions are keys
+	keysNumKeys             // arguments after a key count at position 1 are keys
+	keysDestNum             // destination key, key count and keys
+)
+
+var keyLayouts = map[string]keyLayout{
+	"PING": keysNone, "ECHO": keysNone, "SELECT": keysNone, "TIME": keysNone,
+	"INFO": keysNone, "DBSIZE": keysNone, "FLUSHDB": keysNone, "FLUSHALL": keysNone,
+	"PUBLISH": keysNone, "SCRIPT": keysNone, "FUNCTION": keysNone,
+
+	// Connection and server administration commands have credentials and
+	// other secrets in their first argument.
+	"AUTH": keysNone, "HELLO": keysNone, "ACL": keysNone, "CONFIG": keysNone,
+	"CLIENT": keysNone, "MIGRATE": keysNone, "MODULE": keysNone,
+
+	"GET": keysFirst, "SET": keysFirst, "SETNX": keysFirst, "SETEX": keysFirst,
+	"PSETEX": keysFirst, "GETSET": keysFirst, "GETDEL": keysFirst, "GETEX": keysFirst,
+	"APPEND": keysFirst, "STRLEN": keysFirst, "GETRANGE": keysFirst, "SETRANGE": keysFirst,
+	"INCR": keysFirst, "INCRBY": keysFirst, "INCRBYFLOAT": keysFirst, "DECR": keysFirst,
+	"DECRBY": keysFirst, "GETBIT": keysFirst, "SETBIT": keysFirst, "BITCOUNT": keysFirst,
+	"BITPOS": keysFirst, "BITFIELD": keysFirst,
+	"EXPIRE": keysFirst, "PEXPIRE": keysFirst, "EXPIREAT": keysFirst, "PEXPIREAT": keysFirst,
+	"TTL": keysFirst, "PTTL": keysFirst, "PERSIST": keysFirst, "TYPE": keysFirst,
+	"DUMP": keysFirst, "RESTORE": keysFirst, "SORT": keysFirst,
+	"HSET": keysFirst, "HSETNX": keysFirst, "HMSET": keysFirst, "HGET": keysFirst,
+	"HMGET": keysFirst, "HDEL": keysFirst, "HEXISTS": keysFirst, "HGETALL": keysFirst,
+	"HKEYS": keysFirst, "HVALS": keysFirst, "HLEN": keysFirst, "HSTRLEN": keysFirst,
+	"HINCRBY": keysFirst, "HINCRBYFLOAT": keysFirst, "HSCAN": keysFirst, "HRANDFIELD": keysFirst,
+	"LPUSH": keysFirst, "RPUSH": keysFirst, "LPUSHX": keysFirst, "RPUSHX": keysFirst,
+	"LPOP": keysFirst, "RPOP": keysFirst, "LLEN": keysFirst, "LRANGE": keysFirst,
+	"LINDEX": keysFirst, "LSET": keysFirst, "LREM": keysFirst, "LTRIM": keysFirst,
+	"LINSERT": keysFirst, "LPOS": keysFirst,
+	"SADD": keysFirst, "SREM": keysFirst, "SISMEMBER": keysFirst, "SMISMEMBER": keysFirst,
+	"SMEMBERS": keysFirst, "SCARD": keysFirst, "SPOP": keysFirst, "SRANDMEMBER": keysFirst,
+	"SSCAN": keysFirst,
+	"ZADD":  keysFirst, "ZREM": keysFirst, "ZSCORE": keysFirst, "ZMSCORE": keysFirst,
+	"ZINCRBY": keysFirst, "ZCARD": keysFirst, "ZCOUNT": keysFirst, "ZLEXCOUNT": keysFirst,
+	"ZRANK": keysFirst, "ZREVRANK": keysFirst, "ZRANGE": keysFirst, "ZREVRANGE": keysFirst,
+	"ZRANGEBYSCORE": keysFirst, "ZREVRANGEBYSCORE": keysFirst, "ZRANGEBYLEX": keysFirst,
+	"ZREVRANGEBYLEX": keysFirst, "ZREMRANGEBYRANK": keysFirst, "ZREMRANGEBYSCORE": keysFirst,
+	"ZREMRANGEBYLEX": keysFirst, "ZPOPMIN": keysFirst, "ZPOPMAX": keysFirst,
+	"ZRANDMEMBER": keysFirst, "ZSCAN": keysFirst,
+	"PFADD": keysFirst, "GEOADD": keysFirst, "GEODIST": keysFirst, "GEOPOS": keysFirst,
+	"GEOHASH": keysFirst, "GEOSEARCH": keysFirst,
+	"XADD": keysFirst, "XLEN": keysFirst, "XRANGE": keysFirst, "XREVRANGE": keysFirst,
+	"XDEL": keysFirst, "XTRIM": keysFirst, "XACK": keysFirst, "XCLAIM": keysFirst,
+	"XAUTOCLAIM": keysFirst, "XPENDING": keysFirst,
+
+	"DEL": keysAll, "UNLINK": keysAll, "EXISTS": keysAll, "TOUCH": keysAll,
+	"MGET": keysAll, "WATCH": keysAll, "SINTER": keysAll, "SUNION": keysAll,
+	"SDIFF": keysAll, "SINTERSTORE": keysAll, "SUNIONSTORE": keysAll,
+	"SDIFFSTORE": keysAll, "PFCOUNT": keysAll, "PFMERGE": keysAll,
+
+	"BLPOP": keysAllButOne, "BRPOP": keysAllButOne, "BZPOPMIN": keysAllButOne,
+	"BZPOPMAX": keysAllButOne,
+
+	"RENAME": keysFirstTwo, "RENAMENX": keysFirstTwo, "SMOVE": keysFirstTwo,
+	"RPOPLPUSH": keysFirstTwo, "LMOVE": keysFirstTwo, "BLMOVE": keysFirstTwo,
+	"COPY": keysFirstTwo, "BRPOPLPUSH": keysFirstTwo,
+
+	"MSET": keysEven, "MSETNX": keysEven,
+
+	"EVAL": keysNumKeys, "EVALSHA": keysNumKeys, "EVAL_RO": keysNumKeys,
+	"EVALSHA_RO": keysNumKeys, "FCALL": keysNumKeys, "FCALL_RO": keysNumKeys,
+
+	"ZUNIONSTORE": keysDestNum, "ZINTERSTORE": keysDestNum, "ZDIFFSTORE": keysDestNum,
+}
+
+// commandKeys returns the keys in the arguments to a command. For commands
+// not in the table, the first argument is assumed to be the key.
+func commandKeys(commandName string, args []interface{}) []string {
+	indexes, ok := keyIndexes(commandName, args)
+	if !ok && len(args) > 0 {
+		indexes = []int{0}
+	}
+	var keys []string
+	for _, i := range indexes {
+		keys = append(keys, argString(args[i]))
+	}
+	return keys
+}
+
+// keyIndexes returns the indexes of the keys in the arguments to a command.
+// The ok result is false if the command is not in the table of key layouts.
+func keyIndexes(commandName string, args []interface{}) (indexes []int, ok bool) {
+	layout, ok := keyLayouts[strings.ToUpper(commandName)]
+	if !ok {
+		return nil, false
+	}
+	switch layout {
+	case keysFirst:
+		if len(args) > 0 {
+			indexes = append(indexes, 0)
+		}
+	case keysAll:
+		for i := range args {
+			indexes = append(indexes, i)
+		}
+	case keysAllButOne:
+		for i := 0; i+1 < len(args); i++ {
+			indexes = append(indexes, i)
+		}
+	case keysFirstTwo:
+		for i := 0; i < 2 && i < len(args); i++ {
+			indexes = append(indexes, i)
+		}
+	case keysEven:
+		for i := 0; i < len(args); i += 2 {
+			indexes = append(indexes, i)
+		}
+	case keysNumKeys:
+		if len(args) > 1 {
+			n, _ := strconv.Atoi(argString(args[1]))
+			for i := 2; i < 2+n && i < len(args); i++ {
+				indexes = append(indexes, i)
+			}
+		}
+	case keysDestNum:
+		if len(args) > 0 {
+			indexes = append(indexes, 0)
+		}
+		if len(args) > 1 {
+			n, _ := strconv.Atoi(argString(args[1]))
+			for i := 2; i < 2+n && i < len(args); i++ {
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	return indexes, true
+}
+
+// argString formats a command argument as the string sent to the server.
+func argString(arg interface{}) string {
+	if a, ok := arg.(Argument); ok {
+		arg = a.RedisArg()
+	}
+	switch arg := arg.(type) {
+	case string:
+		return arg
+	case []byte:
+		return string(arg)
+	}
+	return fmt.Sprint(arg)
+}
@@ -15,12 +15,16 @@
 package redis
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 func ensureLen(d reflect.Value, n int) {
@@ -109,7 +113,80 @@ func convertAssignInt(d reflect.Value, s int64) (err error) {
 	return
 }
 
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// convertAssignTime converts RFC 3339 text or UNIX seconds to a time.
+func convertAssignTime(d reflect.Value, s interface{}) error {
+	var t time.Time
+	switch s := s.(type) {
+	case []byte:
+		var err error
+		if t, err = time.Parse(time.RFC3339Nano, string(s)); err != nil {
+			n, err := strconv.ParseInt(string(s), 10, 64)
+			if err != nil {
+				return cannotConvert(d, s)
+			}
+			t = time.Unix(n, 0)
+		}
+	case int64:
+		t = time.Unix(s, 0)
+	default:
+		return cannotConvert(d, s)
+	}
+	d.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// convertAssignDuration converts time.Duration String text or integer
+// nanoseconds to a duration.
+func convertAssignDuration(d reflect.Value, s interface{}) error {
+	switch s := s.(type) {
+	case []byte:
+		x, err := time.ParseDuration(string(s))
+		if err != nil {
+			n, err := strconv.ParseInt(string(s), 10, 64)
+			if err != nil {
+				return cannotConvert(d, s)
+			}
+			x = time.Duration(n)
+		}
+		d.SetInt(int64(x))
+	case int64:
+		d.SetInt(s)
+	default:
+		return cannotConvert(d, s)
+	}
+	return nil
+}
+
 func convertAssignValue(d reflect.Value, s interface{}) (err error) {
+	if d.CanAddr() {
+		if sc, ok := d.Addr().Interface().(Scanner); ok {
+			if err, ok := s.(Error); ok {
+				return err
+			}
+			return sc.RedisScan(s)
+		}
+	}
+
+	switch d.Type() {
+	case timeType:
+		return convertAssignTime(d, s)
+	case durationType:
+		return convertAssignDuration(d, s)
+	}
+	if p, ok := s.([]byte); ok && d.CanAddr() {
+		switch u := d.Addr().Interface().(type) {
+		case encoding.TextUnmarshaler:
+			return u.UnmarshalText(p)
+		case encoding.BinaryUnmarshaler:
+			return u.UnmarshalBinary(p)
+		}
+	}
+
 	switch s := s.(type) {
 	case []byte:
 		err = convertAssignBulkString(d, s)
@@ -134,7 +211,22 @@ func convertAssignArray(d reflect.Value, s []interface{}) error {
 	return nil
 }
 
+// Scanner is implemented by types that convert themselves from a reply.
+type Scanner interface {
+	// RedisScan assigns a value from a Redis reply. The src value is one of
+	// the reply types: int64, string, []byte, []interface{} or nil. Error
+	// replies are not passed to RedisScan.
+	RedisScan(src interface{}) error
+}
+
 func convertAssign(d interface{}, s interface{}) (err error) {
+	if sc, ok := d.(Scanner); ok {
+		if err, ok := s.(Error); ok {
+			return err
+		}
+		return sc.RedisScan(s)
+	}
+
 	// Handle the most common destination types using type switches and
 	// fall back to reflection for all other types.
 	switch s := s.(type) {
@@ -217,7 +309,8 @@ func convertAssign(d interface{}, s interface{}) (err error) {
 //
 // The values pointed at by dest must be an integer, float, boolean, string,
 // []byte, interface{} or slices of these types. Scan uses the standard strconv
-// package to convert bulk strings to numeric and boolean types.
+// package to convert bulk strings to numeric and boolean types. If a dest
+// value implements Scanner, then Scan calls its RedisScan method.
 //
 // If a dest value is nil, then the corresponding src value is skipped.
 //
@@ -255,20 +348,58 @@ func (ss *structSpec) fieldSpec(name []byte) *fieldSpec {
 	return ss.m[string(name)]
 }
 
-func compileStructSpec(t reflect.Type, depth map[string]int, index []int, ss *structSpec) {
+// defaultFieldSeparator separates the names of a nested struct field and its
+// fields.
+const defaultFieldSeparator = "."
+
+var (
+	scannerType           = reflect.TypeOf((*Scanner)(nil)).Elem()
+	argumentType          = reflect.TypeOf((*Argument)(nil)).Elem()
+	textUnmarshalerType   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// nestedStructType returns the struct type for a field that is flattened
+// into the fields of the struct. Structs that convert themselves to and from
+// a single value are not flattened.
+func nestedStructType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil, false
+	}
+	pt := reflect.PtrTo(t)
+	if t.Implements(argumentType) || pt.Implements(argumentType) ||
+		pt.Implements(scannerType) ||
+		pt.Implements(textUnmarshalerType) ||
+		pt.Implements(binaryUnmarshalerType) {
+		return nil, false
+	}
+	return t, true
+}
+
+func compileStructSpec(t reflect.Type, depth map[string]int, index []int, prefix string, seen map[reflect.Type]bool, ss *structSpec) {
+	seen[t] = true
+	defer delete(seen, t)
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
 		switch {
 		case f.PkgPath != "" && !f.Anonymous:
 			// Ignore unexported fields.
 		case f.Anonymous:
-			// TODO: Handle pointers. Requires change to decoder and
-			// protection against infinite recursion.
-			if f.Type.Kind() == reflect.Struct {
-				compileStructSpec(f.Type, depth, append(index, i), ss)
+			// Follow pointers to exported types only. The decoder cannot
+			// allocate a pointer in an unexported field.
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr && f.PkgPath == "" {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !seen[ft] {
+				compileStructSpec(ft, depth, append(index, i), prefix, seen, ss)
 			}
 		default:
 			fs := &fieldSpec{name: f.Name}
+			sep := defaultFieldSeparator
 			tag := f.Tag.Get("redis")
 			p := strings.Split(tag, ",")
 			if len(p) > 0 {
@@ -279,14 +410,23 @@ func compileStructSpec(t reflect.Type, depth map[string]int, index []int, ss *st
 					fs.name = p[0]
 				}
 				for _, s := range p[1:] {
-					switch s {
-					case "omitempty":
+					switch {
+					case s == "omitempty":
 						fs.omitEmpty = true
+					case strings.HasPrefix(s, "sep="):
+						sep = s[len("sep="):]
 					default:
 						panic(fmt.Errorf("redigo: unknown field tag %s for type %s", s, t.Name()))
 					}
 				}
 			}
+			fs.name = prefix + fs.name
+			if nt, ok := nestedStructType(f.Type); ok {
+				if !seen[nt] {
+					compileStructSpec(nt, depth, append(index, i), fs.name+sep, seen, ss)
+				}
+				continue
+			}
 			d, found := depth[fs.name]
 			if !found {
 				d = 1 << 30
@@ -315,6 +455,36 @@ func compileStructSpec(t reflect.Type, depth map[string]int, index []int, ss *st
 	}
 }
 
+// fieldByIndexAlloc returns the nested field of v by index, allocating nil
+// struct pointers on the way.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndex returns the nested field of v by index. The boolean result is
+// false if a struct pointer on the way is nil.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
 var (
 	structSpecMutex  sync.RWMutex
 	structSpecCache  = make(map[reflect.Type]*structSpec)
@@ -338,7 +508,7 @@ func structSpecForType(t reflect.Type) *structSpec {
 	}
 
 	ss = &structSpec{m: make(map[string]*fieldSpec)}
-	compileStructSpec(t, make(map[string]int), nil, ss)
+	compileStructSpec(t, make(map[string]int), nil, "", make(map[reflect.Type]bool), ss)
 	structSpecCache[t] = ss
 	return ss
 }
@@ -355,10 +525,28 @@ var errScanStructValue = errors.New("redigo.ScanStruct: value must be non-nil po
 //
 // Fields with the tag redis:"-" are ignored.
 //
+// The fields of embedded structs and pointers to embedded structs are
+// promoted to the outer struct. The fields of a nested struct field are
+// matched by the field name, a separator and the nested field name. The
+// default separator is "." and can be changed with the tag option sep:
+//
+//      Addr Address `redis:"addr,sep=_"` // matches addr_city
+//
+// Nil pointers to embedded and nested structs are allocated as needed.
+//
 // Integer, float, boolean, string and []byte fields are supported. Scan uses the
 // standard strconv package to convert bulk string values to numeric and
 // boolean types.
 //
+// Fields of type time.Time are converted from RFC 3339 text or UNIX seconds.
+// Fields of type time.Duration are converted from duration text such as
+// "1.5s" or integer nanoseconds. Fields with a pointer type that implements
+// encoding.TextUnmarshaler or encoding.BinaryUnmarshaler are converted using
+// the UnmarshalText or UnmarshalBinary method.
+//
+// Fields with a pointer type that implements Scanner are converted using the
+// RedisScan method.
+//
 // If a src element is nil, then the corresponding field is not modified.
 func ScanStruct(src []interface{}, dest interface{}) error {
 	d := reflect.ValueOf(dest)
@@ -369,6 +557,12 @@ func ScanStruct(src []interface{}, dest interface{}) error {
 	if d.Kind() != reflect.Struct {
 		return errScanStructValue
 	}
+	return scanStructValue(src, d)
+}
+
+// scanStructValue scans alternating names and values from src to the struct
+// value d.
+func scanStructValue(src []interface{}, d reflect.Value) error {
 	ss := structSpecForType(d.Type())
 
 	if len(src)%2 != 0 {
@@ -388,7 +582,7 @@ func ScanStruct(src []interface{}, dest interface{}) error {
 		if fs == nil {
 			continue
 		}
-		if err := convertAssignValue(d.FieldByIndex(fs.index), s); err != nil {
+		if err := convertAssignValue(fieldByIndexAlloc(d, fs.index), s); err != nil {
 			return fmt.Errorf("redigo.ScanStruct: cannot assign field %s: %v", fs.name, err)
 		}
 	}
@@ -396,16 +590,123 @@ func ScanStruct(src []interface{}, dest interface{}) error {
 }
 
 var (
-	errScanSliceValue = errors.New("redigo.ScanSlice: dest must be non-nil pointer to a struct")
+	errScanSliceValue   = errors.New("redigo.ScanSlice: dest must be non-nil pointer to a struct")
+	errScanStructsValue = errors.New("redigo.ScanStructs: dest must be non-nil pointer to a slice of structs")
 )
 
+// ScanStructs scans groups of alternating names and values from src to the
+// slice of structs pointed to by dest. The elements of the dest slice must be
+// structs or pointers to structs. Each group is scanned to a struct using
+// the rules of ScanStruct, including the 'redis' field tag.
+//
+// If n is greater than zero, then src is a flat array where each struct is
+// represented by n consecutive name-value pairs. This format is convenient
+// for Lua scripts that return several hashes in one reply:
+//
+//  // src is [name, a, age, 1, name, b, age, 2]
+//  var people []Person
+//  err := redis.ScanStructs(src, &people, 2)
+//
+// If n is zero, then each element of src is an array of alternating names
+// and values, such as the replies from pipelined HGETALL commands. A nil
+// element results in a zero struct.
+func ScanStructs(src []interface{}, dest interface{}, n int) error {
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return errScanStructsValue
+	}
+	d = d.Elem()
+	if d.Kind() != reflect.Slice {
+		return errScanStructsValue
+	}
+	isPtr := false
+	t := d.Type().Elem()
+	if t.Kind() == reflect.Ptr {
+		isPtr = true
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return errScanStructsValue
+	}
+
+	var groups [][]interface{}
+	if n > 0 {
+		if len(src)%(2*n) != 0 {
+			return errors.New("redigo.ScanStructs: length not a multiple of group size")
+		}
+		for i := 0; i < len(src); i += 2 * n {
+			groups = append(groups, src[i:i+2*n])
+		}
+	} else {
+		groups = make([][]interface{}, len(src))
+		for i, s := range src {
+			if s == nil {
+				continue
+			}
+			group, ok := s.([]interface{})
+			if !ok {
+				return fmt.Errorf("redigo.ScanStructs: element %d is not an array, got type %T", i, s)
+			}
+			groups[i] = group
+		}
+	}
+
+	ensureLen(d, len(groups))
+	for i, group := range groups {
+		d := d.Index(i)
+		if isPtr {
+			if d.IsNil() {
+				d.Set(reflect.New(t))
+			}
+			d = d.Elem()
+		}
+		if err := scanStructValue(group, d); err != nil {
+			return fmt.Errorf("redigo.ScanStructs: group %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
 // ScanSlice scans src to the slice pointed to by dest. The elements the dest
 // slice must be integer, float, boolean, string, struct or pointer to struct
 // values.
 //
 // Struct fields must be integer, float, boolean or string values. All struct
-// fields are used unless a subset is specified using fieldNames.
+// fields are used unless a subset is specified using fieldNames. The field
+// name "-" skips the corresponding value in src.
+//
+// Nil values in src are skipped. Use ScanSliceWithOptions to set the
+// destination to the zero value instead.
 func ScanSlice(src []interface{}, dest interface{}, fieldNames ...string) error {
+	return ScanSliceWithOptions(src, dest, ScanSliceOptions{Fields: fieldNames})
+}
+
+// ScanSliceOptions specifies options for ScanSliceWithOptions.
+type ScanSliceOptions struct {
+	// Fields specifies the struct fields for consecutive values in src as
+	// described in ScanSlice. All struct fields are used if Fields is
+	// empty.
+	Fields []string
+
+	// If ZeroNil is true, then nil values in src set the corresponding
+	// slice element or struct field to the zero value. Otherwise, nil values
+	// leave the destination unchanged.
+	ZeroNil bool
+}
+
+// ScanSliceWithOptions scans src to the slice pointed to by dest using the
+// specified options. See ScanSlice for details.
+//
+// Use ZeroNil when scanning a reply with missing values, such as the reply
+// to HMGET, to a slice with existing elements:
+//
+//  var users []User
+//  // ...
+//  err := redis.ScanSliceWithOptions(values, &users, redis.ScanSliceOptions{
+//      Fields:  []string{"name", "email"},
+//      ZeroNil: true,
+//  })
+func ScanSliceWithOptions(src []interface{}, dest interface{}, opts ScanSliceOptions) error {
 	d := reflect.ValueOf(dest)
 	if d.Kind() != reflect.Ptr || d.IsNil() {
 		return errScanSliceValue
@@ -426,6 +727,9 @@ func ScanSlice(src []interface{}, dest interface{}, fieldNames ...string) error
 		ensureLen(d, len(src))
 		for i, s := range src {
 			if s == nil {
+				if opts.ZeroNil {
+					d.Index(i).Set(reflect.Zero(t))
+				}
 				continue
 			}
 			if err := convertAssignValue(d.Index(i), s); err != nil {
@@ -437,9 +741,13 @@ func ScanSlice(src []interface{}, dest interface{}, fieldNames ...string) error
 
 	ss := structSpecForType(t)
 	fss := ss.l
-	if len(fieldNames) > 0 {
-		fss = make([]*fieldSpec, len(fieldNames))
-		for i, name := range fieldNames {
+	if len(opts.Fields) > 0 {
+		fss = make([]*fieldSpec, len(opts.Fields))
+		for i, name := range opts.Fields {
+			if name == "-" {
+				// A nil spec skips the value.
+				continue
+			}
 			fss[i] = ss.m[name]
 			if fss[i] == nil {
 				return fmt.Errorf("redigo.ScanSlice: ScanSlice bad field name %s", name)
@@ -466,11 +774,19 @@ func ScanSlice(src []interface{}, dest interface{}, fieldNames ...string) error
 			d = d.Elem()
 		}
 		for j, fs := range fss {
+			if fs == nil {
+				continue
+			}
 			s := src[i*len(fss)+j]
 			if s == nil {
+				if opts.ZeroNil {
+					if fv, ok := fieldByIndex(d, fs.index); ok {
+						fv.Set(reflect.Zero(fv.Type()))
+					}
+				}
 				continue
 			}
-			if err := convertAssignValue(d.FieldByIndex(fs.index), s); err != nil {
+			if err := convertAssignValue(fieldByIndexAlloc(d, fs.index), s); err != nil {
 				return fmt.Errorf("redigo.ScanSlice: cannot assign element %d to field %s: %v", i*len(fss)+j, fs.name, err)
 			}
 		}
@@ -478,12 +794,27 @@ func ScanSlice(src []interface{}, dest interface{}, fieldNames ...string) error
 	return nil
 }
 
+// Argument is implemented by types that control how they are written as a
+// command argument.
+type Argument interface {
+	// RedisArg returns a value to write as the command argument. The value
+	// must be a string, []byte, integer, float, bool or nil.
+	RedisArg() interface{}
+}
+
 // Args is a helper for constructing command arguments from structured values.
 type Args []interface{}
 
-// Add returns the result of appending value to args.
+// Add returns the result of appending value to args. Values that implement
+// Argument are replaced with the result of their RedisArg method.
 func (args Args) Add(value ...interface{}) Args {
-	return append(args, value...)
+	for _, v := range value {
+		if a, ok := v.(Argument); ok {
+			v = a.RedisArg()
+		}
+		args = append(args, v)
+	}
+	return args
 }
 
 // AddFlat returns the result of appending the flattened value of v to args.
@@ -494,11 +825,21 @@ func (args Args) Add(value ...interface{}) Args {
 //
 // Structs are flattened by appending the alternating names and values of
 // exported fields to args. If v is a nil struct pointer, then nothing is
-// appended. The 'redis' field tag overrides struct field names. See ScanStruct
-// for more information on the use of the 'redis' field tag.
+// appended. The 'redis' field tag overrides struct field names. Embedded and
+// nested structs are flattened as described in ScanStruct. Fields of nil
+// embedded and nested struct pointers are skipped. See ScanStruct for more
+// information on the use of the 'redis' field tag.
+//
+// Values that implement Argument, including struct fields, are replaced with
+// the result of their RedisArg method and are not flattened.
 //
-// Other types are appended to args as is.
+// Other types are appended to args as is. Connections write time.Time
+// values as RFC 3339 text with nanoseconds, the format expected by
+// ScanStruct.
 func (args Args) AddFlat(v interface{}) Args {
+	if a, ok := v.(Argument); ok {
+		return append(args, a.RedisArg())
+	}
 	rv := reflect.ValueOf(v)
 	switch rv.Kind() {
 	case reflect.Struct:
@@ -528,28 +869,70 @@ func (args Args) AddFlat(v interface{}) Args {
 func flattenStruct(args Args, v reflect.Value) Args {
 	ss := structSpecForType(v.Type())
 	for _, fs := range ss.l {
-		fv := v.FieldByIndex(fs.index)
-		if fs.omitEmpty {
-			var empty = false
-			switch fv.Kind() {
-			case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
-				empty = fv.Len() == 0
-			case reflect.Bool:
-				empty = !fv.Bool()
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				empty = fv.Int() == 0
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-				empty = fv.Uint() == 0
-			case reflect.Float32, reflect.Float64:
-				empty = fv.Float() == 0
-			case reflect.Interface, reflect.Ptr:
-				empty = fv.IsNil()
-			}
-			if empty {
-				continue
-			}
+		fv, ok := fieldByIndex(v, fs.index)
+		if !ok {
+			continue
+		}
+		if fs.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		arg := fv.Interface()
+		if a, ok := arg.(Argument); ok {
+			arg = a.RedisArg()
+		}
+		args = append(args, fs.name, arg)
+	}
+	return args
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// AddJSON returns the result of appending the JSON encoding of v to args. If
+// v cannot be encoded, then args and the encoding error are returned.
+func (args Args) AddJSON(v interface{}) (Args, error) {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return args, err
+	}
+	return append(args, p), nil
+}
+
+// AddFlatMap returns the result of appending the keys and values of m to
+// args. The keys are appended in sorted order. Values that implement the
+// Argument interface are replaced with the result of the RedisArg method. If
+// omitZero is true, then nil values and values equal to the zero value of
+// their type are skipped.
+func (args Args) AddFlatMap(m map[string]interface{}, omitZero bool) Args {
+	keys := make([]string, 0, len(m))
+	for k, v := range m {
+		if omitZero && (v == nil || isEmptyValue(reflect.ValueOf(v))) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := m[k]
+		if a, ok := v.(Argument); ok {
+			v = a.RedisArg()
 		}
-		args = append(args, fs.name, fv.Interface())
+		args = append(args, k, v)
 	}
 	return args
 }
@@ -17,8 +17,11 @@ package redis_test
 import (
 	"fmt"
 	"math"
+	"net"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/garyburd/redigo/redis"
 )
@@ -160,6 +163,56 @@ type s1 struct {
 	s0
 }
 
+// binaryValue implements encoding.BinaryUnmarshaler.
+type binaryValue struct {
+	data string
+}
+
+func (b *binaryValue) UnmarshalBinary(p []byte) error {
+	b.data = "binary:" + string(p)
+	return nil
+}
+
+// millis is a duration stored as integer milliseconds.
+type millis time.Duration
+
+func (m millis) RedisArg() interface{} {
+	return int64(time.Duration(m) / time.Millisecond)
+}
+
+func (m *millis) RedisScan(src interface{}) error {
+	n, err := redis.Int64(src, nil)
+	*m = millis(time.Duration(n) * time.Millisecond)
+	return err
+}
+
+type s2 struct {
+	T  time.Time     `redis:"t"`
+	TU time.Time     `redis:"tu"`
+	D  time.Duration `redis:"d"`
+	DN time.Duration `redis:"dn"`
+	IP net.IP        `redis:"ip"`
+	BV binaryValue   `redis:"bv"`
+	M  millis        `redis:"m"`
+}
+
+type address struct {
+	City string `redis:"city"`
+	Zip  string `redis:"zip"`
+}
+
+type Base struct {
+	ID int `redis:"id"`
+}
+
+type s3 struct {
+	*Base
+	Name string    `redis:"name"`
+	Home address   `redis:"home"`
+	Work *address  `redis:"work,sep=_"`
+	When time.Time `redis:"when"`
+}
+
 var scanStructTests = []struct {
 	title string
 	reply []string
@@ -169,6 +222,54 @@ var scanStructTests = []struct {
 		[]string{"i", "-1234", "u", "5678", "s", "hello", "p", "world", "b", "t", "Bt", "1", "Bf", "0", "X", "123", "y", "456"},
 		&s1{I: -1234, U: 5678, S: "hello", P: []byte("world"), B: true, Bt: true, Bf: false, s0: s0{X: 123, Y: 456}},
 	},
+	{"unmarshalers",
+		[]string{"t", "2017-01-02T03:04:05Z", "tu", "1483326245", "d", "1.5s", "dn", "1000", "ip", "10.0.0.1", "bv", "x", "m", "1500"},
+		&s2{
+			T:  time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC),
+			TU: time.Unix(1483326245, 0),
+			D:  1500 * time.Millisecond,
+			DN: 1000,
+			IP: net.ParseIP("10.0.0.1"),
+			BV: binaryValue{"binary:x"},
+			M:  millis(1500 * time.Millisecond),
+		},
+	},
+	{"nested",
+		[]string{"id", "7", "name", "n", "home.city", "Oslo", "home.zip", "0150", "work_city", "Bergen", "when", "1483326245"},
+		&s3{
+			Base: &Base{ID: 7},
+			Name: "n",
+			Home: address{City: "Oslo", Zip: "0150"},
+			Work: &address{City: "Bergen"},
+			When: time.Unix(1483326245, 0),
+		},
+	},
+}
+
+// scanRecorder records the values passed to RedisScan.
+type scanRecorder struct {
+	src []interface{}
+}
+
+func (r *scanRecorder) RedisScan(src interface{}) error {
+	r.src = append(r.src, src)
+	return nil
+}
+
+func TestScanScannerError(t *testing.T) {
+	replyErr := redis.Error("ERR bad")
+	var values []scanRecorder
+	_, err := redis.Scan([]interface{}{[]interface{}{[]byte("x"), replyErr}}, &values)
+	if err == nil || !strings.Contains(err.Error(), string(replyErr)) {
+		t.Errorf("Scan returned %v, want error containing %q", err, replyErr)
+	}
+	for _, v := range values {
+		for _, src := range v.src {
+			if _, ok := src.(redis.Error); ok {
+				t.Errorf("RedisScan called with error %v", src)
+			}
+		}
+	}
 }
 
 func TestScanStruct(t *testing.T) {
@@ -191,6 +292,34 @@ func TestScanStruct(t *testing.T) {
 	}
 }
 
+func TestScanStructTimeRoundTrip(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	type event struct {
+		Name string    `redis:"name"`
+		At   time.Time `redis:"at"`
+	}
+	in := event{Name: "e", At: time.Now()}
+	if _, err := c.Do("HSET", redis.Args{"event"}.AddFlat(&in)...); err != nil {
+		t.Fatal(err)
+	}
+	v, err := redis.Values(c.Do("HGETALL", "event"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out event
+	if err := redis.ScanStruct(v, &out); err != nil {
+		t.Fatalf("ScanStruct returned error %v", err)
+	}
+	if out.Name != in.Name || !out.At.Equal(in.At) {
+		t.Errorf("ScanStruct returned %v, want %v", out, in)
+	}
+}
+
 func TestBadScanStructArgs(t *testing.T) {
 	x := []interface{}{"A", "b"}
 	test := func(v interface{}) {
@@ -272,6 +401,12 @@ var scanSliceTests = []struct {
 		true,
 		[]struct{ A, C, B string }{{"a1", "", "b1"}, {"a2", "", "b2"}},
 	},
+	{
+		[]interface{}{[]byte("a1"), []byte("x"), []byte("b1"), []byte("a2"), []byte("y"), []byte("b2")},
+		[]string{"A", "-", "B"},
+		true,
+		[]struct{ A, B string }{{"a1", "b1"}, {"a2", "b2"}},
+	},
 	{
 		[]interface{}{[]byte("a1"), []byte("b1"), []byte("a2"), []byte("b2")},
 		nil,
@@ -297,6 +432,91 @@ func TestScanSlice(t *testing.T) {
 	}
 }
 
+func TestScanSliceZeroNil(t *testing.T) {
+	opts := redis.ScanSliceOptions{Fields: []string{"A", "B"}, ZeroNil: true}
+	dest := []struct{ A, B string }{{"old", "old"}, {"old", "old"}}
+	src := []interface{}{[]byte("a1"), nil, nil, []byte("b2")}
+	if err := redis.ScanSliceWithOptions(src, &dest, opts); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{ A, B string }{{"a1", ""}, {"", "b2"}}
+	if !reflect.DeepEqual(dest, expected) {
+		t.Errorf("ScanSliceWithOptions() = %v, want %v", dest, expected)
+	}
+
+	ints := []int{7, 7}
+	if err := redis.ScanSliceWithOptions([]interface{}{nil, int64(1)}, &ints, redis.ScanSliceOptions{ZeroNil: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ints, []int{0, 1}) {
+		t.Errorf("ScanSliceWithOptions() = %v, want [0 1]", ints)
+	}
+}
+
+type scanStructsPerson struct {
+	Name string `redis:"name"`
+	Age  int    `redis:"age"`
+}
+
+var scanStructsTests = []struct {
+	src  []interface{}
+	n    int
+	ok   bool
+	dest interface{}
+}{
+	{
+		[]interface{}{[]byte("name"), []byte("a"), []byte("age"), []byte("1"), []byte("age"), []byte("2"), []byte("name"), []byte("b")},
+		2,
+		true,
+		[]scanStructsPerson{{"a", 1}, {"b", 2}},
+	},
+	{
+		[]interface{}{
+			[]interface{}{[]byte("name"), []byte("a"), []byte("age"), []byte("1")},
+			nil,
+			[]interface{}{},
+			[]interface{}{[]byte("name"), []byte("b")},
+		},
+		0,
+		true,
+		[]*scanStructsPerson{{"a", 1}, {}, {}, {"b", 0}},
+	},
+	{
+		[]interface{}{[]byte("name"), []byte("a"), []byte("age")},
+		1,
+		false,
+		[]scanStructsPerson(nil),
+	},
+	{
+		[]interface{}{[]byte("name")},
+		0,
+		false,
+		[]scanStructsPerson(nil),
+	},
+	{
+		[]interface{}{[]byte("name"), []byte("a")},
+		1,
+		false,
+		[]string(nil),
+	},
+}
+
+func TestScanStructs(t *testing.T) {
+	for _, tt := range scanStructsTests {
+		typ := reflect.ValueOf(tt.dest).Type()
+		dest := reflect.New(typ)
+
+		err := redis.ScanStructs(tt.src, dest.Interface(), tt.n)
+		if tt.ok != (err == nil) {
+			t.Errorf("ScanStructs(%v, %s, %d) returned error %v", tt.src, typ, tt.n, err)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(dest.Elem().Interface(), tt.dest) {
+			t.Errorf("ScanStructs(src, %s, %d) returned %#v, want %#v", typ, tt.n, dest.Elem().Interface(), tt.dest)
+		}
+	}
+}
+
 func ExampleScanSlice() {
 	c, err := dial()
 	if err != nil {
@@ -374,6 +594,39 @@ var argsTests = []struct {
 		}),
 		redis.Args{"Bt", true},
 	},
+	{"nested",
+		redis.Args{}.AddFlat(&s3{
+			Base: &Base{ID: 7},
+			Name: "n",
+			Home: address{City: "Oslo"},
+		}),
+		redis.Args{"id", 7, "name", "n", "home.city", "Oslo", "home.zip", "", "when", time.Time{}},
+	},
+	{"argument",
+		redis.Args{}.Add(millis(time.Second)).AddFlat(millis(2 * time.Second)).AddFlat(struct {
+			M millis `redis:"m"`
+		}{millis(3 * time.Second)}),
+		redis.Args{int64(1000), int64(2000), "m", int64(3000)},
+	},
+	{"json",
+		mustArgs(redis.Args{"key"}.AddJSON(map[string]int{"a": 1})),
+		redis.Args{"key", []byte(`{"a":1}`)},
+	},
+	{"flat map",
+		redis.Args{"key"}.AddFlatMap(map[string]interface{}{"b": 2, "a": "x", "m": millis(time.Second)}, false),
+		redis.Args{"key", "a", "x", "b", 2, "m", int64(1000)},
+	},
+	{"flat map omit zero",
+		redis.Args{"key"}.AddFlatMap(map[string]interface{}{"a": "", "b": 0, "c": nil, "d": 1.5}, true),
+		redis.Args{"key", "d", 1.5},
+	},
+}
+
+func mustArgs(args redis.Args, err error) redis.Args {
+	if err != nil {
+		panic(err)
+	}
+	return args
 }
 
 func TestArgs(t *testing.T) {
@@ -384,6 +637,16 @@ func TestArgs(t *testing.T) {
 	}
 }
 
+func TestAddJSONError(t *testing.T) {
+	args, err := redis.Args{"key"}.AddJSON(make(chan int))
+	if err == nil {
+		t.Fatal("AddJSON did not return error for invalid JSON argument")
+	}
+	if !reflect.DeepEqual(args, redis.Args{"key"}) {
+		t.Errorf("AddJSON returned %v on error, want [key]", args)
+	}
+}
+
 func ExampleArgs() {
 	c, err := dial()
 	if err != nil {
@@ -438,3 +701,16 @@ func ExampleArgs() {
 	// {Title:Example Author:Gary Body:Hello}
 	// {Title:Example2 Author:Steve Body:Map}
 }
+
+func TestScanNull(t *testing.T) {
+	var (
+		s redis.NullString
+		n redis.NullInt64
+	)
+	if _, err := redis.Scan([]interface{}{nil, []byte("42")}, &s, &n); err != nil {
+		t.Fatal(err)
+	}
+	if s.Valid || !n.Valid || n.Int64 != 42 {
+		t.Errorf("Scan() = %+v, %+v, want invalid string and 42", s, n)
+	}
+}
@@ -15,10 +15,16 @@
 package redis
 
 import (
+	"bytes"
 	"crypto/sha1"
 	"encoding/hex"
+	"fmt"
 	"io"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 // Script encapsulates the source, hash and key count for a Lua script. See
@@ -27,6 +33,7 @@ type Script struct {
 	keyCount int
 	src      string
 	hash     string
+	readOnly bool
 }
 
 // NewScript returns a new script object. If keyCount is greater than or equal
@@ -37,19 +44,48 @@ type Script struct {
 func NewScript(keyCount int, src string) *Script {
 	h := sha1.New()
 	io.WriteString(h, src)
-	return &Script{keyCount, src, hex.EncodeToString(h.Sum(nil))}
+	return &Script{keyCount: keyCount, src: src, hash: hex.EncodeToString(h.Sum(nil))}
+}
+
+// ReadOnly returns a copy of the script that is evaluated with the
+// EVAL_RO and EVALSHA_RO commands. Read-only scripts can run on replicas.
+// The server rejects read-only scripts that call commands that write.
+func (s *Script) ReadOnly() *Script {
+	ro := *s
+	ro.readOnly = true
+	return &ro
+}
+
+// commands returns the names of the EVAL and EVALSHA commands for the
+// script.
+func (s *Script) commands() (eval, evalsha string) {
+	if s.readOnly {
+		return "EVAL_RO", "EVALSHA_RO"
+	}
+	return "EVAL", "EVALSHA"
+}
+
+// Hash returns the SHA1 hash of the script source.
+func (s *Script) Hash() string {
+	return s.hash
 }
 
 func (s *Script) args(spec string, keysAndArgs []interface{}) []interface{} {
+	return evalArgs(spec, s.keyCount, keysAndArgs)
+}
+
+// evalArgs returns the arguments to EVAL, EVALSHA or FCALL for the script
+// or function spec.
+func evalArgs(spec string, keyCount int, keysAndArgs []interface{}) []interface{} {
 	var args []interface{}
-	if s.keyCount < 0 {
+	if keyCount < 0 {
 		args = make([]interface{}, 1+len(keysAndArgs))
 		args[0] = spec
 		copy(args[1:], keysAndArgs)
 	} else {
 		args = make([]interface{}, 2+len(keysAndArgs))
 		args[0] = spec
-		args[1] = s.keyCount
+		args[1] = keyCount
 		copy(args[2:], keysAndArgs)
 	}
 	return args
@@ -60,9 +96,10 @@ func (s *Script) args(spec string, keysAndArgs []interface{}) []interface{} {
 // not loaded, then Do evaluates the script using the EVAL command (thus
 // causing the script to load).
 func (s *Script) Do(c Conn, keysAndArgs ...interface{}) (interface{}, error) {
-	v, err := c.Do("EVALSHA", s.args(s.hash, keysAndArgs)...)
+	eval, evalsha := s.commands()
+	v, err := c.Do(evalsha, s.args(s.hash, keysAndArgs)...)
 	if e, ok := err.(Error); ok && strings.HasPrefix(string(e), "NOSCRIPT ") {
-		v, err = c.Do("EVAL", s.args(s.src, keysAndArgs)...)
+		v, err = c.Do(eval, s.args(s.src, keysAndArgs)...)
 	}
 	return v, err
 }
@@ -71,12 +108,14 @@ func (s *Script) Do(c Conn, keysAndArgs ...interface{}) (interface{}, error) {
 // evaluated with the EVALSHA command. The application must ensure that the
 // script is loaded by a previous call to Send, Do or Load methods.
 func (s *Script) SendHash(c Conn, keysAndArgs ...interface{}) error {
-	return c.Send("EVALSHA", s.args(s.hash, keysAndArgs)...)
+	_, evalsha := s.commands()
+	return c.Send(evalsha, s.args(s.hash, keysAndArgs)...)
 }
 
 // Send evaluates the script without waiting for the reply.
 func (s *Script) Send(c Conn, keysAndArgs ...interface{}) error {
-	return c.Send("EVAL", s.args(s.src, keysAndArgs)...)
+	eval, _ := s.commands()
+	return c.Send(eval, s.args(s.src, keysAndArgs)...)
 }
 
 // Load loads the script without evaluating it.
@@ -84,3 +123,314 @@ func (s *Script) Load(c Conn) error {
 	_, err := c.Do("SCRIPT", "LOAD", s.src)
 	return err
 }
+
+// VerifyScripts checks that the scripts are loaded on the server using a
+// single SCRIPT EXISTS command and returns the scripts that are not loaded.
+// The scripts are not evaluated.
+func VerifyScripts(c Conn, scripts ...*Script) (missing []*Script, err error) {
+	if len(scripts) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 1+len(scripts))
+	args[0] = "EXISTS"
+	for i, s := range scripts {
+		args[i+1] = s.hash
+	}
+	exists, err := Ints(c.Do("SCRIPT", args...))
+	if err != nil {
+		return nil, err
+	}
+	if len(exists) != len(scripts) {
+		return nil, fmt.Errorf("redigo: SCRIPT EXISTS returned %d results for %d scripts", len(exists), len(scripts))
+	}
+	for i, s := range scripts {
+		if exists[i] == 0 {
+			missing = append(missing, s)
+		}
+	}
+	return missing, nil
+}
+
+// ScriptRegistry holds named scripts and loads the scripts on new
+// connections. Loading the scripts when a connection is created avoids the
+// NOSCRIPT round trip on the first call to Script.Do after a server restart,
+// failover or SCRIPT FLUSH.
+//
+//  var scripts redis.ScriptRegistry
+//  var getScript = scripts.Register("get", redis.NewScript(1, `return redis.call('get', KEYS[1])`))
+//
+//  pool := &redis.Pool{
+//      Dial: scripts.Dial(func() (redis.Conn, error) {
+//          return redis.Dial("tcp", addr)
+//      }),
+//  }
+//
+// The zero value is an empty registry ready to use.
+type ScriptRegistry struct {
+	mu      sync.RWMutex
+	scripts map[string]*Script
+}
+
+// Register adds a script to the registry and returns the script. A script
+// registered with an existing name replaces the previous script.
+func (r *ScriptRegistry) Register(name string, s *Script) *Script {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.scripts == nil {
+		r.scripts = make(map[string]*Script)
+	}
+	r.scripts[name] = s
+	return s
+}
+
+// Script returns the named script or nil if the script is not registered.
+func (r *ScriptRegistry) Script(name string) *Script {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.scripts[name]
+}
+
+// Load loads all scripts in the registry on the connection using a
+// pipeline.
+func (r *ScriptRegistry) Load(c Conn) error {
+	r.mu.RLock()
+	n := 0
+	for _, s := range r.scripts {
+		if err := c.Send("SCRIPT", "LOAD", s.src); err != nil {
+			r.mu.RUnlock()
+			return err
+		}
+		n++
+	}
+	r.mu.RUnlock()
+	if n == 0 {
+		return nil
+	}
+	replies, err := Values(c.Do(""))
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if err, ok := reply.(Error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dial returns a function that calls dial and loads the scripts in the
+// registry on the new connection. Use the returned function as a Pool's
+// Dial function.
+func (r *ScriptRegistry) Dial(dial func() (Conn, error)) func() (Conn, error) {
+	return func() (Conn, error) {
+		c, err := dial()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Load(c); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+// ScriptPipeline pipelines scripts and other commands on a connection. The
+// pipeline evaluates scripts with EVALSHA. If the server replies with a
+// NOSCRIPT error, then Receive evaluates the script with EVAL and returns
+// the reply from EVAL.
+//
+// Before evaluating a script with EVAL, Receive reads the replies for all
+// commands sent after the script. As a result, the retried script runs
+// after those commands. Scripts that depend on the order of execution
+// should be loaded before use, for example with a ScriptRegistry.
+//
+//  p := redis.NewScriptPipeline(c)
+//  p.SendScript(incrScript, "counter")
+//  p.Send("GET", "other")
+//  p.Flush()
+//  n, err := redis.Int(p.Receive())
+//  v, err := redis.String(p.Receive())
+type ScriptPipeline struct {
+	c Conn
+
+	// calls holds the script for each pending reply or nil for commands
+	// that are not scripts.
+	calls []scriptCall
+
+	// replies holds replies read ahead for the first calls.
+	replies []scriptReply
+}
+
+type scriptCall struct {
+	s           *Script
+	keysAndArgs []interface{}
+}
+
+type scriptReply struct {
+	v   interface{}
+	err error
+}
+
+// NewScriptPipeline returns a pipeline for the connection.
+func NewScriptPipeline(c Conn) *ScriptPipeline {
+	return &ScriptPipeline{c: c}
+}
+
+// SendScript sends the script with EVALSHA without waiting for the reply.
+func (p *ScriptPipeline) SendScript(s *Script, keysAndArgs ...interface{}) error {
+	if err := s.SendHash(p.c, keysAndArgs...); err != nil {
+		return err
+	}
+	p.calls = append(p.calls, scriptCall{s, keysAndArgs})
+	return nil
+}
+
+// Send sends a command without waiting for the reply.
+func (p *ScriptPipeline) Send(commandName string, args ...interface{}) error {
+	if err := p.c.Send(commandName, args...); err != nil {
+		return err
+	}
+	p.calls = append(p.calls, scriptCall{})
+	return nil
+}
+
+// Flush flushes the output buffer to the server.
+func (p *ScriptPipeline) Flush() error {
+	return p.c.Flush()
+}
+
+// Receive receives the reply for the next command sent to the pipeline.
+func (p *ScriptPipeline) Receive() (interface{}, error) {
+	if len(p.calls) == 0 {
+		return p.c.Receive()
+	}
+	call := p.calls[0]
+	p.calls = p.calls[1:]
+
+	var r scriptReply
+	if len(p.replies) > 0 {
+		r = p.replies[0]
+		p.replies = p.replies[1:]
+	} else {
+		r.v, r.err = p.c.Receive()
+	}
+	if call.s == nil || ErrorCode(r.err) != "NOSCRIPT" {
+		return r.v, r.err
+	}
+
+	// Read the remaining replies so that the connection can be used to
+	// evaluate the script.
+	if len(p.replies) < len(p.calls) {
+		if err := p.c.Flush(); err != nil {
+			return nil, err
+		}
+		for len(p.replies) < len(p.calls) {
+			var next scriptReply
+			next.v, next.err = p.c.Receive()
+			p.replies = append(p.replies, next)
+		}
+	}
+	return call.s.Do(p.c, call.keysAndArgs...)
+}
+
+var (
+	scriptKeyPattern   = regexp.MustCompile(`\bKEYS\[(\d+)\]`)
+	scriptConstPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// BuildScript returns a script composed from fragments of Lua source. The
+// fragments are joined with newlines.
+//
+// The consts map defines constants that are injected at the start of the
+// script as local variables. The map values must be strings, booleans,
+// integers or floating point numbers.
+//
+// If keyCount is greater than or equal to zero, then BuildScript returns
+// an error if the source references KEYS with a constant index greater
+// than keyCount. References with a computed index are not checked.
+func BuildScript(keyCount int, consts map[string]interface{}, fragments ...string) (*Script, error) {
+	var buf bytes.Buffer
+	names := make([]string, 0, len(consts))
+	for name := range consts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !scriptConstPattern.MatchString(name) {
+			return nil, fmt.Errorf("redigo: bad script constant name %q", name)
+		}
+		value, err := luaLiteral(consts[name])
+		if err != nil {
+			return nil, fmt.Errorf("redigo: script constant %s: %v", name, err)
+		}
+		fmt.Fprintf(&buf, "local %s = %s\n", name, value)
+	}
+	buf.WriteString(strings.Join(fragments, "\n"))
+	src := buf.String()
+
+	if keyCount >= 0 {
+		for _, m := range scriptKeyPattern.FindAllStringSubmatch(src, -1) {
+			i, err := strconv.Atoi(m[1])
+			if err != nil || i < 1 || i > keyCount {
+				return nil, fmt.Errorf("redigo: script references %s, key count is %d", m[0], keyCount)
+			}
+		}
+	}
+	return NewScript(keyCount, src), nil
+}
+
+// MustBuildScript is like BuildScript but panics if the script cannot be
+// built. It simplifies safe initialization of global variables holding
+// scripts.
+func MustBuildScript(keyCount int, consts map[string]interface{}, fragments ...string) *Script {
+	s, err := BuildScript(keyCount, consts, fragments...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// luaLiteral returns the Lua literal for v.
+func luaLiteral(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return luaQuote(v), nil
+	case []byte:
+		return luaQuote(string(v)), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", v)
+}
+
+// luaQuote returns s as a double quoted Lua string. Bytes outside of the
+// printable ASCII range are written as decimal escapes.
+func luaQuote(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b == '"' || b == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case b < ' ' || b > '~':
+			fmt.Fprintf(&buf, "\\%03d", b)
+		default:
+			buf.WriteByte(b)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
@@ -15,8 +15,10 @@
 package redis_test
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -98,3 +100,187 @@ func TestScript(t *testing.T) {
 	}
 
 }
+
+func TestScriptRegistry(t *testing.T) {
+	var r redis.ScriptRegistry
+	s1 := r.Register("one", redis.NewScript(0, fmt.Sprintf("--%d\nreturn 1", time.Now().UnixNano())))
+	s2 := r.Register("two", redis.NewScript(0, fmt.Sprintf("--%d\nreturn 2", time.Now().UnixNano())))
+	if r.Script("one") != s1 || r.Script("missing") != nil {
+		t.Fatal("Script() returned unexpected script")
+	}
+
+	p := &redis.Pool{Dial: r.Dial(redis.DialDefaultServer)}
+	defer p.Close()
+	c := p.Get()
+	defer c.Close()
+
+	exists, err := redis.Ints(c.Do("SCRIPT", "EXISTS", s1.Hash(), s2.Hash()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exists, []int{1, 1}) {
+		t.Errorf("SCRIPT EXISTS = %v, want [1 1]", exists)
+	}
+}
+
+func TestScriptPipeline(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Do("SCRIPT", "FLUSH"); err != nil {
+		t.Fatal(err)
+	}
+	s := redis.NewScript(2, fmt.Sprintf("--%d\nreturn {KEYS[1],KEYS[2],ARGV[1],ARGV[2]}", time.Now().UnixNano()))
+	c.Do("SET", "k", "v")
+
+	p := redis.NewScriptPipeline(c)
+	p.SendScript(s, "key1", "key2", "arg1", "arg2")
+	p.Send("GET", "k")
+	p.SendScript(s, "key3", "key4", "arg3", "arg4")
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	p.Send("GET", "k")
+
+	expected := []interface{}{
+		[]interface{}{[]byte("key1"), []byte("key2"), []byte("arg1"), []byte("arg2")},
+		[]byte("v"),
+		[]interface{}{[]byte("key3"), []byte("key4"), []byte("arg3"), []byte("arg4")},
+		[]byte("v"),
+	}
+	for i, want := range expected {
+		v, err := p.Receive()
+		if err != nil {
+			t.Fatalf("Receive() %d returned %v", i, err)
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("Receive() %d = %v, want %v", i, v, want)
+		}
+	}
+}
+
+func TestBuildScript(t *testing.T) {
+	s, err := redis.BuildScript(2, map[string]interface{}{
+		"PREFIX": "a\"b\n",
+		"LIMIT":  10,
+		"RATIO":  0.5,
+		"ON":     true,
+	}, "local v = redis.call('get', KEYS[1])", "return KEYS[2]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := redis.NewScript(2, "local LIMIT = 10\n"+
+		"local ON = true\n"+
+		"local PREFIX = \"a\\\"b\\010\"\n"+
+		"local RATIO = 0.5\n"+
+		"local v = redis.call('get', KEYS[1])\nreturn KEYS[2]")
+	if s.Hash() != expected.Hash() {
+		t.Errorf("BuildScript() hash = %s, want %s", s.Hash(), expected.Hash())
+	}
+
+	for _, tt := range []struct {
+		keyCount int
+		consts   map[string]interface{}
+		src      string
+	}{
+		{1, nil, "return KEYS[2]"},
+		{1, nil, "return KEYS[0]"},
+		{0, map[string]interface{}{"bad name": 1}, "return 1"},
+		{0, map[string]interface{}{"X": []int{1}}, "return 1"},
+	} {
+		if _, err := redis.BuildScript(tt.keyCount, tt.consts, tt.src); err == nil {
+			t.Errorf("BuildScript(%d, %v, %q) did not return error", tt.keyCount, tt.consts, tt.src)
+		}
+	}
+	if _, err := redis.BuildScript(-1, nil, "return KEYS[5]"); err != nil {
+		t.Errorf("BuildScript(-1, ...) returned %v", err)
+	}
+}
+
+func TestScriptReadOnly(t *testing.T) {
+	var buf bytes.Buffer
+	r := strings.NewReader("-NOSCRIPT No matching script.\r\n:1\r\n")
+	c, err := redis.Dial("", "", dialTestConn(r, &buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := redis.NewScript(0, "return 1").ReadOnly()
+	if v, err := redis.Int(s.Do(c)); err != nil || v != 1 {
+		t.Fatalf("Do() = %v, %v, want 1, nil", v, err)
+	}
+	expected := "*3\r\n$10\r\nEVALSHA_RO\r\n$40\r\n" + s.Hash() + "\r\n$1\r\n0\r\n" +
+		"*3\r\n$7\r\nEVAL_RO\r\n$8\r\nreturn 1\r\n$1\r\n0\r\n"
+	if buf.String() != expected {
+		t.Errorf("commands = %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestVerifyScripts(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	loaded := redis.NewScript(0, fmt.Sprintf("--%d\nreturn 1", time.Now().UnixNano()))
+	missing := redis.NewScript(0, fmt.Sprintf("--%d\nreturn 2", time.Now().UnixNano()))
+	if err := loaded.Load(c); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := redis.VerifyScripts(c, loaded, missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 1 || actual[0] != missing {
+		t.Errorf("VerifyScripts() = %v, want [missing]", actual)
+	}
+}
+
+func TestScriptDebug(t *testing.T) {
+	var buf bytes.Buffer
+	r := strings.NewReader("+OK\r\n" +
+		"*2\r\n+* Stopped at 1, stop reason = step over\r\n+-> 1   local x = 1\r\n" +
+		"*1\r\n+-> 2   return x\r\n" +
+		"*1\r\n+<endsession>\r\n" +
+		":1\r\n")
+	c, err := redis.Dial("", "", dialTestConn(r, &buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := redis.NewScript(0, "local x = 1\nreturn x")
+	d, lines, err := s.Debug(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[1] != "-> 1   local x = 1" {
+		t.Errorf("Debug() lines = %q", lines)
+	}
+	if lines, err := d.Step(); err != nil || len(lines) != 1 {
+		t.Errorf("Step() = %q, %v", lines, err)
+	}
+	if _, err := d.Result(); err == nil {
+		t.Error("Result() before end returned nil error")
+	}
+	if _, err := d.Continue(); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Ended() {
+		t.Fatal("session not ended")
+	}
+	if v, err := redis.Int(d.Result()); err != nil || v != 1 {
+		t.Errorf("Result() = %v, %v, want 1, nil", v, err)
+	}
+	if _, err := d.Step(); err == nil {
+		t.Error("Step() after end returned nil error")
+	}
+	expected := "*3\r\n$6\r\nSCRIPT\r\n$5\r\nDEBUG\r\n$4\r\nSYNC\r\n" +
+		"*3\r\n$4\r\nEVAL\r\n$20\r\nlocal x = 1\nreturn x\r\n$1\r\n0\r\n" +
+		"*1\r\n$4\r\nstep\r\n" +
+		"*1\r\n$8\r\ncontinue\r\n"
+	if buf.String() != expected {
+		t.Errorf("commands = %q, want %q", buf.String(), expected)
+	}
+}
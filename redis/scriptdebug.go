@@ -0,0 +1,122 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"strconv"
+)
+
+var errDebugSessionEnded = errors.New("redigo: script debug session ended")
+
+// ScriptDebugSession is a session with the Lua debugger started by the
+// Script Debug method. See https://redis.io/docs/manual/programmability/lua-debugging/
+// for information on the debugger.
+//
+// The session uses the synchronous debugging mode. The server blocks while
+// the session is active and keeps changes made by the script. The server
+// closes the connection after the session ends.
+type ScriptDebugSession struct {
+	c      Conn
+	ended  bool
+	result interface{}
+	err    error
+}
+
+// Debug starts a debugging session for the script on the connection. Debug
+// returns the session and the debugger output for the first line of the
+// script. The connection is closed by the server when the session ends.
+func (s *Script) Debug(c Conn, keysAndArgs ...interface{}) (*ScriptDebugSession, []string, error) {
+	if _, err := c.Do("SCRIPT", "DEBUG", "SYNC"); err != nil {
+		return nil, nil, err
+	}
+	// The debugger requires EVAL. Scripts called with EVALSHA are not
+	// debugged.
+	d := &ScriptDebugSession{c: c}
+	lines, err := d.reply(c.Do("EVAL", s.args(s.src, keysAndArgs)...))
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, lines, nil
+}
+
+// Command sends a debugger command and returns the debugger output.
+func (d *ScriptDebugSession) Command(commandName string, args ...interface{}) ([]string, error) {
+	if d.ended {
+		return nil, errDebugSessionEnded
+	}
+	return d.reply(d.c.Do(commandName, args...))
+}
+
+// reply converts debugger output to lines. If the output ends the session,
+// then reply reads the script result.
+func (d *ScriptDebugSession) reply(reply interface{}, err error) ([]string, error) {
+	lines, err := statusStrings(reply)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		if line == "<endsession>" {
+			d.ended = true
+			d.result, d.err = d.c.Receive()
+			break
+		}
+	}
+	return lines, nil
+}
+
+// Step runs the current line and stops at the next line.
+func (d *ScriptDebugSession) Step() ([]string, error) {
+	return d.Command("step")
+}
+
+// Continue runs the script until the next breakpoint or the end of the
+// script.
+func (d *ScriptDebugSession) Continue() ([]string, error) {
+	return d.Command("continue")
+}
+
+// Break sets a breakpoint at line.
+func (d *ScriptDebugSession) Break(line int) ([]string, error) {
+	return d.Command("break", strconv.Itoa(line))
+}
+
+// Print returns the local variables and their values.
+func (d *ScriptDebugSession) Print() ([]string, error) {
+	return d.Command("print")
+}
+
+// Eval evaluates a Lua expression in the context of the script.
+func (d *ScriptDebugSession) Eval(code string) ([]string, error) {
+	return d.Command("eval", code)
+}
+
+// Abort stops the script.
+func (d *ScriptDebugSession) Abort() ([]string, error) {
+	return d.Command("abort")
+}
+
+// Ended returns true if the session ended.
+func (d *ScriptDebugSession) Ended() bool {
+	return d.ended
+}
+
+// Result returns the reply to the script after the session ended.
+func (d *ScriptDebugSession) Result() (interface{}, error) {
+	if !d.ended {
+		return nil, errors.New("redigo: script debug session is active")
+	}
+	return d.result, d.err
+}
@@ -13,6 +13,10 @@ type Sentinel struct {
 	addrs      []string
 	activeAddr int
 	sync.Mutex
+
+	// Logger specifies an optional logger for failed sentinel servers. Set
+	// the field before calling the Sentinel methods.
+	Logger Logger
 }
 
 // NewSentinel creates a new sentinel client connection. Dial options passed to
@@ -40,6 +44,9 @@ func (sc *Sentinel) do(cmd string, args ...interface{}) (interface{}, error) {
 	for i := 0; i < len(sc.addrs); i++ {
 		reply, err = sc.doOnce(cmd, args...)
 		if err != nil {
+			if sc.Logger != nil {
+				sc.Logger.Log(LogWarn, "sentinel failed", "addr", sc.addrs[sc.activeAddr], "err", err)
+			}
 			// Retry with the next sentinel in the list.
 			sc.activeAddr = (sc.activeAddr + 1) % len(sc.addrs)
 			continue
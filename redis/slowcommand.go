@@ -0,0 +1,145 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SlowCommand represents a command recorded by a SlowCommandLog.
+type SlowCommand struct {
+	// The time that the command was sent.
+	Time time.Time
+
+	// The time between sending the command and receiving the reply.
+	Duration time.Duration
+
+	CommandName string
+
+	// The command arguments redacted with the policy specified by the
+	// DialRedaction option.
+	Args []string
+
+	// The address of the server.
+	Target string
+
+	// The error returned for the command, if any.
+	Err error
+}
+
+// SlowCommandLog records commands that take longer than a threshold. The
+// log is kept on the client and is independent of the server's SLOWLOG.
+// Attach the log to connections with the DialSlowCommandLog option.
+//
+// The zero value is a log that records all commands in a buffer of 128
+// entries.
+type SlowCommandLog struct {
+	// Commands that take longer than Threshold are recorded.
+	Threshold time.Duration
+
+	// Size is the maximum number of commands kept in the log. When the log
+	// is full, the oldest command is discarded. If zero, then 128 is used.
+	Size int
+
+	// OnSlowCommand specifies an optional function that is called with each
+	// recorded command. The function is called synchronously from the
+	// goroutine executing the command.
+	OnSlowCommand func(SlowCommand)
+
+	mu      sync.Mutex
+	entries []SlowCommand
+	next    int
+}
+
+// DialSlowCommandLog specifies a log for slow commands executed on the
+// connection.
+func DialSlowCommandLog(l *SlowCommandLog) DialOption {
+	return DialOption{func(do *dialOptions) {
+		do.addHook(func(address string) Hook {
+			return slowCommandHook{l: l, target: address, redaction: do.redaction}
+		})
+	}}
+}
+
+// Entries returns the recorded commands, most recent first.
+func (l *SlowCommandLog) Entries() []SlowCommand {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(l.entries)
+	result := make([]SlowCommand, n)
+	for i := range result {
+		result[i] = l.entries[(l.next-1-i+n)%n]
+	}
+	return result
+}
+
+// Reset discards the recorded commands.
+func (l *SlowCommandLog) Reset() {
+	l.mu.Lock()
+	l.entries = nil
+	l.next = 0
+	l.mu.Unlock()
+}
+
+func (l *SlowCommandLog) add(cmd SlowCommand) {
+	size := l.Size
+	if size <= 0 {
+		size = 128
+	}
+	l.mu.Lock()
+	if len(l.entries) < size {
+		l.entries = append(l.entries, cmd)
+		l.next = len(l.entries) % size
+	} else {
+		l.entries[l.next] = cmd
+		l.next = (l.next + 1) % size
+	}
+	l.mu.Unlock()
+	if l.OnSlowCommand != nil {
+		l.OnSlowCommand(cmd)
+	}
+}
+
+type slowCommandArgs struct{}
+
+// slowCommandHook records slow commands to a SlowCommandLog.
+type slowCommandHook struct {
+	l         *SlowCommandLog
+	target    string
+	redaction *RedactionPolicy
+}
+
+func (h slowCommandHook) BeforeCommand(ctx context.Context, commandName string, args []interface{}) context.Context {
+	return context.WithValue(ctx, slowCommandArgs{}, args)
+}
+
+func (h slowCommandHook) AfterCommand(ctx context.Context, commandName string, d time.Duration, reply interface{}, err error) {
+	if d < h.l.Threshold {
+		return
+	}
+	args, _ := ctx.Value(slowCommandArgs{}).([]interface{})
+	h.l.add(SlowCommand{
+		Time:        time.Now().Add(-d),
+		Duration:    d,
+		CommandName: commandName,
+		Args:        h.redaction.Args(commandName, args),
+		Target:      h.target,
+		Err:         err,
+	})
+}
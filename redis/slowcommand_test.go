@@ -0,0 +1,63 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redis_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestSlowCommandLog(t *testing.T) {
+	var callbacks int
+	l := &redis.SlowCommandLog{
+		Size:          2,
+		OnSlowCommand: func(redis.SlowCommand) { callbacks++ },
+	}
+	r := strings.NewReader("+OK\r\n+OK\r\n-ERR bad\r\n")
+	c, err := redis.Dial("", "example.com:6379", dialTestConn(r, &bytes.Buffer{}), redis.DialSlowCommandLog(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Do("SET", "a", "secret")
+	c.Do("MSET", "b", "secret", "c", "secret")
+	c.Do("BAD", "d")
+
+	entries := l.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.CommandName != "BAD" || e.Err == nil || e.Target != "example.com:6379" {
+		t.Errorf("entries[0] = %+v", e)
+	}
+	if e := entries[1]; e.CommandName != "MSET" || !reflect.DeepEqual(e.Args, []string{"b", "?", "c", "?"}) {
+		t.Errorf("entries[1] = %+v", e)
+	}
+	if callbacks != 3 {
+		t.Errorf("callbacks = %d, want 3", callbacks)
+	}
+
+	l.Reset()
+	if n := len(l.Entries()); n != 0 {
+		t.Errorf("got %d entries after reset, want 0", n)
+	}
+}
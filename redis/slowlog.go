@@ -0,0 +1,84 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+	"time"
+)
+
+// SlowLog represents an entry in the reply to the SLOWLOG GET command.
+type SlowLog struct {
+	// The unique identifier of the entry.
+	ID int64
+
+	// The time that the command was processed.
+	Time time.Time
+
+	// The execution time of the command.
+	Duration time.Duration
+
+	// The command name and arguments. The server truncates long argument
+	// lists and arguments.
+	Args []string
+
+	// The address and name of the client that sent the command. These
+	// fields are empty for servers before Redis 4.0.
+	ClientAddr string
+	ClientName string
+}
+
+// SlowLogs is a helper that converts the reply to the SLOWLOG GET command to
+// a slice of SlowLog.
+func SlowLogs(reply interface{}, err error) ([]SlowLog, error) {
+	entries, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SlowLog, len(entries))
+	for i, entry := range entries {
+		fields, err := Values(entry, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("redigo: SlowLogs expects at least 4 fields in entry, got %d", len(fields))
+		}
+		s := &result[i]
+		if s.ID, err = Int64(fields[0], nil); err != nil {
+			return nil, err
+		}
+		if s.Time, err = Time(fields[1], nil); err != nil {
+			return nil, err
+		}
+		us, err := Int64(fields[2], nil)
+		if err != nil {
+			return nil, err
+		}
+		s.Duration = time.Duration(us) * time.Microsecond
+		if s.Args, err = Strings(fields[3], nil); err != nil {
+			return nil, err
+		}
+		if len(fields) >= 6 {
+			if s.ClientAddr, err = String(fields[4], nil); err != nil {
+				return nil, err
+			}
+			if s.ClientName, err = String(fields[5], nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,435 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamID represents the ID of a stream entry. The ID is made of the entry
+// creation time in milliseconds and a sequence number.
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// ParseStreamID parses a stream ID in the format "<ms>-<seq>". The sequence
+// number is optional.
+func ParseStreamID(s string) (StreamID, error) {
+	var id StreamID
+	ms, seq := s, ""
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		ms, seq = s[:i], s[i+1:]
+	}
+	var err error
+	if id.Ms, err = strconv.ParseUint(ms, 10, 64); err != nil {
+		return id, fmt.Errorf("redigo: bad stream ID %q", s)
+	}
+	if seq != "" {
+		if id.Seq, err = strconv.ParseUint(seq, 10, 64); err != nil {
+			return id, fmt.Errorf("redigo: bad stream ID %q", s)
+		}
+	}
+	return id, nil
+}
+
+// String returns the ID in the format "<ms>-<seq>".
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// Time returns the entry creation time encoded in the ID.
+func (id StreamID) Time() time.Time {
+	return time.Unix(int64(id.Ms/1000), int64(id.Ms%1000)*int64(time.Millisecond))
+}
+
+// Less returns true if id is ordered before other in a stream.
+func (id StreamID) Less(other StreamID) bool {
+	return id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq)
+}
+
+// RedisArg implements the Argument interface.
+func (id StreamID) RedisArg() interface{} {
+	return id.String()
+}
+
+// RedisScan implements the Scanner interface.
+func (id *StreamID) RedisScan(src interface{}) error {
+	s, err := String(src, nil)
+	if err != nil {
+		return err
+	}
+	*id, err = ParseStreamID(s)
+	return err
+}
+
+// StreamIDReply is a helper that converts a command reply to a StreamID.
+// StreamIDReply returns ErrNil for a nil reply.
+func StreamIDReply(reply interface{}, err error) (StreamID, error) {
+	var id StreamID
+	if err != nil {
+		return id, err
+	}
+	if reply == nil {
+		return id, ErrNil
+	}
+	err = id.RedisScan(reply)
+	return id, err
+}
+
+// XAddOption specifies an option for XAddStruct.
+type XAddOption struct {
+	f func(*xAddOptions)
+}
+
+type xAddOptions struct {
+	id         string
+	noMkStream bool
+	trim       []interface{}
+}
+
+// XAddID specifies the ID of the new entry. The default is "*", which asks
+// the server to generate the ID.
+func XAddID(id string) XAddOption {
+	return XAddOption{func(o *xAddOptions) {
+		o.id = id
+	}}
+}
+
+// XAddNoMkStream specifies that the stream is not created if it does not
+// exist. XAddStruct returns ErrNil if the stream does not exist.
+func XAddNoMkStream() XAddOption {
+	return XAddOption{func(o *xAddOptions) {
+		o.noMkStream = true
+	}}
+}
+
+// XAddMaxLen specifies that the stream is trimmed to at most n entries. If
+// approx is true, then the server trims the stream lazily and may keep more
+// than n entries.
+func XAddMaxLen(n int64, approx bool) XAddOption {
+	return XAddOption{func(o *xAddOptions) {
+		o.trim = trimArgs("MAXLEN", n, approx)
+	}}
+}
+
+// XAddMinID specifies that entries with IDs lower than id are evicted from
+// the stream. See XAddMaxLen for the meaning of approx.
+func XAddMinID(id string, approx bool) XAddOption {
+	return XAddOption{func(o *xAddOptions) {
+		o.trim = trimArgs("MINID", id, approx)
+	}}
+}
+
+func trimArgs(strategy string, threshold interface{}, approx bool) []interface{} {
+	if approx {
+		return []interface{}{strategy, "~", threshold}
+	}
+	return []interface{}{strategy, threshold}
+}
+
+// XAddStruct appends an entry to stream with the fields of the struct v. The
+// struct is flattened as described in Args.AddFlat. XAddStruct returns the
+// ID of the new entry.
+//
+//  id, err := redis.XAddStruct(c, "events", &Event{Kind: "login", User: "gary"},
+//      redis.XAddMaxLen(1000, true))
+func XAddStruct(c Conn, stream string, v interface{}, opts ...XAddOption) (StreamID, error) {
+	o := xAddOptions{id: "*"}
+	for _, option := range opts {
+		option.f(&o)
+	}
+	args := Args{stream}
+	if o.noMkStream {
+		args = append(args, "NOMKSTREAM")
+	}
+	args = append(args, o.trim...)
+	args = append(args, o.id)
+	fields := Args{}.AddFlat(v)
+	if len(fields) == 0 {
+		return StreamID{}, errors.New("redigo: XAddStruct requires a struct with at least one field")
+	}
+	args = append(args, fields...)
+	return StreamIDReply(c.Do("XADD", args...))
+}
+
+// StreamEntry represents an entry in a stream.
+type StreamEntry struct {
+	ID string
+
+	// Fields is nil for an entry that was deleted from the stream after it
+	// was delivered to a consumer group.
+	Fields map[string]string
+}
+
+// StreamEntries is a helper that converts an array of stream entries to a
+// []StreamEntry. The XRANGE, XREVRANGE and XCLAIM commands return replies in
+// this format.
+func StreamEntries(reply interface{}, err error) ([]StreamEntry, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]StreamEntry, len(values))
+	for i, v := range values {
+		fields, err := Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("redigo: StreamEntries expects two element entry, got %d", len(fields))
+		}
+		if entries[i].ID, err = String(fields[0], nil); err != nil {
+			return nil, err
+		}
+		if fields[1] == nil {
+			continue
+		}
+		if entries[i].Fields, err = StringMap(fields[1], nil); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// StreamID parses the entry ID.
+func (e StreamEntry) StreamID() (StreamID, error) {
+	return ParseStreamID(e.ID)
+}
+
+// StreamMessage represents an entry read from a stream with the XREAD or
+// XREADGROUP commands.
+type StreamMessage struct {
+	Stream string
+	StreamEntry
+}
+
+// StreamMessages is a helper that converts the reply to the XREAD or
+// XREADGROUP commands to a []StreamMessage. The messages are ordered by
+// stream as returned by the server and then by ID. StreamMessages returns
+// ErrNil when the command times out.
+//
+// The reply is an array of stream name and entries pairs or, on a connection
+// with RESP3 native types enabled, a map from stream name to entries.
+func StreamMessages(reply interface{}, err error) ([]StreamMessage, error) {
+	if err != nil {
+		return nil, err
+	}
+	var messages []StreamMessage
+	add := func(stream string, reply interface{}) error {
+		entries, err := StreamEntries(reply, nil)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			messages = append(messages, StreamMessage{Stream: stream, StreamEntry: e})
+		}
+		return nil
+	}
+	if m, ok := reply.(map[string]interface{}); ok {
+		streams := make([]string, 0, len(m))
+		for stream := range m {
+			streams = append(streams, stream)
+		}
+		sort.Strings(streams)
+		for _, stream := range streams {
+			if err := add(stream, m[stream]); err != nil {
+				return nil, err
+			}
+		}
+		return messages, nil
+	}
+	values, err := Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		pair, err := Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("redigo: StreamMessages expects two element stream, got %d", len(pair))
+		}
+		stream, err := String(pair[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(stream, pair[1]); err != nil {
+			return nil, err
+		}
+	}
+	return messages, nil
+}
+
+// XInfoStream represents the reply to the XINFO STREAM command.
+type XInfoStream struct {
+	Length               int64  `redis:"length"`
+	RadixTreeKeys        int64  `redis:"radix-tree-keys"`
+	RadixTreeNodes       int64  `redis:"radix-tree-nodes"`
+	Groups               int64  `redis:"groups"`
+	LastGeneratedID      string `redis:"last-generated-id"`
+	MaxDeletedEntryID    string `redis:"max-deleted-entry-id"`
+	EntriesAdded         int64  `redis:"entries-added"`
+	RecordedFirstEntryID string `redis:"recorded-first-entry-id"`
+
+	// The first and last entries in the stream as returned by the server.
+	// The fields are nil if the stream is empty.
+	FirstEntry interface{} `redis:"-"`
+	LastEntry  interface{} `redis:"-"`
+}
+
+// XInfoGroup represents a consumer group in the reply to the XINFO GROUPS
+// command.
+type XInfoGroup struct {
+	Name            string `redis:"name"`
+	Consumers       int64  `redis:"consumers"`
+	Pending         int64  `redis:"pending"`
+	LastDeliveredID string `redis:"last-delivered-id"`
+	EntriesRead     int64  `redis:"entries-read"`
+
+	// The number of entries in the stream that are not yet delivered to
+	// the group. Lag is -1 when the server cannot compute the lag or
+	// does not report it.
+	Lag int64 `redis:"-"`
+}
+
+// XInfoConsumer represents a consumer in the reply to the XINFO CONSUMERS
+// command.
+type XInfoConsumer struct {
+	Name    string `redis:"name"`
+	Pending int64  `redis:"pending"`
+
+	// The time since the consumer last attempted an interaction.
+	Idle time.Duration `redis:"-"`
+
+	// The time since the consumer last successfully read or claimed a
+	// message. Inactive is -1 if the consumer never did so or the server
+	// does not report it.
+	Inactive time.Duration `redis:"-"`
+}
+
+// XInfoStreamReply is a helper that converts the reply to the XINFO STREAM
+// command to an XInfoStream.
+func XInfoStreamReply(reply interface{}, err error) (XInfoStream, error) {
+	var info XInfoStream
+	values, err := Values(reply, err)
+	if err != nil {
+		return info, err
+	}
+	if err := ScanStruct(values, &info); err != nil {
+		return info, err
+	}
+	err = forEachPair(values, func(name string, value interface{}) error {
+		switch name {
+		case "first-entry":
+			info.FirstEntry = value
+		case "last-entry":
+			info.LastEntry = value
+		}
+		return nil
+	})
+	return info, err
+}
+
+// XInfoGroups is a helper that converts the reply to the XINFO GROUPS
+// command to a slice of XInfoGroup.
+func XInfoGroups(reply interface{}, err error) ([]XInfoGroup, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]XInfoGroup, len(values))
+	for i, v := range values {
+		fields, err := Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		g := &groups[i]
+		if err := ScanStruct(fields, g); err != nil {
+			return nil, err
+		}
+		g.Lag = -1
+		err = forEachPair(fields, func(name string, value interface{}) (err error) {
+			if name == "lag" && value != nil {
+				g.Lag, err = Int64(value, nil)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// XInfoConsumers is a helper that converts the reply to the XINFO CONSUMERS
+// command to a slice of XInfoConsumer.
+func XInfoConsumers(reply interface{}, err error) ([]XInfoConsumer, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	consumers := make([]XInfoConsumer, len(values))
+	for i, v := range values {
+		fields, err := Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		c := &consumers[i]
+		if err := ScanStruct(fields, c); err != nil {
+			return nil, err
+		}
+		c.Inactive = -1
+		err = forEachPair(fields, func(name string, value interface{}) (err error) {
+			var n int64
+			switch name {
+			case "idle":
+				n, err = Int64(value, nil)
+				c.Idle = time.Duration(n) * time.Millisecond
+			case "inactive":
+				// The server reports -1 for a consumer that was never active.
+				n, err = Int64(value, nil)
+				if err == nil && n >= 0 {
+					c.Inactive = time.Duration(n) * time.Millisecond
+				}
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return consumers, nil
+}
+
+// forEachPair calls f for each name-value pair in values.
+func forEachPair(values []interface{}, f func(name string, value interface{}) error) error {
+	for i := 0; i+1 < len(values); i += 2 {
+		name, err := String(values[i], nil)
+		if err != nil {
+			return err
+		}
+		if err := f(name, values[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
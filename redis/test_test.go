@@ -154,6 +154,20 @@ func DialDefaultServer() (Conn, error) {
 	return c, nil
 }
 
+// DialDefaultServerOptions is like DialDefaultServer, but it dials with the
+// specified options only.
+func DialDefaultServerOptions(options ...DialOption) (Conn, error) {
+	if err := startDefaultServer(); err != nil {
+		return nil, err
+	}
+	c, err := Dial("tcp", fmt.Sprintf(":%d", *serverBasePort), options...)
+	if err != nil {
+		return nil, err
+	}
+	c.Do("FLUSHDB")
+	return c, nil
+}
+
 func TestMain(m *testing.M) {
 	os.Exit(func() int {
 		flag.Parse()
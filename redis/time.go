@@ -0,0 +1,116 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// NoExpiry is returned from Duration and DurationMillis when the key
+	// exists and has no associated expiry.
+	NoExpiry time.Duration = -1
+
+	// KeyNotFound is returned from Duration and DurationMillis when the key
+	// does not exist.
+	KeyNotFound time.Duration = -2
+)
+
+// Duration is a helper that converts a TTL command reply in seconds to a
+// time.Duration. If err is not equal to nil, then Duration returns 0, err.
+// The reply values -1 and -2 are converted to NoExpiry and KeyNotFound.
+func Duration(reply interface{}, err error) (time.Duration, error) {
+	return duration(reply, err, time.Second)
+}
+
+// DurationMillis is a helper that converts a PTTL command reply in
+// milliseconds to a time.Duration. See the Duration function for details.
+func DurationMillis(reply interface{}, err error) (time.Duration, error) {
+	return duration(reply, err, time.Millisecond)
+}
+
+func duration(reply interface{}, err error, unit time.Duration) (time.Duration, error) {
+	n, err := Int64(reply, err)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case -1:
+		return NoExpiry, nil
+	case -2:
+		return KeyNotFound, nil
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("redigo: unexpected value for Duration, got %d", n)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// Time is a helper that converts a command reply to a time.Time. If err is
+// not equal to nil, then Time returns the zero time, err. Otherwise, Time
+// converts the reply as follows:
+//
+//  Reply type                  Result
+//  integer                     UNIX time in seconds, nil
+//  bulk string                 parsed UNIX time in seconds, nil
+//  array of two integers       UNIX seconds and microseconds from TIME, nil
+//  negative integer            zero time, ErrNil
+//  nil                         zero time, ErrNil
+//  other                       zero time, error
+//
+// Negative replies are returned by EXPIRETIME when the key has no expiry or
+// does not exist.
+func Time(reply interface{}, err error) (time.Time, error) {
+	if err != nil {
+		return time.Time{}, err
+	}
+	if values, ok := reply.([]interface{}); ok {
+		if len(values) != 2 {
+			return time.Time{}, fmt.Errorf("redigo: unexpected array length for Time, got %d", len(values))
+		}
+		sec, err := Int64(values[0], nil)
+		if err != nil {
+			return time.Time{}, err
+		}
+		usec, err := Int64(values[1], nil)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, usec*int64(time.Microsecond)), nil
+	}
+	return unixTime(reply, time.Second)
+}
+
+// TimeMillis is a helper that converts a command reply in UNIX milliseconds,
+// such as the reply from PEXPIRETIME, to a time.Time. See the Time function
+// for details.
+func TimeMillis(reply interface{}, err error) (time.Time, error) {
+	if err != nil {
+		return time.Time{}, err
+	}
+	return unixTime(reply, time.Millisecond)
+}
+
+func unixTime(reply interface{}, unit time.Duration) (time.Time, error) {
+	n, err := Int64(reply, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n < 0 {
+		return time.Time{}, ErrNil
+	}
+	return time.Unix(0, 0).Add(time.Duration(n) * unit), nil
+}
@@ -0,0 +1,371 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Result holds the reply to a command in a transaction or pipeline. Err is
+// the error reply from the server for the command or, if the command was not
+// executed, the error that prevented execution.
+//
+// Error replies from the server have type Error. Other errors, such as a
+// network error, mean that the reply to the command was not received and the
+// command may or may not have been executed.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// Int converts the result with the Int helper.
+func (r *Result) Int() (int, error) { return Int(r.Value, r.Err) }
+
+// Int64 converts the result with the Int64 helper.
+func (r *Result) Int64() (int64, error) { return Int64(r.Value, r.Err) }
+
+// Float64 converts the result with the Float64 helper.
+func (r *Result) Float64() (float64, error) { return Float64(r.Value, r.Err) }
+
+// String converts the result with the String helper.
+func (r *Result) String() (string, error) { return String(r.Value, r.Err) }
+
+// Bytes converts the result with the Bytes helper.
+func (r *Result) Bytes() ([]byte, error) { return Bytes(r.Value, r.Err) }
+
+// Bool converts the result with the Bool helper.
+func (r *Result) Bool() (bool, error) { return Bool(r.Value, r.Err) }
+
+// Strings converts the result with the Strings helper.
+func (r *Result) Strings() ([]string, error) { return Strings(r.Value, r.Err) }
+
+// Values converts the result with the Values helper.
+func (r *Result) Values() ([]interface{}, error) { return Values(r.Value, r.Err) }
+
+// IntResult holds the reply to a command converted with the Int helper.
+type IntResult struct {
+	Value int
+	Err   error
+}
+
+// Int64Result holds the reply to a command converted with the Int64 helper.
+type Int64Result struct {
+	Value int64
+	Err   error
+}
+
+// Float64Result holds the reply to a command converted with the Float64
+// helper.
+type Float64Result struct {
+	Value float64
+	Err   error
+}
+
+// StringResult holds the reply to a command converted with the String
+// helper.
+type StringResult struct {
+	Value string
+	Err   error
+}
+
+// BytesResult holds the reply to a command converted with the Bytes helper.
+type BytesResult struct {
+	Value []byte
+	Err   error
+}
+
+// BoolResult holds the reply to a command converted with the Bool helper.
+type BoolResult struct {
+	Value bool
+	Err   error
+}
+
+// StringsResult holds the reply to a command converted with the Strings
+// helper.
+type StringsResult struct {
+	Value []string
+	Err   error
+}
+
+// Tx builds a MULTI/EXEC transaction. Commands are queued with methods that
+// return a placeholder for the reply. The Exec method runs the transaction
+// and sets the placeholders.
+//
+//  tx := redis.NewTx(c)
+//  n := tx.Int("INCR", "counter")
+//  v := tx.String("GET", "name")
+//  if err := tx.Exec(); err != nil {
+//      // handle error
+//  }
+//  fmt.Println(n.Value, v.Value)
+//
+// A Tx is used for a single transaction.
+type Tx struct {
+	c          Conn
+	cmds       []txCommand
+	constraint func(keys []string) error
+}
+
+type txCommand struct {
+	name   string
+	args   []interface{}
+	set    func(reply interface{}, err error)
+	result Result
+}
+
+// NewTx returns a transaction builder for the connection.
+func NewTx(c Conn) *Tx {
+	return &Tx{c: c}
+}
+
+func (tx *Tx) queue(commandName string, args []interface{}, set func(interface{}, error)) {
+	tx.cmds = append(tx.cmds, txCommand{name: commandName, args: args, set: set})
+}
+
+// setResult sets the placeholder and the raw result for the i-th command.
+func (tx *Tx) setResult(i int, v interface{}, err error) {
+	cmd := &tx.cmds[i]
+	cmd.result = Result{v, err}
+	cmd.set(v, err)
+}
+
+// Results returns the unconverted results for the queued commands in the
+// order that the commands were queued.
+func (tx *Tx) Results() []Result {
+	results := make([]Result, len(tx.cmds))
+	for i, cmd := range tx.cmds {
+		results[i] = cmd.result
+	}
+	return results
+}
+
+// Do queues a command and returns a placeholder for the unconverted reply.
+func (tx *Tx) Do(commandName string, args ...interface{}) *Result {
+	r := &Result{}
+	tx.queue(commandName, args, func(v interface{}, err error) { r.Value, r.Err = v, err })
+	return r
+}
+
+// Int queues a command and returns a placeholder for the reply converted
+// with the Int helper.
+func (tx *Tx) Int(commandName string, args ...interface{}) *IntResult {
+	r := &IntResult{}
+	tx.queue(commandName, args, func(v interface{}, err error) { r.Value, r.Err = Int(v, err) })
+	return r
+}
+
+// Int64 queues a command and returns a placeholder for the reply converted
+// with the Int64 helper.
+func (tx *Tx) Int64(commandName string, args ...interface{}) *Int64Result {
+	r := &Int64Result{}
+	tx.queue(commandName, args, func(v interface{}, err error) { r.Value, r.Err = Int64(v, err) })
+	return r
+}
+
+// Float64 queues a command and returns a placeholder for the reply
+// converted with the Float64 helper.
+func (tx *Tx) Float64(commandName string, args ...interface{}) *Float64Result {
+	r := &Float64Result{}
+	tx.queue(commandName, args, func(v interface{}, err error) { r.Value, r.Err = Float64(v, err) })
+	return r
+}
+
+// String queues a command and returns a placeholder for the reply converted
+// with the String helper.
+func (tx *Tx) String(commandName string, args ...interface{}) *StringResult {
+	r := &StringResult{}
+	tx.queue(commandName, args, func(v interface{}, err error) { r.Value, r.Err = String(v, err) })
+	return r
+}
+
+// Bytes queues a command and returns a placeholder for the reply converted
+// with the Bytes helper.
+func (tx *Tx) Bytes(commandName string, args ...interface{}) *BytesResult {
+	r := &BytesResult{}
+	tx.queue(commandName, args, func(v interface{}, err error) { r.Value, r.Err = Bytes(v, err) })
+	return r
+}
+
+// Bool queues a command and returns a placeholder for the reply converted
+// with the Bool helper.
+func (tx *Tx) Bool(commandName string, args ...interface{}) *BoolResult {
+	r := &BoolResult{}
+	tx.queue(commandName, args, func(v interface{}, err error) { r.Value, r.Err = Bool(v, err) })
+	return r
+}
+
+// Strings queues a command and returns a placeholder for the reply
+// converted with the Strings helper.
+func (tx *Tx) Strings(commandName string, args ...interface{}) *StringsResult {
+	r := &StringsResult{}
+	tx.queue(commandName, args, func(v interface{}, err error) { r.Value, r.Err = Strings(v, err) })
+	return r
+}
+
+// Exec sends MULTI, the queued commands and EXEC to the server and sets the
+// placeholders from the replies.
+//
+// If the server rejects a command when it is queued, then the server aborts
+// the transaction. The placeholder for the rejected command is set to the
+// server's error and the other placeholders are set to the EXECABORT error
+// returned by Exec.
+//
+// If a routing constraint is set with Constrain, then Exec returns a
+// *TxConstraintError without sending commands if the keys of the queued
+// commands violate the constraint.
+//
+// Exec returns ErrTxAborted if the transaction was aborted because a watched
+// key changed. Exec does not return errors from commands executed by the
+// server. Check the placeholders for these errors.
+func (tx *Tx) Exec() error {
+	if tx.constraint != nil {
+		if err := tx.Validate(tx.constraint); err != nil {
+			return tx.fail(err)
+		}
+	}
+	c := tx.c
+	c.Send("MULTI")
+	for _, cmd := range tx.cmds {
+		c.Send(cmd.name, cmd.args...)
+	}
+	c.Send("EXEC")
+	if err := c.Flush(); err != nil {
+		return tx.fail(err)
+	}
+
+	if _, err := c.Receive(); err != nil {
+		if _, ok := err.(Error); !ok {
+			return tx.fail(err)
+		}
+		// The connection is in an unexpected state. Read the remaining
+		// replies to keep the connection in sync.
+		for i := 0; i <= len(tx.cmds); i++ {
+			if _, e := c.Receive(); e != nil {
+				if _, ok := e.(Error); !ok {
+					return tx.fail(e)
+				}
+			}
+		}
+		return tx.fail(err)
+	}
+
+	queueErrs := make([]error, len(tx.cmds))
+	for i := range tx.cmds {
+		_, err := c.Receive()
+		if _, ok := err.(Error); !ok && err != nil {
+			return tx.fail(err)
+		}
+		queueErrs[i] = err
+	}
+
+	replies, err := Values(c.Receive())
+	if err == ErrNil {
+		return tx.fail(ErrTxAborted)
+	}
+	if err != nil {
+		if _, ok := err.(Error); ok {
+			for i := range tx.cmds {
+				if queueErrs[i] != nil {
+					tx.setResult(i, nil, queueErrs[i])
+				} else {
+					tx.setResult(i, nil, err)
+				}
+			}
+			return err
+		}
+		return tx.fail(err)
+	}
+	if len(replies) != len(tx.cmds) {
+		return tx.fail(fmt.Errorf("redigo: EXEC returned %d replies for %d commands", len(replies), len(tx.cmds)))
+	}
+	for i := range tx.cmds {
+		if e, ok := replies[i].(Error); ok {
+			tx.setResult(i, nil, e)
+		} else {
+			tx.setResult(i, replies[i], nil)
+		}
+	}
+	return nil
+}
+
+// fail sets all placeholders to err and returns err.
+func (tx *Tx) fail(err error) error {
+	for i := range tx.cmds {
+		tx.setResult(i, nil, err)
+	}
+	return err
+}
+
+// ErrTxAborted is returned by the Tx Exec method when the transaction is
+// aborted because a watched key changed.
+var ErrTxAborted = errors.New("redigo: transaction aborted by change to watched key")
+
+// ErrWatchRetries is returned by WatchDo when watched keys change on every
+// attempt.
+var ErrWatchRetries = errors.New("redigo: watched keys changed on every attempt")
+
+// DefaultWatchRetries is the number of attempts made by WatchDo.
+const DefaultWatchRetries = 10
+
+// WatchDo runs fn with optimistic locking on keys. WatchDo watches the keys
+// and calls fn. The function reads the keys and then executes a
+// transaction with MULTI and EXEC or a Tx. If the transaction is aborted
+// because a watched key changed, then fn returns ErrTxAborted and WatchDo
+// tries again. The Tx Exec method returns ErrTxAborted for an aborted
+// transaction. A function that executes EXEC directly must return
+// ErrTxAborted when the reply to EXEC is nil.
+//
+//  err := redis.WatchDo(c, func(c redis.Conn) error {
+//      n, err := redis.Int(c.Do("GET", key))
+//      if err != nil && err != redis.ErrNil {
+//          return err
+//      }
+//      tx := redis.NewTx(c)
+//      tx.Do("SET", key, n*2)
+//      return tx.Exec()
+//  }, key)
+//
+// WatchDo makes DefaultWatchRetries attempts and returns ErrWatchRetries
+// if all attempts are aborted.
+func WatchDo(c Conn, fn func(Conn) error, keys ...string) error {
+	return WatchDoRetries(c, DefaultWatchRetries, fn, keys...)
+}
+
+// WatchDoRetries is like WatchDo, but makes up to attempts attempts.
+func WatchDoRetries(c Conn, attempts int, fn func(Conn) error, keys ...string) error {
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	for i := 0; i < attempts; i++ {
+		if _, err := c.Do("WATCH", args...); err != nil {
+			return err
+		}
+		err := fn(c)
+		if err == ErrTxAborted {
+			continue
+		}
+		if err != nil {
+			// Release the keys if fn did not execute the transaction.
+			c.Do("UNWATCH")
+		}
+		return err
+	}
+	// EXEC releases the keys, but fn may have returned ErrTxAborted without
+	// executing a transaction.
+	c.Do("UNWATCH")
+	return ErrWatchRetries
+}
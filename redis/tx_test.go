@@ -0,0 +1,203 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+func TestTx(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	c.Do("SET", "name", "gopher")
+
+	tx := redis.NewTx(c)
+	n := tx.Int("INCR", "counter")
+	name := tx.String("GET", "name")
+	bad := tx.Int("INCR", "name")
+	raw := tx.Do("GET", "missing")
+	if err := tx.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if n.Value != 1 || n.Err != nil {
+		t.Errorf("INCR = %+v, want 1", n)
+	}
+	if name.Value != "gopher" || name.Err != nil {
+		t.Errorf("GET = %+v, want gopher", name)
+	}
+	if _, ok := bad.Err.(redis.Error); !ok {
+		t.Errorf("INCR string = %+v, want server error", bad)
+	}
+	if raw.Value != nil || raw.Err != nil {
+		t.Errorf("GET missing = %+v, want nil", raw)
+	}
+
+	// A command rejected when queued aborts the transaction.
+	tx = redis.NewTx(c)
+	n = tx.Int("INCR", "counter")
+	unknown := tx.Do("NOSUCHCOMMAND")
+	err = tx.Exec()
+	if redis.ErrorCode(err) != "EXECABORT" {
+		t.Fatalf("Exec() returned %v, want EXECABORT", err)
+	}
+	if n.Err != err {
+		t.Errorf("INCR err = %v, want %v", n.Err, err)
+	}
+	if redis.ErrorCode(unknown.Err) != "ERR" {
+		t.Errorf("NOSUCHCOMMAND err = %v, want ERR", unknown.Err)
+	}
+	if v, _ := redis.Int(c.Do("GET", "counter")); v != 1 {
+		t.Errorf("counter = %d, want 1", v)
+	}
+}
+
+func TestWatchDo(t *testing.T) {
+	c, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	other, err := redis.DialDefaultServer()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer other.Close()
+
+	c.Do("SET", "k", 1)
+
+	attempts := 0
+	err = redis.WatchDo(c, func(c redis.Conn) error {
+		attempts++
+		n, err := redis.Int(c.Do("GET", "k"))
+		if err != nil {
+			return err
+		}
+		if attempts == 1 {
+			// Change the key after WATCH to abort the first attempt.
+			other.Do("INCR", "k")
+		}
+		tx := redis.NewTx(c)
+		tx.Do("SET", "k", n*10)
+		return tx.Exec()
+	}, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	if n, _ := redis.Int(c.Do("GET", "k")); n != 20 {
+		t.Errorf("k = %d, want 20", n)
+	}
+
+	attempts = 0
+	err = redis.WatchDoRetries(c, 3, func(c redis.Conn) error {
+		attempts++
+		other.Do("INCR", "k")
+		c.Send("MULTI")
+		c.Send("SET", "k", 0)
+		_, err := redis.Values(c.Do("EXEC"))
+		if err == redis.ErrNil {
+			return redis.ErrTxAborted
+		}
+		return err
+	}, "k")
+	if err != redis.ErrWatchRetries || attempts != 3 {
+		t.Errorf("WatchDoRetries() = %v after %d attempts, want %v after 3", err, attempts, redis.ErrWatchRetries)
+	}
+
+	// ErrNil from fn is returned without retrying.
+	c.Do("DEL", "missing")
+	attempts = 0
+	err = redis.WatchDo(c, func(c redis.Conn) error {
+		attempts++
+		_, err := redis.String(c.Do("GET", "missing"))
+		return err
+	}, "missing")
+	if err != redis.ErrNil || attempts != 1 {
+		t.Errorf("WatchDo() = %v after %d attempts, want %v after 1", err, attempts, redis.ErrNil)
+	}
+}
+
+func TestWatchDoRetriesUnwatch(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(strings.NewReader("+OK\r\n+OK\r\n+OK\r\n"), &buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = redis.WatchDoRetries(c, 2, func(c redis.Conn) error {
+		return redis.ErrTxAborted
+	}, "k")
+	if err != redis.ErrWatchRetries {
+		t.Fatalf("WatchDoRetries() = %v, want %v", err, redis.ErrWatchRetries)
+	}
+	if !strings.HasSuffix(buf.String(), "*1\r\n$7\r\nUNWATCH\r\n") {
+		t.Errorf("commands = %q, want UNWATCH last", buf.String())
+	}
+}
+
+func TestTxConstraint(t *testing.T) {
+	if slot := redis.KeySlot("foo"); slot != 12182 {
+		t.Errorf("KeySlot(foo) = %d, want 12182", slot)
+	}
+	if redis.KeySlot("{user1000}.following") != redis.KeySlot("user1000") {
+		t.Error("hash tag not used for slot")
+	}
+
+	var buf bytes.Buffer
+	c, err := redis.Dial("", "", dialTestConn(strings.NewReader(""), &buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := redis.NewTx(c)
+	tx.Do("MSET", "{a}1", "x", "{a}2", "y")
+	tx.Do("EVAL", "return 1", 2, "{a}3", "{a}4", "arg")
+	tx.Do("PING")
+	expected := []string{"{a}1", "{a}2", "{a}3", "{a}4"}
+	if keys := tx.Keys(); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Keys() = %q, want %q", keys, expected)
+	}
+	if err := tx.Validate(redis.SameSlot); err != nil {
+		t.Errorf("Validate() returned %v", err)
+	}
+
+	r := tx.Int("INCR", "b")
+	tx.Constrain(redis.SameSlot)
+	err = tx.Exec()
+	if _, ok := err.(*redis.TxConstraintError); !ok {
+		t.Fatalf("Exec() returned %v, want *TxConstraintError", err)
+	}
+	if r.Err != err {
+		t.Errorf("placeholder err = %v, want %v", r.Err, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Exec() sent %q", buf.String())
+	}
+
+	byPrefix := redis.SameShard(func(key string) string { return key[:1] })
+	if err := byPrefix([]string{"a1", "b2"}); err == nil {
+		t.Error("SameShard() returned nil for keys in different shards")
+	}
+}
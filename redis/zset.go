@@ -0,0 +1,95 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ScoredMember represents a sorted set member and its score.
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// ScoredMembers is a helper that converts a sorted set reply with scores to
+// a []ScoredMember. The helper accepts the flat array of alternating members
+// and scores returned by ZRANGE WITHSCORES, ZPOPMIN and ZPOPMAX in RESP2 and
+// the array of member-score pairs returned by the commands in RESP3.
+func ScoredMembers(reply interface{}, err error) ([]ScoredMember, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return []ScoredMember{}, nil
+	}
+	if _, ok := values[0].([]interface{}); ok {
+		result := make([]ScoredMember, len(values))
+		for i, v := range values {
+			pair, err := Values(v, nil)
+			if err != nil {
+				return nil, err
+			}
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("redigo: ScoredMembers expects member-score pair, got %d elements", len(pair))
+			}
+			if result[i], err = scoredMember(pair[0], pair[1]); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	if len(values)%2 != 0 {
+		return nil, errors.New("redigo: ScoredMembers expects even number of values result")
+	}
+	result := make([]ScoredMember, len(values)/2)
+	for i := range result {
+		if result[i], err = scoredMember(values[2*i], values[2*i+1]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// KeyScoredMember is a helper that converts the reply to the BZPOPMIN and
+// BZPOPMAX commands to the key and a ScoredMember. KeyScoredMember returns
+// ErrNil when the command times out.
+func KeyScoredMember(reply interface{}, err error) (string, ScoredMember, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return "", ScoredMember{}, err
+	}
+	if len(values) != 3 {
+		return "", ScoredMember{}, fmt.Errorf("redigo: KeyScoredMember expects three elements, got %d", len(values))
+	}
+	key, err := String(values[0], nil)
+	if err != nil {
+		return "", ScoredMember{}, err
+	}
+	m, err := scoredMember(values[1], values[2])
+	return key, m, err
+}
+
+func scoredMember(member, score interface{}) (ScoredMember, error) {
+	var m ScoredMember
+	var err error
+	if m.Member, err = String(member, nil); err != nil {
+		return m, err
+	}
+	m.Score, err = Float64(score, nil)
+	return m, err
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisbloom
+
+import (
+	"fmt"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// BFReserveOptions specifies the options for creating a Bloom filter.
+type BFReserveOptions struct {
+	// Expansion is the growth factor of the capacity when the filter is
+	// full. The server default is used if Expansion is zero.
+	Expansion int
+
+	// NonScaling specifies that the filter does not grow. Adding items to
+	// a full filter returns an error.
+	NonScaling bool
+}
+
+// BFReserve creates a Bloom filter at key with the BF.RESERVE command. The
+// filter has the given false positive rate for up to capacity items.
+func BFReserve(c redis.Conn, key string, errorRate float64, capacity int64, opts BFReserveOptions) error {
+	args := redis.Args{key, errorRate, capacity}
+	if opts.Expansion > 0 {
+		args = append(args, "EXPANSION", opts.Expansion)
+	}
+	if opts.NonScaling {
+		args = append(args, "NONSCALING")
+	}
+	_, err := c.Do("BF.RESERVE", args...)
+	return err
+}
+
+// BFAdd adds item to the Bloom filter at key with the BF.ADD command. BFAdd
+// returns false if the item may have been added before.
+func BFAdd(c redis.Conn, key string, item interface{}) (bool, error) {
+	return redis.Bool(c.Do("BF.ADD", key, item))
+}
+
+// BFMAdd adds the items to the Bloom filter at key with the BF.MADD command.
+// The element for an item that may have been added before is false.
+func BFMAdd(c redis.Conn, key string, items ...interface{}) ([]bool, error) {
+	return Bools(c.Do("BF.MADD", redis.Args{key}.Add(items...)...))
+}
+
+// BFExists returns false if item was definitely not added to the Bloom
+// filter at key using the BF.EXISTS command.
+func BFExists(c redis.Conn, key string, item interface{}) (bool, error) {
+	return redis.Bool(c.Do("BF.EXISTS", key, item))
+}
+
+// BFMExists is like BFExists for multiple items using the BF.MEXISTS
+// command.
+func BFMExists(c redis.Conn, key string, items ...interface{}) ([]bool, error) {
+	return Bools(c.Do("BF.MEXISTS", redis.Args{key}.Add(items...)...))
+}
+
+// BFInfo is the reply to the BF.INFO command.
+type BFInfo struct {
+	Capacity      int64
+	Size          int64
+	Filters       int64
+	Items         int64
+	ExpansionRate int64
+}
+
+// BFInfoReply is a helper that converts the reply to BF.INFO to a BFInfo.
+func BFInfoReply(reply interface{}, err error) (BFInfo, error) {
+	var info BFInfo
+	err = forEachInfo(reply, err, func(name string, v interface{}) (err error) {
+		switch name {
+		case "Capacity":
+			info.Capacity, err = redis.Int64(v, nil)
+		case "Size":
+			info.Size, err = redis.Int64(v, nil)
+		case "Number of filters":
+			info.Filters, err = redis.Int64(v, nil)
+		case "Number of items inserted":
+			info.Items, err = redis.Int64(v, nil)
+		case "Expansion rate":
+			// The expansion rate is nil for a non-scaling filter.
+			if v != nil {
+				info.ExpansionRate, err = redis.Int64(v, nil)
+			}
+		}
+		return err
+	})
+	return info, err
+}
+
+// Bools is a helper that converts an array of integer replies to a []bool.
+// Error elements in the array are returned as an error.
+func Bools(reply interface{}, err error) ([]bool, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]bool, len(values))
+	for i, v := range values {
+		if result[i], err = redis.Bool(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// forEachInfo calls f for each name-value pair in the reply to an INFO
+// command of the module.
+func forEachInfo(reply interface{}, err error, f func(name string, value interface{}) error) error {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return err
+	}
+	if len(values)%2 != 0 {
+		return fmt.Errorf("redigo: info expects even number of values, got %d", len(values))
+	}
+	for i := 0; i < len(values); i += 2 {
+		name, err := redis.String(values[i], nil)
+		if err != nil {
+			return err
+		}
+		if err := f(name, values[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
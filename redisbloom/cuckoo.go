@@ -0,0 +1,137 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisbloom
+
+import (
+	"github.com/garyburd/redigo/redis"
+)
+
+// CFReserveOptions specifies the options for creating a Cuckoo filter. The
+// server default is used for options with the zero value.
+type CFReserveOptions struct {
+	BucketSize    int
+	MaxIterations int
+	Expansion     int
+}
+
+// CFReserve creates a Cuckoo filter at key for capacity items with the
+// CF.RESERVE command.
+func CFReserve(c redis.Conn, key string, capacity int64, opts CFReserveOptions) error {
+	args := redis.Args{key, capacity}
+	if opts.BucketSize > 0 {
+		args = append(args, "BUCKETSIZE", opts.BucketSize)
+	}
+	if opts.MaxIterations > 0 {
+		args = append(args, "MAXITERATIONS", opts.MaxIterations)
+	}
+	if opts.Expansion > 0 {
+		args = append(args, "EXPANSION", opts.Expansion)
+	}
+	_, err := c.Do("CF.RESERVE", args...)
+	return err
+}
+
+// CFAdd adds item to the Cuckoo filter at key with the CF.ADD command. An
+// item can be added more than once.
+func CFAdd(c redis.Conn, key string, item interface{}) error {
+	_, err := c.Do("CF.ADD", key, item)
+	return err
+}
+
+// CFAddNX adds item to the Cuckoo filter at key with the CF.ADDNX command if
+// the item does not exist in the filter. CFAddNX returns false if the item
+// may exist.
+func CFAddNX(c redis.Conn, key string, item interface{}) (bool, error) {
+	return redis.Bool(c.Do("CF.ADDNX", key, item))
+}
+
+// CFInsert adds the items to the Cuckoo filter at key with the CF.INSERT
+// command, or with the CF.INSERTNX command if nx is true. With nx, the
+// element for an item that may exist is false.
+func CFInsert(c redis.Conn, key string, nx bool, items ...interface{}) ([]bool, error) {
+	cmd := "CF.INSERT"
+	if nx {
+		cmd = "CF.INSERTNX"
+	}
+	return Bools(c.Do(cmd, redis.Args{key, "ITEMS"}.Add(items...)...))
+}
+
+// CFExists returns false if item definitely does not exist in the Cuckoo
+// filter at key using the CF.EXISTS command.
+func CFExists(c redis.Conn, key string, item interface{}) (bool, error) {
+	return redis.Bool(c.Do("CF.EXISTS", key, item))
+}
+
+// CFMExists is like CFExists for multiple items using the CF.MEXISTS
+// command.
+func CFMExists(c redis.Conn, key string, items ...interface{}) ([]bool, error) {
+	return Bools(c.Do("CF.MEXISTS", redis.Args{key}.Add(items...)...))
+}
+
+// CFDel deletes one occurrence of item from the Cuckoo filter at key with the
+// CF.DEL command. CFDel returns false if the item was not found.
+func CFDel(c redis.Conn, key string, item interface{}) (bool, error) {
+	return redis.Bool(c.Do("CF.DEL", key, item))
+}
+
+// CFCount returns an estimate of the number of times item was added to the
+// Cuckoo filter at key with the CF.COUNT command.
+func CFCount(c redis.Conn, key string, item interface{}) (int64, error) {
+	return redis.Int64(c.Do("CF.COUNT", key, item))
+}
+
+// CFInfo is the reply to the CF.INFO command.
+type CFInfo struct {
+	Size          int64
+	Buckets       int64
+	Filters       int64
+	Items         int64
+	Deleted       int64
+	BucketSize    int64
+	ExpansionRate int64
+	MaxIterations int64
+}
+
+// CFInfoReply is a helper that converts the reply to CF.INFO to a CFInfo.
+func CFInfoReply(reply interface{}, err error) (CFInfo, error) {
+	var info CFInfo
+	err = forEachInfo(reply, err, func(name string, v interface{}) error {
+		var p *int64
+		switch name {
+		case "Size":
+			p = &info.Size
+		case "Number of buckets":
+			p = &info.Buckets
+		case "Number of filters":
+			p = &info.Filters
+		case "Number of items inserted":
+			p = &info.Items
+		case "Number of items deleted":
+			p = &info.Deleted
+		case "Bucket size":
+			p = &info.BucketSize
+		case "Expansion rate":
+			p = &info.ExpansionRate
+		case "Max iterations":
+			p = &info.MaxIterations
+		default:
+			return nil
+		}
+		var err error
+		*p, err = redis.Int64(v, nil)
+		return err
+	})
+	return info, err
+}
@@ -0,0 +1,22 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redisbloom provides helpers for the probabilistic data structures
+// of the RedisBloom module: Bloom filters (BF.*), Cuckoo filters (CF.*),
+// Count-Min Sketch (CMS.*) and Top-K (TOPK.*).
+//
+//  redisbloom.BFReserve(c, "seen", 0.001, 1000000, redisbloom.BFReserveOptions{})
+//  added, err := redisbloom.BFMAdd(c, "seen", "a", "b", "c")
+//  exists, err := redisbloom.BFMExists(c, "seen", "a", "z")
+package redisbloom // import "github.com/garyburd/redigo/redisbloom"
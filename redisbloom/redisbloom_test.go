@@ -0,0 +1,156 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisbloom_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisbloom"
+	"github.com/garyburd/redigo/redistest"
+)
+
+func TestBloom(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("BF.RESERVE", "bf", 0.01, 1000, "NONSCALING").Reply("OK")
+	c.Expect("BF.ADD", "bf", "a").Reply(int64(1))
+	c.Expect("BF.MADD", "bf", "a", "b").Reply([]interface{}{int64(0), int64(1)})
+	c.Expect("BF.MEXISTS", "bf", "a", "z").Reply([]interface{}{int64(1), int64(0)})
+	c.Expect("BF.MADD", "bf", "c").Reply([]interface{}{redis.Error("ERR non scaling filter is full")})
+	c.Expect("BF.INFO", "bf").Reply([]interface{}{
+		[]byte("Capacity"), int64(1000),
+		[]byte("Size"), int64(1392),
+		[]byte("Number of filters"), int64(1),
+		[]byte("Number of items inserted"), int64(2),
+		[]byte("Expansion rate"), nil,
+	})
+
+	if err := redisbloom.BFReserve(c, "bf", 0.01, 1000, redisbloom.BFReserveOptions{NonScaling: true}); err != nil {
+		t.Errorf("BFReserve() returned %v", err)
+	}
+	if added, err := redisbloom.BFAdd(c, "bf", "a"); !added || err != nil {
+		t.Errorf("BFAdd() = %v, %v, want true, nil", added, err)
+	}
+	if added, err := redisbloom.BFMAdd(c, "bf", "a", "b"); !reflect.DeepEqual(added, []bool{false, true}) || err != nil {
+		t.Errorf("BFMAdd() = %v, %v, want [false true], nil", added, err)
+	}
+	if exists, err := redisbloom.BFMExists(c, "bf", "a", "z"); !reflect.DeepEqual(exists, []bool{true, false}) || err != nil {
+		t.Errorf("BFMExists() = %v, %v, want [true false], nil", exists, err)
+	}
+	if _, err := redisbloom.BFMAdd(c, "bf", "c"); err == nil {
+		t.Error("BFMAdd() on full filter did not return error")
+	}
+	info, err := redisbloom.BFInfoReply(c.Do("BF.INFO", "bf"))
+	if expected := (redisbloom.BFInfo{Capacity: 1000, Size: 1392, Filters: 1, Items: 2}); info != expected || err != nil {
+		t.Errorf("BFInfoReply() = %+v, %v, want %+v", info, err, expected)
+	}
+}
+
+func TestCuckoo(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("CF.RESERVE", "cf", 1000, "BUCKETSIZE", 4).Reply("OK")
+	c.Expect("CF.INSERTNX", "cf", "ITEMS", "a", "b").Reply([]interface{}{int64(1), int64(0)})
+	c.Expect("CF.COUNT", "cf", "a").Reply(int64(1))
+	c.Expect("CF.DEL", "cf", "a").Reply(int64(1))
+	c.Expect("CF.INFO", "cf").Reply([]interface{}{
+		[]byte("Size"), int64(1080),
+		[]byte("Number of buckets"), int64(512),
+		[]byte("Number of filters"), int64(1),
+		[]byte("Number of items inserted"), int64(1),
+		[]byte("Number of items deleted"), int64(1),
+		[]byte("Bucket size"), int64(4),
+		[]byte("Expansion rate"), int64(1),
+		[]byte("Max iterations"), int64(20),
+	})
+
+	if err := redisbloom.CFReserve(c, "cf", 1000, redisbloom.CFReserveOptions{BucketSize: 4}); err != nil {
+		t.Errorf("CFReserve() returned %v", err)
+	}
+	if added, err := redisbloom.CFInsert(c, "cf", true, "a", "b"); !reflect.DeepEqual(added, []bool{true, false}) || err != nil {
+		t.Errorf("CFInsert() = %v, %v, want [true false], nil", added, err)
+	}
+	if n, err := redisbloom.CFCount(c, "cf", "a"); n != 1 || err != nil {
+		t.Errorf("CFCount() = %d, %v, want 1, nil", n, err)
+	}
+	if deleted, err := redisbloom.CFDel(c, "cf", "a"); !deleted || err != nil {
+		t.Errorf("CFDel() = %v, %v, want true, nil", deleted, err)
+	}
+	info, err := redisbloom.CFInfoReply(c.Do("CF.INFO", "cf"))
+	expected := redisbloom.CFInfo{Size: 1080, Buckets: 512, Filters: 1, Items: 1, Deleted: 1, BucketSize: 4, ExpansionRate: 1, MaxIterations: 20}
+	if info != expected || err != nil {
+		t.Errorf("CFInfoReply() = %+v, %v, want %+v", info, err, expected)
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("CMS.INITBYPROB", "cms", 0.001, 0.01).Reply("OK")
+	c.Expect("CMS.INCRBY", "cms", "a", 2, "b", 1).Reply([]interface{}{int64(2), int64(1)})
+	c.Expect("CMS.QUERY", "cms", "a", "z").Reply([]interface{}{int64(2), int64(0)})
+	c.Expect("CMS.MERGE", "dest", 2, "cms", "other", "WEIGHTS", 1, 2).Reply("OK")
+	c.Expect("CMS.INFO", "cms").Reply([]interface{}{[]byte("width"), int64(2000), []byte("depth"), int64(7), []byte("count"), int64(3)})
+
+	if err := redisbloom.CMSInitByProb(c, "cms", 0.001, 0.01); err != nil {
+		t.Errorf("CMSInitByProb() returned %v", err)
+	}
+	counts, err := redisbloom.CMSIncrBy(c, "cms", redisbloom.ItemIncrement{Item: "a", Increment: 2}, redisbloom.ItemIncrement{Item: "b", Increment: 1})
+	if !reflect.DeepEqual(counts, []int64{2, 1}) || err != nil {
+		t.Errorf("CMSIncrBy() = %v, %v, want [2 1], nil", counts, err)
+	}
+	if counts, err := redisbloom.CMSQuery(c, "cms", "a", "z"); !reflect.DeepEqual(counts, []int64{2, 0}) || err != nil {
+		t.Errorf("CMSQuery() = %v, %v, want [2 0], nil", counts, err)
+	}
+	if err := redisbloom.CMSMerge(c, "dest", []string{"cms", "other"}, []int64{1, 2}); err != nil {
+		t.Errorf("CMSMerge() returned %v", err)
+	}
+	info, err := redisbloom.CMSInfoReply(c.Do("CMS.INFO", "cms"))
+	if expected := (redisbloom.CMSInfo{Width: 2000, Depth: 7, Count: 3}); info != expected || err != nil {
+		t.Errorf("CMSInfoReply() = %+v, %v, want %+v", info, err, expected)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("TOPK.RESERVE", "tk", 2).Reply("OK")
+	c.Expect("TOPK.ADD", "tk", "a", "b", "c").Reply([]interface{}{nil, nil, []byte("a")})
+	c.Expect("TOPK.QUERY", "tk", "a", "c").Reply([]interface{}{int64(0), int64(1)})
+	c.Expect("TOPK.LIST", "tk", "WITHCOUNT").Reply([]interface{}{[]byte("c"), int64(3), []byte("b"), int64(1)})
+	c.Expect("TOPK.INFO", "tk").Reply([]interface{}{
+		[]byte("k"), int64(2), []byte("width"), int64(8), []byte("depth"), int64(7), []byte("decay"), "0.9",
+	})
+
+	if err := redisbloom.TopKReserve(c, "tk", 2, 0, 0, 0); err != nil {
+		t.Errorf("TopKReserve() returned %v", err)
+	}
+	if expelled, err := redisbloom.TopKAdd(c, "tk", "a", "b", "c"); !reflect.DeepEqual(expelled, []string{"", "", "a"}) || err != nil {
+		t.Errorf("TopKAdd() = %q, %v, want [\"\" \"\" a], nil", expelled, err)
+	}
+	if found, err := redisbloom.TopKQuery(c, "tk", "a", "c"); !reflect.DeepEqual(found, []bool{false, true}) || err != nil {
+		t.Errorf("TopKQuery() = %v, %v, want [false true], nil", found, err)
+	}
+	items, err := redisbloom.TopKList(c, "tk")
+	if expected := []redisbloom.TopKItem{{"c", 3}, {"b", 1}}; !reflect.DeepEqual(items, expected) || err != nil {
+		t.Errorf("TopKList() = %v, %v, want %v", items, err, expected)
+	}
+	info, err := redisbloom.TopKInfoReply(c.Do("TOPK.INFO", "tk"))
+	if expected := (redisbloom.TopKInfo{K: 2, Width: 8, Depth: 7, Decay: 0.9}); info != expected || err != nil {
+		t.Errorf("TopKInfoReply() = %+v, %v, want %+v", info, err, expected)
+	}
+}
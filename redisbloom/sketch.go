@@ -0,0 +1,203 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisbloom
+
+import (
+	"strconv"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// CMSInitByDim creates a Count-Min Sketch at key with the given width and
+// depth using the CMS.INITBYDIM command.
+func CMSInitByDim(c redis.Conn, key string, width, depth int64) error {
+	_, err := c.Do("CMS.INITBYDIM", key, width, depth)
+	return err
+}
+
+// CMSInitByProb creates a Count-Min Sketch at key for the given estimation
+// error and probability of an inflated count using the CMS.INITBYPROB
+// command.
+func CMSInitByProb(c redis.Conn, key string, errorRate, probability float64) error {
+	_, err := c.Do("CMS.INITBYPROB", key, errorRate, probability)
+	return err
+}
+
+// ItemIncrement is an item and the amount to increment its count.
+type ItemIncrement struct {
+	Item      interface{}
+	Increment int64
+}
+
+// CMSIncrBy increments the counts of items in the Count-Min Sketch at key
+// with the CMS.INCRBY command and returns the new counts.
+func CMSIncrBy(c redis.Conn, key string, items ...ItemIncrement) ([]int64, error) {
+	args := redis.Args{key}
+	for _, item := range items {
+		args = append(args, item.Item, item.Increment)
+	}
+	return redis.Int64s(c.Do("CMS.INCRBY", args...))
+}
+
+// CMSQuery returns the counts of the items in the Count-Min Sketch at key
+// with the CMS.QUERY command.
+func CMSQuery(c redis.Conn, key string, items ...interface{}) ([]int64, error) {
+	return redis.Int64s(c.Do("CMS.QUERY", redis.Args{key}.Add(items...)...))
+}
+
+// CMSMerge merges the sketches in sources into dest with the CMS.MERGE
+// command. If weights is not nil, then the counts of each source are
+// multiplied by the corresponding weight.
+func CMSMerge(c redis.Conn, dest string, sources []string, weights []int64) error {
+	args := redis.Args{dest, len(sources)}.AddFlat(sources)
+	if weights != nil {
+		args = append(args, "WEIGHTS").AddFlat(weights)
+	}
+	_, err := c.Do("CMS.MERGE", args...)
+	return err
+}
+
+// CMSInfo is the reply to the CMS.INFO command.
+type CMSInfo struct {
+	Width int64
+	Depth int64
+	Count int64
+}
+
+// CMSInfoReply is a helper that converts the reply to CMS.INFO to a CMSInfo.
+func CMSInfoReply(reply interface{}, err error) (CMSInfo, error) {
+	var info CMSInfo
+	err = forEachInfo(reply, err, func(name string, v interface{}) (err error) {
+		switch name {
+		case "width":
+			info.Width, err = redis.Int64(v, nil)
+		case "depth":
+			info.Depth, err = redis.Int64(v, nil)
+		case "count":
+			info.Count, err = redis.Int64(v, nil)
+		}
+		return err
+	})
+	return info, err
+}
+
+// TopKReserve creates a Top-K structure at key that keeps the k most
+// frequent items using the TOPK.RESERVE command. If width is zero, then the
+// server defaults are used for width, depth and decay.
+func TopKReserve(c redis.Conn, key string, k, width, depth int64, decay float64) error {
+	args := redis.Args{key, k}
+	if width > 0 {
+		args = append(args, width, depth, decay)
+	}
+	_, err := c.Do("TOPK.RESERVE", args...)
+	return err
+}
+
+// TopKAdd adds the items to the Top-K structure at key with the TOPK.ADD
+// command. The element for each item is the item that was expelled from the
+// top k by adding the item or "" if no item was expelled.
+func TopKAdd(c redis.Conn, key string, items ...interface{}) ([]string, error) {
+	return expelled(c.Do("TOPK.ADD", redis.Args{key}.Add(items...)...))
+}
+
+// TopKIncrBy increments the counts of items in the Top-K structure at key
+// with the TOPK.INCRBY command. The result is as for TopKAdd.
+func TopKIncrBy(c redis.Conn, key string, items ...ItemIncrement) ([]string, error) {
+	args := redis.Args{key}
+	for _, item := range items {
+		args = append(args, item.Item, item.Increment)
+	}
+	return expelled(c.Do("TOPK.INCRBY", args...))
+}
+
+func expelled(reply interface{}, err error) ([]string, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if result[i], err = redis.String(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// TopKQuery reports whether each of the items is in the top k of the
+// Top-K structure at key using the TOPK.QUERY command.
+func TopKQuery(c redis.Conn, key string, items ...interface{}) ([]bool, error) {
+	return Bools(c.Do("TOPK.QUERY", redis.Args{key}.Add(items...)...))
+}
+
+// TopKItem is an item in the top k and its estimated count.
+type TopKItem struct {
+	Item  string
+	Count int64
+}
+
+// TopKList returns the top k items in the Top-K structure at key with
+// their counts using the TOPK.LIST command with the WITHCOUNT option.
+func TopKList(c redis.Conn, key string) ([]TopKItem, error) {
+	values, err := redis.Values(c.Do("TOPK.LIST", key, "WITHCOUNT"))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]TopKItem, len(values)/2)
+	for i := range items {
+		if items[i].Item, err = redis.String(values[2*i], nil); err != nil {
+			return nil, err
+		}
+		if items[i].Count, err = redis.Int64(values[2*i+1], nil); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// TopKInfo is the reply to the TOPK.INFO command.
+type TopKInfo struct {
+	K     int64
+	Width int64
+	Depth int64
+	Decay float64
+}
+
+// TopKInfoReply is a helper that converts the reply to TOPK.INFO to a
+// TopKInfo.
+func TopKInfoReply(reply interface{}, err error) (TopKInfo, error) {
+	var info TopKInfo
+	err = forEachInfo(reply, err, func(name string, v interface{}) (err error) {
+		switch name {
+		case "k":
+			info.K, err = redis.Int64(v, nil)
+		case "width":
+			info.Width, err = redis.Int64(v, nil)
+		case "depth":
+			info.Depth, err = redis.Int64(v, nil)
+		case "decay":
+			// The decay is a simple string in RESP2.
+			var s string
+			if s, err = redis.String(v, nil); err == nil {
+				info.Decay, err = strconv.ParseFloat(s, 64)
+			}
+		}
+		return err
+	})
+	return info, err
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisearch
+
+import (
+	"fmt"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Reducer is a reduce function in a GROUPBY step of an aggregation.
+type Reducer struct {
+	// Function is the name of the reduce function, such as COUNT, SUM or
+	// AVG.
+	Function string
+
+	// Args are the arguments to the function.
+	Args []interface{}
+
+	// As is the optional name of the result.
+	As string
+}
+
+// AggregateQuery is an aggregation for the FT.AGGREGATE command. The steps of
+// the pipeline are executed in the order that the methods are called. The
+// methods return the aggregation to allow chaining.
+type AggregateQuery struct {
+	query   string
+	load    []string
+	steps   redis.Args
+	params  []interface{}
+	dialect int
+}
+
+// NewAggregateQuery returns an aggregation of the documents matching the
+// query string q.
+func NewAggregateQuery(q string) *AggregateQuery {
+	return &AggregateQuery{query: q}
+}
+
+// Load loads document fields that are not sortable.
+func (a *AggregateQuery) Load(fields ...string) *AggregateQuery {
+	a.load = append(a.load, fields...)
+	return a
+}
+
+// GroupBy adds a step that groups the results by properties and applies
+// the reducers to each group.
+func (a *AggregateQuery) GroupBy(properties []string, reducers ...Reducer) *AggregateQuery {
+	a.steps = append(a.steps, "GROUPBY", len(properties)).AddFlat(properties)
+	for _, r := range reducers {
+		a.steps = append(a.steps, "REDUCE", r.Function, len(r.Args)).Add(r.Args...)
+		if r.As != "" {
+			a.steps = append(a.steps, "AS", r.As)
+		}
+	}
+	return a
+}
+
+// Apply adds a step that computes expression and stores the result as the
+// property as.
+func (a *AggregateQuery) Apply(expression, as string) *AggregateQuery {
+	a.steps = append(a.steps, "APPLY", expression, "AS", as)
+	return a
+}
+
+// Filter adds a step that removes results not matching expression.
+func (a *AggregateQuery) Filter(expression string) *AggregateQuery {
+	a.steps = append(a.steps, "FILTER", expression)
+	return a
+}
+
+// SortBy adds a step that sorts the results by property.
+func (a *AggregateQuery) SortBy(property string, ascending bool) *AggregateQuery {
+	order := "DESC"
+	if ascending {
+		order = "ASC"
+	}
+	a.steps = append(a.steps, "SORTBY", 2, property, order)
+	return a
+}
+
+// Limit adds a step that keeps num results starting at offset.
+func (a *AggregateQuery) Limit(offset, num int) *AggregateQuery {
+	a.steps = append(a.steps, "LIMIT", offset, num)
+	return a
+}
+
+// Param sets the value of a parameter referenced in the query string as
+// $name.
+func (a *AggregateQuery) Param(name string, value interface{}) *AggregateQuery {
+	a.params = append(a.params, name, value)
+	return a
+}
+
+// Dialect specifies the query dialect.
+func (a *AggregateQuery) Dialect(version int) *AggregateQuery {
+	a.dialect = version
+	return a
+}
+
+// Args returns the arguments to FT.AGGREGATE for the aggregation on index.
+func (a *AggregateQuery) Args(index string) redis.Args {
+	args := redis.Args{index, a.query}
+	if len(a.load) > 0 {
+		args = append(args, "LOAD", len(a.load)).AddFlat(a.load)
+	}
+	args = append(args, a.steps...)
+	if len(a.params) > 0 {
+		args = append(args, "PARAMS", len(a.params)).Add(a.params...)
+	}
+	if a.dialect != 0 {
+		args = append(args, "DIALECT", a.dialect)
+	}
+	return args
+}
+
+// AggregateResult is the result of an aggregation.
+type AggregateResult struct {
+	// Total is the number of results before the LIMIT step.
+	Total int64
+
+	// Rows holds the properties of each result.
+	Rows []map[string]string
+}
+
+// Aggregate executes the aggregation on index with the FT.AGGREGATE command.
+func Aggregate(c redis.Conn, index string, a *AggregateQuery) (*AggregateResult, error) {
+	return AggregateReply(c.Do("FT.AGGREGATE", a.Args(index)...))
+}
+
+// AggregateReply is a helper that converts the reply to FT.AGGREGATE to an
+// AggregateResult.
+func AggregateReply(reply interface{}, err error) (*AggregateResult, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("redigo: FT.AGGREGATE reply is empty")
+	}
+	result := &AggregateResult{}
+	if result.Total, err = redis.Int64(values[0], nil); err != nil {
+		return nil, err
+	}
+	result.Rows = make([]map[string]string, len(values)-1)
+	for i, v := range values[1:] {
+		if result.Rows[i], err = redis.StringMap(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
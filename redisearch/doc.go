@@ -0,0 +1,47 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redisearch provides helpers for the commands of the RediSearch
+// module.
+//
+// Create an index with CreateIndex:
+//
+//  err := redisearch.CreateIndex(c, "products", redisearch.IndexDefinition{
+//      Prefixes: []string{"product:"},
+//      Fields: []redisearch.Field{
+//          {Name: "title", Type: redisearch.TextField, Weight: 2},
+//          {Name: "brand", Type: redisearch.TagField},
+//          {Name: "price", Type: redisearch.NumericField, Sortable: true},
+//      },
+//  })
+//
+// Build queries with NewQuery and execute the query with Search:
+//
+//  q := redisearch.NewQuery("@title:phone").
+//      Filter("price", 100, 500).
+//      SortBy("price", true).
+//      Limit(0, 20).
+//      WithScores()
+//  result, err := redisearch.Search(c, "products", q)
+//
+// Build aggregations with NewAggregateQuery and execute the aggregation
+// with Aggregate:
+//
+//  a := redisearch.NewAggregateQuery("*").
+//      GroupBy([]string{"@brand"}, redisearch.Reducer{Function: "COUNT", As: "n"}).
+//      SortBy("@n", false)
+//  result, err := redisearch.Aggregate(c, "products", a)
+//
+// The reply helpers parse RESP2 replies.
+package redisearch // import "github.com/garyburd/redigo/redisearch"
@@ -0,0 +1,150 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisearch
+
+import (
+	"github.com/garyburd/redigo/redis"
+)
+
+// FieldType is the type of an indexed field.
+type FieldType string
+
+// Field types.
+const (
+	TextField    FieldType = "TEXT"
+	TagField     FieldType = "TAG"
+	NumericField FieldType = "NUMERIC"
+	GeoField     FieldType = "GEO"
+)
+
+// Field is a field in the schema of an index.
+type Field struct {
+	// Name is the name of the hash field or the JSON path of the value.
+	Name string
+
+	// As is an optional alias for the field in queries.
+	As string
+
+	Type FieldType
+
+	// Sortable specifies that the field can be used with SortBy.
+	Sortable bool
+
+	// NoIndex specifies that the field is not indexed. A field that is not
+	// indexed can be sortable.
+	NoIndex bool
+
+	// Weight is the importance of a TEXT field in scoring. The server
+	// default is used if Weight is zero.
+	Weight float64
+
+	// Separator is the separator of values in a TAG field. The server
+	// default is used if Separator is empty.
+	Separator string
+
+	// CaseSensitive specifies that a TAG field is case sensitive.
+	CaseSensitive bool
+}
+
+func (f Field) args(args redis.Args) redis.Args {
+	args = append(args, f.Name)
+	if f.As != "" {
+		args = append(args, "AS", f.As)
+	}
+	args = append(args, string(f.Type))
+	if f.Weight != 0 {
+		args = append(args, "WEIGHT", f.Weight)
+	}
+	if f.Separator != "" {
+		args = append(args, "SEPARATOR", f.Separator)
+	}
+	if f.CaseSensitive {
+		args = append(args, "CASESENSITIVE")
+	}
+	if f.Sortable {
+		args = append(args, "SORTABLE")
+	}
+	if f.NoIndex {
+		args = append(args, "NOINDEX")
+	}
+	return args
+}
+
+// IndexDefinition defines an index for FT.CREATE.
+type IndexDefinition struct {
+	// OnJSON specifies that the index covers JSON documents. The index
+	// covers hashes by default.
+	OnJSON bool
+
+	// Prefixes is the list of key prefixes of the indexed documents. All
+	// keys are indexed if Prefixes is empty.
+	Prefixes []string
+
+	// Filter is an optional expression that selects the indexed documents.
+	Filter string
+
+	// Language is the default language of the documents.
+	Language string
+
+	// StopWords is the list of stop words. The server default is used if
+	// StopWords is nil. An empty non-nil slice disables stop words.
+	StopWords []string
+
+	Fields []Field
+}
+
+// Args returns the arguments to FT.CREATE for the index.
+func (d IndexDefinition) Args(index string) redis.Args {
+	args := redis.Args{index, "ON"}
+	if d.OnJSON {
+		args = append(args, "JSON")
+	} else {
+		args = append(args, "HASH")
+	}
+	if len(d.Prefixes) > 0 {
+		args = append(args, "PREFIX", len(d.Prefixes)).AddFlat(d.Prefixes)
+	}
+	if d.Filter != "" {
+		args = append(args, "FILTER", d.Filter)
+	}
+	if d.Language != "" {
+		args = append(args, "LANGUAGE", d.Language)
+	}
+	if d.StopWords != nil {
+		args = append(args, "STOPWORDS", len(d.StopWords)).AddFlat(d.StopWords)
+	}
+	args = append(args, "SCHEMA")
+	for _, f := range d.Fields {
+		args = f.args(args)
+	}
+	return args
+}
+
+// CreateIndex creates an index with the FT.CREATE command.
+func CreateIndex(c redis.Conn, index string, d IndexDefinition) error {
+	_, err := c.Do("FT.CREATE", d.Args(index)...)
+	return err
+}
+
+// DropIndex deletes an index with the FT.DROPINDEX command. If deleteDocs
+// is true, then the indexed documents are also deleted.
+func DropIndex(c redis.Conn, index string, deleteDocs bool) error {
+	args := redis.Args{index}
+	if deleteDocs {
+		args = append(args, "DD")
+	}
+	_, err := c.Do("FT.DROPINDEX", args...)
+	return err
+}
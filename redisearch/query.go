@@ -0,0 +1,272 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisearch
+
+import (
+	"fmt"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+type numericFilter struct {
+	field    string
+	min, max interface{}
+}
+
+// Query is a query for the FT.SEARCH command. The methods return the query
+// to allow chaining.
+type Query struct {
+	query      string
+	noContent  bool
+	withScores bool
+	verbatim   bool
+	filters    []numericFilter
+	inKeys     []string
+	inFields   []string
+	returns    []string
+	highlight  *highlight
+	sortBy     string
+	ascending  bool
+	offset     int
+	num        int
+	limit      bool
+	params     []interface{}
+	dialect    int
+}
+
+type highlight struct {
+	fields      []string
+	open, close string
+}
+
+// NewQuery returns a query for the query string q.
+func NewQuery(q string) *Query {
+	return &Query{query: q}
+}
+
+// NoContent specifies that only the IDs of the documents are returned.
+func (q *Query) NoContent() *Query {
+	q.noContent = true
+	return q
+}
+
+// WithScores specifies that the relevance score of each document is
+// returned.
+func (q *Query) WithScores() *Query {
+	q.withScores = true
+	return q
+}
+
+// Verbatim specifies that the query terms are not expanded with stemming.
+func (q *Query) Verbatim() *Query {
+	q.verbatim = true
+	return q
+}
+
+// Filter limits the results to documents with a value of the numeric field
+// between min and max inclusive. Use "-inf" and "+inf" for open ranges.
+func (q *Query) Filter(field string, min, max interface{}) *Query {
+	q.filters = append(q.filters, numericFilter{field, min, max})
+	return q
+}
+
+// InKeys limits the results to the given document IDs.
+func (q *Query) InKeys(keys ...string) *Query {
+	q.inKeys = append(q.inKeys, keys...)
+	return q
+}
+
+// InFields limits the search to the given fields.
+func (q *Query) InFields(fields ...string) *Query {
+	q.inFields = append(q.inFields, fields...)
+	return q
+}
+
+// Return limits the fields returned for each document.
+func (q *Query) Return(fields ...string) *Query {
+	q.returns = append(q.returns, fields...)
+	return q
+}
+
+// Highlight specifies that the matched terms in fields are enclosed in the
+// open and close tags. All fields are highlighted if fields is empty. The
+// server default tags are used if open and close are empty.
+func (q *Query) Highlight(fields []string, open, close string) *Query {
+	q.highlight = &highlight{fields, open, close}
+	return q
+}
+
+// SortBy sorts the results by field.
+func (q *Query) SortBy(field string, ascending bool) *Query {
+	q.sortBy = field
+	q.ascending = ascending
+	return q
+}
+
+// Limit returns num results starting at offset. The server returns the
+// first ten results by default.
+func (q *Query) Limit(offset, num int) *Query {
+	q.offset = offset
+	q.num = num
+	q.limit = true
+	return q
+}
+
+// Param sets the value of a parameter referenced in the query string as
+// $name.
+func (q *Query) Param(name string, value interface{}) *Query {
+	q.params = append(q.params, name, value)
+	return q
+}
+
+// Dialect specifies the query dialect.
+func (q *Query) Dialect(version int) *Query {
+	q.dialect = version
+	return q
+}
+
+// Args returns the arguments to FT.SEARCH for the query on index.
+func (q *Query) Args(index string) redis.Args {
+	args := redis.Args{index, q.query}
+	if q.noContent {
+		args = append(args, "NOCONTENT")
+	}
+	if q.verbatim {
+		args = append(args, "VERBATIM")
+	}
+	if q.withScores {
+		args = append(args, "WITHSCORES")
+	}
+	for _, f := range q.filters {
+		args = append(args, "FILTER", f.field, f.min, f.max)
+	}
+	if len(q.inKeys) > 0 {
+		args = append(args, "INKEYS", len(q.inKeys)).AddFlat(q.inKeys)
+	}
+	if len(q.inFields) > 0 {
+		args = append(args, "INFIELDS", len(q.inFields)).AddFlat(q.inFields)
+	}
+	if len(q.returns) > 0 {
+		args = append(args, "RETURN", len(q.returns)).AddFlat(q.returns)
+	}
+	if h := q.highlight; h != nil {
+		args = append(args, "HIGHLIGHT")
+		if len(h.fields) > 0 {
+			args = append(args, "FIELDS", len(h.fields)).AddFlat(h.fields)
+		}
+		if h.open != "" || h.close != "" {
+			args = append(args, "TAGS", h.open, h.close)
+		}
+	}
+	if q.sortBy != "" {
+		args = append(args, "SORTBY", q.sortBy)
+		if q.ascending {
+			args = append(args, "ASC")
+		} else {
+			args = append(args, "DESC")
+		}
+	}
+	if q.limit {
+		args = append(args, "LIMIT", q.offset, q.num)
+	}
+	if len(q.params) > 0 {
+		args = append(args, "PARAMS", len(q.params)).Add(q.params...)
+	}
+	if q.dialect != 0 {
+		args = append(args, "DIALECT", q.dialect)
+	}
+	return args
+}
+
+// Document is a document in the result of a search.
+type Document struct {
+	ID string
+
+	// Score is the relevance score. Score is set only when the query
+	// specifies WithScores.
+	Score float64
+
+	// Fields holds the returned fields of the document. Highlighted terms
+	// are enclosed in the highlight tags. Fields is nil when the query
+	// specifies NoContent.
+	Fields map[string]string
+}
+
+// SearchResult is the result of a search.
+type SearchResult struct {
+	// Total is the number of matching documents. Total can be greater than
+	// the number of returned documents.
+	Total int64
+
+	Documents []Document
+}
+
+// Search executes the query on index with the FT.SEARCH command.
+func Search(c redis.Conn, index string, q *Query) (*SearchResult, error) {
+	return q.ParseReply(c.Do("FT.SEARCH", q.Args(index)...))
+}
+
+// ParseReply converts the reply to FT.SEARCH for the query to a
+// SearchResult. The layout of the reply depends on the NoContent and
+// WithScores options of the query. Use ParseReply with pipelined commands:
+//
+//	c.Send("FT.SEARCH", q.Args("products")...)
+//	...
+//	result, err := q.ParseReply(c.Receive())
+func (q *Query) ParseReply(reply interface{}, err error) (*SearchResult, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("redigo: FT.SEARCH reply is empty")
+	}
+	result := &SearchResult{}
+	if result.Total, err = redis.Int64(values[0], nil); err != nil {
+		return nil, err
+	}
+	values = values[1:]
+	for len(values) > 0 {
+		var doc Document
+		if doc.ID, err = redis.String(values[0], nil); err != nil {
+			return nil, err
+		}
+		values = values[1:]
+		if q.withScores {
+			if len(values) == 0 {
+				return nil, fmt.Errorf("redigo: FT.SEARCH reply is missing score for %s", doc.ID)
+			}
+			if doc.Score, err = redis.Float64(values[0], nil); err != nil {
+				return nil, err
+			}
+			values = values[1:]
+		}
+		if !q.noContent {
+			if len(values) == 0 {
+				return nil, fmt.Errorf("redigo: FT.SEARCH reply is missing fields for %s", doc.ID)
+			}
+			// The fields are nil for a document that expired after it
+			// was matched.
+			if values[0] != nil {
+				if doc.Fields, err = redis.StringMap(values[0], nil); err != nil {
+					return nil, err
+				}
+			}
+			values = values[1:]
+		}
+		result.Documents = append(result.Documents, doc)
+	}
+	return result, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisearch_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/garyburd/redigo/redisearch"
+	"github.com/garyburd/redigo/redistest"
+)
+
+func TestCreateIndex(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("FT.CREATE", "idx", "ON", "HASH", "PREFIX", 1, "p:", "STOPWORDS", 0, "SCHEMA",
+		"title", "TEXT", "WEIGHT", 2, "SORTABLE",
+		"tags", "AS", "tag", "TAG", "SEPARATOR", ";",
+		"price", "NUMERIC", "SORTABLE", "NOINDEX").Reply("OK")
+	c.Expect("FT.DROPINDEX", "idx", "DD").Reply("OK")
+
+	err := redisearch.CreateIndex(c, "idx", redisearch.IndexDefinition{
+		Prefixes:  []string{"p:"},
+		StopWords: []string{},
+		Fields: []redisearch.Field{
+			{Name: "title", Type: redisearch.TextField, Weight: 2, Sortable: true},
+			{Name: "tags", As: "tag", Type: redisearch.TagField, Separator: ";"},
+			{Name: "price", Type: redisearch.NumericField, Sortable: true, NoIndex: true},
+		},
+	})
+	if err != nil {
+		t.Errorf("CreateIndex() returned %v", err)
+	}
+	if err := redisearch.DropIndex(c, "idx", true); err != nil {
+		t.Errorf("DropIndex() returned %v", err)
+	}
+}
+
+func TestQueryArgs(t *testing.T) {
+	q := redisearch.NewQuery("@title:$term").
+		WithScores().
+		Filter("price", 10, "+inf").
+		InKeys("p:1", "p:2").
+		Return("title").
+		Highlight([]string{"title"}, "<b>", "</b>").
+		SortBy("price", true).
+		Limit(0, 5).
+		Param("term", "phone").
+		Dialect(2)
+	expected := "[idx @title:$term WITHSCORES FILTER price 10 +inf INKEYS 2 p:1 p:2 RETURN 1 title HIGHLIGHT FIELDS 1 title TAGS <b> </b> SORTBY price ASC LIMIT 0 5 PARAMS 2 term phone DIALECT 2]"
+	if actual := fmt.Sprint(q.Args("idx")); actual != expected {
+		t.Errorf("Args() =\n%s\nwant\n%s", actual, expected)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("FT.SEARCH", "idx", "phone", "WITHSCORES").Reply([]interface{}{
+		int64(12),
+		[]byte("p:1"), []byte("1.5"), []interface{}{[]byte("title"), []byte("<b>phone</b> case")},
+		[]byte("p:2"), []byte("0.5"), nil,
+	})
+	c.Expect("FT.SEARCH", "idx", "phone", "NOCONTENT").Reply([]interface{}{
+		int64(2), []byte("p:1"), []byte("p:2"),
+	})
+
+	result, err := redisearch.Search(c, "idx", redisearch.NewQuery("phone").WithScores())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &redisearch.SearchResult{
+		Total: 12,
+		Documents: []redisearch.Document{
+			{ID: "p:1", Score: 1.5, Fields: map[string]string{"title": "<b>phone</b> case"}},
+			{ID: "p:2", Score: 0.5},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Search() = %+v, want %+v", result, expected)
+	}
+
+	result, err = redisearch.Search(c, "idx", redisearch.NewQuery("phone").NoContent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = &redisearch.SearchResult{Total: 2, Documents: []redisearch.Document{{ID: "p:1"}, {ID: "p:2"}}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Search(NoContent) = %+v, want %+v", result, expected)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("FT.AGGREGATE", "idx", "*", "LOAD", 1, "@brand",
+		"GROUPBY", 1, "@brand", "REDUCE", "COUNT", 0, "AS", "n",
+		"APPLY", "@n*2", "AS", "n2", "FILTER", "@n>1", "SORTBY", 2, "@n", "DESC", "LIMIT", 0, 10).Reply([]interface{}{
+		int64(2),
+		[]interface{}{[]byte("brand"), []byte("acme"), []byte("n"), []byte("3"), []byte("n2"), []byte("6")},
+		[]interface{}{[]byte("brand"), []byte("zeta"), []byte("n"), []byte("2"), []byte("n2"), []byte("4")},
+	})
+
+	a := redisearch.NewAggregateQuery("*").
+		Load("@brand").
+		GroupBy([]string{"@brand"}, redisearch.Reducer{Function: "COUNT", As: "n"}).
+		Apply("@n*2", "n2").
+		Filter("@n>1").
+		SortBy("@n", false).
+		Limit(0, 10)
+	result, err := redisearch.Aggregate(c, "idx", a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &redisearch.AggregateResult{
+		Total: 2,
+		Rows: []map[string]string{
+			{"brand": "acme", "n": "3", "n2": "6"},
+			{"brand": "zeta", "n": "2", "n2": "4"},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Aggregate() = %+v, want %+v", result, expected)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redisjson provides helpers for the commands of the RedisJSON
+// module.
+//
+// Values are encoded to JSON with the encoding/json package. Paths use the
+// JSONPath syntax of the module. A path that starts with "$" can match
+// multiple values and the module returns the results for all matches. The
+// helpers return the results for such paths as slices with one element per
+// match. The legacy path syntax, such as "." or ".name", matches a single
+// value.
+//
+//  type User struct {
+//      Name   string   `json:"name"`
+//      Visits int      `json:"visits"`
+//      Tags   []string `json:"tags"`
+//  }
+//
+//  redisjson.Set(c, "user:1", "$", User{Name: "gary"})
+//  redisjson.NumIncrBy(c, "user:1", "$.visits", 1)
+//  redisjson.ArrAppend(c, "user:1", "$.tags", "admin")
+//
+//  var u User
+//  err := redisjson.Get(c, "user:1", ".", &u)
+package redisjson // import "github.com/garyburd/redigo/redisjson"
@@ -0,0 +1,243 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// SetOption specifies an option for Set.
+type SetOption struct {
+	arg string
+}
+
+var (
+	// SetIfNotExists specifies that the value is set only if the path does
+	// not exist.
+	SetIfNotExists = SetOption{"NX"}
+
+	// SetIfExists specifies that the value is set only if the path exists.
+	SetIfExists = SetOption{"XX"}
+)
+
+// Set sets the value at path in key to the JSON encoding of v with the
+// JSON.SET command. Set returns false if the value was not set because of
+// a SetIfNotExists or SetIfExists option.
+func Set(c redis.Conn, key, path string, v interface{}, opts ...SetOption) (bool, error) {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+	args := redis.Args{key, path, p}
+	for _, opt := range opts {
+		args = append(args, opt.arg)
+	}
+	_, err = redis.String(c.Do("JSON.SET", args...))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Get decodes the value at path in key to the value pointed to by v with
+// the JSON.GET command. For a path that starts with "$", the value is a
+// JSON array of the matches. Get returns redis.ErrNil if the key does not
+// exist.
+func Get(c redis.Conn, key, path string, v interface{}) error {
+	p, err := redis.Bytes(c.Do("JSON.GET", key, path))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(p, v)
+}
+
+// GetPaths returns the values at the paths in key with the JSON.GET
+// command. The result is keyed by path. GetPaths returns redis.ErrNil if
+// the key does not exist.
+func GetPaths(c redis.Conn, key string, paths ...string) (map[string]json.RawMessage, error) {
+	if len(paths) == 1 {
+		// The server returns the value without the enclosing object for
+		// a single path.
+		p, err := redis.Bytes(c.Do("JSON.GET", key, paths[0]))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]json.RawMessage{paths[0]: p}, nil
+	}
+	p, err := redis.Bytes(c.Do("JSON.GET", redis.Args{key}.AddFlat(paths)...))
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(p, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MGet returns the value at path in each of the keys with the JSON.MGET
+// command. The element for a missing key is nil.
+func MGet(c redis.Conn, path string, keys ...string) ([]json.RawMessage, error) {
+	values, err := redis.ByteSlices(c.Do("JSON.MGET", redis.Args{}.AddFlat(keys).Add(path)...))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]json.RawMessage, len(values))
+	for i, v := range values {
+		if v != nil {
+			result[i] = v
+		}
+	}
+	return result, nil
+}
+
+// Del deletes the values at path in key with the JSON.DEL command and
+// returns the number of values deleted.
+func Del(c redis.Conn, key, path string) (int, error) {
+	return redis.Int(c.Do("JSON.DEL", key, path))
+}
+
+// Type returns the JSON types of the values at path in key with the
+// JSON.TYPE command.
+func Type(c redis.Conn, key, path string) ([]string, error) {
+	reply, err := c.Do("JSON.TYPE", key, path)
+	if err != nil {
+		return nil, err
+	}
+	switch reply := reply.(type) {
+	case []interface{}:
+		// RESP3 wraps the result in an additional array.
+		if len(reply) == 1 {
+			if inner, ok := reply[0].([]interface{}); ok {
+				reply = inner
+			}
+		}
+		return redis.Strings(reply, nil)
+	case nil:
+		return nil, redis.ErrNil
+	}
+	s, err := redis.String(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+// NumIncrBy increments the numbers at path in key by n with the
+// JSON.NUMINCRBY command and returns the new values. The element for a
+// matched value that is not a number is NaN.
+func NumIncrBy(c redis.Conn, key, path string, n float64) ([]float64, error) {
+	return numberResults(c.Do("JSON.NUMINCRBY", key, path, n))
+}
+
+// NumMultBy multiplies the numbers at path in key by n with the
+// JSON.NUMMULTBY command and returns the new values. The element for a
+// matched value that is not a number is NaN.
+func NumMultBy(c redis.Conn, key, path string, n float64) ([]float64, error) {
+	return numberResults(c.Do("JSON.NUMMULTBY", key, path, n))
+}
+
+// ArrAppend appends the JSON encodings of values to the arrays at path in
+// key with the JSON.ARRAPPEND command and returns the new lengths of the
+// arrays. The element for a matched value that is not an array is -1.
+func ArrAppend(c redis.Conn, key, path string, values ...interface{}) ([]int64, error) {
+	args := redis.Args{key, path}
+	for _, v := range values {
+		p, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, p)
+	}
+	return lengthResults(c.Do("JSON.ARRAPPEND", args...))
+}
+
+// ArrLen returns the lengths of the arrays at path in key with the
+// JSON.ARRLEN command. The element for a matched value that is not an array
+// is -1.
+func ArrLen(c redis.Conn, key, path string) ([]int64, error) {
+	return lengthResults(c.Do("JSON.ARRLEN", key, path))
+}
+
+// StrLen returns the lengths of the strings at path in key with the
+// JSON.STRLEN command. The element for a matched value that is not a string
+// is -1.
+func StrLen(c redis.Conn, key, path string) ([]int64, error) {
+	return lengthResults(c.Do("JSON.STRLEN", key, path))
+}
+
+// lengthResults converts an integer reply or an array reply of integers and
+// nils to a slice. Nil elements are converted to -1.
+func lengthResults(reply interface{}, err error) ([]int64, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch reply := reply.(type) {
+	case int64:
+		return []int64{reply}, nil
+	case []interface{}:
+		result := make([]int64, len(reply))
+		for i, v := range reply {
+			switch v := v.(type) {
+			case int64:
+				result[i] = v
+			case nil:
+				result[i] = -1
+			default:
+				return nil, fmt.Errorf("redigo: unexpected element type for lengths, got type %T", v)
+			}
+		}
+		return result, nil
+	case nil:
+		return nil, redis.ErrNil
+	case redis.Error:
+		return nil, reply
+	}
+	return nil, fmt.Errorf("redigo: unexpected type for lengths, got type %T", reply)
+}
+
+// numberResults converts the JSON encoded reply to JSON.NUMINCRBY and
+// JSON.NUMMULTBY to a slice.
+func numberResults(reply interface{}, err error) ([]float64, error) {
+	p, err := redis.Bytes(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	var values []*float64
+	if len(p) > 0 && p[0] == '[' {
+		if err := json.Unmarshal(p, &values); err != nil {
+			return nil, err
+		}
+	} else {
+		var f float64
+		if err := json.Unmarshal(p, &f); err != nil {
+			return nil, err
+		}
+		values = []*float64{&f}
+	}
+	result := make([]float64, len(values))
+	for i, v := range values {
+		if v == nil {
+			result[i] = math.NaN()
+		} else {
+			result[i] = *v
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisjson_test
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisjson"
+	"github.com/garyburd/redigo/redistest"
+)
+
+type user struct {
+	Name   string   `json:"name"`
+	Visits int      `json:"visits"`
+	Tags   []string `json:"tags"`
+}
+
+func TestJSON(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("JSON.SET", "u", "$", `{"name":"gary","visits":1,"tags":null}`).Reply("OK")
+	c.Expect("JSON.SET", "u", "$", `{"name":"x","visits":0,"tags":null}`, "NX").Reply(nil)
+	c.Expect("JSON.GET", "u", ".").Reply([]byte(`{"name":"gary","visits":1,"tags":null}`))
+	c.Expect("JSON.GET", "missing", ".").Reply(nil)
+	c.Expect("JSON.GET", "u", "$.name", "$.visits").Reply([]byte(`{"$.name":["gary"],"$.visits":[1]}`))
+	c.Expect("JSON.MGET", "u", "missing", "$.name").Reply([]interface{}{[]byte(`["gary"]`), nil})
+	c.Expect("JSON.NUMINCRBY", "u", "$..visits", 2).Reply([]byte(`[3,null]`))
+	c.Expect("JSON.NUMINCRBY", "u", ".visits", 2).Reply([]byte(`5`))
+	c.Expect("JSON.ARRAPPEND", "u", "$.tags", `"a"`, `{"b":1}`).Reply([]interface{}{nil})
+	c.Expect("JSON.ARRLEN", "u", ".tags").Reply(int64(2))
+	c.Expect("JSON.TYPE", "u", "$.name").Reply([]interface{}{[]byte("string")})
+	c.Expect("JSON.DEL", "u", "$.tags").Reply(int64(1))
+
+	if ok, err := redisjson.Set(c, "u", "$", user{Name: "gary", Visits: 1}); !ok || err != nil {
+		t.Errorf("Set() = %v, %v, want true, nil", ok, err)
+	}
+	if ok, err := redisjson.Set(c, "u", "$", user{Name: "x"}, redisjson.SetIfNotExists); ok || err != nil {
+		t.Errorf("Set(NX) = %v, %v, want false, nil", ok, err)
+	}
+
+	var u user
+	if err := redisjson.Get(c, "u", ".", &u); err != nil || !reflect.DeepEqual(u, user{Name: "gary", Visits: 1}) {
+		t.Errorf("Get() = %+v, %v", u, err)
+	}
+	if err := redisjson.Get(c, "missing", ".", &u); err != redis.ErrNil {
+		t.Errorf("Get(missing) returned %v, want ErrNil", err)
+	}
+
+	paths, err := redisjson.GetPaths(c, "u", "$.name", "$.visits")
+	if expected := map[string]json.RawMessage{"$.name": json.RawMessage(`["gary"]`), "$.visits": json.RawMessage(`[1]`)}; err != nil || !reflect.DeepEqual(paths, expected) {
+		t.Errorf("GetPaths() = %s, %v, want %s", paths, err, expected)
+	}
+
+	values, err := redisjson.MGet(c, "$.name", "u", "missing")
+	if expected := []json.RawMessage{json.RawMessage(`["gary"]`), nil}; err != nil || !reflect.DeepEqual(values, expected) {
+		t.Errorf("MGet() = %s, %v, want %s", values, err, expected)
+	}
+
+	numbers, err := redisjson.NumIncrBy(c, "u", "$..visits", 2)
+	if err != nil || len(numbers) != 2 || numbers[0] != 3 || !math.IsNaN(numbers[1]) {
+		t.Errorf("NumIncrBy($..visits) = %v, %v, want [3 NaN]", numbers, err)
+	}
+	numbers, err = redisjson.NumIncrBy(c, "u", ".visits", 2)
+	if err != nil || !reflect.DeepEqual(numbers, []float64{5}) {
+		t.Errorf("NumIncrBy(.visits) = %v, %v, want [5]", numbers, err)
+	}
+
+	lengths, err := redisjson.ArrAppend(c, "u", "$.tags", "a", map[string]int{"b": 1})
+	if err != nil || !reflect.DeepEqual(lengths, []int64{-1}) {
+		t.Errorf("ArrAppend() = %v, %v, want [-1]", lengths, err)
+	}
+	lengths, err = redisjson.ArrLen(c, "u", ".tags")
+	if err != nil || !reflect.DeepEqual(lengths, []int64{2}) {
+		t.Errorf("ArrLen() = %v, %v, want [2]", lengths, err)
+	}
+
+	types, err := redisjson.Type(c, "u", "$.name")
+	if err != nil || !reflect.DeepEqual(types, []string{"string"}) {
+		t.Errorf("Type() = %v, %v, want [string]", types, err)
+	}
+	if n, err := redisjson.Del(c, "u", "$.tags"); n != 1 || err != nil {
+		t.Errorf("Del() = %d, %v, want 1, nil", n, err)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redisotel instruments Redigo connections with OpenTelemetry style
+// tracing and metrics.
+//
+// To avoid a dependency on the OpenTelemetry modules, the package defines the
+// small Tracer, Span and Meter interfaces. The application adapts the
+// OpenTelemetry API to these interfaces:
+//
+//  type tracer struct{ t trace.Tracer }
+//
+//  func (t tracer) Start(ctx context.Context, name string) (context.Context, redisotel.Span) {
+//      ctx, span := t.t.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//      return ctx, spanAdapter{span}
+//  }
+//
+//  type spanAdapter struct{ trace.Span }
+//
+//  func (s spanAdapter) SetAttributes(attrs ...redisotel.Attribute) {
+//      for _, a := range attrs {
+//          s.Span.SetAttributes(attribute.String(a.Key, fmt.Sprint(a.Value)))
+//      }
+//  }
+//
+//  func (s spanAdapter) RecordError(err error) { s.Span.RecordError(err) }
+//  func (s spanAdapter) End()                  { s.Span.End() }
+//
+// Attach the hook to connections with the redis.DialHook option:
+//
+//  hook := &redisotel.Hook{Tracer: tracer{otel.Tracer("redigo")}}
+//  pool := &redis.Pool{
+//      Dial: func() (redis.Conn, error) {
+//          return redis.Dial("tcp", addr, redis.DialHook(hook))
+//      },
+//  }
+package redisotel // import "github.com/garyburd/redigo/redisotel"
@@ -0,0 +1,119 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Attribute is a key-value pair attached to a span or measurement.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a client span with the given name.
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Meter records measurements.
+type Meter interface {
+	RecordInt64(ctx context.Context, name string, value int64, attrs ...Attribute)
+}
+
+// Hook is a redis.Hook that creates a span for each command. The span name
+// is the command name. The span has the attributes db.system,
+// db.operation and db.statement.
+type Hook struct {
+	// Tracer starts the spans. Tracer must not be nil.
+	Tracer Tracer
+
+	// Attributes are added to every span. Use this field to set attributes
+	// such as net.peer.name and db.redis.database_index.
+	Attributes []Attribute
+
+	// Redaction specifies the policy for keys in the db.statement
+	// attribute. Argument values other than keys are never included in the
+	// statement. If nil, then redis.DefaultRedactionPolicy is used.
+	Redaction *redis.RedactionPolicy
+
+	// Filter specifies an optional function for selecting the commands to
+	// trace. If Filter returns false, then no span is created for the
+	// command.
+	Filter func(commandName string) bool
+}
+
+type spanKey struct{}
+
+// BeforeCommand implements the redis.Hook interface.
+func (h *Hook) BeforeCommand(ctx context.Context, commandName string, args []interface{}) context.Context {
+	if h.Filter != nil && !h.Filter(commandName) {
+		return ctx
+	}
+	ctx, span := h.Tracer.Start(ctx, commandName)
+	attrs := []Attribute{
+		{Key: "db.system", Value: "redis"},
+		{Key: "db.operation", Value: commandName},
+		{Key: "db.statement", Value: h.Redaction.Statement(commandName, args)},
+	}
+	span.SetAttributes(append(attrs, h.Attributes...)...)
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// AfterCommand implements the redis.Hook interface.
+func (h *Hook) AfterCommand(ctx context.Context, commandName string, d time.Duration, reply interface{}, err error) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+	if err != nil && err != redis.ErrNil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+var _ redis.Hook = (*Hook)(nil)
+
+// RecordPoolStats records the statistics for pool p to meter m. The metric
+// db.client.connections.usage is recorded with the state attribute set to
+// "used" and "idle". The metric db.client.connections.max is recorded if
+// the pool has a limit on the number of connections. The pool.name
+// attribute is set to name.
+//
+// Call RecordPoolStats from an observable gauge callback or periodically
+// from a goroutine.
+func RecordPoolStats(ctx context.Context, m Meter, name string, p *redis.Pool) {
+	stats := p.Stats()
+	pool := Attribute{Key: "pool.name", Value: name}
+	m.RecordInt64(ctx, "db.client.connections.usage", int64(stats.ActiveCount-stats.IdleCount), pool, Attribute{Key: "state", Value: "used"})
+	m.RecordInt64(ctx, "db.client.connections.usage", int64(stats.IdleCount), pool, Attribute{Key: "state", Value: "idle"})
+	if p.MaxActive > 0 {
+		m.RecordInt64(ctx, "db.client.connections.max", int64(p.MaxActive), pool)
+	}
+}
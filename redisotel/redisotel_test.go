@@ -0,0 +1,123 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisotel_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisotel"
+)
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttributes(attrs ...redisotel.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) { s.err = err }
+func (s *testSpan) End()                  { s.ended = true }
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, redisotel.Span) {
+	s := &testSpan{name: name, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestHook(t *testing.T) {
+	var tracer testTracer
+	hook := &redisotel.Hook{
+		Tracer:     &tracer,
+		Attributes: []redisotel.Attribute{{Key: "net.peer.name", Value: "localhost"}},
+	}
+	c, err := redis.Dial("tcp", ":6379", redis.DialDatabase(9), redis.DialHook(hook))
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	c.Do("GET", "redisotel")
+	c.Do("NOSUCHCOMMAND")
+	hook.Redaction = &redis.RedactionPolicy{Keys: redis.KeysHidden}
+	c.Do("MGET", "a", "b")
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(tracer.spans))
+	}
+	for i, expected := range []string{"GET redisotel", "NOSUCHCOMMAND", "MGET ? ?"} {
+		s := tracer.spans[i]
+		if stmt := s.attrs["db.statement"]; stmt != expected {
+			t.Errorf("span %d: db.statement = %q, want %q", i, stmt, expected)
+		}
+		if s.attrs["db.system"] != "redis" || s.attrs["net.peer.name"] != "localhost" {
+			t.Errorf("span %d: attributes = %v", i, s.attrs)
+		}
+		if !s.ended {
+			t.Errorf("span %d not ended", i)
+		}
+	}
+	if tracer.spans[1].err == nil {
+		t.Error("error not recorded for unknown command")
+	}
+}
+
+type testMeter map[string]int64
+
+func (m testMeter) RecordInt64(ctx context.Context, name string, value int64, attrs ...redisotel.Attribute) {
+	m[fmt.Sprintf("%s %v", name, attrs)] = value
+}
+
+func TestRecordPoolStats(t *testing.T) {
+	p := &redis.Pool{
+		Dial:      func() (redis.Conn, error) { return redis.Dial("tcp", ":6379", redis.DialDatabase(9)) },
+		MaxIdle:   2,
+		MaxActive: 5,
+	}
+	defer p.Close()
+
+	c1, c2 := p.Get(), p.Get()
+	c1.Do("PING")
+	c2.Do("PING")
+	c1.Close()
+
+	m := testMeter{}
+	redisotel.RecordPoolStats(context.Background(), m, "test", p)
+	c2.Close()
+
+	expected := testMeter{
+		"db.client.connections.usage [{pool.name test} {state used}]": 1,
+		"db.client.connections.usage [{pool.name test} {state idle}]": 1,
+		"db.client.connections.max [{pool.name test}]":                5,
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("measurements = %v, want %v", m, expected)
+	}
+}
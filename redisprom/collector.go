@@ -0,0 +1,232 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisprom
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultBuckets are the default histogram bucket upper bounds in seconds.
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+// Collector records command latencies and errors and reports pool
+// statistics. The zero value is ready to use. A Collector is a redis.Hook
+// and a prometheus.Collector.
+type Collector struct {
+	// Namespace is the prefix for metric names. If zero, then "redigo" is
+	// used. Namespace must not be modified after the collector is
+	// registered.
+	Namespace string
+
+	// Buckets are the upper bounds of the latency histogram buckets in
+	// seconds. If nil, then DefaultBuckets is used. Buckets must not be
+	// modified after the first command is recorded.
+	Buckets []float64
+
+	mu       sync.Mutex
+	commands map[string]*histogram
+	errors   map[errorKey]uint64
+	pools    []namedPool
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative; last element is +Inf
+	sum    float64
+	count  uint64
+}
+
+type errorKey struct {
+	command, class, code string
+}
+
+type namedPool struct {
+	name string
+	p    *redis.Pool
+}
+
+var (
+	_ redis.Hook           = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// AddPool adds a pool to the collector. The name is the value of the pool
+// label.
+func (c *Collector) AddPool(name string, p *redis.Pool) {
+	c.mu.Lock()
+	c.pools = append(c.pools, namedPool{name, p})
+	c.mu.Unlock()
+}
+
+// BeforeCommand implements the redis.Hook interface.
+func (c *Collector) BeforeCommand(ctx context.Context, commandName string, args []interface{}) context.Context {
+	return ctx
+}
+
+// AfterCommand implements the redis.Hook interface.
+func (c *Collector) AfterCommand(ctx context.Context, commandName string, d time.Duration, reply interface{}, err error) {
+	commandName = strings.ToLower(commandName)
+	buckets := c.buckets()
+	seconds := d.Seconds()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.commands == nil {
+		c.commands = make(map[string]*histogram)
+		c.errors = make(map[errorKey]uint64)
+	}
+	h := c.commands[commandName]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(buckets)+1)}
+		c.commands[commandName] = h
+	}
+	i := sort.SearchFloat64s(buckets, seconds)
+	h.counts[i]++
+	h.sum += seconds
+	h.count++
+	if err != nil && err != redis.ErrNil {
+		c.errors[classifyError(commandName, err)]++
+	}
+}
+
+func classifyError(commandName string, err error) errorKey {
+	if code := redis.ErrorCode(err); code != "" {
+		return errorKey{commandName, "server", code}
+	}
+	if _, ok := err.(redis.Error); ok {
+		return errorKey{commandName, "server", ""}
+	}
+	if err, ok := err.(net.Error); ok && err.Timeout() {
+		return errorKey{commandName, "timeout", ""}
+	}
+	return errorKey{commandName, "connection", ""}
+}
+
+func (c *Collector) buckets() []float64 {
+	if c.Buckets != nil {
+		return c.Buckets
+	}
+	return DefaultBuckets
+}
+
+func (c *Collector) namespace() string {
+	if c.Namespace != "" {
+		return c.Namespace
+	}
+	return "redigo"
+}
+
+// descs holds the descriptions of the metrics exported by a Collector.
+type descs struct {
+	commandDuration    *prometheus.Desc
+	commandErrors      *prometheus.Desc
+	poolConnections    *prometheus.Desc
+	poolMaxConnections *prometheus.Desc
+	poolSaturation     *prometheus.Desc
+	poolErrors         *prometheus.Desc
+}
+
+func (c *Collector) descs() *descs {
+	ns := c.namespace()
+	return &descs{
+		commandDuration: prometheus.NewDesc(ns+"_command_duration_seconds",
+			"Duration of commands.", []string{"command"}, nil),
+		commandErrors: prometheus.NewDesc(ns+"_command_errors_total",
+			"Number of command errors.", []string{"command", "class", "code"}, nil),
+		poolConnections: prometheus.NewDesc(ns+"_pool_connections",
+			"Number of connections in the pool.", []string{"pool", "state"}, nil),
+		poolMaxConnections: prometheus.NewDesc(ns+"_pool_max_connections",
+			"Maximum number of connections in the pool.", []string{"pool"}, nil),
+		poolSaturation: prometheus.NewDesc(ns+"_pool_saturation_ratio",
+			"Ratio of connections in use to the maximum. Zero for pools with no maximum.", []string{"pool"}, nil),
+		poolErrors: prometheus.NewDesc(ns+"_pool_errors_total",
+			"Number of errors on pool connections. Requires Pool.CountErrors.", []string{"pool", "class", "code"}, nil),
+	}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	d := c.descs()
+	ch <- d.commandDuration
+	ch <- d.commandErrors
+	ch <- d.poolConnections
+	ch <- d.poolMaxConnections
+	ch <- d.poolSaturation
+	ch <- d.poolErrors
+}
+
+// Collect implements the prometheus.Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	d := c.descs()
+	buckets := c.buckets()
+
+	c.mu.Lock()
+	for command, h := range c.commands {
+		cumulative := make(map[float64]uint64, len(buckets))
+		var n uint64
+		for i, bound := range buckets {
+			n += h.counts[i]
+			cumulative[bound] = n
+		}
+		ch <- prometheus.MustNewConstHistogram(d.commandDuration, h.count, h.sum, cumulative, command)
+	}
+	for k, n := range c.errors {
+		ch <- prometheus.MustNewConstMetric(d.commandErrors, prometheus.CounterValue, float64(n), k.command, k.class, k.code)
+	}
+	pools := c.pools
+	c.mu.Unlock()
+
+	for _, p := range pools {
+		stats := p.p.Stats()
+		used := stats.ActiveCount - stats.IdleCount
+		ch <- prometheus.MustNewConstMetric(d.poolConnections, prometheus.GaugeValue, float64(used), p.name, "used")
+		ch <- prometheus.MustNewConstMetric(d.poolConnections, prometheus.GaugeValue, float64(stats.IdleCount), p.name, "idle")
+		ch <- prometheus.MustNewConstMetric(d.poolMaxConnections, prometheus.GaugeValue, float64(p.p.MaxActive), p.name)
+		var ratio float64
+		if p.p.MaxActive > 0 {
+			ratio = float64(used) / float64(p.p.MaxActive)
+		}
+		ch <- prometheus.MustNewConstMetric(d.poolSaturation, prometheus.GaugeValue, ratio, p.name)
+
+		if !p.p.CountErrors {
+			continue
+		}
+		errs := stats.Errors
+		for _, e := range []struct {
+			class string
+			n     int64
+		}{
+			{"dial", errs.Dial},
+			{"read_timeout", errs.ReadTimeout},
+			{"write_timeout", errs.WriteTimeout},
+			{"protocol", errs.Protocol},
+			{"connection", errs.Connection},
+		} {
+			ch <- prometheus.MustNewConstMetric(d.poolErrors, prometheus.CounterValue, float64(e.n), p.name, e.class, "")
+		}
+		for code, n := range errs.Server {
+			ch <- prometheus.MustNewConstMetric(d.poolErrors, prometheus.CounterValue, float64(n), p.name, "server", code)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisprom_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisprom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	c := &redisprom.Collector{Buckets: []float64{1, 2}}
+	ctx := context.Background()
+	c.AfterCommand(ctx, "GET", 500*time.Millisecond, nil, redis.ErrNil)
+	c.AfterCommand(ctx, "GET", 1500*time.Millisecond, nil, nil)
+	c.AfterCommand(ctx, "GET", 4*time.Second, nil, errors.New("broken pipe"))
+	c.AfterCommand(ctx, "INCR", 250*time.Millisecond, nil, redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value"))
+
+	p := &redis.Pool{Dial: func() (redis.Conn, error) { return nil, errors.New("no dial") }, MaxActive: 4, CountErrors: true}
+	p.Get().Close()
+	c.AddPool("default", p)
+
+	const expected = `
+# HELP redigo_command_duration_seconds Duration of commands.
+# TYPE redigo_command_duration_seconds histogram
+redigo_command_duration_seconds_bucket{command="get",le="1"} 1
+redigo_command_duration_seconds_bucket{command="get",le="2"} 2
+redigo_command_duration_seconds_bucket{command="get",le="+Inf"} 3
+redigo_command_duration_seconds_sum{command="get"} 6
+redigo_command_duration_seconds_count{command="get"} 3
+redigo_command_duration_seconds_bucket{command="incr",le="1"} 1
+redigo_command_duration_seconds_bucket{command="incr",le="2"} 1
+redigo_command_duration_seconds_bucket{command="incr",le="+Inf"} 1
+redigo_command_duration_seconds_sum{command="incr"} 0.25
+redigo_command_duration_seconds_count{command="incr"} 1
+# HELP redigo_command_errors_total Number of command errors.
+# TYPE redigo_command_errors_total counter
+redigo_command_errors_total{class="connection",code="",command="get"} 1
+redigo_command_errors_total{class="server",code="WRONGTYPE",command="incr"} 1
+# HELP redigo_pool_connections Number of connections in the pool.
+# TYPE redigo_pool_connections gauge
+redigo_pool_connections{pool="default",state="idle"} 0
+redigo_pool_connections{pool="default",state="used"} 0
+# HELP redigo_pool_max_connections Maximum number of connections in the pool.
+# TYPE redigo_pool_max_connections gauge
+redigo_pool_max_connections{pool="default"} 4
+# HELP redigo_pool_saturation_ratio Ratio of connections in use to the maximum. Zero for pools with no maximum.
+# TYPE redigo_pool_saturation_ratio gauge
+redigo_pool_saturation_ratio{pool="default"} 0
+# HELP redigo_pool_errors_total Number of errors on pool connections. Requires Pool.CountErrors.
+# TYPE redigo_pool_errors_total counter
+redigo_pool_errors_total{class="connection",code="",pool="default"} 0
+redigo_pool_errors_total{class="dial",code="",pool="default"} 1
+redigo_pool_errors_total{class="protocol",code="",pool="default"} 0
+redigo_pool_errors_total{class="read_timeout",code="",pool="default"} 0
+redigo_pool_errors_total{class="write_timeout",code="",pool="default"} 0
+`
+	if err := testutil.GatherAndCompare(registry(t, c), strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectorNoPoolErrors(t *testing.T) {
+	c := &redisprom.Collector{}
+	c.AddPool("default", &redis.Pool{Dial: func() (redis.Conn, error) { return nil, errors.New("no dial") }})
+	if n, err := testutil.GatherAndCount(registry(t, c), "redigo_pool_errors_total"); n != 0 || err != nil {
+		t.Errorf("pool errors metrics = %d, %v, want 0, nil", n, err)
+	}
+}
+
+func registry(t *testing.T, c prometheus.Collector) *prometheus.Registry {
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatal(err)
+	}
+	return reg
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redisprom exports Redigo command and pool metrics to Prometheus.
+//
+// A Collector is a prometheus.Collector. Register the collector with a
+// Prometheus registry and serve the registry for scraping:
+//
+//  collector := &redisprom.Collector{}
+//  pool := &redis.Pool{
+//      Dial: func() (redis.Conn, error) {
+//          return redis.Dial("tcp", addr, redis.DialHook(collector))
+//      },
+//  }
+//  collector.AddPool("default", pool)
+//  prometheus.MustRegister(collector)
+//  http.Handle("/metrics", promhttp.Handler())
+//
+// The exported metrics are:
+//
+//  redigo_command_duration_seconds   histogram  command
+//  redigo_command_errors_total       counter    command, class, code
+//  redigo_pool_connections           gauge      pool, state
+//  redigo_pool_max_connections       gauge      pool
+//  redigo_pool_saturation_ratio      gauge      pool
+//  redigo_pool_errors_total          counter    pool, class, code
+//
+// The command error class is one of "server", "timeout" or "connection". The
+// code label is the error code for server errors and is empty otherwise. The
+// pool error counts are exported for pools with the CountErrors field set.
+package redisprom // import "github.com/garyburd/redigo/redisprom"
@@ -0,0 +1,114 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest
+
+import (
+	"sync"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Command is a command recorded by a Capture.
+type Command struct {
+	Name string
+	Args []interface{}
+}
+
+func (c Command) String() string {
+	return formatCommand(c.Name, c.Args)
+}
+
+// Capture is a connection that records the commands executed on an
+// underlying connection. Use Capture to assert the side effects of code
+// without mocking every reply.
+type Capture struct {
+	redis.Conn
+
+	mu       sync.Mutex
+	commands []Command
+}
+
+// NewCapture returns a connection that records the commands executed on c.
+func NewCapture(c redis.Conn) *Capture {
+	return &Capture{Conn: c}
+}
+
+func (c *Capture) add(commandName string, args []interface{}) {
+	c.mu.Lock()
+	c.commands = append(c.commands, Command{Name: commandName, Args: args})
+	c.mu.Unlock()
+}
+
+// Do implements the redis.Conn interface.
+func (c *Capture) Do(commandName string, args ...interface{}) (interface{}, error) {
+	if commandName != "" {
+		c.add(commandName, args)
+	}
+	return c.Conn.Do(commandName, args...)
+}
+
+// Send implements the redis.Conn interface.
+func (c *Capture) Send(commandName string, args ...interface{}) error {
+	c.add(commandName, args)
+	return c.Conn.Send(commandName, args...)
+}
+
+// Commands returns the recorded commands in the order executed.
+func (c *Capture) Commands() []Command {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Command(nil), c.commands...)
+}
+
+// Reset discards the recorded commands.
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	c.commands = nil
+	c.mu.Unlock()
+}
+
+// Count returns the number of recorded commands that match the command
+// name and arguments. The arguments can include Matchers.
+func (c *Capture) Count(commandName string, args ...interface{}) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, cmd := range c.commands {
+		if matchCommand(commandName, args, cmd.Name, cmd.Args) {
+			n++
+		}
+	}
+	return n
+}
+
+// AssertCalled reports an error to t if no recorded command matches the
+// command name and arguments.
+func (c *Capture) AssertCalled(t TB, commandName string, args ...interface{}) bool {
+	if c.Count(commandName, args...) == 0 {
+		t.Errorf("redistest: %s not called; commands are %v", formatCommand(commandName, args), c.Commands())
+		return false
+	}
+	return true
+}
+
+// AssertNotCalled reports an error to t if a recorded command matches the
+// command name and arguments.
+func (c *Capture) AssertNotCalled(t TB, commandName string, args ...interface{}) bool {
+	if c.Count(commandName, args...) != 0 {
+		t.Errorf("redistest: %s called", formatCommand(commandName, args))
+		return false
+	}
+	return true
+}
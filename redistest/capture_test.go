@@ -0,0 +1,54 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest_test
+
+import (
+	"testing"
+
+	"github.com/garyburd/redigo/redistest"
+)
+
+func TestCapture(t *testing.T) {
+	mock := redistest.NewConn(t)
+	mock.Expect("SET", "k", redistest.Any()).Reply("OK").Times(2)
+	mock.Expect("EXPIRE", "k", 10).Reply(int64(1))
+	defer mock.Verify()
+
+	c := redistest.NewCapture(mock)
+	c.Do("SET", "k", "a")
+	c.Send("SET", "k", "b")
+	c.Send("EXPIRE", "k", 10)
+	c.Do("")
+
+	if n := len(c.Commands()); n != 3 {
+		t.Errorf("got %d commands, want 3", n)
+	}
+	if s := c.Commands()[2].String(); s != `EXPIRE "k" "10"` {
+		t.Errorf("Commands()[2] = %s", s)
+	}
+	if n := c.Count("SET", "k", redistest.Any()); n != 2 {
+		t.Errorf("Count(SET) = %d, want 2", n)
+	}
+	c.AssertCalled(t, "expire", "k", "10")
+	c.AssertNotCalled(t, "DEL", "k")
+
+	var tb recordingTB
+	c.AssertCalled(&tb, "DEL", "k")
+	c.Reset()
+	c.AssertNotCalled(&tb, "SET", "k", "a")
+	if len(tb.errors) != 1 {
+		t.Errorf("errors = %q, want one error", tb.errors)
+	}
+}
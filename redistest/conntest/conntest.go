@@ -0,0 +1,177 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+// Package conntest tests implementations of the redis.Conn interface.
+//
+// Use the package to check that a connection wrapper or a reimplementation
+// of the interface follows the contract of the connections returned from
+// redis.Dial:
+//
+//  func TestMyConn(t *testing.T) {
+//      conntest.Run(t, func() (redis.Conn, error) {
+//          c, err := redis.Dial("tcp", ":6379")
+//          if err != nil {
+//              return nil, err
+//          }
+//          return mypackage.Wrap(c), nil
+//      })
+//  }
+//
+// The tests execute the ECHO, PING and an unknown command. The tests do not
+// modify the database.
+package conntest // import "github.com/garyburd/redigo/redistest/conntest"
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Run runs the conformance tests as subtests of t. Each subtest calls
+// factory to get a new connection to a server.
+func Run(t *testing.T, factory func() (redis.Conn, error)) {
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			c, err := factory()
+			if err != nil {
+				t.Fatalf("factory returned error %v", err)
+			}
+			defer c.Close()
+			test.f(t, c)
+		})
+	}
+}
+
+var tests = []struct {
+	name string
+	f    func(t *testing.T, c redis.Conn)
+}{
+	{"Do", testDo},
+	{"SendFlushReceive", testSendFlushReceive},
+	{"DoAfterSend", testDoAfterSend},
+	{"DoEmpty", testDoEmpty},
+	{"ServerError", testServerError},
+	{"Close", testClose},
+}
+
+func testDo(t *testing.T, c redis.Conn) {
+	reply, err := c.Do("ECHO", "hello")
+	if err != nil {
+		t.Fatalf("Do(ECHO) returned error %v", err)
+	}
+	if !reflect.DeepEqual(reply, []byte("hello")) {
+		t.Errorf("Do(ECHO) = %#v, want %#v", reply, []byte("hello"))
+	}
+	if err := c.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
+
+func testSendFlushReceive(t *testing.T, c redis.Conn) {
+	args := []string{"a", "b", "c"}
+	for _, arg := range args {
+		if err := c.Send("ECHO", arg); err != nil {
+			t.Fatalf("Send(ECHO, %s) returned error %v", arg, err)
+		}
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush() returned error %v", err)
+	}
+	for _, arg := range args {
+		reply, err := redis.String(c.Receive())
+		if err != nil {
+			t.Fatalf("Receive() returned error %v", err)
+		}
+		if reply != arg {
+			t.Errorf("Receive() = %q, want %q", reply, arg)
+		}
+	}
+}
+
+func testDoAfterSend(t *testing.T, c redis.Conn) {
+	if err := c.Send("ECHO", "a"); err != nil {
+		t.Fatalf("Send(ECHO) returned error %v", err)
+	}
+	// Do flushes the sent command and discards its reply.
+	reply, err := redis.String(c.Do("ECHO", "b"))
+	if err != nil {
+		t.Fatalf("Do(ECHO) returned error %v", err)
+	}
+	if reply != "b" {
+		t.Errorf("Do(ECHO) = %q, want %q", reply, "b")
+	}
+	if reply, err := redis.String(c.Do("PING")); reply != "PONG" || err != nil {
+		t.Errorf("Do(PING) = %q, %v, want PONG, nil", reply, err)
+	}
+}
+
+func testDoEmpty(t *testing.T, c redis.Conn) {
+	reply, err := c.Do("")
+	if reply != nil || err != nil {
+		t.Errorf("Do(\"\") with no pending commands = %#v, %v, want nil, nil", reply, err)
+	}
+	c.Send("ECHO", "a")
+	c.Send("NOSUCHCOMMAND")
+	c.Send("ECHO", "b")
+	replies, err := redis.Values(c.Do(""))
+	if err != nil {
+		t.Fatalf("Do(\"\") returned error %v", err)
+	}
+	if len(replies) != 3 {
+		t.Fatalf("Do(\"\") returned %d replies, want 3", len(replies))
+	}
+	if !reflect.DeepEqual(replies[0], []byte("a")) || !reflect.DeepEqual(replies[2], []byte("b")) {
+		t.Errorf("Do(\"\") = %#v, want replies a and b", replies)
+	}
+	if _, ok := replies[1].(redis.Error); !ok {
+		t.Errorf("Do(\"\") reply for unknown command is %#v, want redis.Error", replies[1])
+	}
+}
+
+func testServerError(t *testing.T, c redis.Conn) {
+	reply, err := c.Do("NOSUCHCOMMAND")
+	if _, ok := err.(redis.Error); !ok {
+		t.Fatalf("Do(NOSUCHCOMMAND) = %#v, %v, want redis.Error", reply, err)
+	}
+	// Error replies do not break the connection.
+	if err := c.Err(); err != nil {
+		t.Errorf("Err() = %v after error reply, want nil", err)
+	}
+	c.Send("NOSUCHCOMMAND")
+	c.Flush()
+	if _, err := c.Receive(); err == nil {
+		t.Error("Receive() for unknown command returned nil error")
+	}
+	if reply, err := redis.String(c.Do("ECHO", "ok")); reply != "ok" || err != nil {
+		t.Errorf("Do(ECHO) after error reply = %q, %v", reply, err)
+	}
+}
+
+func testClose(t *testing.T, c redis.Conn) {
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() returned error %v", err)
+	}
+	if c.Err() == nil {
+		t.Error("Err() returned nil after Close")
+	}
+	// A second call to Close may return an error, but must not panic.
+	c.Close()
+	if _, err := c.Do("PING"); err == nil {
+		t.Error("Do(PING) after Close returned nil error")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package conntest_test
+
+import (
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
+	"github.com/garyburd/redigo/redistest/conntest"
+)
+
+func TestConn(t *testing.T) {
+	s, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conntest.Run(t, func() (redis.Conn, error) {
+		return redis.Dial("tcp", s.Addr())
+	})
+}
+
+func TestPooledConn(t *testing.T) {
+	s, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	p := &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return redis.Dial("tcp", s.Addr()) },
+		MaxIdle: 1,
+	}
+	defer p.Close()
+	conntest.Run(t, func() (redis.Conn, error) {
+		return p.Get(), nil
+	})
+}
+
+func TestHookConn(t *testing.T) {
+	s, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var l redis.LatencyRecorder
+	conntest.Run(t, func() (redis.Conn, error) {
+		return redis.Dial("tcp", s.Addr(), redis.DialHook(&l))
+	})
+}
@@ -0,0 +1,43 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redistest
+
+import (
+	"context"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var _ redis.ConnWithContext = (*Conn)(nil)
+
+// DoContext implements the redis.ConnWithContext interface. DoContext
+// returns the context's error without matching the command if the context
+// is done.
+func (c *Conn) DoContext(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Do(commandName, args...)
+}
+
+// ReceiveContext implements the redis.ConnWithContext interface.
+func (c *Conn) ReceiveContext(ctx context.Context) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Receive()
+}
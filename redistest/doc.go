@@ -0,0 +1,39 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redistest provides support for testing code that uses Redigo.
+//
+// The Conn type is a mock connection for unit tests. The test registers the
+// commands that the code under test is expected to execute along with the
+// replies for those commands:
+//
+//  func TestIncrement(t *testing.T) {
+//      c := redistest.NewConn(t)
+//      defer c.Verify()
+//      c.Expect("INCR", "counter").Reply(int64(1))
+//      c.Expect("EXPIRE", "counter", redistest.Any()).Reply(int64(1))
+//
+//      if err := increment(c); err != nil {
+//          t.Fatal(err)
+//      }
+//  }
+//
+// The Recorder type records the commands and replies on a real connection.
+// The ReplayConn type replays a recording so that tests can run without a
+// server after the recording is made.
+//
+// The Server type is a minimal in-process server that supports string, hash,
+// expiry and pub/sub commands. Use the server to test code that dials
+// connections, such as code using a Pool or PubSubConn.
+package redistest // import "github.com/garyburd/redigo/redistest"
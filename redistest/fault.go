@@ -0,0 +1,237 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Fault specifies a fault injected by a FaultInjector.
+type Fault struct {
+	// Commands restricts the fault to the named commands. The names are
+	// not case sensitive. If empty, the fault applies to all commands.
+	Commands []string
+
+	// Probability is the probability that the fault is injected for a
+	// matching command. If zero, the fault is always injected.
+	Probability float64
+
+	// Latency is a delay added before the command is executed.
+	Latency time.Duration
+
+	// Drop specifies that the connection is closed instead of executing
+	// the command.
+	Drop bool
+
+	// PartialWrite specifies that half of the command is written to the
+	// network before the network connection is closed. PartialWrite faults
+	// are injected by network connections created with NetDial.
+	PartialWrite bool
+
+	// Reply is an error returned instead of executing the command. Use a
+	// redis.Error value for an error reply from the server.
+	Reply error
+}
+
+// ErrInjectedDrop is returned for commands on a connection closed by a Drop
+// fault.
+var ErrInjectedDrop = errors.New("redistest: injected connection drop")
+
+// FaultInjector injects faults into connections. Use the Conn method to
+// inject latency, dropped connections and error replies. Use NetDial with
+// the redis.DialNetDial option to inject partial writes.
+type FaultInjector struct {
+	Faults []Fault
+
+	// Rand specifies an optional source of random numbers in [0, 1) for
+	// fault probabilities. If nil, then math/rand.Float64 is used.
+	Rand func() float64
+
+	mu sync.Mutex
+}
+
+// fault returns the fault to inject for a command, if any. Only faults for
+// which f returns true are considered.
+func (fi *FaultInjector) fault(commandName string, f func(*Fault) bool) *Fault {
+	for i := range fi.Faults {
+		fault := &fi.Faults[i]
+		if !f(fault) || !fault.matches(commandName) {
+			continue
+		}
+		if fault.Probability == 0 || fi.random() < fault.Probability {
+			return fault
+		}
+	}
+	return nil
+}
+
+func (fi *FaultInjector) random() float64 {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	if fi.Rand != nil {
+		return fi.Rand()
+	}
+	return rand.Float64()
+}
+
+func (f *Fault) matches(commandName string) bool {
+	if len(f.Commands) == 0 {
+		return true
+	}
+	for _, name := range f.Commands {
+		if strings.EqualFold(name, commandName) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCommandFault(f *Fault) bool { return !f.PartialWrite }
+func isWriteFault(f *Fault) bool   { return f.PartialWrite }
+
+// Conn returns a connection that injects faults into commands executed on
+// c.
+func (fi *FaultInjector) Conn(c redis.Conn) redis.Conn {
+	return &faultConn{Conn: c, fi: fi}
+}
+
+type faultConn struct {
+	redis.Conn
+	fi *FaultInjector
+
+	// pending is the injected replies for sent commands. A nil element is
+	// a command sent to the server.
+	pending []*Fault
+}
+
+// inject applies the fault for a command. If the command should not be
+// executed, inject returns the fault and a non-nil error.
+func (c *faultConn) inject(commandName string) (*Fault, error) {
+	f := c.fi.fault(commandName, isCommandFault)
+	if f == nil {
+		return nil, nil
+	}
+	if f.Latency > 0 {
+		time.Sleep(f.Latency)
+	}
+	switch {
+	case f.Drop:
+		c.Conn.Close()
+		return f, ErrInjectedDrop
+	case f.Reply != nil:
+		return f, f.Reply
+	}
+	return nil, nil
+}
+
+func (c *faultConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	pending := c.pending
+	c.pending = nil
+	if commandName != "" {
+		if _, err := c.inject(commandName); err != nil {
+			return nil, err
+		}
+		return c.Conn.Do(commandName, args...)
+	}
+
+	// Merge the injected replies with the replies from the server.
+	reply, err := c.Conn.Do("")
+	if err != nil || len(pending) == 0 {
+		return reply, err
+	}
+	replies, _ := reply.([]interface{})
+	result := make([]interface{}, 0, len(pending))
+	for _, f := range pending {
+		if f != nil {
+			result = append(result, f.Reply)
+		} else if len(replies) > 0 {
+			result = append(result, replies[0])
+			replies = replies[1:]
+		}
+	}
+	return result, nil
+}
+
+func (c *faultConn) Send(commandName string, args ...interface{}) error {
+	f, err := c.inject(commandName)
+	if f != nil && f.Drop {
+		return err
+	}
+	if f == nil {
+		if err := c.Conn.Send(commandName, args...); err != nil {
+			return err
+		}
+	}
+	c.pending = append(c.pending, f)
+	return nil
+}
+
+func (c *faultConn) Receive() (interface{}, error) {
+	if len(c.pending) > 0 {
+		f := c.pending[0]
+		c.pending = c.pending[1:]
+		if f != nil {
+			return nil, f.Reply
+		}
+	}
+	return c.Conn.Receive()
+}
+
+// NetDial dials a network connection that injects PartialWrite faults. Use
+// NetDial with the redis.DialNetDial option. The command name is read from
+// the start of each write, so a fault restricted to a command is injected
+// only when the command is the first in a pipeline.
+func (fi *FaultInjector) NetDial(network, address string) (net.Conn, error) {
+	c, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &faultNetConn{Conn: c, fi: fi}, nil
+}
+
+type faultNetConn struct {
+	net.Conn
+	fi *FaultInjector
+}
+
+func (c *faultNetConn) Write(p []byte) (int, error) {
+	if c.fi.fault(firstCommandName(p), isWriteFault) != nil {
+		n, _ := c.Conn.Write(p[:len(p)/2])
+		c.Conn.Close()
+		return n, ErrInjectedDrop
+	}
+	return c.Conn.Write(p)
+}
+
+// firstCommandName returns the name of the first command in p, an encoded
+// array of bulk strings.
+func firstCommandName(p []byte) string {
+	lines := strings.SplitN(string(p), "\r\n", 4)
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "*") || !strings.HasPrefix(lines[1], "$") {
+		return ""
+	}
+	if n, err := strconv.Atoi(lines[1][1:]); err != nil || n != len(lines[2]) {
+		return ""
+	}
+	return lines[2]
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
+)
+
+func TestFaultInjector(t *testing.T) {
+	s, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	fi := &redistest.FaultInjector{}
+	p := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			c, err := redis.Dial("tcp", s.Addr(), redis.DialNetDial(fi.NetDial))
+			if err != nil {
+				return nil, err
+			}
+			return fi.Conn(c), nil
+		},
+		MaxIdle: 1,
+	}
+	defer p.Close()
+
+	// Error replies and latency.
+	fi.Faults = []redistest.Fault{{Commands: []string{"incr"}, Reply: redis.Error("ERR injected"), Latency: 10 * time.Millisecond}}
+	c := p.Get()
+	start := time.Now()
+	if _, err := c.Do("INCR", "n"); err == nil || err.Error() != "ERR injected" {
+		t.Errorf("INCR returned %v, want injected error", err)
+	}
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("INCR took %v, want latency of 10ms", d)
+	}
+	c.Send("SET", "k", "v")
+	c.Send("INCR", "n")
+	if replies, err := c.Do(""); !reflect.DeepEqual(replies, []interface{}{"OK", redis.Error("ERR injected")}) || err != nil {
+		t.Errorf("pipeline returned %#v, %v", replies, err)
+	}
+	c.Close()
+
+	// Dropped connections are removed from the pool.
+	fi.Faults = []redistest.Fault{{Commands: []string{"GET"}, Drop: true}}
+	c = p.Get()
+	if _, err := c.Do("GET", "k"); err != redistest.ErrInjectedDrop {
+		t.Errorf("GET returned %v, want %v", err, redistest.ErrInjectedDrop)
+	}
+	if c.Err() == nil {
+		t.Error("connection not broken after drop")
+	}
+	c.Close()
+	if n := p.ActiveCount(); n != 0 {
+		t.Errorf("active = %d after drop, want 0", n)
+	}
+
+	// Partial writes.
+	fi.Faults = []redistest.Fault{{Commands: []string{"SET"}, PartialWrite: true}}
+	c = p.Get()
+	if _, err := c.Do("SET", "k", "v"); err == nil || c.Err() == nil {
+		t.Errorf("SET returned %v, Err() = %v, want errors", err, c.Err())
+	}
+	c.Close()
+
+	// Probability.
+	fi.Faults = []redistest.Fault{{Reply: redis.Error("ERR injected"), Probability: 0.5}}
+	fi.Rand = func() float64 { return 0.7 }
+	c = p.Get()
+	if _, err := c.Do("PING"); err != nil {
+		t.Errorf("PING returned %v with fault not chosen", err)
+	}
+	c.Close()
+}
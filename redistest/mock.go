@@ -0,0 +1,307 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// TB is the subset of testing.TB used by Conn.
+type TB interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Matcher matches a command argument.
+type Matcher interface {
+	Match(arg interface{}) bool
+	String() string
+}
+
+type anyMatcher struct{}
+
+func (anyMatcher) Match(interface{}) bool { return true }
+func (anyMatcher) String() string         { return "<any>" }
+
+// Any returns a matcher that matches any argument.
+func Any() Matcher { return anyMatcher{} }
+
+type funcMatcher struct {
+	f    func(arg string) bool
+	desc string
+}
+
+func (m funcMatcher) Match(arg interface{}) bool { return m.f(argString(arg)) }
+func (m funcMatcher) String() string             { return m.desc }
+
+// MatchFunc returns a matcher that calls f with the argument formatted as
+// the string sent to the server. The description is used in error messages.
+func MatchFunc(desc string, f func(arg string) bool) Matcher {
+	return funcMatcher{f: f, desc: desc}
+}
+
+// argString formats a command argument as the string sent to the server.
+func argString(arg interface{}) string {
+	if a, ok := arg.(redis.Argument); ok {
+		arg = a.RedisArg()
+	}
+	switch arg := arg.(type) {
+	case string:
+		return arg
+	case []byte:
+		return string(arg)
+	case nil:
+		return ""
+	case time.Time:
+		return arg.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(arg)
+}
+
+// Expectation is a command expected by a Conn. Use the Reply and Error
+// methods to set the result of the command.
+type Expectation struct {
+	commandName string
+	args        []interface{}
+	reply       interface{}
+	err         error
+	times       int
+	calls       int
+}
+
+// Reply sets the reply to the command. Use a redis.Error value for an error
+// reply from the server.
+func (e *Expectation) Reply(reply interface{}) *Expectation {
+	e.reply = reply
+	return e
+}
+
+// Error sets the error returned for the command. Use Error for connection
+// errors; use Reply with a redis.Error value for server errors.
+func (e *Expectation) Error(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times sets the number of times that the command is expected. The default
+// is one.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+func (e *Expectation) match(commandName string, args []interface{}) bool {
+	return matchCommand(e.commandName, e.args, commandName, args)
+}
+
+// matchCommand returns true if the command matches the expected command.
+// The expected arguments can include Matchers.
+func matchCommand(expectedName string, expectedArgs []interface{}, commandName string, args []interface{}) bool {
+	if !strings.EqualFold(commandName, expectedName) || len(args) != len(expectedArgs) {
+		return false
+	}
+	for i, expected := range expectedArgs {
+		if m, ok := expected.(Matcher); ok {
+			if !m.Match(args[i]) {
+				return false
+			}
+		} else if argString(expected) != argString(args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) String() string {
+	return formatCommand(e.commandName, e.args)
+}
+
+func formatCommand(commandName string, args []interface{}) string {
+	var buf bytes.Buffer
+	buf.WriteString(commandName)
+	for _, arg := range args {
+		if m, ok := arg.(Matcher); ok {
+			fmt.Fprintf(&buf, " %s", m)
+		} else {
+			fmt.Fprintf(&buf, " %q", argString(arg))
+		}
+	}
+	return buf.String()
+}
+
+type result struct {
+	reply interface{}
+	err   error
+}
+
+var errUnexpected = errors.New("redistest: unexpected command")
+
+// Conn is a mock connection. Commands are matched against the expectations
+// in the order that the expectations are registered. A command that does not
+// match an expectation is reported to the test and returns an error.
+type Conn struct {
+	t TB
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	pending      []result
+	closed       bool
+}
+
+// NewConn returns a mock connection that reports errors to t.
+func NewConn(t TB) *Conn {
+	return &Conn{t: t}
+}
+
+var _ redis.Conn = (*Conn)(nil)
+
+// Expect registers an expected command. Arguments are compared with the
+// string sent to the server, so int64(1), 1 and "1" are equal. Use a Matcher
+// to match arguments by other criteria.
+func (c *Conn) Expect(commandName string, args ...interface{}) *Expectation {
+	e := &Expectation{commandName: commandName, args: args, times: 1}
+	c.mu.Lock()
+	c.expectations = append(c.expectations, e)
+	c.mu.Unlock()
+	return e
+}
+
+// Verify reports expectations that were not met to the test.
+func (c *Conn) Verify() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.expectations {
+		if e.calls < e.times {
+			c.t.Errorf("redistest: expected %s %d times, called %d times", e, e.times, e.calls)
+		}
+	}
+}
+
+// exec finds the expectation for a command and returns its result. The
+// caller must hold c.mu.
+func (c *Conn) exec(commandName string, args []interface{}) result {
+	for _, e := range c.expectations {
+		if e.calls < e.times && e.match(commandName, args) {
+			e.calls++
+			return result{e.reply, e.err}
+		}
+	}
+	c.t.Errorf("redistest: unexpected command %s", formatCommand(commandName, args))
+	return result{err: errUnexpected}
+}
+
+// Do implements the redis.Conn interface.
+func (c *Conn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, errClosed
+	}
+	pending := c.pending
+	c.pending = nil
+	if commandName == "" {
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		replies := make([]interface{}, len(pending))
+		for i, r := range pending {
+			if r.err != nil {
+				return nil, r.err
+			}
+			replies[i] = r.reply
+		}
+		return replies, nil
+	}
+	for _, r := range pending {
+		if r.err != nil {
+			return nil, r.err
+		}
+	}
+	r := c.exec(commandName, args)
+	return r.result()
+}
+
+func (r result) result() (interface{}, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err, ok := r.reply.(redis.Error); ok {
+		return nil, err
+	}
+	return r.reply, nil
+}
+
+var errClosed = errors.New("redistest: connection closed")
+
+// Send implements the redis.Conn interface.
+func (c *Conn) Send(commandName string, args ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errClosed
+	}
+	c.pending = append(c.pending, c.exec(commandName, args))
+	return nil
+}
+
+// Flush implements the redis.Conn interface.
+func (c *Conn) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errClosed
+	}
+	return nil
+}
+
+// Receive implements the redis.Conn interface.
+func (c *Conn) Receive() (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, errClosed
+	}
+	if len(c.pending) == 0 {
+		c.t.Errorf("redistest: receive with no pending commands")
+		return nil, errUnexpected
+	}
+	r := c.pending[0]
+	c.pending = c.pending[1:]
+	return r.result()
+}
+
+// Err implements the redis.Conn interface.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errClosed
+	}
+	return nil
+}
+
+// Close implements the redis.Conn interface.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
+)
+
+type recordingTB struct {
+	errors []string
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestConn(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+
+	c.Expect("SET", "k", 1).Reply("OK")
+	c.Expect("GET", redistest.MatchFunc("prefix k", func(arg string) bool { return strings.HasPrefix(arg, "k") })).Reply([]byte("1")).Times(2)
+	c.Expect("INCR", "s").Reply(redis.Error("WRONGTYPE bad type"))
+	c.Expect("PING").Error(errors.New("broken"))
+
+	if s, err := redis.String(c.Do("SET", "k", "1")); s != "OK" || err != nil {
+		t.Errorf("SET returned %q, %v", s, err)
+	}
+	c.Send("GET", "k1")
+	c.Send("GET", "k2")
+	c.Flush()
+	if replies, err := redis.Strings(c.Do("")); !reflect.DeepEqual(replies, []string{"1", "1"}) || err != nil {
+		t.Errorf("GET returned %q, %v", replies, err)
+	}
+	if _, err := c.Do("INCR", "s"); redis.ErrorCode(err) != "WRONGTYPE" {
+		t.Errorf("INCR returned error %v, want WRONGTYPE", err)
+	}
+	if _, err := c.Do("PING"); err == nil || err.Error() != "broken" {
+		t.Errorf("PING returned error %v, want broken", err)
+	}
+}
+
+func TestConnUnexpected(t *testing.T) {
+	var tb recordingTB
+	c := redistest.NewConn(&tb)
+	c.Expect("GET", "a").Reply(nil)
+	c.Expect("DEL", "a").Reply(int64(1))
+
+	if _, err := c.Do("GET", "b"); err == nil {
+		t.Error("unexpected command did not return an error")
+	}
+	c.Do("GET", "a")
+	c.Verify()
+
+	expected := []string{
+		`redistest: unexpected command GET "b"`,
+		`redistest: expected DEL "a" 1 times, called 0 times`,
+	}
+	if !reflect.DeepEqual(tb.errors, expected) {
+		t.Errorf("errors = %q, want %q", tb.errors, expected)
+	}
+}
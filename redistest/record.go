@@ -0,0 +1,454 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// exchange is a command and its reply in a recording. The recording is a
+// sequence of exchanges encoded as JSON, one per line.
+type exchange struct {
+	Command string `json:"cmd"`
+	Args    []str  `json:"args,omitempty"`
+
+	// Reply is nil if the reply to the command was not read by the
+	// application.
+	Reply *value `json:"reply,omitempty"`
+
+	// Err is a connection error.
+	Err string `json:"err,omitempty"`
+}
+
+// value is a tagged encoding of a reply value. Exactly one field is set.
+type value struct {
+	Nil    bool              `json:"nil,omitempty"`
+	Status *string           `json:"status,omitempty"`
+	Bulk   *str              `json:"bulk,omitempty"`
+	Int    *int64            `json:"int,omitempty"`
+	Error  *string           `json:"error,omitempty"`
+	Array  *[]value          `json:"array,omitempty"`
+	Bool   *bool             `json:"bool,omitempty"`
+	Float  *float64          `json:"float,omitempty"`
+	Map    *map[string]value `json:"map,omitempty"`
+}
+
+func encodeValue(v interface{}) (*value, error) {
+	switch v := v.(type) {
+	case nil:
+		return &value{Nil: true}, nil
+	case string:
+		return &value{Status: &v}, nil
+	case []byte:
+		s := str(v)
+		return &value{Bulk: &s}, nil
+	case int64:
+		return &value{Int: &v}, nil
+	case redis.Error:
+		s := string(v)
+		return &value{Error: &s}, nil
+	case bool:
+		return &value{Bool: &v}, nil
+	case float64:
+		return &value{Float: &v}, nil
+	case []interface{}:
+		a := make([]value, len(v))
+		for i, e := range v {
+			ev, err := encodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = *ev
+		}
+		return &value{Array: &a}, nil
+	case map[string]interface{}:
+		m := make(map[string]value, len(v))
+		for k, e := range v {
+			ev, err := encodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = *ev
+		}
+		return &value{Map: &m}, nil
+	}
+	return nil, fmt.Errorf("redistest: cannot record reply of type %T", v)
+}
+
+func (v *value) decode() interface{} {
+	switch {
+	case v.Status != nil:
+		return *v.Status
+	case v.Bulk != nil:
+		return []byte(*v.Bulk)
+	case v.Int != nil:
+		return *v.Int
+	case v.Error != nil:
+		return redis.Error(*v.Error)
+	case v.Bool != nil:
+		return *v.Bool
+	case v.Float != nil:
+		return *v.Float
+	case v.Array != nil:
+		a := make([]interface{}, len(*v.Array))
+		for i := range a {
+			a[i] = (*v.Array)[i].decode()
+		}
+		return a
+	case v.Map != nil:
+		m := make(map[string]interface{}, len(*v.Map))
+		for k, e := range *v.Map {
+			m[k] = e.decode()
+		}
+		return m
+	}
+	return nil
+}
+
+func newExchange(commandName string, args []interface{}) *exchange {
+	x := &exchange{Command: commandName}
+	for _, arg := range args {
+		x.Args = append(x.Args, str(argString(arg)))
+	}
+	return x
+}
+
+func (x *exchange) String() string {
+	var args []interface{}
+	for _, arg := range x.Args {
+		args = append(args, string(arg))
+	}
+	return formatCommand(x.Command, args)
+}
+
+// str is a string that is encoded as a JSON string if the string is valid
+// UTF-8 and as an object with base64 encoded data otherwise.
+type str string
+
+type base64Str struct {
+	Base64 []byte `json:"base64"`
+}
+
+func (s str) MarshalJSON() ([]byte, error) {
+	if utf8.ValidString(string(s)) {
+		return json.Marshal(string(s))
+	}
+	return json.Marshal(base64Str{[]byte(s)})
+}
+
+func (s *str) UnmarshalJSON(p []byte) error {
+	if len(p) > 0 && p[0] == '{' {
+		var b base64Str
+		if err := json.Unmarshal(p, &b); err != nil {
+			return err
+		}
+		*s = str(b.Base64)
+		return nil
+	}
+	return json.Unmarshal(p, (*string)(s))
+}
+
+// Recorder is a connection that records the commands executed on a real
+// connection and the replies to those commands. Use NewReplayConn to replay
+// the recording.
+type Recorder struct {
+	c redis.Conn
+
+	mu      sync.Mutex
+	enc     *json.Encoder
+	pending []*exchange
+	err     error
+}
+
+var _ redis.Conn = (*Recorder)(nil)
+
+// NewRecorder returns a connection that executes commands on c and writes
+// the exchanges to w.
+func NewRecorder(c redis.Conn, w io.Writer) *Recorder {
+	return &Recorder{c: c, enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error encountered writing the recording, or the
+// connection error if there is none.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	err := r.err
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return r.c.Err()
+}
+
+// write writes the exchange with the reply to the recording. The caller
+// must hold r.mu.
+func (r *Recorder) write(x *exchange, reply interface{}, err error) {
+	if err != nil {
+		if e, ok := err.(redis.Error); ok {
+			reply = e
+		} else {
+			x.Err = err.Error()
+		}
+	}
+	if x.Err == "" {
+		v, err := encodeValue(reply)
+		if err != nil {
+			r.setErr(err)
+			return
+		}
+		x.Reply = v
+	}
+	r.setErr(r.enc.Encode(x))
+}
+
+// writePending writes the pending exchanges without replies. The caller
+// must hold r.mu.
+func (r *Recorder) writePending() {
+	for _, x := range r.pending {
+		r.setErr(r.enc.Encode(x))
+	}
+	r.pending = nil
+}
+
+func (r *Recorder) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// Do implements the redis.Conn interface.
+func (r *Recorder) Do(commandName string, args ...interface{}) (interface{}, error) {
+	reply, err := r.c.Do(commandName, args...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if commandName == "" {
+		replies, _ := reply.([]interface{})
+		if err == nil && len(replies) == len(r.pending) {
+			for i, x := range r.pending {
+				r.write(x, replies[i], nil)
+			}
+			r.pending = nil
+		}
+		r.writePending()
+		return reply, err
+	}
+	r.writePending()
+	r.write(newExchange(commandName, args), reply, err)
+	return reply, err
+}
+
+// Send implements the redis.Conn interface.
+func (r *Recorder) Send(commandName string, args ...interface{}) error {
+	err := r.c.Send(commandName, args...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	x := newExchange(commandName, args)
+	if err != nil {
+		r.write(x, nil, err)
+		return err
+	}
+	r.pending = append(r.pending, x)
+	return nil
+}
+
+// Flush implements the redis.Conn interface.
+func (r *Recorder) Flush() error {
+	return r.c.Flush()
+}
+
+// Receive implements the redis.Conn interface. Replies that are not
+// associated with a command, such as pub/sub messages, are not recorded.
+func (r *Recorder) Receive() (interface{}, error) {
+	reply, err := r.c.Receive()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) > 0 {
+		x := r.pending[0]
+		r.pending = r.pending[1:]
+		r.write(x, reply, err)
+	}
+	return reply, err
+}
+
+// Close closes the underlying connection.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	r.writePending()
+	r.mu.Unlock()
+	return r.c.Close()
+}
+
+// ReplayConn is a connection that replays a recording made with a
+// Recorder. Commands must be executed in the recorded order.
+type ReplayConn struct {
+	mu        sync.Mutex
+	exchanges []*exchange
+	pending   []*exchange
+	err       error
+}
+
+var _ redis.Conn = (*ReplayConn)(nil)
+
+var errReplayClosed = errors.New("redistest: replay connection closed")
+
+// NewReplayConn returns a connection that replays the recording read from
+// rd.
+func NewReplayConn(rd io.Reader) (*ReplayConn, error) {
+	c := &ReplayConn{}
+	s := bufio.NewScanner(rd)
+	s.Buffer(nil, 64<<20)
+	for s.Scan() {
+		var x exchange
+		if err := json.Unmarshal(s.Bytes(), &x); err != nil {
+			return nil, err
+		}
+		c.exchanges = append(c.exchanges, &x)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Remaining returns the number of recorded commands that have not been
+// executed.
+func (c *ReplayConn) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.exchanges)
+}
+
+// next returns the next exchange if it matches the command. The caller must
+// hold c.mu.
+func (c *ReplayConn) next(commandName string, args []interface{}) (*exchange, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	got := newExchange(commandName, args)
+	if len(c.exchanges) == 0 {
+		return nil, fmt.Errorf("redistest: replay got %s after end of recording", got)
+	}
+	x := c.exchanges[0]
+	if x.Command != commandName || len(x.Args) != len(got.Args) {
+		return nil, fmt.Errorf("redistest: replay got %s, want %s", got, x)
+	}
+	for i := range x.Args {
+		if x.Args[i] != got.Args[i] {
+			return nil, fmt.Errorf("redistest: replay got %s, want %s", got, x)
+		}
+	}
+	c.exchanges = c.exchanges[1:]
+	return x, nil
+}
+
+func (x *exchange) result() (interface{}, error) {
+	if x.Err != "" {
+		return nil, errors.New(x.Err)
+	}
+	if x.Reply == nil {
+		return nil, fmt.Errorf("redistest: reply to %s not recorded", x)
+	}
+	reply := x.Reply.decode()
+	if err, ok := reply.(redis.Error); ok {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Do implements the redis.Conn interface.
+func (c *ReplayConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending := c.pending
+	c.pending = nil
+	if commandName == "" {
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		replies := make([]interface{}, len(pending))
+		for i, x := range pending {
+			if x.Err != "" {
+				return nil, errors.New(x.Err)
+			}
+			if x.Reply == nil {
+				return nil, fmt.Errorf("redistest: reply to %s not recorded", x)
+			}
+			replies[i] = x.Reply.decode()
+		}
+		return replies, nil
+	}
+	x, err := c.next(commandName, args)
+	if err != nil {
+		return nil, err
+	}
+	return x.result()
+}
+
+// Send implements the redis.Conn interface.
+func (c *ReplayConn) Send(commandName string, args ...interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	x, err := c.next(commandName, args)
+	if err != nil {
+		return err
+	}
+	c.pending = append(c.pending, x)
+	return nil
+}
+
+// Flush implements the redis.Conn interface.
+func (c *ReplayConn) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Receive implements the redis.Conn interface.
+func (c *ReplayConn) Receive() (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	if len(c.pending) == 0 {
+		return nil, errors.New("redistest: replay receive with no pending commands")
+	}
+	x := c.pending[0]
+	c.pending = c.pending[1:]
+	return x.result()
+}
+
+// Err implements the redis.Conn interface.
+func (c *ReplayConn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close implements the redis.Conn interface.
+func (c *ReplayConn) Close() error {
+	c.mu.Lock()
+	c.err = errReplayClosed
+	c.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
+)
+
+func runRecordedCommands(c redis.Conn) []interface{} {
+	var results []interface{}
+	add := func(reply interface{}, err error) {
+		results = append(results, reply, err)
+	}
+	add(c.Do("SET", "k", []byte("\x00\xff")))
+	add(c.Do("GET", "k"))
+	c.Send("INCR", "n")
+	c.Send("HGETALL", "h")
+	c.Flush()
+	add(c.Receive())
+	add(c.Receive())
+	c.Send("LPUSH", "l", 1.5)
+	add(c.Do(""))
+	add(c.Do("INCR", "s"))
+	return results
+}
+
+func TestRecordReplay(t *testing.T) {
+	mock := redistest.NewConn(t)
+	mock.Expect("SET", "k", "\x00\xff").Reply("OK")
+	mock.Expect("GET", "k").Reply([]byte("\x00\xff"))
+	mock.Expect("INCR", "n").Reply(int64(1))
+	mock.Expect("HGETALL", "h").Reply(map[string]interface{}{"f": []byte("v"), "g": nil})
+	mock.Expect("LPUSH", "l", "1.5").Reply(int64(1))
+	mock.Expect("INCR", "s").Reply(redis.Error("WRONGTYPE bad type"))
+
+	var buf bytes.Buffer
+	r := redistest.NewRecorder(mock, &buf)
+	expected := runRecordedCommands(r)
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	mock.Verify()
+
+	c, err := redistest.NewReplayConn(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := runRecordedCommands(c)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("replay results =\n%#v\nwant\n%#v", results, expected)
+	}
+	if n := c.Remaining(); n != 0 {
+		t.Errorf("Remaining() = %d, want 0", n)
+	}
+
+	c, _ = redistest.NewReplayConn(bytes.NewReader(nil))
+	if _, err := c.Do("PING"); err == nil {
+		t.Error("command after end of recording did not return an error")
+	}
+}
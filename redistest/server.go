@@ -0,0 +1,760 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a minimal in-process Redis server for tests. The server
+// supports a subset of the Redis commands:
+//
+//  Connection: AUTH ECHO PING QUIT SELECT CLIENT
+//  Keys: DEL EXISTS EXPIRE PEXPIRE TTL PTTL PERSIST TYPE KEYS DBSIZE FLUSHDB FLUSHALL
+//  Strings: GET SET SETNX SETEX MGET MSET INCR INCRBY DECR DECRBY APPEND STRLEN
+//  Hashes: HSET HGET HMGET HDEL HEXISTS HGETALL HKEYS HVALS HLEN HINCRBY
+//  Pub/Sub: SUBSCRIBE UNSUBSCRIBE PSUBSCRIBE PUNSUBSCRIBE PUBLISH
+//
+// The server speaks RESP2. Keys with an expiry are removed when accessed
+// after the expiry time. Patterns in the KEYS and PSUBSCRIBE commands use
+// the syntax of path.Match.
+type Server struct {
+	ln net.Listener
+
+	mu     sync.Mutex
+	dbs    map[int]map[string]*entry
+	conns  map[*serverConn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type entry struct {
+	value  interface{} // string or map[string]string
+	expire time.Time
+}
+
+// NewServer starts a server listening on a loopback address.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:    ln,
+		dbs:   make(map[int]map[string]*entry),
+		conns: make(map[*serverConn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address of the server.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops the server and closes all client connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.netConn.Close()
+	}
+	s.mu.Unlock()
+	err := s.ln.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		netConn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &serverConn{
+			s:        s,
+			netConn:  netConn,
+			br:       bufio.NewReader(netConn),
+			bw:       bufio.NewWriter(netConn),
+			channels: make(map[string]bool),
+			patterns: make(map[string]bool),
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			netConn.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go c.serve()
+	}
+}
+
+type serverConn struct {
+	s       *Server
+	netConn net.Conn
+	br      *bufio.Reader
+
+	// wmu protects bw. Messages are written to subscribers from the
+	// goroutines of publishing connections.
+	wmu sync.Mutex
+	bw  *bufio.Writer
+
+	// The following fields are protected by s.mu.
+	db       int
+	channels map[string]bool
+	patterns map[string]bool
+}
+
+func (c *serverConn) serve() {
+	defer c.s.wg.Done()
+	defer func() {
+		c.s.mu.Lock()
+		delete(c.s.conns, c)
+		c.s.mu.Unlock()
+		c.netConn.Close()
+	}()
+	for {
+		args, err := readCommand(c.br)
+		if err != nil {
+			if err != io.EOF {
+				c.write(func(w *bufio.Writer) { writeError(w, "ERR "+err.Error()) })
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		name := strings.ToUpper(args[0])
+		if name == "QUIT" {
+			c.write(func(w *bufio.Writer) { writeStatus(w, "OK") })
+			return
+		}
+		c.s.mu.Lock()
+		reply := c.exec(name, args[1:])
+		c.s.mu.Unlock()
+		if reply != nil {
+			c.write(reply)
+		}
+	}
+}
+
+// write calls f to write a reply and flushes the connection.
+func (c *serverConn) write(f func(w *bufio.Writer)) {
+	c.wmu.Lock()
+	f(c.bw)
+	c.bw.Flush()
+	c.wmu.Unlock()
+}
+
+var errProtocol = errors.New("Protocol error")
+
+// readCommand reads a command encoded as an array of bulk strings.
+func readCommand(br *bufio.Reader) ([]string, error) {
+	line, err := readLine(br)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		// Inline command.
+		return strings.Fields(line), nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, errProtocol
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(br)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, errProtocol
+		}
+		p := make([]byte, size+2)
+		if _, err := io.ReadFull(br, p); err != nil {
+			return nil, err
+		}
+		args[i] = string(p[:size])
+	}
+	return args, nil
+}
+
+func readLine(br *bufio.Reader) (string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeStatus(w *bufio.Writer, s string) { fmt.Fprintf(w, "+%s\r\n", s) }
+func writeError(w *bufio.Writer, s string)  { fmt.Fprintf(w, "-%s\r\n", s) }
+func writeInt(w *bufio.Writer, n int64)     { fmt.Fprintf(w, ":%d\r\n", n) }
+func writeNil(w *bufio.Writer)              { w.WriteString("$-1\r\n") }
+func writeBulk(w *bufio.Writer, s string)   { fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s) }
+
+// writeBulks writes an array of bulk strings. Nil elements are written as
+// nil bulk strings.
+func writeBulks(w *bufio.Writer, values []*string) {
+	fmt.Fprintf(w, "*%d\r\n", len(values))
+	for _, v := range values {
+		if v == nil {
+			writeNil(w)
+		} else {
+			writeBulk(w, *v)
+		}
+	}
+}
+
+func statusReply(s string) func(*bufio.Writer) {
+	return func(w *bufio.Writer) { writeStatus(w, s) }
+}
+
+func errorReply(s string) func(*bufio.Writer) {
+	return func(w *bufio.Writer) { writeError(w, s) }
+}
+
+func intReply(n int64) func(*bufio.Writer) {
+	return func(w *bufio.Writer) { writeInt(w, n) }
+}
+
+func bulkReply(s string) func(*bufio.Writer) {
+	return func(w *bufio.Writer) { writeBulk(w, s) }
+}
+
+func nilReply(w *bufio.Writer) { writeNil(w) }
+
+func bulksReply(values []*string) func(*bufio.Writer) {
+	return func(w *bufio.Writer) { writeBulks(w, values) }
+}
+
+func stringsReply(values []string) func(*bufio.Writer) {
+	p := make([]*string, len(values))
+	for i := range values {
+		p[i] = &values[i]
+	}
+	return bulksReply(p)
+}
+
+const (
+	errWrongType = "WRONGTYPE Operation against a key holding the wrong kind of value"
+	errNotInt    = "ERR value is not an integer or out of range"
+	errSyntax    = "ERR syntax error"
+)
+
+func errArgs(name string) func(*bufio.Writer) {
+	return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// arity is the minimum and maximum number of arguments for commands. A
+// maximum of -1 specifies no limit.
+var arity = map[string][2]int{
+	"AUTH": {1, 2}, "ECHO": {1, 1}, "PING": {0, 1}, "SELECT": {1, 1}, "CLIENT": {1, -1},
+	"DEL": {1, -1}, "EXISTS": {1, -1}, "EXPIRE": {2, 2}, "PEXPIRE": {2, 2}, "TTL": {1, 1},
+	"PTTL": {1, 1}, "PERSIST": {1, 1}, "TYPE": {1, 1}, "KEYS": {1, 1}, "DBSIZE": {0, 0},
+	"FLUSHDB": {0, 0}, "FLUSHALL": {0, 0},
+	"GET": {1, 1}, "SET": {2, -1}, "SETNX": {2, 2}, "SETEX": {3, 3}, "MGET": {1, -1},
+	"MSET": {2, -1}, "INCR": {1, 1}, "INCRBY": {2, 2}, "DECR": {1, 1}, "DECRBY": {2, 2},
+	"APPEND": {2, 2}, "STRLEN": {1, 1},
+	"HSET": {3, -1}, "HGET": {2, 2}, "HMGET": {2, -1}, "HDEL": {2, -1}, "HEXISTS": {2, 2},
+	"HGETALL": {1, 1}, "HKEYS": {1, 1}, "HVALS": {1, 1}, "HLEN": {1, 1}, "HINCRBY": {3, 3},
+	"SUBSCRIBE": {1, -1}, "UNSUBSCRIBE": {0, -1}, "PSUBSCRIBE": {1, -1},
+	"PUNSUBSCRIBE": {0, -1}, "PUBLISH": {2, 2},
+}
+
+// exec executes a command and returns the reply. The caller must hold s.mu.
+func (c *serverConn) exec(name string, args []string) func(*bufio.Writer) {
+	n, ok := arity[name]
+	if !ok {
+		return errorReply(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+	}
+	if len(args) < n[0] || (n[1] >= 0 && len(args) > n[1]) {
+		return errArgs(name)
+	}
+	if c.subscribed() {
+		switch name {
+		case "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE", "PING":
+		default:
+			return errorReply("ERR only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT allowed in this context")
+		}
+	}
+
+	switch name {
+	case "AUTH", "CLIENT":
+		return statusReply("OK")
+	case "ECHO":
+		return bulkReply(args[0])
+	case "PING":
+		if c.subscribed() {
+			return stringsReply([]string{"pong", ""})
+		}
+		if len(args) == 1 {
+			return bulkReply(args[0])
+		}
+		return statusReply("PONG")
+	case "SELECT":
+		db, err := strconv.Atoi(args[0])
+		if err != nil || db < 0 || db > 15 {
+			return errorReply("ERR DB index is out of range")
+		}
+		c.db = db
+		return statusReply("OK")
+	case "SUBSCRIBE", "PSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		return c.subscribe(name, args)
+	case "PUBLISH":
+		return intReply(c.s.publish(args[0], args[1]))
+	case "FLUSHALL":
+		c.s.dbs = make(map[int]map[string]*entry)
+		return statusReply("OK")
+	}
+	return c.execKeys(name, args)
+}
+
+// database returns the connection's database. The caller must hold s.mu.
+func (c *serverConn) database() map[string]*entry {
+	db := c.s.dbs[c.db]
+	if db == nil {
+		db = make(map[string]*entry)
+		c.s.dbs[c.db] = db
+	}
+	return db
+}
+
+// lookup returns the entry for key, removing the entry if expired.
+func lookup(db map[string]*entry, key string) *entry {
+	e := db[key]
+	if e != nil && !e.expire.IsZero() && !time.Now().Before(e.expire) {
+		delete(db, key)
+		return nil
+	}
+	return e
+}
+
+func (c *serverConn) execKeys(name string, args []string) func(*bufio.Writer) {
+	db := c.database()
+	switch name {
+	case "DEL", "EXISTS":
+		var n int64
+		for _, key := range args {
+			if lookup(db, key) != nil {
+				n++
+				if name == "DEL" {
+					delete(db, key)
+				}
+			}
+		}
+		return intReply(n)
+	case "EXPIRE", "PEXPIRE":
+		d, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return errorReply(errNotInt)
+		}
+		e := lookup(db, args[0])
+		if e == nil {
+			return intReply(0)
+		}
+		unit := time.Second
+		if name == "PEXPIRE" {
+			unit = time.Millisecond
+		}
+		e.expire = time.Now().Add(time.Duration(d) * unit)
+		return intReply(1)
+	case "TTL", "PTTL":
+		e := lookup(db, args[0])
+		switch {
+		case e == nil:
+			return intReply(-2)
+		case e.expire.IsZero():
+			return intReply(-1)
+		}
+		d := e.expire.Sub(time.Now())
+		if name == "TTL" {
+			return intReply(int64((d + time.Second/2) / time.Second))
+		}
+		return intReply(int64(d / time.Millisecond))
+	case "PERSIST":
+		e := lookup(db, args[0])
+		if e == nil || e.expire.IsZero() {
+			return intReply(0)
+		}
+		e.expire = time.Time{}
+		return intReply(1)
+	case "TYPE":
+		switch lookup(db, args[0]).typ() {
+		case "string":
+			return statusReply("string")
+		case "hash":
+			return statusReply("hash")
+		}
+		return statusReply("none")
+	case "KEYS":
+		var keys []string
+		for key := range db {
+			if ok, _ := path.Match(args[0], key); ok && lookup(db, key) != nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		return stringsReply(keys)
+	case "DBSIZE":
+		var n int64
+		for key := range db {
+			if lookup(db, key) != nil {
+				n++
+			}
+		}
+		return intReply(n)
+	case "FLUSHDB":
+		delete(c.s.dbs, c.db)
+		return statusReply("OK")
+	}
+	if strings.HasPrefix(name, "H") {
+		return execHash(db, name, args)
+	}
+	return execString(db, name, args)
+}
+
+func (e *entry) typ() string {
+	if e == nil {
+		return ""
+	}
+	if _, ok := e.value.(string); ok {
+		return "string"
+	}
+	return "hash"
+}
+
+func execString(db map[string]*entry, name string, args []string) func(*bufio.Writer) {
+	switch name {
+	case "GET":
+		e := lookup(db, args[0])
+		switch e.typ() {
+		case "":
+			return nilReply
+		case "hash":
+			return errorReply(errWrongType)
+		}
+		return bulkReply(e.value.(string))
+	case "SET":
+		var (
+			expire time.Time
+			nx, xx bool
+		)
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "XX":
+				xx = true
+			case "EX", "PX":
+				if i+1 >= len(args) {
+					return errorReply(errSyntax)
+				}
+				d, err := strconv.ParseInt(args[i+1], 10, 64)
+				if err != nil || d <= 0 {
+					return errorReply("ERR invalid expire time in 'set' command")
+				}
+				unit := time.Second
+				if strings.ToUpper(args[i]) == "PX" {
+					unit = time.Millisecond
+				}
+				expire = time.Now().Add(time.Duration(d) * unit)
+				i++
+			default:
+				return errorReply(errSyntax)
+			}
+		}
+		exists := lookup(db, args[0]) != nil
+		if (nx && exists) || (xx && !exists) {
+			return nilReply
+		}
+		db[args[0]] = &entry{value: args[1], expire: expire}
+		return statusReply("OK")
+	case "SETNX":
+		if lookup(db, args[0]) != nil {
+			return intReply(0)
+		}
+		db[args[0]] = &entry{value: args[1]}
+		return intReply(1)
+	case "SETEX":
+		d, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || d <= 0 {
+			return errorReply("ERR invalid expire time in 'setex' command")
+		}
+		db[args[0]] = &entry{value: args[2], expire: time.Now().Add(time.Duration(d) * time.Second)}
+		return statusReply("OK")
+	case "MGET":
+		values := make([]*string, len(args))
+		for i, key := range args {
+			if e := lookup(db, key); e.typ() == "string" {
+				s := e.value.(string)
+				values[i] = &s
+			}
+		}
+		return bulksReply(values)
+	case "MSET":
+		if len(args)%2 != 0 {
+			return errArgs(name)
+		}
+		for i := 0; i < len(args); i += 2 {
+			db[args[i]] = &entry{value: args[i+1]}
+		}
+		return statusReply("OK")
+	case "INCR", "INCRBY", "DECR", "DECRBY":
+		delta := int64(1)
+		if len(args) > 1 {
+			var err error
+			if delta, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+				return errorReply(errNotInt)
+			}
+		}
+		if strings.HasPrefix(name, "DECR") {
+			delta = -delta
+		}
+		e := lookup(db, args[0])
+		var n int64
+		switch e.typ() {
+		case "":
+			e = &entry{}
+			db[args[0]] = e
+		case "hash":
+			return errorReply(errWrongType)
+		default:
+			var err error
+			if n, err = strconv.ParseInt(e.value.(string), 10, 64); err != nil {
+				return errorReply(errNotInt)
+			}
+		}
+		n += delta
+		e.value = strconv.FormatInt(n, 10)
+		return intReply(n)
+	case "APPEND":
+		e := lookup(db, args[0])
+		switch e.typ() {
+		case "":
+			e = &entry{value: ""}
+			db[args[0]] = e
+		case "hash":
+			return errorReply(errWrongType)
+		}
+		s := e.value.(string) + args[1]
+		e.value = s
+		return intReply(int64(len(s)))
+	case "STRLEN":
+		e := lookup(db, args[0])
+		switch e.typ() {
+		case "":
+			return intReply(0)
+		case "hash":
+			return errorReply(errWrongType)
+		}
+		return intReply(int64(len(e.value.(string))))
+	}
+	panic("redistest: unhandled command " + name)
+}
+
+func execHash(db map[string]*entry, name string, args []string) func(*bufio.Writer) {
+	e := lookup(db, args[0])
+	if e.typ() == "string" {
+		return errorReply(errWrongType)
+	}
+	var h map[string]string
+	if e != nil {
+		h = e.value.(map[string]string)
+	}
+	switch name {
+	case "HSET":
+		if len(args)%2 != 1 {
+			return errArgs(name)
+		}
+		if h == nil {
+			h = make(map[string]string)
+			db[args[0]] = &entry{value: h}
+		}
+		var n int64
+		for i := 1; i < len(args); i += 2 {
+			if _, ok := h[args[i]]; !ok {
+				n++
+			}
+			h[args[i]] = args[i+1]
+		}
+		return intReply(n)
+	case "HGET":
+		v, ok := h[args[1]]
+		if !ok {
+			return nilReply
+		}
+		return bulkReply(v)
+	case "HMGET":
+		values := make([]*string, len(args)-1)
+		for i, field := range args[1:] {
+			if v, ok := h[field]; ok {
+				values[i] = &v
+			}
+		}
+		return bulksReply(values)
+	case "HDEL":
+		var n int64
+		for _, field := range args[1:] {
+			if _, ok := h[field]; ok {
+				delete(h, field)
+				n++
+			}
+		}
+		if h != nil && len(h) == 0 {
+			delete(db, args[0])
+		}
+		return intReply(n)
+	case "HEXISTS":
+		if _, ok := h[args[1]]; ok {
+			return intReply(1)
+		}
+		return intReply(0)
+	case "HGETALL", "HKEYS", "HVALS":
+		fields := make([]string, 0, len(h))
+		for field := range h {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		var values []string
+		for _, field := range fields {
+			if name != "HVALS" {
+				values = append(values, field)
+			}
+			if name != "HKEYS" {
+				values = append(values, h[field])
+			}
+		}
+		return stringsReply(values)
+	case "HLEN":
+		return intReply(int64(len(h)))
+	case "HINCRBY":
+		delta, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errorReply(errNotInt)
+		}
+		if h == nil {
+			h = make(map[string]string)
+			db[args[0]] = &entry{value: h}
+		}
+		var n int64
+		if v, ok := h[args[1]]; ok {
+			if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return errorReply("ERR hash value is not an integer")
+			}
+		}
+		n += delta
+		h[args[1]] = strconv.FormatInt(n, 10)
+		return intReply(n)
+	}
+	panic("redistest: unhandled command " + name)
+}
+
+// subscribed returns true if the connection is in subscribe mode. The
+// caller must hold s.mu.
+func (c *serverConn) subscribed() bool {
+	return len(c.channels)+len(c.patterns) > 0
+}
+
+// subscribe executes a subscription command. The caller must hold s.mu.
+func (c *serverConn) subscribe(name string, args []string) func(*bufio.Writer) {
+	subs := c.channels
+	if name[0] == 'P' {
+		subs = c.patterns
+	}
+	kind := strings.ToLower(name)
+	if len(args) == 0 {
+		// Unsubscribe from all.
+		for arg := range subs {
+			args = append(args, arg)
+		}
+		sort.Strings(args)
+	}
+	var replies []func(*bufio.Writer)
+	for _, arg := range args {
+		if strings.HasSuffix(kind, "unsubscribe") {
+			delete(subs, arg)
+		} else {
+			subs[arg] = true
+		}
+		arg := arg
+		count := int64(len(c.channels) + len(c.patterns))
+		replies = append(replies, func(w *bufio.Writer) {
+			w.WriteString("*3\r\n")
+			writeBulk(w, kind)
+			writeBulk(w, arg)
+			writeInt(w, count)
+		})
+	}
+	if len(replies) == 0 {
+		// Unsubscribe with no subscriptions.
+		return func(w *bufio.Writer) {
+			w.WriteString("*3\r\n")
+			writeBulk(w, kind)
+			writeNil(w)
+			writeInt(w, 0)
+		}
+	}
+	return func(w *bufio.Writer) {
+		for _, reply := range replies {
+			reply(w)
+		}
+	}
+}
+
+// publish sends a message to subscribers and returns the number of
+// receivers. The caller must hold s.mu.
+func (s *Server) publish(channel, message string) int64 {
+	var n int64
+	for c := range s.conns {
+		if c.channels[channel] {
+			n++
+			c.write(func(w *bufio.Writer) { writeBulks(w, []*string{strp("message"), &channel, &message}) })
+		}
+		for pattern := range c.patterns {
+			if ok, _ := path.Match(pattern, channel); ok {
+				n++
+				pattern := pattern
+				c.write(func(w *bufio.Writer) {
+					writeBulks(w, []*string{strp("pmessage"), &pattern, &channel, &message})
+				})
+			}
+		}
+	}
+	return n
+}
+
+func strp(s string) *string { return &s }
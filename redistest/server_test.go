@@ -0,0 +1,121 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistest_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
+)
+
+func TestServer(t *testing.T) {
+	s, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	p := &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return redis.Dial("tcp", s.Addr(), redis.DialDatabase(1)) },
+		MaxIdle: 2,
+	}
+	defer p.Close()
+	c := p.Get()
+	defer c.Close()
+
+	for _, tt := range []struct {
+		args     []interface{}
+		expected interface{}
+	}{
+		{[]interface{}{"SET", "s", "hello"}, "OK"},
+		{[]interface{}{"GET", "s"}, []byte("hello")},
+		{[]interface{}{"SET", "s", "x", "NX"}, nil},
+		{[]interface{}{"INCRBY", "n", 5}, int64(5)},
+		{[]interface{}{"HSET", "h", "a", 1, "b", 2}, int64(2)},
+		{[]interface{}{"HGETALL", "h"}, []interface{}{[]byte("a"), []byte("1"), []byte("b"), []byte("2")}},
+		{[]interface{}{"MGET", "s", "missing"}, []interface{}{[]byte("hello"), nil}},
+		{[]interface{}{"INCR", "h"}, redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value")},
+		{[]interface{}{"PEXPIRE", "s", 20}, int64(1)},
+		{[]interface{}{"TTL", "n"}, int64(-1)},
+		{[]interface{}{"DBSIZE"}, int64(3)},
+		{[]interface{}{"NOSUCHCOMMAND"}, redis.Error("ERR unknown command 'nosuchcommand'")},
+	} {
+		reply, err := c.Do(tt.args[0].(string), tt.args[1:]...)
+		if err != nil {
+			reply = err
+		}
+		if !reflect.DeepEqual(reply, tt.expected) {
+			t.Errorf("%v returned %#v, want %#v", tt.args, reply, tt.expected)
+		}
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if exists, _ := redis.Bool(c.Do("EXISTS", "s")); exists {
+		t.Error("key exists after expiry")
+	}
+}
+
+func TestServerPubSub(t *testing.T) {
+	s, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c, err := redis.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	pc, err := redis.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	psc := redis.PubSubConn{Conn: pc}
+	defer psc.Close()
+
+	psc.Subscribe("c")
+	psc.PSubscribe("p*")
+	for i := 0; i < 2; i++ {
+		if _, ok := psc.Receive().(redis.Subscription); !ok {
+			t.Fatal("expected subscription")
+		}
+	}
+	if n, err := redis.Int(c.Do("PUBLISH", "c", "hello")); n != 1 || err != nil {
+		t.Fatalf("PUBLISH returned %d, %v", n, err)
+	}
+	c.Do("PUBLISH", "pq", "world")
+
+	if m, ok := psc.Receive().(redis.Message); !ok || m.Channel != "c" || string(m.Data) != "hello" {
+		t.Errorf("received %#v, want message on c", m)
+	}
+	if m, ok := psc.Receive().(redis.PMessage); !ok || m.Pattern != "p*" || m.Channel != "pq" || string(m.Data) != "world" {
+		t.Errorf("received %#v, want pmessage on pq", m)
+	}
+
+	psc.Unsubscribe()
+	psc.PUnsubscribe()
+	for i := 0; i < 2; i++ {
+		if _, ok := psc.Receive().(redis.Subscription); !ok {
+			t.Fatal("expected subscription")
+		}
+	}
+	if pong, err := redis.String(pc.Do("PING")); pong != "PONG" || err != nil {
+		t.Errorf("PING returned %q, %v", pong, err)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package redistimeseries provides helpers for the commands of the
+// RedisTimeSeries module.
+//
+// Timestamps are milliseconds since the Unix epoch. Use Timestamp to convert
+// a time.Time to a timestamp.
+//
+//  redistimeseries.Create(c, "temp:1", redistimeseries.CreateOptions{
+//      Retention: 24 * time.Hour,
+//      Labels:    map[string]string{"sensor": "1", "room": "kitchen"},
+//  })
+//  redistimeseries.Add(c, "temp:1", redistimeseries.AutoTimestamp, 21.5)
+//
+//  samples, err := redistimeseries.Range(c, "temp:1", "-", "+", redistimeseries.RangeOptions{
+//      Aggregation: &redistimeseries.Aggregation{Type: redistimeseries.AggAvg, Bucket: time.Minute},
+//  })
+package redistimeseries // import "github.com/garyburd/redigo/redistimeseries"
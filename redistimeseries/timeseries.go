@@ -0,0 +1,412 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistimeseries
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// AutoTimestamp specifies that the server assigns the current time as the
+// timestamp of a sample.
+const AutoTimestamp int64 = -1
+
+// Timestamp returns t as milliseconds since the Unix epoch.
+func Timestamp(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// Sample is a sample in a time series.
+type Sample struct {
+	// Timestamp is the time of the sample in milliseconds since the Unix
+	// epoch.
+	Timestamp int64
+
+	Value float64
+}
+
+// Time returns the timestamp of the sample as a time.Time.
+func (s Sample) Time() time.Time {
+	return time.Unix(0, s.Timestamp*int64(time.Millisecond))
+}
+
+// KeySample is a sample for the time series at Key.
+type KeySample struct {
+	Key string
+	Sample
+}
+
+// Series is a time series in the reply to TS.MRANGE.
+type Series struct {
+	Key     string
+	Labels  map[string]string
+	Samples []Sample
+}
+
+// CreateOptions specifies the options for creating a time series.
+type CreateOptions struct {
+	// Retention is the maximum age of samples compared to the latest
+	// sample. Samples are kept forever if Retention is zero.
+	Retention time.Duration
+
+	// Encoding is COMPRESSED or UNCOMPRESSED. The server default is used
+	// if Encoding is empty.
+	Encoding string
+
+	// ChunkSize is the memory size in bytes of each data chunk. The server
+	// default is used if ChunkSize is zero.
+	ChunkSize int
+
+	// DuplicatePolicy is the policy for samples with the timestamp of an
+	// existing sample, such as BLOCK, FIRST, LAST, MIN, MAX or SUM. The
+	// server default is used if DuplicatePolicy is empty.
+	DuplicatePolicy string
+
+	Labels map[string]string
+}
+
+func labelArgs(args redis.Args, labels map[string]string) redis.Args {
+	if len(labels) == 0 {
+		return args
+	}
+	// Sort the labels for a deterministic command.
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args = append(args, "LABELS")
+	for _, name := range names {
+		args = append(args, name, labels[name])
+	}
+	return args
+}
+
+// Create creates a time series with the TS.CREATE command.
+func Create(c redis.Conn, key string, opts CreateOptions) error {
+	args := redis.Args{key}
+	if opts.Retention > 0 {
+		args = append(args, "RETENTION", int64(opts.Retention/time.Millisecond))
+	}
+	if opts.Encoding != "" {
+		args = append(args, "ENCODING", opts.Encoding)
+	}
+	if opts.ChunkSize > 0 {
+		args = append(args, "CHUNK_SIZE", opts.ChunkSize)
+	}
+	if opts.DuplicatePolicy != "" {
+		args = append(args, "DUPLICATE_POLICY", opts.DuplicatePolicy)
+	}
+	args = labelArgs(args, opts.Labels)
+	_, err := c.Do("TS.CREATE", args...)
+	return err
+}
+
+func timestampArg(timestamp int64) interface{} {
+	if timestamp == AutoTimestamp {
+		return "*"
+	}
+	return timestamp
+}
+
+// Add appends a sample to the time series at key with the TS.ADD command
+// and returns the timestamp of the sample. The time series is created if it
+// does not exist.
+func Add(c redis.Conn, key string, timestamp int64, value float64) (int64, error) {
+	return redis.Int64(c.Do("TS.ADD", key, timestampArg(timestamp), value))
+}
+
+// MAdd appends samples to one or more time series with the TS.MADD command
+// and returns the timestamp of each sample. The time series must exist. If
+// the server rejects a sample, then the timestamp of the sample is zero and
+// MAdd returns the error for the first rejected sample along with the
+// timestamps.
+func MAdd(c redis.Conn, samples ...KeySample) ([]int64, error) {
+	args := make(redis.Args, 0, 3*len(samples))
+	for _, s := range samples {
+		args = append(args, s.Key, timestampArg(s.Timestamp), s.Value)
+	}
+	values, err := redis.Values(c.Do("TS.MADD", args...))
+	if err != nil {
+		return nil, err
+	}
+	timestamps := make([]int64, len(values))
+	var firstErr error
+	for i, v := range values {
+		if e, ok := v.(redis.Error); ok {
+			if firstErr == nil {
+				firstErr = e
+			}
+			continue
+		}
+		if timestamps[i], err = redis.Int64(v, nil); err != nil {
+			return nil, err
+		}
+	}
+	return timestamps, firstErr
+}
+
+// Get returns the latest sample in the time series at key with the TS.GET
+// command. Get returns redis.ErrNil if the time series is empty.
+func Get(c redis.Conn, key string) (Sample, error) {
+	values, err := redis.Values(c.Do("TS.GET", key))
+	if err != nil {
+		return Sample{}, err
+	}
+	if len(values) == 0 {
+		return Sample{}, redis.ErrNil
+	}
+	return sample(values)
+}
+
+// AggregationType is the type of an aggregation.
+type AggregationType string
+
+// Aggregation types.
+const (
+	AggAvg   AggregationType = "AVG"
+	AggSum   AggregationType = "SUM"
+	AggMin   AggregationType = "MIN"
+	AggMax   AggregationType = "MAX"
+	AggRange AggregationType = "RANGE"
+	AggCount AggregationType = "COUNT"
+	AggFirst AggregationType = "FIRST"
+	AggLast  AggregationType = "LAST"
+	AggStdP  AggregationType = "STD.P"
+	AggStdS  AggregationType = "STD.S"
+	AggVarP  AggregationType = "VAR.P"
+	AggVarS  AggregationType = "VAR.S"
+	AggTWA   AggregationType = "TWA"
+)
+
+// Aggregation aggregates the samples in time buckets.
+type Aggregation struct {
+	Type AggregationType
+
+	// Bucket is the duration of each time bucket.
+	Bucket time.Duration
+
+	// EmptyBuckets specifies that empty buckets are reported.
+	EmptyBuckets bool
+}
+
+// RangeOptions specifies the options for querying a range of samples.
+type RangeOptions struct {
+	// Reverse specifies that the samples are returned newest first.
+	Reverse bool
+
+	// Latest specifies that the latest, possibly partial, bucket of a
+	// compaction is reported.
+	Latest bool
+
+	// FilterByTimestamps limits the samples to the given timestamps.
+	FilterByTimestamps []int64
+
+	// FilterByValue limits the samples to values between the minimum and
+	// maximum inclusive.
+	FilterByValue *[2]float64
+
+	// Count is the maximum number of samples returned. All samples are
+	// returned if Count is zero.
+	Count int
+
+	Aggregation *Aggregation
+}
+
+func (o RangeOptions) args(args redis.Args) redis.Args {
+	if o.Latest {
+		args = append(args, "LATEST")
+	}
+	if len(o.FilterByTimestamps) > 0 {
+		args = append(args, "FILTER_BY_TS").AddFlat(o.FilterByTimestamps)
+	}
+	if o.FilterByValue != nil {
+		args = append(args, "FILTER_BY_VALUE", o.FilterByValue[0], o.FilterByValue[1])
+	}
+	if o.Count > 0 {
+		args = append(args, "COUNT", o.Count)
+	}
+	if a := o.Aggregation; a != nil {
+		args = append(args, "AGGREGATION", string(a.Type), int64(a.Bucket/time.Millisecond))
+		if a.EmptyBuckets {
+			args = append(args, "EMPTY")
+		}
+	}
+	return args
+}
+
+func (o RangeOptions) command(multi bool) string {
+	switch {
+	case multi && o.Reverse:
+		return "TS.MREVRANGE"
+	case multi:
+		return "TS.MRANGE"
+	case o.Reverse:
+		return "TS.REVRANGE"
+	}
+	return "TS.RANGE"
+}
+
+// Range returns the samples in the time series at key with timestamps
+// between from and to inclusive using the TS.RANGE or TS.REVRANGE command.
+// The from and to arguments are timestamps or "-" and "+" for the earliest
+// and latest samples.
+func Range(c redis.Conn, key string, from, to interface{}, opts RangeOptions) ([]Sample, error) {
+	args := opts.args(redis.Args{key, from, to})
+	return Samples(c.Do(opts.command(false), args...))
+}
+
+// MRangeOptions specifies the options for querying a range of samples from
+// multiple time series.
+type MRangeOptions struct {
+	RangeOptions
+
+	// WithLabels specifies that all labels of each series are returned.
+	WithLabels bool
+
+	// SelectedLabels is the list of labels returned for each series. It is
+	// ignored when WithLabels is true.
+	SelectedLabels []string
+
+	// GroupBy and Reduce specify that series with the same value of the
+	// label GroupBy are aggregated with the reducer Reduce.
+	GroupBy string
+	Reduce  AggregationType
+}
+
+// MRange returns the samples in the time series matching the filters with
+// timestamps between from and to inclusive using the TS.MRANGE or
+// TS.MREVRANGE command. Filters have the form label=value, label!=value,
+// label=(value1,value2) and so on.
+func MRange(c redis.Conn, from, to interface{}, filters []string, opts MRangeOptions) ([]Series, error) {
+	args := opts.RangeOptions.args(redis.Args{from, to})
+	if opts.WithLabels {
+		args = append(args, "WITHLABELS")
+	} else if len(opts.SelectedLabels) > 0 {
+		args = append(args, "SELECTED_LABELS").AddFlat(opts.SelectedLabels)
+	}
+	args = append(args, "FILTER").AddFlat(filters)
+	if opts.GroupBy != "" {
+		args = append(args, "GROUPBY", opts.GroupBy, "REDUCE", string(opts.Reduce))
+	}
+	return SeriesReply(c.Do(opts.command(true), args...))
+}
+
+// QueryIndex returns the keys of the time series matching the filters with
+// the TS.QUERYINDEX command.
+func QueryIndex(c redis.Conn, filters ...string) ([]string, error) {
+	return redis.Strings(c.Do("TS.QUERYINDEX", redis.Args{}.AddFlat(filters)...))
+}
+
+// Samples is a helper that converts an array of samples to a slice of
+// Sample.
+func Samples(reply interface{}, err error) ([]Sample, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]Sample, len(values))
+	for i, v := range values {
+		fields, err := redis.Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if samples[i], err = sample(fields); err != nil {
+			return nil, err
+		}
+	}
+	return samples, nil
+}
+
+func sample(fields []interface{}) (Sample, error) {
+	if len(fields) != 2 {
+		return Sample{}, fmt.Errorf("redigo: sample expects two elements, got %d", len(fields))
+	}
+	var (
+		s   Sample
+		err error
+	)
+	if s.Timestamp, err = redis.Int64(fields[0], nil); err != nil {
+		return s, err
+	}
+	if f, ok := fields[1].(float64); ok {
+		s.Value = f
+		return s, nil
+	}
+	v, err := redis.String(fields[1], nil)
+	if err != nil {
+		return s, err
+	}
+	s.Value, err = strconv.ParseFloat(v, 64)
+	return s, err
+}
+
+// SeriesReply is a helper that converts the reply to TS.MRANGE or
+// TS.MREVRANGE to a slice of Series.
+func SeriesReply(reply interface{}, err error) ([]Series, error) {
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	series := make([]Series, len(values))
+	for i, v := range values {
+		fields, err := redis.Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("redigo: series expects three elements, got %d", len(fields))
+		}
+		s := &series[i]
+		if s.Key, err = redis.String(fields[0], nil); err != nil {
+			return nil, err
+		}
+		labels, err := redis.Values(fields[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(labels) > 0 {
+			s.Labels = make(map[string]string, len(labels))
+		}
+		for _, l := range labels {
+			pair, err := redis.Values(l, nil)
+			if err != nil {
+				return nil, err
+			}
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("redigo: label expects two elements, got %d", len(pair))
+			}
+			name, err := redis.String(pair[0], nil)
+			if err != nil {
+				return nil, err
+			}
+			// The value of a selected label that the series does not
+			// have is nil.
+			value, err := redis.String(pair[1], nil)
+			if err != nil && err != redis.ErrNil {
+				return nil, err
+			}
+			s.Labels[name] = value
+		}
+		if s.Samples, err = Samples(fields[2], nil); err != nil {
+			return nil, err
+		}
+	}
+	return series, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redistimeseries_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
+	"github.com/garyburd/redigo/redistimeseries"
+)
+
+func TestTimeSeries(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("TS.CREATE", "t", "RETENTION", 3600000, "DUPLICATE_POLICY", "LAST", "LABELS", "a", "1", "b", "2").Reply("OK")
+	c.Expect("TS.ADD", "t", "*", 1.5).Reply(int64(1000))
+	c.Expect("TS.MADD", "t", 2000, 2.5, "u", 2000, 3).Reply([]interface{}{int64(2000), redis.Error("ERR TSDB: the key does not exist")})
+	c.Expect("TS.GET", "t").Reply([]interface{}{int64(2000), "2.5"})
+	c.Expect("TS.GET", "empty").Reply([]interface{}{})
+	c.Expect("TS.REVRANGE", "t", "-", "+", "COUNT", 10, "AGGREGATION", "AVG", 60000, "EMPTY").Reply([]interface{}{
+		[]interface{}{int64(2000), "2.5"},
+		[]interface{}{int64(1000), "1.5"},
+	})
+	c.Expect("TS.MRANGE", 0, "+", "SELECTED_LABELS", "a", "c", "FILTER", "a=1", "GROUPBY", "a", "REDUCE", "SUM").Reply([]interface{}{
+		[]interface{}{
+			[]byte("a=1"),
+			[]interface{}{[]interface{}{[]byte("a"), []byte("1")}, []interface{}{[]byte("c"), nil}},
+			[]interface{}{[]interface{}{int64(1000), "4"}},
+		},
+	})
+	c.Expect("TS.QUERYINDEX", "a=1", "b!=3").Reply([]interface{}{[]byte("t")})
+
+	err := redistimeseries.Create(c, "t", redistimeseries.CreateOptions{
+		Retention:       time.Hour,
+		DuplicatePolicy: "LAST",
+		Labels:          map[string]string{"b": "2", "a": "1"},
+	})
+	if err != nil {
+		t.Errorf("Create() returned %v", err)
+	}
+	if ts, err := redistimeseries.Add(c, "t", redistimeseries.AutoTimestamp, 1.5); ts != 1000 || err != nil {
+		t.Errorf("Add() = %d, %v, want 1000, nil", ts, err)
+	}
+	timestamps, err := redistimeseries.MAdd(c,
+		redistimeseries.KeySample{Key: "t", Sample: redistimeseries.Sample{Timestamp: 2000, Value: 2.5}},
+		redistimeseries.KeySample{Key: "u", Sample: redistimeseries.Sample{Timestamp: 2000, Value: 3}})
+	if !reflect.DeepEqual(timestamps, []int64{2000, 0}) || err == nil {
+		t.Errorf("MAdd() = %v, %v, want [2000 0], error", timestamps, err)
+	}
+
+	if s, err := redistimeseries.Get(c, "t"); s != (redistimeseries.Sample{Timestamp: 2000, Value: 2.5}) || err != nil {
+		t.Errorf("Get() = %v, %v", s, err)
+	}
+	if _, err := redistimeseries.Get(c, "empty"); err != redis.ErrNil {
+		t.Errorf("Get(empty) returned %v, want ErrNil", err)
+	}
+
+	samples, err := redistimeseries.Range(c, "t", "-", "+", redistimeseries.RangeOptions{
+		Reverse:     true,
+		Count:       10,
+		Aggregation: &redistimeseries.Aggregation{Type: redistimeseries.AggAvg, Bucket: time.Minute, EmptyBuckets: true},
+	})
+	if expected := []redistimeseries.Sample{{2000, 2.5}, {1000, 1.5}}; err != nil || !reflect.DeepEqual(samples, expected) {
+		t.Errorf("Range() = %v, %v, want %v", samples, err, expected)
+	}
+
+	series, err := redistimeseries.MRange(c, 0, "+", []string{"a=1"}, redistimeseries.MRangeOptions{
+		SelectedLabels: []string{"a", "c"},
+		GroupBy:        "a",
+		Reduce:         redistimeseries.AggSum,
+	})
+	expected := []redistimeseries.Series{{
+		Key:     "a=1",
+		Labels:  map[string]string{"a": "1", "c": ""},
+		Samples: []redistimeseries.Sample{{1000, 4}},
+	}}
+	if err != nil || !reflect.DeepEqual(series, expected) {
+		t.Errorf("MRange() = %v, %v, want %v", series, err, expected)
+	}
+
+	if keys, err := redistimeseries.QueryIndex(c, "a=1", "b!=3"); err != nil || !reflect.DeepEqual(keys, []string{"t"}) {
+		t.Errorf("QueryIndex() = %v, %v, want [t]", keys, err)
+	}
+}
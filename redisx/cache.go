@@ -0,0 +1,164 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// ErrNotFound is returned by a Cache loader to report that the value does
+// not exist. GetOrLoad returns ErrNotFound for the value until the negative
+// cache entry expires. The Scheduler Cancel and Reschedule methods return
+// ErrNotFound for jobs that are not pending.
+var ErrNotFound = errors.New("redigo: not found")
+
+// Cache is a read-through cache of values stored in Redis.
+//
+// Concurrent calls to GetOrLoad for a key that is not cached share a single
+// call to the loader. The coalescing is per Cache value; callers in other
+// processes can load the same key concurrently.
+//
+// Negative cache entries are stored as an empty string. The codec must not
+// encode values as empty data.
+type Cache struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Codec encodes the values. JSONCodec is used if Codec is nil.
+	// Msgpack and other formats are supported by implementing Codec.
+	Codec Codec
+
+	// NegativeTTL is the time to cache ErrNotFound returned from a loader.
+	// If NegativeTTL is zero, then ErrNotFound is not cached.
+	NegativeTTL time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*cacheCall
+}
+
+// cacheCall is an in-flight call to a loader.
+type cacheCall struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+func (c *Cache) codec() Codec {
+	if c.Codec == nil {
+		return JSONCodec
+	}
+	return c.Codec
+}
+
+// GetOrLoad decodes the value stored at key to the value pointed to by v.
+// If the key does not exist, then GetOrLoad calls loader to get the value,
+// stores the value at key with expiration ttl and decodes the value to v.
+//
+// The loader is called with the context of the first caller. Other callers
+// waiting for the loader return the context's error when their context is
+// done.
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, v interface{}, loader func(ctx context.Context) (interface{}, error)) error {
+	conn := c.Pool.Get()
+	data, err := redis.Bytes(redis.DoContext(conn, ctx, "GET", key))
+	conn.Close()
+	switch {
+	case err == redis.ErrNil:
+		data, err = c.load(ctx, key, ttl, loader)
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case len(data) == 0:
+		return ErrNotFound
+	}
+	return c.codec().Unmarshal(data, v)
+}
+
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) ([]byte, error) {
+	c.mu.Lock()
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.data, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	if c.calls == nil {
+		c.calls = make(map[string]*cacheCall)
+	}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	call.data, call.err = c.loadAndStore(ctx, key, ttl, loader)
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.mu.Unlock()
+	close(call.done)
+	return call.data, call.err
+}
+
+func (c *Cache) loadAndStore(ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (interface{}, error)) ([]byte, error) {
+	value, err := loader(ctx)
+	if err == ErrNotFound {
+		if c.NegativeTTL > 0 {
+			if err := c.set(ctx, key, []byte{}, c.NegativeTTL); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.codec().Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.set(ctx, key, data, ttl); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *Cache) set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+	args := []interface{}{key, data}
+	if ttl > 0 {
+		args = append(args, "PX", int64(ttl/time.Millisecond))
+	}
+	_, err := redis.DoContext(conn, ctx, "SET", args...)
+	return err
+}
+
+// Delete removes the value stored at key.
+func (c *Cache) Delete(key string) error {
+	conn := c.Pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("DEL", key)
+	return err
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+type cachedUser struct {
+	Name string
+	Age  int
+}
+
+func TestCache(t *testing.T) {
+	s, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	p := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", s.Addr()) }, MaxIdle: 10}
+	defer p.Close()
+
+	for _, codec := range []redisx.Codec{redisx.JSONCodec, redisx.GobCodec} {
+		c := &redisx.Cache{Pool: p, Codec: codec, NegativeTTL: time.Minute}
+		c.Delete("user")
+
+		var (
+			loads   int32
+			release = make(chan struct{})
+			wg      sync.WaitGroup
+		)
+		loader := func(ctx context.Context) (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			<-release
+			return cachedUser{Name: "gopher", Age: 10}, nil
+		}
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var u cachedUser
+				if err := c.GetOrLoad(context.Background(), "user", time.Minute, &u, loader); err != nil {
+					t.Errorf("GetOrLoad() returned %v", err)
+				} else if u.Name != "gopher" || u.Age != 10 {
+					t.Errorf("GetOrLoad() decoded %+v", u)
+				}
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		if n := atomic.LoadInt32(&loads); n != 1 {
+			t.Errorf("loader called %d times, want 1", n)
+		}
+
+		// The value is served from Redis without calling the loader.
+		var u cachedUser
+		if err := c.GetOrLoad(context.Background(), "user", time.Minute, &u, loader); err != nil || u.Name != "gopher" {
+			t.Errorf("GetOrLoad() = %+v, %v", u, err)
+		}
+		if n := atomic.LoadInt32(&loads); n != 1 {
+			t.Errorf("loader called %d times, want 1", n)
+		}
+	}
+
+	// ErrNotFound is cached.
+	c := &redisx.Cache{Pool: p, NegativeTTL: time.Minute}
+	missing := 0
+	loader := func(ctx context.Context) (interface{}, error) {
+		missing++
+		return nil, redisx.ErrNotFound
+	}
+	for i := 0; i < 2; i++ {
+		var u cachedUser
+		if err := c.GetOrLoad(context.Background(), "missing", time.Minute, &u, loader); err != redisx.ErrNotFound {
+			t.Errorf("GetOrLoad(missing) returned %v, want ErrNotFound", err)
+		}
+	}
+	if missing != 1 {
+		t.Errorf("loader for missing key called %d times, want 1", missing)
+	}
+}
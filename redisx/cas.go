@@ -0,0 +1,85 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx
+
+import (
+	"github.com/garyburd/redigo/redis"
+)
+
+// SetIfEquals sets key to value if the current value of key is expected.
+// SetIfEquals returns true if the key was set.
+func SetIfEquals(c redis.Conn, key, expected, value string) (bool, error) {
+	return compareAndDo(c, key, expected, "SET", key, value)
+}
+
+// DeleteIfEquals deletes key if the current value of key is expected.
+// DeleteIfEquals returns true if the key was deleted.
+func DeleteIfEquals(c redis.Conn, key, expected string) (bool, error) {
+	return compareAndDo(c, key, expected, "DEL", key)
+}
+
+// compareAndDo executes a command in a transaction if the value of key is
+// expected.
+func compareAndDo(c redis.Conn, key, expected string, commandName string, args ...interface{}) (bool, error) {
+	done := false
+	err := redis.WatchDo(c, func(c redis.Conn) error {
+		v, err := redis.String(c.Do("GET", key))
+		if err == redis.ErrNil || (err == nil && v != expected) {
+			c.Do("UNWATCH")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		tx := redis.NewTx(c)
+		r := tx.Do(commandName, args...)
+		if err := tx.Exec(); err != nil {
+			return err
+		}
+		done = true
+		return r.Err
+	}, key)
+	return done, err
+}
+
+// IncrWithCeiling increments the integer value of key by delta if the
+// result does not exceed ceiling. A missing key has the value zero.
+// IncrWithCeiling returns the value of the key and true if the key was
+// incremented.
+func IncrWithCeiling(c redis.Conn, key string, delta, ceiling int64) (int64, bool, error) {
+	var (
+		result int64
+		done   bool
+	)
+	err := redis.WatchDo(c, func(c redis.Conn) error {
+		n, err := redis.Int64(c.Do("GET", key))
+		if err != nil && err != redis.ErrNil {
+			return err
+		}
+		if n+delta > ceiling {
+			c.Do("UNWATCH")
+			result, done = n, false
+			return nil
+		}
+		tx := redis.NewTx(c)
+		r := tx.Int64("INCRBY", key, delta)
+		if err := tx.Exec(); err != nil {
+			return err
+		}
+		result, done = r.Value, r.Err == nil
+		return r.Err
+	}, key)
+	return result, done, err
+}
@@ -0,0 +1,58 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx_test
+
+import (
+	"testing"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestCompareAndSet(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	c.Do("SET", "k", "a")
+	if ok, err := redisx.SetIfEquals(c, "k", "x", "b"); err != nil || ok {
+		t.Errorf("SetIfEquals(x) = %v, %v, want false, nil", ok, err)
+	}
+	if ok, err := redisx.SetIfEquals(c, "k", "a", "b"); err != nil || !ok {
+		t.Errorf("SetIfEquals(a) = %v, %v, want true, nil", ok, err)
+	}
+	if v, _ := redis.String(c.Do("GET", "k")); v != "b" {
+		t.Errorf("k = %q, want b", v)
+	}
+	if ok, err := redisx.SetIfEquals(c, "missing", "", "b"); err != nil || ok {
+		t.Errorf("SetIfEquals(missing) = %v, %v, want false, nil", ok, err)
+	}
+	if ok, err := redisx.DeleteIfEquals(c, "k", "b"); err != nil || !ok {
+		t.Errorf("DeleteIfEquals(b) = %v, %v, want true, nil", ok, err)
+	}
+
+	for i, want := range []struct {
+		n  int64
+		ok bool
+	}{{2, true}, {4, true}, {4, false}} {
+		n, ok, err := redisx.IncrWithCeiling(c, "n", 2, 5)
+		if err != nil || n != want.n || ok != want.ok {
+			t.Errorf("%d: IncrWithCeiling() = %d, %v, %v, want %d, %v, nil", i, n, ok, err, want.n, want.ok)
+		}
+	}
+}
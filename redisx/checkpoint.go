@@ -0,0 +1,101 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx
+
+import (
+	"errors"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// ErrFenced is returned by Checkpoint.Save when another owner acquired the
+// checkpoint.
+var ErrFenced = errors.New("redigo: checkpoint acquired by another owner")
+
+// Checkpoint stores the ID of the last stream entry processed by a consumer
+// in a hash. Each call to AcquireCheckpoint increments a fencing token in
+// the hash. Save fails with ErrFenced after another owner acquires the
+// checkpoint, so a stalled consumer cannot overwrite the progress of its
+// replacement.
+//
+// A consumer that saves the ID after processing an entry gets at-least-once
+// processing. Sinks outside of Redis can store the token with their writes
+// and reject writes with an older token.
+type Checkpoint struct {
+	key   string
+	token int64
+	id    string
+}
+
+// AcquireCheckpoint acquires the checkpoint stored at key.
+func AcquireCheckpoint(c redis.Conn, key string) (*Checkpoint, error) {
+	c.Send("MULTI")
+	c.Send("HINCRBY", key, "token", 1)
+	c.Send("HGET", key, "id")
+	values, err := redis.Values(c.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{key: key, id: "0"}
+	if _, err := redis.Scan(values, &cp.token, &cp.id); err != nil {
+		return nil, err
+	}
+	if cp.id == "" {
+		cp.id = "0"
+	}
+	return cp, nil
+}
+
+// ID returns the last saved ID. ID returns "0" if no ID was saved. The ID
+// can be passed to XREAD to continue reading after the last processed entry.
+func (cp *Checkpoint) ID() string {
+	return cp.id
+}
+
+// Token returns the fencing token for this owner of the checkpoint.
+func (cp *Checkpoint) Token() int64 {
+	return cp.token
+}
+
+// Save stores id as the last processed ID if the checkpoint was not
+// acquired by another owner.
+func (cp *Checkpoint) Save(c redis.Conn, id string) error {
+	for {
+		if _, err := c.Do("WATCH", cp.key); err != nil {
+			return err
+		}
+		token, err := redis.Int64(c.Do("HGET", cp.key, "token"))
+		if err != nil && err != redis.ErrNil {
+			c.Do("UNWATCH")
+			return err
+		}
+		if token != cp.token {
+			c.Do("UNWATCH")
+			return ErrFenced
+		}
+		c.Send("MULTI")
+		c.Send("HSET", cp.key, "id", id)
+		_, err = redis.Values(c.Do("EXEC"))
+		if err == redis.ErrNil {
+			// The hash changed after WATCH. Check the token again.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		cp.id = id
+		return nil
+	}
+}
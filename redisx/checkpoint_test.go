@@ -0,0 +1,58 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx_test
+
+import (
+	"testing"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestCheckpoint(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	cp1, err := redisx.AcquireCheckpoint(c, "cp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp1.ID() != "0" || cp1.Token() != 1 {
+		t.Errorf("ID, Token = %q, %d, want 0, 1", cp1.ID(), cp1.Token())
+	}
+	if err := cp1.Save(c, "1-0"); err != nil {
+		t.Fatal(err)
+	}
+
+	cp2, err := redisx.AcquireCheckpoint(c, "cp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp2.ID() != "1-0" || cp2.Token() != 2 {
+		t.Errorf("ID, Token = %q, %d, want 1-0, 2", cp2.ID(), cp2.Token())
+	}
+	if err := cp1.Save(c, "2-0"); err != redisx.ErrFenced {
+		t.Errorf("Save() by fenced owner returned %v, want %v", err, redisx.ErrFenced)
+	}
+	if err := cp2.Save(c, "3-0"); err != nil {
+		t.Fatal(err)
+	}
+	if cp2.ID() != "3-0" {
+		t.Errorf("ID() = %q, want 3-0", cp2.ID())
+	}
+}
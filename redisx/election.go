@@ -0,0 +1,220 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errElectionConfig = errors.New("redigo: Election requires Pool and Key")
+
+// Election elects a single leader from a set of candidates. Each candidate
+// runs an Election with the same Key and a unique ID. The leader stores its
+// ID at Key with expiration TTL and renews the expiration at one third of
+// the TTL. When the leader stops renewing, another candidate is elected
+// after the key expires.
+//
+// A leader that cannot renew its term before the TTL elapses steps down, so
+// two candidates do not lead at the same time unless the clocks of the
+// candidates and server drift by more than the renewal interval.
+type Election struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Key is the key of the election.
+	Key string
+
+	// ID identifies the candidate. The ID of the leader is returned by the
+	// Leader method. If ID is empty, then a random ID is used.
+	ID string
+
+	// TTL is the time after which the leadership expires if it is not
+	// renewed. The default is ten seconds.
+	TTL time.Duration
+
+	// OnElected is called when the candidate becomes the leader. The
+	// context is canceled when the candidate loses the leadership. OnElected
+	// is called from the goroutine executing Run and must start long
+	// running work in a new goroutine.
+	OnElected func(ctx context.Context)
+
+	// OnLost is called when the candidate loses the leadership or steps
+	// down because the context passed to Run is done.
+	OnLost func()
+
+	// OnLeaderChange is called with the ID of the leader when a change of
+	// leader is observed. The ID is empty when there is no leader.
+	OnLeaderChange func(leader string)
+
+	// ErrorHandler is an optional function called with errors from the
+	// server.
+	ErrorHandler func(err error)
+
+	mu     sync.Mutex
+	leader bool
+}
+
+// IsLeader returns true if the candidate is the leader.
+func (e *Election) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Leader returns the ID of the current leader or "" if there is no leader.
+func (e *Election) Leader() (string, error) {
+	c := e.Pool.Get()
+	defer c.Close()
+	leader, err := redis.String(c.Do("GET", e.Key))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return leader, err
+}
+
+func (e *Election) setLeader(leader bool) {
+	e.mu.Lock()
+	e.leader = leader
+	e.mu.Unlock()
+}
+
+func (e *Election) handleError(err error) {
+	if e.ErrorHandler != nil {
+		e.ErrorHandler(err)
+	}
+}
+
+// Run campaigns for the leadership until the context is done. If the
+// candidate is the leader when the context is done, then Run resigns the
+// leadership so that another candidate can be elected without waiting for
+// the TTL. Run returns the context's error or an error for invalid
+// configuration.
+func (e *Election) Run(ctx context.Context) error {
+	if e.Pool == nil || e.Key == "" {
+		return errElectionConfig
+	}
+	id := e.ID
+	if id == "" {
+		var err error
+		if id, err = randomToken(); err != nil {
+			return err
+		}
+	}
+	ttl := e.TTL
+	if ttl <= 0 {
+		ttl = 10 * time.Second
+	}
+
+	var (
+		observed  string
+		renewed   time.Time
+		cancelRun = func() {}
+	)
+	lose := func() {
+		e.setLeader(false)
+		cancelRun()
+		if e.OnLost != nil {
+			e.OnLost()
+		}
+	}
+	observe := func(leader string) {
+		if leader != observed {
+			observed = leader
+			if e.OnLeaderChange != nil {
+				e.OnLeaderChange(leader)
+			}
+		}
+	}
+
+	for {
+		start := time.Now()
+		if e.IsLeader() {
+			ok, err := e.renew(id, ttl)
+			switch {
+			case ok:
+				renewed = start
+			case err == nil || time.Since(renewed)+ttl/3 >= ttl:
+				// The term is not renewed or can expire before the next
+				// attempt to renew.
+				if err != nil {
+					e.handleError(err)
+				}
+				lose()
+				observe("")
+			default:
+				e.handleError(err)
+			}
+		} else {
+			ok, err := e.campaign(id, ttl)
+			switch {
+			case err != nil:
+				e.handleError(err)
+			case ok:
+				renewed = start
+				e.setLeader(true)
+				runCtx, cancel := context.WithCancel(ctx)
+				cancelRun = cancel
+				if e.OnElected != nil {
+					e.OnElected(runCtx)
+				}
+				observe(id)
+			default:
+				if leader, err := e.Leader(); err != nil {
+					e.handleError(err)
+				} else {
+					observe(leader)
+				}
+			}
+		}
+
+		if !sleepContext(ctx, ttl/3) {
+			if e.IsLeader() {
+				c := e.Pool.Get()
+				_, err := DeleteIfEquals(c, e.Key, id)
+				c.Close()
+				if err != nil {
+					e.handleError(err)
+				}
+				lose()
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// campaign attempts to become the leader.
+func (e *Election) campaign(id string, ttl time.Duration) (bool, error) {
+	c := e.Pool.Get()
+	defer c.Close()
+	_, err := redis.String(c.Do("SET", e.Key, id, "NX", "PX", int64(ttl/time.Millisecond)))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// renew extends the term of the leader.
+func (e *Election) renew(id string, ttl time.Duration) (bool, error) {
+	c := e.Pool.Get()
+	defer c.Close()
+	return compareAndDo(c, e.Key, id, "PEXPIRE", e.Key, int64(ttl/time.Millisecond))
+}
@@ -0,0 +1,111 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestElection(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 4}
+	defer p.Close()
+
+	type event struct {
+		candidate string
+		what      string
+	}
+	events := make(chan event, 20)
+	newElection := func(id string) *redisx.Election {
+		var elected context.Context
+		return &redisx.Election{
+			Pool: p,
+			Key:  "leader",
+			ID:   id,
+			TTL:  150 * time.Millisecond,
+			OnElected: func(ctx context.Context) {
+				elected = ctx
+				events <- event{id, "elected"}
+			},
+			OnLost: func() {
+				if elected.Err() == nil {
+					t.Errorf("%s: context not canceled before OnLost", id)
+				}
+				events <- event{id, "lost"}
+			},
+			OnLeaderChange: func(leader string) {
+				events <- event{id, "leader " + leader}
+			},
+			ErrorHandler: func(err error) { t.Errorf("%s: %v", id, err) },
+		}
+	}
+	expect := func(expected ...event) {
+		for _, e := range expected {
+			select {
+			case actual := <-events:
+				if actual != e {
+					t.Fatalf("event = %v, want %v", actual, e)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("timeout waiting for %v", e)
+			}
+		}
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	e1 := newElection("e1")
+	done1 := make(chan error, 1)
+	go func() { done1 <- e1.Run(ctx1) }()
+	expect(event{"e1", "elected"}, event{"e1", "leader e1"})
+	if !e1.IsLeader() {
+		t.Error("e1.IsLeader() = false, want true")
+	}
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	e2 := newElection("e2")
+	done2 := make(chan error, 1)
+	go func() { done2 <- e2.Run(ctx2) }()
+	expect(event{"e2", "leader e1"})
+
+	// The leader remains elected for longer than the TTL.
+	time.Sleep(300 * time.Millisecond)
+	if leader, err := e2.Leader(); leader != "e1" || err != nil {
+		t.Errorf("Leader() = %q, %v, want e1, nil", leader, err)
+	}
+
+	// The leader resigns when the context is done.
+	cancel1()
+	if err := <-done1; err != context.Canceled {
+		t.Errorf("e1.Run() returned %v, want context.Canceled", err)
+	}
+	expect(event{"e1", "lost"}, event{"e2", "elected"}, event{"e2", "leader e2"})
+
+	cancel2()
+	<-done2
+	expect(event{"e2", "lost"})
+}
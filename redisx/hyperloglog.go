@@ -0,0 +1,192 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errHyperLogLogShards = errors.New("redigo: HyperLogLogs have different number of shards")
+
+// HyperLogLog estimates the number of distinct elements added to a set
+// using the PFADD, PFCOUNT and PFMERGE commands.
+//
+// A sharded HyperLogLog distributes the elements over Shards keys by a hash
+// of the element. Because each element is added to exactly one shard, the
+// estimate for the set is the sum of the estimates for the shards. In Redis
+// Cluster, the shards are stored on different nodes to spread the load of a
+// high volume stream of elements.
+type HyperLogLog struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Key is the key of the HyperLogLog. The shards are stored at Key +
+	// ":0", Key + ":1" and so on.
+	Key string
+
+	// Shards is the number of shards. The HyperLogLog is not sharded if
+	// Shards is less than two.
+	Shards int
+}
+
+func (h *HyperLogLog) shards() int {
+	if h.Shards < 2 {
+		return 1
+	}
+	return h.Shards
+}
+
+// shardKey returns the key of shard i.
+func (h *HyperLogLog) shardKey(i int) string {
+	if h.Shards < 2 {
+		return h.Key
+	}
+	return h.Key + ":" + strconv.Itoa(i)
+}
+
+func (h *HyperLogLog) shard(element string) int {
+	if h.Shards < 2 {
+		return 0
+	}
+	f := fnv.New32a()
+	f.Write([]byte(element))
+	return int(f.Sum32() % uint32(h.Shards))
+}
+
+// Add adds the elements to the HyperLogLog. Add returns true if the
+// estimated cardinality changed.
+func (h *HyperLogLog) Add(elements ...string) (bool, error) {
+	n := h.shards()
+	args := make([]redis.Args, n)
+	for _, e := range elements {
+		i := h.shard(e)
+		if args[i] == nil {
+			args[i] = redis.Args{h.shardKey(i)}
+		}
+		args[i] = append(args[i], e)
+	}
+
+	c := h.Pool.Get()
+	defer c.Close()
+	sent := 0
+	for _, a := range args {
+		if a != nil {
+			c.Send("PFADD", a...)
+			sent++
+		}
+	}
+	if sent == 0 {
+		return false, nil
+	}
+	if err := c.Flush(); err != nil {
+		return false, err
+	}
+	changed := false
+	for i := 0; i < sent; i++ {
+		ok, err := redis.Bool(c.Receive())
+		if err != nil {
+			return false, err
+		}
+		changed = changed || ok
+	}
+	return changed, nil
+}
+
+// Count returns the estimated number of distinct elements added to the
+// HyperLogLog.
+func (h *HyperLogLog) Count() (int64, error) {
+	return h.CountUnion()
+}
+
+// CountUnion returns the estimated number of distinct elements in the union
+// of h and others. The HyperLogLogs must have the same number of shards. In
+// Redis Cluster, the keys of corresponding shards must be stored in the same
+// hash slot.
+func (h *HyperLogLog) CountUnion(others ...*HyperLogLog) (int64, error) {
+	for _, o := range others {
+		if o.shards() != h.shards() {
+			return 0, errHyperLogLogShards
+		}
+	}
+	c := h.Pool.Get()
+	defer c.Close()
+	n := h.shards()
+	for i := 0; i < n; i++ {
+		args := redis.Args{h.shardKey(i)}
+		for _, o := range others {
+			args = append(args, o.shardKey(i))
+		}
+		c.Send("PFCOUNT", args...)
+	}
+	if err := c.Flush(); err != nil {
+		return 0, err
+	}
+	var total int64
+	for i := 0; i < n; i++ {
+		count, err := redis.Int64(c.Receive())
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// Merge merges the sources into h with PFMERGE. The HyperLogLogs must have
+// the same number of shards. In Redis Cluster, the keys of corresponding
+// shards must be stored in the same hash slot.
+func (h *HyperLogLog) Merge(sources ...*HyperLogLog) error {
+	for _, s := range sources {
+		if s.shards() != h.shards() {
+			return errHyperLogLogShards
+		}
+	}
+	c := h.Pool.Get()
+	defer c.Close()
+	n := h.shards()
+	for i := 0; i < n; i++ {
+		args := redis.Args{h.shardKey(i)}
+		for _, s := range sources {
+			args = append(args, s.shardKey(i))
+		}
+		c.Send("PFMERGE", args...)
+	}
+	if err := c.Flush(); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if _, err := c.Receive(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete deletes the keys of the HyperLogLog.
+func (h *HyperLogLog) Delete() error {
+	n := h.shards()
+	args := make(redis.Args, n)
+	for i := range args {
+		args[i] = h.shardKey(i)
+	}
+	c := h.Pool.Get()
+	defer c.Close()
+	_, err := c.Do("DEL", args...)
+	return err
+}
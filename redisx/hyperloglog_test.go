@@ -0,0 +1,79 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 1}
+	defer p.Close()
+
+	for _, shards := range []int{0, 4} {
+		a := &redisx.HyperLogLog{Pool: p, Key: "a", Shards: shards}
+		b := &redisx.HyperLogLog{Pool: p, Key: "b", Shards: shards}
+
+		var elements []string
+		for i := 0; i < 20; i++ {
+			elements = append(elements, strconv.Itoa(i))
+		}
+		if changed, err := a.Add(elements[:15]...); !changed || err != nil {
+			t.Errorf("shards=%d: a.Add() = %v, %v, want true, nil", shards, changed, err)
+		}
+		if changed, err := a.Add(elements[:5]...); changed || err != nil {
+			t.Errorf("shards=%d: a.Add(existing) = %v, %v, want false, nil", shards, changed, err)
+		}
+		if _, err := b.Add(elements[10:]...); err != nil {
+			t.Errorf("shards=%d: b.Add() returned %v", shards, err)
+		}
+
+		if n, err := a.Count(); n != 15 || err != nil {
+			t.Errorf("shards=%d: a.Count() = %d, %v, want 15, nil", shards, n, err)
+		}
+		if n, err := a.CountUnion(b); n != 20 || err != nil {
+			t.Errorf("shards=%d: a.CountUnion(b) = %d, %v, want 20, nil", shards, n, err)
+		}
+		if err := a.Merge(b); err != nil {
+			t.Errorf("shards=%d: a.Merge(b) returned %v", shards, err)
+		}
+		if n, err := a.Count(); n != 20 || err != nil {
+			t.Errorf("shards=%d: a.Count() after merge = %d, %v, want 20, nil", shards, n, err)
+		}
+		if err := a.Delete(); err != nil {
+			t.Errorf("shards=%d: a.Delete() returned %v", shards, err)
+		}
+		if n, err := a.Count(); n != 0 || err != nil {
+			t.Errorf("shards=%d: a.Count() after delete = %d, %v, want 0, nil", shards, n, err)
+		}
+		b.Delete()
+	}
+
+	other := &redisx.HyperLogLog{Pool: p, Key: "o", Shards: 2}
+	if _, err := (&redisx.HyperLogLog{Pool: p, Key: "a"}).CountUnion(other); err == nil {
+		t.Error("CountUnion() with different shards did not return error")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// KeyspaceEvent represents a keyspace or keyevent notification. See
+// http://redis.io/topics/notifications for information on notifications in
+// Redis.
+type KeyspaceEvent struct {
+
+	// The database of the key.
+	DB int
+
+	// The key that was modified.
+	Key string
+
+	// The event name. Examples are "set", "del" and "expired".
+	Event string
+}
+
+const (
+	keyspacePrefix = "__keyspace@"
+	keyeventPrefix = "__keyevent@"
+)
+
+// EnableKeyspaceEvents sets the notify-keyspace-events server configuration
+// to flags. For example, the flags "Ex" enable keyevent notifications for
+// expired keys.
+func EnableKeyspaceEvents(c redis.Conn, flags string) error {
+	_, err := c.Do("CONFIG", "SET", "notify-keyspace-events", flags)
+	return err
+}
+
+// KeyspacePattern returns the pattern for subscribing to keyspace
+// notifications for keys matching keyPattern in database db. Use a negative
+// db to match all databases.
+func KeyspacePattern(db int, keyPattern string) string {
+	return keyspacePrefix + dbPattern(db) + "__:" + keyPattern
+}
+
+// KeyeventPattern returns the pattern for subscribing to keyevent
+// notifications for events matching eventPattern in database db. Use a
+// negative db to match all databases.
+func KeyeventPattern(db int, eventPattern string) string {
+	return keyeventPrefix + dbPattern(db) + "__:" + eventPattern
+}
+
+func dbPattern(db int) string {
+	if db < 0 {
+		return "*"
+	}
+	return strconv.Itoa(db)
+}
+
+// ParseKeyspaceEvent converts a message received on a keyspace or keyevent
+// channel to a KeyspaceEvent. The boolean result is false if the message was
+// not sent to a keyspace or keyevent channel.
+func ParseKeyspaceEvent(m Message) (KeyspaceEvent, bool) {
+	var prefix string
+	switch {
+	case strings.HasPrefix(m.Channel, keyspacePrefix):
+		prefix = keyspacePrefix
+	case strings.HasPrefix(m.Channel, keyeventPrefix):
+		prefix = keyeventPrefix
+	default:
+		return KeyspaceEvent{}, false
+	}
+
+	rest := m.Channel[len(prefix):]
+	i := strings.Index(rest, "__:")
+	if i < 0 {
+		return KeyspaceEvent{}, false
+	}
+	db, err := strconv.Atoi(rest[:i])
+	if err != nil {
+		return KeyspaceEvent{}, false
+	}
+
+	e := KeyspaceEvent{DB: db}
+	if prefix == keyspacePrefix {
+		e.Key = rest[i+3:]
+		e.Event = string(m.Data)
+	} else {
+		e.Key = string(m.Data)
+		e.Event = rest[i+3:]
+	}
+	return e, true
+}
+
+// ReceiveKeyspaceEvents calls handler for each keyspace and keyevent
+// notification delivered by s. Other messages are ignored.
+// ReceiveKeyspaceEvents returns when the Subscriber is closed.
+func ReceiveKeyspaceEvents(s *Subscriber, handler func(KeyspaceEvent)) {
+	for m := range s.Messages() {
+		if e, ok := ParseKeyspaceEvent(m); ok {
+			handler(e)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+var parseKeyspaceEventTests = []struct {
+	m        redisx.Message
+	expected redisx.KeyspaceEvent
+	ok       bool
+}{
+	{
+		redisx.Message{Channel: "__keyspace@0__:foo", Data: []byte("set")},
+		redisx.KeyspaceEvent{DB: 0, Key: "foo", Event: "set"},
+		true,
+	},
+	{
+		redisx.Message{Channel: "__keyevent@9__:expired", Data: []byte("bar:1")},
+		redisx.KeyspaceEvent{DB: 9, Key: "bar:1", Event: "expired"},
+		true,
+	},
+	{
+		redisx.Message{Channel: "__keyspace@x__:foo", Data: []byte("set")},
+		redisx.KeyspaceEvent{},
+		false,
+	},
+	{
+		redisx.Message{Channel: "news", Data: []byte("hello")},
+		redisx.KeyspaceEvent{},
+		false,
+	},
+}
+
+func TestParseKeyspaceEvent(t *testing.T) {
+	for _, tt := range parseKeyspaceEventTests {
+		e, ok := redisx.ParseKeyspaceEvent(tt.m)
+		if e != tt.expected || ok != tt.ok {
+			t.Errorf("ParseKeyspaceEvent(%v) = %v, %v, want %v, %v", tt.m, e, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestKeyspacePatterns(t *testing.T) {
+	if p := redisx.KeyspacePattern(3, "user:*"); p != "__keyspace@3__:user:*" {
+		t.Errorf("KeyspacePattern(3, user:*) = %q", p)
+	}
+	if p := redisx.KeyeventPattern(-1, "expired"); p != "__keyevent@*__:expired" {
+		t.Errorf("KeyeventPattern(-1, expired) = %q", p)
+	}
+}
+
+func TestReceiveKeyspaceEvents(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	if err := redisx.EnableKeyspaceEvents(c, "E$"); err != nil {
+		t.Fatal(err)
+	}
+	defer redisx.EnableKeyspaceEvents(c, "")
+
+	s := redisx.NewSubscriber(&redisx.ResilientPubSub{Dial: dialPubSub})
+	events := make(chan redisx.KeyspaceEvent, 1)
+	done := make(chan struct{})
+	go func() {
+		redisx.ReceiveKeyspaceEvents(s, func(e redisx.KeyspaceEvent) { events <- e })
+		close(done)
+	}()
+
+	s.PSubscribe(redisx.KeyeventPattern(9, "set"))
+	for i := 0; i < 100; i++ {
+		n, _ := c.Do("PUBSUB", "NUMPAT")
+		if n, ok := n.(int64); ok && n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c.Do("SET", "foo", "bar")
+	select {
+	case e := <-events:
+		expected := redisx.KeyspaceEvent{DB: 9, Key: "foo", Event: "set"}
+		if e != expected {
+			t.Errorf("event = %v, want %v", e, expected)
+		}
+	case <-time.After(time.Second):
+		t.Error("timeout waiting for keyspace event")
+	}
+
+	s.Close()
+	<-done
+}
@@ -0,0 +1,173 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errGroupMonitorConfig = errors.New("redigo: GroupMonitor requires Pool and Streams")
+
+// GroupStats is a snapshot of the state of a stream consumer group.
+type GroupStats struct {
+	Stream string
+	Group  string
+
+	// The number of entries in the stream that are not yet delivered to
+	// the group. Lag is -1 when the server cannot compute the lag.
+	Lag int64
+
+	// The number of entries delivered to the group and not acknowledged.
+	Pending int64
+
+	LastDeliveredID string
+	Consumers       []redis.XInfoConsumer
+
+	// IdleConsumers is the number of consumers idle for at least the
+	// monitor's IdleThreshold.
+	IdleConsumers int
+}
+
+// GroupMonitor periodically collects statistics for the consumer groups of
+// a set of streams with the XINFO GROUPS and XINFO CONSUMERS commands.
+type GroupMonitor struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Streams is the list of stream keys to monitor. All groups of each
+	// stream are monitored.
+	Streams []string
+
+	// Interval is the time between polls. The default is ten seconds.
+	Interval time.Duration
+
+	// IdleThreshold is the idle time after which a consumer is counted in
+	// GroupStats.IdleConsumers. If IdleThreshold is zero, then consumers
+	// are not counted.
+	IdleThreshold time.Duration
+
+	// LagThreshold and OnLag specify an optional alert. If LagThreshold is
+	// greater than zero, then OnLag is called with exceeded set to true
+	// when the lag of a group reaches LagThreshold and with exceeded set to
+	// false when the lag drops below LagThreshold.
+	LagThreshold int64
+	OnLag        func(stats GroupStats, exceeded bool)
+
+	// ErrorHandler is an optional function called with errors from the
+	// server.
+	ErrorHandler func(err error)
+
+	mu       sync.Mutex
+	stats    []GroupStats
+	exceeded map[string]bool
+}
+
+// Stats returns the statistics collected by the most recent poll.
+func (m *GroupMonitor) Stats() []GroupStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]GroupStats(nil), m.stats...)
+}
+
+// Poll collects statistics for all groups, updates the snapshot returned by
+// Stats and calls OnLag for groups that crossed the lag threshold.
+func (m *GroupMonitor) Poll() ([]GroupStats, error) {
+	if m.Pool == nil || len(m.Streams) == 0 {
+		return nil, errGroupMonitorConfig
+	}
+	c := m.Pool.Get()
+	defer c.Close()
+
+	var stats []GroupStats
+	for _, stream := range m.Streams {
+		groups, err := redis.XInfoGroups(c.Do("XINFO", "GROUPS", stream))
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			s := GroupStats{
+				Stream:          stream,
+				Group:           g.Name,
+				Lag:             g.Lag,
+				Pending:         g.Pending,
+				LastDeliveredID: g.LastDeliveredID,
+			}
+			s.Consumers, err = redis.XInfoConsumers(c.Do("XINFO", "CONSUMERS", stream, g.Name))
+			if err != nil {
+				return nil, err
+			}
+			if m.IdleThreshold > 0 {
+				for _, consumer := range s.Consumers {
+					if consumer.Idle >= m.IdleThreshold {
+						s.IdleConsumers++
+					}
+				}
+			}
+			stats = append(stats, s)
+		}
+	}
+
+	m.mu.Lock()
+	m.stats = stats
+	var changed []GroupStats
+	if m.LagThreshold > 0 {
+		if m.exceeded == nil {
+			m.exceeded = make(map[string]bool)
+		}
+		for _, s := range stats {
+			key := s.Stream + "\x00" + s.Group
+			exceeded := s.Lag >= m.LagThreshold
+			if exceeded != m.exceeded[key] {
+				m.exceeded[key] = exceeded
+				changed = append(changed, s)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if m.OnLag != nil {
+		for _, s := range changed {
+			m.OnLag(s, s.Lag >= m.LagThreshold)
+		}
+	}
+	return stats, nil
+}
+
+// Run polls at the configured interval until the context is done. Run
+// returns the context's error or an error for invalid configuration. Errors
+// from the server are reported to ErrorHandler.
+func (m *GroupMonitor) Run(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	for {
+		if _, err := m.Poll(); err == errGroupMonitorConfig {
+			return err
+		} else if err != nil && m.ErrorHandler != nil {
+			m.ErrorHandler(err)
+		}
+		if !sleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestGroupMonitor(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Do("XADD", "s", "*", "n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Do("XGROUP", "CREATE", "s", "g", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do("XREADGROUP", "GROUP", "g", "c1", "COUNT", 1, "STREAMS", "s", ">"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	type alert struct {
+		lag      int64
+		exceeded bool
+	}
+	var alerts []alert
+	m := &redisx.GroupMonitor{
+		Pool:          p,
+		Streams:       []string{"s"},
+		IdleThreshold: 5 * time.Millisecond,
+		LagThreshold:  2,
+		OnLag: func(s redisx.GroupStats, exceeded bool) {
+			alerts = append(alerts, alert{s.Lag, exceeded})
+		},
+	}
+	stats, err := m.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("Poll() returned %d groups, want 1", len(stats))
+	}
+	s := stats[0]
+	if s.Group != "g" || s.Lag != 2 || s.Pending != 1 || len(s.Consumers) != 1 || s.IdleConsumers != 1 {
+		t.Errorf("Poll() = %+v", s)
+	}
+
+	// A second poll with the same lag does not alert again.
+	if _, err := m.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do("XREADGROUP", "GROUP", "g", "c1", "STREAMS", "s", ">"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []alert{{2, true}, {0, false}}
+	if len(alerts) != len(expected) || alerts[0] != expected[0] || alerts[1] != expected[1] {
+		t.Errorf("alerts = %v, want %v", alerts, expected)
+	}
+	if stats := m.Stats(); len(stats) != 1 || stats[0].Lag != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errListenerRunning = errors.New("redigo: listener already running")
+
+// Listener dispatches pub/sub messages to handler functions registered for
+// channels and patterns. The Run method manages the subscription connection,
+// reconnects and replays subscriptions after failures and calls the
+// handlers.
+//
+// Handlers can be registered and removed before or while Run is executing.
+// Handlers are called sequentially from the goroutine executing Run.
+type Listener struct {
+
+	// Dial is an application supplied function for creating and configuring
+	// a connection.
+	Dial func() (redis.Conn, error)
+
+	// PingInterval specifies the keepalive interval for the connection. See
+	// the ResilientPubSub PingInterval field for details.
+	PingInterval time.Duration
+
+	// BufferSize is the number of messages buffered between the connection
+	// and the handlers.
+	BufferSize int
+
+	// Overflow specifies how messages are handled when the buffer is full
+	// because the handlers do not keep up. The default is OverflowBlock.
+	Overflow OverflowPolicy
+
+	// ErrorHandler is an optional function called with receive and dial
+	// errors. ErrorHandler is called from the goroutine executing Run.
+	ErrorHandler func(err error)
+
+	// mu protects fields defined below.
+	mu       sync.Mutex
+	channels map[string]func(Message)
+	patterns map[string]func(Message)
+	s        *Subscriber
+}
+
+// Handle registers the handler for messages sent to channel. If handler is
+// nil, then the handler for channel is removed and the channel is
+// unsubscribed.
+func (l *Listener) Handle(channel string, handler func(Message)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.register(&l.channels, channel, handler, false)
+}
+
+// HandlePattern registers the handler for messages sent to channels matching
+// pattern. If handler is nil, then the handler for pattern is removed and the
+// pattern is unsubscribed.
+func (l *Listener) HandlePattern(pattern string, handler func(Message)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.register(&l.patterns, pattern, handler, true)
+}
+
+// register updates the handlers and the subscriptions of the running
+// Subscriber. The caller must hold l.mu.
+func (l *Listener) register(handlers *map[string]func(Message), name string, handler func(Message), pattern bool) error {
+	if handler == nil {
+		if _, ok := (*handlers)[name]; !ok {
+			return nil
+		}
+		delete(*handlers, name)
+		if l.s == nil {
+			return nil
+		}
+		if pattern {
+			return l.s.PUnsubscribe(name)
+		}
+		return l.s.Unsubscribe(name)
+	}
+
+	if *handlers == nil {
+		*handlers = make(map[string]func(Message))
+	}
+	_, ok := (*handlers)[name]
+	(*handlers)[name] = handler
+	if ok || l.s == nil {
+		return nil
+	}
+	if pattern {
+		return l.s.PSubscribe(name)
+	}
+	return l.s.Subscribe(name)
+}
+
+// Stats returns statistics for the current execution of Run. The zero value
+// is returned if Run is not executing.
+func (l *Listener) Stats() PubSubStats {
+	l.mu.Lock()
+	s := l.s
+	l.mu.Unlock()
+	if s == nil {
+		return PubSubStats{}
+	}
+	return s.Stats()
+}
+
+// Run receives messages and calls the registered handlers until the context
+// is done. Run closes the connection and returns the context's error when
+// the context is done.
+func (l *Listener) Run(ctx context.Context) error {
+	ps := &ResilientPubSub{Dial: l.Dial, PingInterval: l.PingInterval}
+
+	l.mu.Lock()
+	if l.s != nil {
+		l.mu.Unlock()
+		return errListenerRunning
+	}
+	for channel := range l.channels {
+		ps.Subscribe(channel)
+	}
+	for pattern := range l.patterns {
+		ps.PSubscribe(pattern)
+	}
+	s := NewSubscriber(ps, SubscriberBufferSize(l.BufferSize), SubscriberOverflow(l.Overflow))
+	l.s = s
+	l.mu.Unlock()
+
+	defer func() {
+		s.Close()
+		l.mu.Lock()
+		l.s = nil
+		l.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m := <-s.Messages():
+			l.dispatch(m)
+		case err := <-s.Errors():
+			if l.ErrorHandler != nil {
+				l.ErrorHandler(err)
+			}
+		}
+	}
+}
+
+// dispatch calls the handler for m.
+func (l *Listener) dispatch(m Message) {
+	l.mu.Lock()
+	var handler func(Message)
+	if m.Pattern != "" {
+		handler = l.patterns[m.Pattern]
+	} else {
+		handler = l.channels[m.Channel]
+	}
+	l.mu.Unlock()
+	if handler != nil {
+		handler(m)
+	}
+}
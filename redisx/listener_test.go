@@ -0,0 +1,79 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestListener(t *testing.T) {
+	pc, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	messages := make(chan redisx.Message, 10)
+	handler := func(m redisx.Message) { messages <- m }
+
+	l := &redisx.Listener{Dial: dialPubSub}
+	l.Handle("c1", handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Run(ctx) }()
+
+	waitSubscribers(t, pc, "c1", 1)
+	l.HandlePattern("p*", handler)
+	for i := 0; i < 100; i++ {
+		n, _ := pc.Do("PUBSUB", "NUMPAT")
+		if n, ok := n.(int64); ok && n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	pc.Do("PUBLISH", "c1", "hello")
+	pc.Do("PUBLISH", "p1", "world")
+	for _, expected := range []redisx.Message{
+		{Channel: "c1", Data: []byte("hello")},
+		{Pattern: "p*", Channel: "p1", Data: []byte("world")},
+	} {
+		select {
+		case m := <-messages:
+			if !reflect.DeepEqual(m, expected) {
+				t.Fatalf("message = %v, want %v", m, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for message %v", expected)
+		}
+	}
+
+	l.Handle("c1", nil)
+	waitSubscribers(t, pc, "c1", 0)
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Run() returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errLockConfig = errors.New("redigo: Lock requires Pool or Pools, Key and TTL")
+
+// ErrLockNotObtained is returned by the Lock TryLock and Lock methods when
+// another owner holds the lock.
+var ErrLockNotObtained = errors.New("redigo: lock not obtained")
+
+// ErrLockNotHeld is returned by the Lock Extend and Unlock methods when the
+// lock expired or was obtained by another owner.
+var ErrLockNotHeld = errors.New("redigo: lock not held")
+
+// Lock is a distributed lock. The lock is obtained by setting Key to a
+// random token with SET NX PX. The lock is extended and released only if
+// the key still holds the token, so an owner whose lock expired cannot
+// release or extend the lock of the next owner.
+//
+// When Pools specifies more than one pool, the lock uses the Redlock
+// algorithm. Each pool must connect to an independent Redis node. The lock
+// is obtained when the key is set on a majority of the nodes before the TTL
+// elapses.
+//
+// A Lock value is used by one owner at a time. The methods are safe to call
+// concurrently so that KeepAlive can run in a separate goroutine.
+type Lock struct {
+	// Pool is the connection pool for a single Redis node.
+	Pool *redis.Pool
+
+	// Pools is the list of connection pools for the nodes used by the
+	// Redlock algorithm. Pool is ignored when Pools is set.
+	Pools []*redis.Pool
+
+	// Key is the key of the lock.
+	Key string
+
+	// TTL is the time after which the lock expires if it is not extended.
+	TTL time.Duration
+
+	// RetryDelay is the time between attempts in the Lock method. The
+	// default is 50 milliseconds.
+	RetryDelay time.Duration
+
+	mu         sync.Mutex
+	token      string
+	validUntil time.Time
+}
+
+func (l *Lock) pools() ([]*redis.Pool, error) {
+	if l.Key == "" || l.TTL <= 0 {
+		return nil, errLockConfig
+	}
+	if len(l.Pools) > 0 {
+		return l.Pools, nil
+	}
+	if l.Pool == nil {
+		return nil, errLockConfig
+	}
+	return []*redis.Pool{l.Pool}, nil
+}
+
+// lockResult counts the results of a lock operation on the nodes.
+type lockResult struct {
+	n        int
+	rejected bool
+	err      error
+}
+
+func (r *lockResult) add(ok bool, err error) {
+	switch {
+	case ok:
+		r.n++
+	case err == nil:
+		r.rejected = true
+	case r.err == nil:
+		r.err = err
+	}
+}
+
+// result returns nil if the operation succeeded on a majority of nodes.
+// Otherwise, result returns notDone if a node rejected the operation or the
+// first error.
+func (r *lockResult) result(nodes int, notDone error) error {
+	if r.n >= nodes/2+1 {
+		return nil
+	}
+	if r.rejected || r.err == nil {
+		return notDone
+	}
+	return r.err
+}
+
+// TryLock attempts to obtain the lock. TryLock returns ErrLockNotObtained
+// if another owner holds the lock.
+func (l *Lock) TryLock() error {
+	pools, err := l.pools()
+	if err != nil {
+		return err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var r lockResult
+	for _, p := range pools {
+		r.add(setLock(p, l.Key, token, l.TTL))
+	}
+
+	// Allow for clock drift between the nodes as described in the Redlock
+	// algorithm.
+	validity := l.TTL - time.Since(start) - (l.TTL/100 + 2*time.Millisecond)
+	err = r.result(len(pools), ErrLockNotObtained)
+	if err == nil && validity <= 0 {
+		err = ErrLockNotObtained
+	}
+	if err != nil {
+		for _, p := range pools {
+			deleteLock(p, l.Key, token)
+		}
+		return err
+	}
+
+	l.mu.Lock()
+	l.token = token
+	l.validUntil = start.Add(validity)
+	l.mu.Unlock()
+	return nil
+}
+
+// Lock obtains the lock, retrying until the lock is obtained or the
+// context is done.
+func (l *Lock) Lock(ctx context.Context) error {
+	delay := l.RetryDelay
+	if delay <= 0 {
+		delay = 50 * time.Millisecond
+	}
+	for {
+		err := l.TryLock()
+		if err != ErrLockNotObtained {
+			return err
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Extend resets the expiration of the lock to TTL.
+func (l *Lock) Extend() error {
+	pools, err := l.pools()
+	if err != nil {
+		return err
+	}
+	token := l.Token()
+	if token == "" {
+		return ErrLockNotHeld
+	}
+
+	start := time.Now()
+	var r lockResult
+	for _, p := range pools {
+		r.add(extendLock(p, l.Key, token, l.TTL))
+	}
+	if err := r.result(len(pools), ErrLockNotHeld); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	if l.token == token {
+		l.validUntil = start.Add(l.TTL - time.Since(start) - (l.TTL/100 + 2*time.Millisecond))
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+// KeepAlive extends the lock at one third of the TTL until the context is
+// done, the lock is released or an extension fails. KeepAlive returns nil
+// when the lock is released and the error from Extend when an extension
+// fails. Run KeepAlive in a goroutine and stop work protected by the lock
+// when KeepAlive returns an error.
+func (l *Lock) KeepAlive(ctx context.Context) error {
+	t := time.NewTicker(l.TTL / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		if l.Token() == "" {
+			return nil
+		}
+		if err := l.Extend(); err != nil {
+			if l.Token() == "" {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	pools, err := l.pools()
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	token := l.token
+	l.token = ""
+	l.validUntil = time.Time{}
+	l.mu.Unlock()
+	if token == "" {
+		return ErrLockNotHeld
+	}
+
+	var r lockResult
+	for _, p := range pools {
+		r.add(deleteLock(p, l.Key, token))
+	}
+	return r.result(len(pools), ErrLockNotHeld)
+}
+
+// Token returns the random token stored at the key while the lock is held.
+// Token returns "" if the lock is not held.
+func (l *Lock) Token() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.token
+}
+
+// ValidUntil returns the time that the lock is guaranteed to be held
+// until. ValidUntil returns the zero time if the lock is not held.
+func (l *Lock) ValidUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.validUntil
+}
+
+func randomToken() (string, error) {
+	var p [16]byte
+	if _, err := rand.Read(p[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(p[:]), nil
+}
+
+func setLock(p *redis.Pool, key, token string, ttl time.Duration) (bool, error) {
+	c := p.Get()
+	defer c.Close()
+	_, err := redis.String(c.Do("SET", key, token, "NX", "PX", int64(ttl/time.Millisecond)))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func extendLock(p *redis.Pool, key, token string, ttl time.Duration) (bool, error) {
+	c := p.Get()
+	defer c.Close()
+	return compareAndDo(c, key, token, "PEXPIRE", key, int64(ttl/time.Millisecond))
+}
+
+func deleteLock(p *redis.Pool, key, token string) (bool, error) {
+	c := p.Get()
+	defer c.Close()
+	return DeleteIfEquals(c, key, token)
+}
@@ -0,0 +1,162 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestLock(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	l1 := &redisx.Lock{Pool: p, Key: "lock", TTL: time.Second}
+	l2 := &redisx.Lock{Pool: p, Key: "lock", TTL: time.Second, RetryDelay: 10 * time.Millisecond}
+
+	if err := l1.TryLock(); err != nil {
+		t.Fatalf("l1.TryLock() returned %v", err)
+	}
+	if v, _ := redis.String(c.Do("GET", "lock")); v != l1.Token() || v == "" {
+		t.Errorf("lock = %q, want token %q", v, l1.Token())
+	}
+	if l1.ValidUntil().Before(time.Now()) {
+		t.Errorf("l1.ValidUntil() = %v, want time in the future", l1.ValidUntil())
+	}
+	if err := l2.TryLock(); err != redisx.ErrLockNotObtained {
+		t.Errorf("l2.TryLock() returned %v, want ErrLockNotObtained", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l2.Lock(ctx); err != context.DeadlineExceeded {
+		t.Errorf("l2.Lock() returned %v, want context.DeadlineExceeded", err)
+	}
+
+	if err := l1.Extend(); err != nil {
+		t.Errorf("l1.Extend() returned %v", err)
+	}
+	if err := l1.Unlock(); err != nil {
+		t.Errorf("l1.Unlock() returned %v", err)
+	}
+	if err := l2.Lock(context.Background()); err != nil {
+		t.Fatalf("l2.Lock() returned %v", err)
+	}
+
+	// An owner that lost the lock cannot extend or release the lock of the
+	// next owner.
+	c.Do("SET", "lock", "other")
+	if err := l2.Extend(); err != redisx.ErrLockNotHeld {
+		t.Errorf("l2.Extend() returned %v, want ErrLockNotHeld", err)
+	}
+	if err := l2.Unlock(); err != redisx.ErrLockNotHeld {
+		t.Errorf("l2.Unlock() returned %v, want ErrLockNotHeld", err)
+	}
+	if v, _ := redis.String(c.Do("GET", "lock")); v != "other" {
+		t.Errorf("lock = %q, want other", v)
+	}
+}
+
+func TestLockKeepAlive(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	l := &redisx.Lock{Pool: p, Key: "lock", TTL: 150 * time.Millisecond}
+	if err := l.TryLock(); err != nil {
+		t.Fatalf("TryLock() returned %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- l.KeepAlive(context.Background()) }()
+
+	time.Sleep(300 * time.Millisecond)
+	if n, _ := redis.Int(c.Do("EXISTS", "lock")); n != 1 {
+		t.Errorf("lock expired while kept alive")
+	}
+	if err := l.Unlock(); err != nil {
+		t.Errorf("Unlock() returned %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("KeepAlive() returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("KeepAlive() did not return after Unlock")
+	}
+}
+
+func TestRedlock(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	dialDB := func(db int) func() (redis.Conn, error) {
+		return func() (redis.Conn, error) {
+			return redis.Dial("tcp", ":6379", redis.DialDatabase(db))
+		}
+	}
+	dialDown := func() (redis.Conn, error) { return nil, errors.New("node down") }
+
+	p9 := &redis.Pool{Dial: dialDB(9)}
+	defer p9.Close()
+	p10 := &redis.Pool{Dial: dialDB(10)}
+	defer p10.Close()
+	down := &redis.Pool{Dial: dialDown}
+	defer down.Close()
+
+	// The lock is obtained on a majority of the nodes.
+	l := &redisx.Lock{Pools: []*redis.Pool{p9, p10, down}, Key: "redlock", TTL: time.Second}
+	if err := l.TryLock(); err != nil {
+		t.Fatalf("TryLock() returned %v", err)
+	}
+	if err := l.Extend(); err != nil {
+		t.Errorf("Extend() returned %v", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Errorf("Unlock() returned %v", err)
+	}
+
+	// The lock is not obtained on a minority of the nodes and the key is
+	// removed from the nodes where it was set.
+	l = &redisx.Lock{Pools: []*redis.Pool{p9, down, down}, Key: "redlock", TTL: time.Second}
+	if err := l.TryLock(); err == nil || err == redisx.ErrLockNotObtained {
+		t.Errorf("TryLock() returned %v, want node error", err)
+	}
+	if n, _ := redis.Int(c.Do("EXISTS", "redlock")); n != 0 {
+		t.Errorf("redlock not removed after failed TryLock")
+	}
+}
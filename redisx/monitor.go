@@ -0,0 +1,171 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// MonitorEvent represents a command reported by the MONITOR command.
+type MonitorEvent struct {
+	// The time that the server processed the command.
+	Time time.Time
+
+	// The database selected by the client.
+	DB int
+
+	// The address of the client. The address is "lua" for commands
+	// executed by a script and "unix:path" for clients connected with a
+	// Unix domain socket.
+	ClientAddr string
+
+	CommandName string
+	Args        []string
+}
+
+// Monitor sends the MONITOR command on c and sends the commands reported by
+// the server to events. Monitor returns when the context is done or when
+// there is an error receiving from the connection. The connection remains in
+// monitor mode on return; the application should close the connection.
+//
+// The connection should not have a read timeout because the server does
+// not send data to an idle monitor.
+func Monitor(ctx context.Context, c redis.Conn, events chan<- MonitorEvent) error {
+	if _, err := c.Do("MONITOR"); err != nil {
+		return err
+	}
+	for {
+		line, err := redis.String(redis.ReceiveContext(c, ctx))
+		if err != nil {
+			return err
+		}
+		event, err := ParseMonitorEvent(line)
+		if err != nil {
+			return err
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+var errMonitorSyntax = errors.New("redigo: syntax error in monitor output")
+
+// ParseMonitorEvent parses a line of output from the MONITOR command.
+//
+//  1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+func ParseMonitorEvent(line string) (MonitorEvent, error) {
+	var event MonitorEvent
+
+	i := strings.Index(line, " [")
+	if i < 0 {
+		return event, errMonitorSyntax
+	}
+	ts, err := strconv.ParseFloat(line[:i], 64)
+	if err != nil {
+		return event, errMonitorSyntax
+	}
+	sec := int64(ts)
+	event.Time = time.Unix(sec, int64((ts-float64(sec))*1e6+0.5)*1e3)
+	line = line[i+2:]
+
+	i = strings.Index(line, "] ")
+	if i < 0 {
+		return event, errMonitorSyntax
+	}
+	client := strings.SplitN(line[:i], " ", 2)
+	if len(client) != 2 {
+		return event, errMonitorSyntax
+	}
+	if event.DB, err = strconv.Atoi(client[0]); err != nil {
+		return event, errMonitorSyntax
+	}
+	event.ClientAddr = client[1]
+	line = line[i+2:]
+
+	var args []string
+	for line != "" {
+		arg, rest, err := unquoteMonitorArg(line)
+		if err != nil {
+			return event, err
+		}
+		args = append(args, arg)
+		line = strings.TrimPrefix(rest, " ")
+	}
+	if len(args) == 0 {
+		return event, errMonitorSyntax
+	}
+	event.CommandName = args[0]
+	event.Args = args[1:]
+	return event, nil
+}
+
+// unquoteMonitorArg unquotes the argument at the start of s. The server
+// quotes arguments as C strings with \xHH escapes for nonprintable bytes.
+func unquoteMonitorArg(s string) (arg, rest string, err error) {
+	if s == "" || s[0] != '"' {
+		return "", "", errMonitorSyntax
+	}
+	buf := make([]byte, 0, len(s))
+	for i := 1; i < len(s); i++ {
+		b := s[i]
+		switch b {
+		case '"':
+			return string(buf), s[i+1:], nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", "", errMonitorSyntax
+			}
+			switch s[i] {
+			case 'n':
+				b = '\n'
+			case 'r':
+				b = '\r'
+			case 't':
+				b = '\t'
+			case 'a':
+				b = '\a'
+			case 'b':
+				b = '\b'
+			case 'x':
+				if i+2 >= len(s) {
+					return "", "", errMonitorSyntax
+				}
+				n, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+				if err != nil {
+					return "", "", fmt.Errorf("redigo: bad escape in monitor output: %v", err)
+				}
+				b = byte(n)
+				i += 2
+			default:
+				b = s[i]
+			}
+		}
+		buf = append(buf, b)
+	}
+	return "", "", errMonitorSyntax
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+var parseMonitorEventTests = []struct {
+	line     string
+	expected redisx.MonitorEvent
+}{
+	{
+		`1339518083.107412 [0 127.0.0.1:60866] "keys" "*"`,
+		redisx.MonitorEvent{Time: time.Unix(1339518083, 107412000), ClientAddr: "127.0.0.1:60866", CommandName: "keys", Args: []string{"*"}},
+	},
+	{
+		`1339518083.000001 [3 lua] "set" "a \"b\"" "\x00\n\\"`,
+		redisx.MonitorEvent{Time: time.Unix(1339518083, 1000), DB: 3, ClientAddr: "lua", CommandName: "set", Args: []string{`a "b"`, "\x00\n\\"}},
+	},
+	{
+		`1339518083.5 [0 unix:/tmp/redis.sock] "ping"`,
+		redisx.MonitorEvent{Time: time.Unix(1339518083, 500000000), ClientAddr: "unix:/tmp/redis.sock", CommandName: "ping", Args: []string{}},
+	},
+}
+
+func TestParseMonitorEvent(t *testing.T) {
+	for _, tt := range parseMonitorEventTests {
+		event, err := redisx.ParseMonitorEvent(tt.line)
+		if err != nil {
+			t.Errorf("ParseMonitorEvent(%q) returned error %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(event, tt.expected) {
+			t.Errorf("ParseMonitorEvent(%q) = %+v, want %+v", tt.line, event, tt.expected)
+		}
+	}
+	for _, line := range []string{"", "OK", `1.0 [0 lua] "unterminated`, `1.0 [0 lua] bare`} {
+		if _, err := redisx.ParseMonitorEvent(line); err == nil {
+			t.Errorf("ParseMonitorEvent(%q) did not return an error", line)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	mc, err := redis.Dial("tcp", ":6379")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan redisx.MonitorEvent)
+	monitorErr := make(chan error, 1)
+	go func() { monitorErr <- redisx.Monitor(ctx, mc, events) }()
+
+	deadline := time.After(time.Second)
+	for found := false; !found; {
+		if _, err := c.Do("GET", "redisx-monitor"); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case event := <-events:
+			found = event.CommandName == "GET" && event.DB == 9 && reflect.DeepEqual(event.Args, []string{"redisx-monitor"})
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("timeout waiting for monitor event")
+		}
+	}
+	cancel()
+	if err := <-monitorErr; err != context.Canceled {
+		t.Errorf("Monitor() returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx
+
+import "github.com/garyburd/redigo/redis"
+
+// Publication is a message to publish with PublishBatch.
+type Publication struct {
+
+	// The channel to publish to.
+	Channel string
+
+	// The message. See the Publisher Publish method for how the message is
+	// encoded.
+	Message interface{}
+}
+
+// Publisher publishes messages using connections from a pool.
+type Publisher struct {
+
+	// Pool is the pool of connections used to publish messages.
+	Pool *redis.Pool
+
+	// Codec encodes messages that are not a string or []byte. JSONCodec is
+	// used if Codec is nil.
+	Codec Codec
+}
+
+// Publish publishes message to channel and returns the number of clients
+// that received the message. Messages of type string and []byte are sent
+// as is. Other messages are encoded with the publisher's codec.
+func (p *Publisher) Publish(channel string, message interface{}) (receivers int, err error) {
+	data, err := p.encode(message)
+	if err != nil {
+		return 0, err
+	}
+	c := p.Pool.Get()
+	defer c.Close()
+	return redis.Int(c.Do("PUBLISH", channel, data))
+}
+
+// PublishBatch publishes the messages in a single round trip to the server
+// and returns the number of clients that received each message.
+func (p *Publisher) PublishBatch(pubs []Publication) (receivers []int, err error) {
+	args := make([]interface{}, len(pubs))
+	for i, pub := range pubs {
+		if args[i], err = p.encode(pub.Message); err != nil {
+			return nil, err
+		}
+	}
+
+	c := p.Pool.Get()
+	defer c.Close()
+	for i, pub := range pubs {
+		c.Send("PUBLISH", pub.Channel, args[i])
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+	receivers = make([]int, len(pubs))
+	for i := range pubs {
+		if receivers[i], err = redis.Int(c.Receive()); err != nil {
+			return nil, err
+		}
+	}
+	return receivers, nil
+}
+
+func (p *Publisher) encode(message interface{}) (interface{}, error) {
+	switch message := message.(type) {
+	case string, []byte:
+		return message, nil
+	}
+	codec := p.Codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return codec.Marshal(message)
+}
+
+// Publish publishes message to channel using a connection from pool. See
+// the Publisher Publish method for details.
+func Publish(pool *redis.Pool, channel string, message interface{}) (receivers int, err error) {
+	p := Publisher{Pool: pool}
+	return p.Publish(channel, message)
+}
+
+// PublishBatch publishes the messages using a connection from pool. See the
+// Publisher PublishBatch method for details.
+func PublishBatch(pool *redis.Pool, pubs []Publication) (receivers []int, err error) {
+	p := Publisher{Pool: pool}
+	return p.PublishBatch(pubs)
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestPublish(t *testing.T) {
+	pc, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	p := &redis.Pool{Dial: redistest.Dial, MaxIdle: 1}
+	defer p.Close()
+
+	s := redisx.NewSubscriber(&redisx.ResilientPubSub{Dial: dialPubSub}, redisx.SubscriberBufferSize(10))
+	defer s.Close()
+	s.Subscribe("c1")
+	waitSubscribers(t, pc, "c1", 1)
+
+	n, err := redisx.Publish(p, "c1", "hello")
+	if n != 1 || err != nil {
+		t.Fatalf("Publish() = %d, %v, want 1, nil", n, err)
+	}
+	expectMessage(t, s, redisx.Message{Channel: "c1", Data: []byte("hello")})
+
+	receivers, err := redisx.PublishBatch(p, []redisx.Publication{
+		{Channel: "c1", Message: map[string]int{"a": 1}},
+		{Channel: "c2", Message: []byte("world")},
+	})
+	if !reflect.DeepEqual(receivers, []int{1, 0}) || err != nil {
+		t.Fatalf("PublishBatch() = %v, %v, want [1 0], nil", receivers, err)
+	}
+	expectMessage(t, s, redisx.Message{Channel: "c1", Data: []byte(`{"a":1}`)})
+}
@@ -0,0 +1,396 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// ErrPongTimeout is reported to the ResilientPubSub HealthCheck function when
+// a keepalive PING is not answered in time.
+var ErrPongTimeout = errors.New("redigo: pubsub pong timeout")
+
+// keepaliveData is the PING argument used to identify keepalive pongs.
+const keepaliveData = "redigo-keepalive"
+
+var (
+	errPubSubClosed       = errors.New("redigo: pubsub closed")
+	errPubSubNotConnected = errors.New("redigo: pubsub not connected")
+)
+
+// Reconnect is returned from the ResilientPubSub Receive method after the
+// connection to the server is re-established. The subscriptions are replayed
+// on the new connection before Reconnect is returned. Messages published
+// while the connection was down are lost.
+type Reconnect struct {
+
+	// The error that broke the previous connection.
+	Err error
+}
+
+// PubSubStats contains statistics for a ResilientPubSub, Subscriber or
+// Listener.
+type PubSubStats struct {
+
+	// The number of messages received from the server.
+	Received uint64
+
+	// The number of messages discarded by the Subscriber overflow policy.
+	Dropped uint64
+
+	// The number of times the connection was re-established after a
+	// failure.
+	Reconnects uint64
+
+	// The current number of subscribed channels and patterns.
+	Channels int
+	Patterns int
+}
+
+// ResilientPubSub is a subscriber that survives connection failures.
+//
+// ResilientPubSub tracks the channels and patterns that the application
+// subscribes to. When the Receive method encounters an error on the
+// underlying connection, the connection is closed, a new connection is
+// dialed and the subscriptions are replayed on the new connection.
+//
+// The connection is dialed on the first call to Receive. Subscriptions
+// made before that are sent when the connection is dialed.
+//
+// ResilientPubSub supports one concurrent caller to the Receive method and
+// concurrent callers to the other methods.
+type ResilientPubSub struct {
+
+	// Dial is an application supplied function for creating and configuring
+	// a connection.
+	Dial func() (redis.Conn, error)
+
+	// If PingInterval is not zero, then a PING is sent on the connection at
+	// this interval while there are subscriptions. If the Pong is not
+	// received before the next PING is due, then the connection is closed
+	// and Receive reconnects. Receive does not return keepalive Pongs.
+	PingInterval time.Duration
+
+	// HealthCheck is an optional function called with the result of each
+	// keepalive PING: nil when the Pong is received or ErrPongTimeout when
+	// the Pong is missing. HealthCheck must not block.
+	HealthCheck func(err error)
+
+	// mu protects fields defined below.
+	mu            sync.Mutex
+	psc           redis.PubSubConn
+	lost          error
+	closed        bool
+	channels      map[string]bool
+	patterns      map[string]bool
+	pingPending   bool
+	stopKeepalive chan struct{}
+	received      uint64
+	reconnects    uint64
+}
+
+// Subscribe subscribes to the specified channels.
+func (p *ResilientPubSub) Subscribe(channel ...string) error {
+	return p.update("SUBSCRIBE", channel)
+}
+
+// PSubscribe subscribes to the given patterns.
+func (p *ResilientPubSub) PSubscribe(pattern ...string) error {
+	return p.update("PSUBSCRIBE", pattern)
+}
+
+// Unsubscribe unsubscribes from the given channels, or from all of them if
+// none is given.
+func (p *ResilientPubSub) Unsubscribe(channel ...string) error {
+	return p.update("UNSUBSCRIBE", channel)
+}
+
+// PUnsubscribe unsubscribes from the given patterns, or from all of them if
+// none is given.
+func (p *ResilientPubSub) PUnsubscribe(pattern ...string) error {
+	return p.update("PUNSUBSCRIBE", pattern)
+}
+
+// Ping sends a PING to the server with the specified data.
+func (p *ResilientPubSub) Ping(data string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.closed:
+		return errPubSubClosed
+	case p.psc.Conn == nil:
+		return errPubSubNotConnected
+	}
+	return p.psc.Ping(data)
+}
+
+// Receive returns a pushed message as a Subscription, Message, PMessage,
+// Pong, Reconnect or error. The return value is intended to be used directly
+// in a type switch.
+//
+// If the connection is not established, Receive dials a new connection and
+// replays the subscriptions. Receive returns the error if the dial fails. The
+// application can call Receive again to retry.
+func (p *ResilientPubSub) Receive() interface{} {
+	for {
+		p.mu.Lock()
+		psc, closed := p.psc, p.closed
+		p.mu.Unlock()
+
+		if closed {
+			return errPubSubClosed
+		}
+
+		if psc.Conn == nil {
+			var err error
+			psc, err = p.dial()
+			if err != nil {
+				return err
+			}
+			p.mu.Lock()
+			lost := p.lost
+			p.lost = nil
+			if lost != nil {
+				p.reconnects++
+			}
+			p.mu.Unlock()
+			if lost != nil {
+				return Reconnect{Err: lost}
+			}
+		}
+
+		switch v := psc.Receive().(type) {
+		case redis.Pong:
+			if v.Data != keepaliveData {
+				return v
+			}
+			p.mu.Lock()
+			if p.psc.Conn == psc.Conn {
+				p.pingPending = false
+			}
+			healthCheck := p.HealthCheck
+			p.mu.Unlock()
+			if healthCheck != nil {
+				healthCheck(nil)
+			}
+		case error:
+			if psc.Conn.Err() == nil {
+				return v
+			}
+			p.mu.Lock()
+			if p.psc.Conn == psc.Conn {
+				p.dropConn()
+				p.lost = v
+			}
+			p.mu.Unlock()
+			psc.Close()
+		case redis.Message, redis.PMessage:
+			p.mu.Lock()
+			p.received++
+			p.mu.Unlock()
+			return v
+		default:
+			return v
+		}
+	}
+}
+
+// Stats returns statistics for the ResilientPubSub.
+func (p *ResilientPubSub) Stats() PubSubStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return PubSubStats{
+		Received:   p.received,
+		Reconnects: p.reconnects,
+		Channels:   len(p.channels),
+		Patterns:   len(p.patterns),
+	}
+}
+
+// Close closes the connection. Receive returns an error after Close is
+// called.
+func (p *ResilientPubSub) Close() error {
+	p.mu.Lock()
+	c := p.psc.Conn
+	p.dropConn()
+	p.closed = true
+	p.mu.Unlock()
+	if c == nil {
+		return nil
+	}
+	return c.Close()
+}
+
+// dropConn forgets the current connection and stops the keepalive goroutine
+// for the connection. The caller must hold p.mu and close the connection.
+func (p *ResilientPubSub) dropConn() {
+	p.psc.Conn = nil
+	p.pingPending = false
+	if p.stopKeepalive != nil {
+		close(p.stopKeepalive)
+		p.stopKeepalive = nil
+	}
+}
+
+// keepalive pings connection c until stop is closed.
+func (p *ResilientPubSub) keepalive(c redis.Conn, interval time.Duration, stop chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		p.mu.Lock()
+		if p.psc.Conn != c {
+			p.mu.Unlock()
+			return
+		}
+		if p.pingPending {
+			p.dropConn()
+			p.lost = ErrPongTimeout
+			healthCheck := p.HealthCheck
+			p.mu.Unlock()
+			if healthCheck != nil {
+				healthCheck(ErrPongTimeout)
+			}
+			// Closing the connection causes Receive to reconnect.
+			c.Close()
+			return
+		}
+		if len(p.channels)+len(p.patterns) > 0 {
+			p.pingPending = true
+			p.psc.Ping(keepaliveData)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// dial dials a new connection and replays the current subscriptions.
+func (p *ResilientPubSub) dial() (redis.PubSubConn, error) {
+	c, err := p.Dial()
+	if err != nil {
+		return redis.PubSubConn{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		c.Close()
+		return redis.PubSubConn{}, errPubSubClosed
+	}
+	if len(p.channels) > 0 {
+		c.Send("SUBSCRIBE", setArgs(p.channels)...)
+	}
+	if len(p.patterns) > 0 {
+		c.Send("PSUBSCRIBE", setArgs(p.patterns)...)
+	}
+	if err := c.Flush(); err != nil {
+		c.Close()
+		return redis.PubSubConn{}, err
+	}
+	p.psc = redis.PubSubConn{Conn: c}
+	if p.PingInterval > 0 {
+		p.stopKeepalive = make(chan struct{})
+		go p.keepalive(c, p.PingInterval, p.stopKeepalive)
+	}
+	return p.psc, nil
+}
+
+// unsubscribeAll removes all subscriptions. The function returns true if the
+// commands were sent to the server.
+func (p *ResilientPubSub) unsubscribeAll() (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false, errPubSubClosed
+	}
+	p.channels = nil
+	p.patterns = nil
+	if p.psc.Conn == nil {
+		return false, nil
+	}
+	p.psc.Conn.Send("UNSUBSCRIBE")
+	p.psc.Conn.Send("PUNSUBSCRIBE")
+	return true, p.psc.Conn.Flush()
+}
+
+// update records a subscription change and sends the command to the server
+// if the connection is established.
+func (p *ResilientPubSub) update(cmd string, names []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return errPubSubClosed
+	}
+
+	switch cmd {
+	case "SUBSCRIBE":
+		p.channels = addToSet(p.channels, names)
+	case "PSUBSCRIBE":
+		p.patterns = addToSet(p.patterns, names)
+	case "UNSUBSCRIBE":
+		p.channels = removeFromSet(p.channels, names)
+	case "PUNSUBSCRIBE":
+		p.patterns = removeFromSet(p.patterns, names)
+	}
+
+	if p.psc.Conn == nil {
+		// The command is sent when the connection is dialed.
+		return nil
+	}
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+	p.psc.Conn.Send(cmd, args...)
+	return p.psc.Conn.Flush()
+}
+
+func addToSet(set map[string]bool, names []string) map[string]bool {
+	if set == nil {
+		set = make(map[string]bool)
+	}
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
+func removeFromSet(set map[string]bool, names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
+	}
+	for _, name := range names {
+		delete(set, name)
+	}
+	return set
+}
+
+func setArgs(set map[string]bool) []interface{} {
+	args := make([]interface{}, 0, len(set))
+	for name := range set {
+		args = append(args, name)
+	}
+	return args
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+type pubSubDialer struct {
+	conns []redis.Conn
+}
+
+// dialPubSub dials the local Redis server. Unlike redistest.Dial, the
+// returned connection can be closed while another goroutine is receiving.
+func dialPubSub() (redis.Conn, error) {
+	return redis.Dial("tcp", ":6379")
+}
+
+func (d *pubSubDialer) dial() (redis.Conn, error) {
+	c, err := dialPubSub()
+	if err != nil {
+		return nil, err
+	}
+	d.conns = append(d.conns, c)
+	return c, nil
+}
+
+func expectReceived(t *testing.T, p *redisx.ResilientPubSub, message string, expected interface{}) {
+	actual := p.Receive()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("%s = %v, want %v", message, actual, expected)
+	}
+}
+
+func TestResilientPubSub(t *testing.T) {
+	pc, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	var d pubSubDialer
+	p := &redisx.ResilientPubSub{Dial: d.dial}
+	defer p.Close()
+
+	p.Subscribe("c1")
+	expectReceived(t, p, "Subscribe(c1)", redis.Subscription{Kind: "subscribe", Channel: "c1", Count: 1})
+
+	pc.Do("PUBLISH", "c1", "hello")
+	expectReceived(t, p, "PUBLISH c1 hello", redis.Message{Channel: "c1", Data: []byte("hello")})
+
+	// Break the connection. The next receive reconnects and replays the
+	// subscription.
+	d.conns[0].Close()
+	if r, ok := p.Receive().(redisx.Reconnect); !ok || r.Err == nil {
+		t.Fatalf("Receive() = %v, want Reconnect with error", r)
+	}
+	expectReceived(t, p, "replayed Subscribe(c1)", redis.Subscription{Kind: "subscribe", Channel: "c1", Count: 1})
+
+	pc.Do("PUBLISH", "c1", "world")
+	expectReceived(t, p, "PUBLISH c1 world", redis.Message{Channel: "c1", Data: []byte("world")})
+
+	if len(d.conns) != 2 {
+		t.Errorf("dialed %d connections, want 2", len(d.conns))
+	}
+
+	expected := redisx.PubSubStats{Received: 2, Reconnects: 1, Channels: 1}
+	if stats := p.Stats(); stats != expected {
+		t.Errorf("Stats() = %+v, want %+v", stats, expected)
+	}
+
+	p.Close()
+	if _, ok := p.Receive().(error); !ok {
+		t.Errorf("Receive() after Close did not return error")
+	}
+}
+
+func TestResilientPubSubKeepalive(t *testing.T) {
+	pc, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	health := make(chan error, 10)
+	p := &redisx.ResilientPubSub{
+		Dial:         dialPubSub,
+		PingInterval: 10 * time.Millisecond,
+		HealthCheck: func(err error) {
+			select {
+			case health <- err:
+			default:
+			}
+		},
+	}
+	defer p.Close()
+
+	p.Subscribe("c1")
+	expectReceived(t, p, "Subscribe(c1)", redis.Subscription{Kind: "subscribe", Channel: "c1", Count: 1})
+
+	received := make(chan interface{}, 1)
+	go func() { received <- p.Receive() }()
+
+	select {
+	case err := <-health:
+		if err != nil {
+			t.Fatalf("HealthCheck(%v), want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for keepalive pong")
+	}
+
+	pc.Do("PUBLISH", "c1", "hello")
+	select {
+	case v := <-received:
+		expected := redis.Message{Channel: "c1", Data: []byte("hello")}
+		if !reflect.DeepEqual(v, expected) {
+			t.Fatalf("Receive() = %v, want %v", v, expected)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
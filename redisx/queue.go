@@ -0,0 +1,231 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// ErrNotInFlight is returned by the Queue Ack and Extend methods when the
+// message is not in flight. The message was acknowledged or requeued after
+// its visibility timeout expired.
+var ErrNotInFlight = errors.New("redigo: message not in flight")
+
+// queueIDLen is the length of the random message ID prefixed to each item.
+const queueIDLen = 32
+
+// dequeueScript moves up to ARGV[1] items from the queue KEYS[1] to the
+// processing list KEYS[2] and records the visibility deadline of each item
+// in the sorted set KEYS[3]. The queue scripts call replicate_commands before
+// TIME for servers that do not replicate script effects by default.
+var dequeueScript = redis.NewScript(3, `
+redis.replicate_commands()
+local t = redis.call('TIME')
+local deadline = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000) + tonumber(ARGV[2])
+local items = {}
+for i = 1, tonumber(ARGV[1]) do
+  local item = redis.call('LMOVE', KEYS[1], KEYS[2], 'RIGHT', 'LEFT')
+  if not item then
+    break
+  end
+  redis.call('ZADD', KEYS[3], deadline, item)
+  items[#items + 1] = item
+end
+return items
+`)
+
+// extendScript sets the visibility deadline of item ARGV[2] in KEYS[1] to
+// ARGV[1] milliseconds from now if the item is in flight.
+var extendScript = redis.NewScript(1, `
+redis.replicate_commands()
+local t = redis.call('TIME')
+local deadline = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000) + tonumber(ARGV[1])
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+  return 0
+end
+redis.call('ZADD', KEYS[1], deadline, ARGV[2])
+return 1
+`)
+
+// requeueScript moves up to ARGV[1] items with an expired visibility
+// deadline in KEYS[3] from the processing list KEYS[2] to the head of the
+// queue KEYS[1].
+var requeueScript = redis.NewScript(3, `
+redis.replicate_commands()
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local items = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', now, 'LIMIT', 0, tonumber(ARGV[1]))
+local n = 0
+for _, item in ipairs(items) do
+  if redis.call('LREM', KEYS[2], 1, item) > 0 then
+    redis.call('RPUSH', KEYS[1], item)
+    n = n + 1
+  end
+  redis.call('ZREM', KEYS[3], item)
+end
+return n
+`)
+
+// QueueMessage is a message received from a Queue.
+type QueueMessage struct {
+	// ID is a random identifier assigned to the message by Enqueue.
+	ID string
+
+	Body []byte
+}
+
+func (m QueueMessage) item() []byte {
+	item := make([]byte, 0, len(m.ID)+len(m.Body))
+	item = append(item, m.ID...)
+	return append(item, m.Body...)
+}
+
+// Queue is a reliable first-in first-out queue with at-least-once delivery.
+// Dequeue moves messages to a processing list with LMOVE. A message that is
+// not acknowledged with Ack before its visibility timeout expires is
+// returned to the queue by RequeueExpired and delivered again.
+//
+// The queue is stored in a list at Name, the messages in flight are stored
+// in a list at Name + ":processing" and the visibility deadlines are stored
+// in a sorted set at Name + ":deadlines". In Redis Cluster, use a hash tag in
+// Name so that the keys are stored on the same node. The LMOVE command
+// requires Redis 6.2 or later.
+type Queue struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Name is the key of the queue.
+	Name string
+
+	// VisibilityTimeout is the time that a dequeued message is hidden from
+	// other consumers. The default is 30 seconds.
+	VisibilityTimeout time.Duration
+}
+
+func (q *Queue) keys() []interface{} {
+	return []interface{}{q.Name, q.Name + ":processing", q.Name + ":deadlines"}
+}
+
+// Enqueue adds messages with the given bodies to the tail of the queue.
+func (q *Queue) Enqueue(ctx context.Context, bodies ...[]byte) error {
+	if len(bodies) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 1, 1+len(bodies))
+	args[0] = q.Name
+	for _, body := range bodies {
+		id, err := randomToken()
+		if err != nil {
+			return err
+		}
+		args = append(args, QueueMessage{ID: id, Body: body}.item())
+	}
+	c := q.Pool.Get()
+	defer c.Close()
+	_, err := redis.DoContext(c, ctx, "LPUSH", args...)
+	return err
+}
+
+// Dequeue receives up to n messages from the head of the queue. Dequeue
+// returns an empty slice if the queue is empty.
+func (q *Queue) Dequeue(ctx context.Context, n int) ([]QueueMessage, error) {
+	vt := q.VisibilityTimeout
+	if vt <= 0 {
+		vt = 30 * time.Second
+	}
+	c := q.Pool.Get()
+	defer c.Close()
+	items, err := redis.ByteSlices(dequeueScript.DoContext(c, ctx, append(q.keys(), n, int64(vt/time.Millisecond))...))
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+	msgs := make([]QueueMessage, 0, len(items))
+	for _, item := range items {
+		if len(item) < queueIDLen {
+			return nil, errors.New("redigo: malformed queue item")
+		}
+		msgs = append(msgs, QueueMessage{ID: string(item[:queueIDLen]), Body: item[queueIDLen:]})
+	}
+	return msgs, nil
+}
+
+// Ack acknowledges the processing of a message and removes the message from
+// the queue.
+func (q *Queue) Ack(ctx context.Context, m QueueMessage) error {
+	item := m.item()
+	c := q.Pool.Get()
+	defer c.Close()
+	c.Send("MULTI")
+	c.Send("LREM", q.Name+":processing", 1, item)
+	c.Send("ZREM", q.Name+":deadlines", item)
+	values, err := redis.Ints(redis.DoContext(c, ctx, "EXEC"))
+	if err != nil {
+		return err
+	}
+	if values[0] == 0 {
+		return ErrNotInFlight
+	}
+	return nil
+}
+
+// Extend sets the visibility timeout of a message in flight to d from now.
+// Consumers call Extend to keep processing a message for longer than the
+// queue's visibility timeout.
+func (q *Queue) Extend(ctx context.Context, m QueueMessage, d time.Duration) error {
+	c := q.Pool.Get()
+	defer c.Close()
+	n, err := redis.Int(extendScript.DoContext(c, ctx, q.Name+":deadlines", int64(d/time.Millisecond), m.item()))
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotInFlight
+	}
+	return nil
+}
+
+// RequeueExpired returns up to max messages with an expired visibility
+// timeout to the head of the queue and returns the number of messages
+// requeued. Call RequeueExpired periodically from one or more processes.
+func (q *Queue) RequeueExpired(ctx context.Context, max int) (int, error) {
+	c := q.Pool.Get()
+	defer c.Close()
+	return redis.Int(requeueScript.DoContext(c, ctx, append(q.keys(), max)...))
+}
+
+// Len returns the number of messages waiting in the queue and the number of
+// messages in flight.
+func (q *Queue) Len(ctx context.Context) (waiting, inFlight int, err error) {
+	c := q.Pool.Get()
+	defer c.Close()
+	c.Send("LLEN", q.Name)
+	c.Send("LLEN", q.Name+":processing")
+	if err := c.Flush(); err != nil {
+		return 0, 0, err
+	}
+	if waiting, err = redis.Int(redis.ReceiveContext(c, ctx)); err != nil {
+		return 0, 0, err
+	}
+	if inFlight, err = redis.Int(redis.ReceiveContext(c, ctx)); err != nil {
+		return 0, 0, err
+	}
+	return waiting, inFlight, nil
+}
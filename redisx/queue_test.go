@@ -0,0 +1,77 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestQueue(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+
+	id := strings.Repeat("a", 32)
+	body := redistest.MatchFunc("item with body", func(arg string) bool {
+		return len(arg) == 32+len("job") && strings.HasSuffix(arg, "job")
+	})
+	c.Expect("LPUSH", "q", body, body).Reply(int64(2))
+	c.Expect("EVALSHA", redistest.Any(), 3, "q", "q:processing", "q:deadlines", 10, 5000).Reply([]interface{}{[]byte(id + "job")})
+	c.Expect("EVALSHA", redistest.Any(), 1, "q:deadlines", 60000, id+"job").Reply(int64(1))
+	c.Expect("MULTI").Reply("OK")
+	c.Expect("LREM", "q:processing", 1, id+"job").Reply("QUEUED")
+	c.Expect("ZREM", "q:deadlines", id+"job").Reply("QUEUED")
+	c.Expect("EXEC").Reply([]interface{}{int64(1), int64(1)})
+	c.Expect("EVALSHA", redistest.Any(), 1, "q:deadlines", 60000, id+"job").Reply(int64(0))
+	c.Expect("EVALSHA", redistest.Any(), 3, "q", "q:processing", "q:deadlines", 100).Reply(int64(1))
+	c.Expect("LLEN", "q").Reply(int64(2))
+	c.Expect("LLEN", "q:processing").Reply(int64(0))
+
+	q := &redisx.Queue{Pool: mockPool(c), Name: "q", VisibilityTimeout: 5 * time.Second}
+	ctx := context.Background()
+	if err := q.Enqueue(ctx, []byte("job"), []byte("job")); err != nil {
+		t.Fatalf("Enqueue() returned %v", err)
+	}
+	msgs, err := q.Dequeue(ctx, 10)
+	if err != nil {
+		t.Fatalf("Dequeue() returned %v", err)
+	}
+	if expected := []redisx.QueueMessage{{ID: id, Body: []byte("job")}}; !reflect.DeepEqual(msgs, expected) {
+		t.Fatalf("Dequeue() = %+v, want %+v", msgs, expected)
+	}
+	if err := q.Extend(ctx, msgs[0], time.Minute); err != nil {
+		t.Errorf("Extend() returned %v", err)
+	}
+	if err := q.Ack(ctx, msgs[0]); err != nil {
+		t.Errorf("Ack() returned %v", err)
+	}
+	if err := q.Extend(ctx, msgs[0], time.Minute); err != redisx.ErrNotInFlight {
+		t.Errorf("Extend() returned %v, want ErrNotInFlight", err)
+	}
+	if n, err := q.RequeueExpired(ctx, 100); n != 1 || err != nil {
+		t.Errorf("RequeueExpired() = %d, %v, want 1, nil", n, err)
+	}
+	if waiting, inFlight, err := q.Len(ctx); waiting != 2 || inFlight != 0 || err != nil {
+		t.Errorf("Len() = %d, %d, %v, want 2, 0, nil", waiting, inFlight, err)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// RateLimitResult is the result of a rate limit check.
+type RateLimitResult struct {
+	// Allowed is true if the request is allowed.
+	Allowed bool
+
+	// Remaining is the number of requests allowed before the limit is
+	// reached.
+	Remaining int64
+
+	// RetryAfter is the time to wait before the next request is allowed.
+	// RetryAfter is zero when the request is allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimiter limits the rate of requests identified by a key.
+type RateLimiter interface {
+	// Allow reports whether a request for key is allowed when at most
+	// limit requests are allowed per window.
+	Allow(ctx context.Context, key string, limit int64, window time.Duration) (RateLimitResult, error)
+}
+
+// tokenBucketScript refills the bucket in KEYS[1] at ARGV[1] tokens per
+// ARGV[2] microseconds and takes a token if one is available. Time is taken
+// from the server so that clients with skewed clocks share one limit. The
+// scripts enable effects replication so that they can write after calling
+// TIME on Redis versions before 5.
+var tokenBucketScript = redis.NewScript(1, `
+redis.replicate_commands()
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local rate = limit / window
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil or ts == nil then
+  tokens = limit
+  ts = now
+end
+tokens = math.min(limit, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+return {allowed, math.floor(tokens), retry}
+`)
+
+// slidingWindowScript records the request ARGV[3] in the sorted set KEYS[1]
+// if fewer than ARGV[1] requests were recorded in the last ARGV[2]
+// microseconds.
+var slidingWindowScript = redis.NewScript(1, `
+redis.replicate_commands()
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local n = redis.call('ZCARD', KEYS[1])
+if n < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[3])
+  redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+  return {1, limit - n - 1, 0}
+end
+local e = redis.call('ZRANGE', KEYS[1], n - limit, n - limit, 'WITHSCORES')
+return {0, 0, math.max(0, tonumber(e[2]) + window - now)}
+`)
+
+// TokenBucketLimiter is a token bucket rate limiter. Each key has a bucket
+// that holds up to limit tokens and refills at limit tokens per window. A
+// request takes a token from the bucket. The bucket allows bursts of up to
+// limit requests.
+//
+// The bucket is stored in a hash at the key. The hash expires after window.
+type TokenBucketLimiter struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+}
+
+// Allow takes a token from the bucket for key if a token is available.
+func (l *TokenBucketLimiter) Allow(ctx context.Context, key string, limit int64, window time.Duration) (RateLimitResult, error) {
+	c := l.Pool.Get()
+	defer c.Close()
+	return rateLimitResult(tokenBucketScript.DoContext(c, ctx, key, limit, int64(window/time.Microsecond)))
+}
+
+// SlidingWindowLimiter is a sliding window log rate limiter. The limiter
+// allows a request for a key if fewer than limit requests were allowed for
+// the key in the preceding window. Unlike TokenBucketLimiter, the limiter
+// does not allow a burst to exceed the limit at any point in time.
+//
+// The times of the allowed requests are stored in a sorted set at the key.
+// Memory use is proportional to limit.
+type SlidingWindowLimiter struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+}
+
+// Allow records a request for key if the request is allowed.
+func (l *SlidingWindowLimiter) Allow(ctx context.Context, key string, limit int64, window time.Duration) (RateLimitResult, error) {
+	id, err := randomToken()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	c := l.Pool.Get()
+	defer c.Close()
+	return rateLimitResult(slidingWindowScript.DoContext(c, ctx, key, limit, int64(window/time.Microsecond), id))
+}
+
+func rateLimitResult(reply interface{}, err error) (RateLimitResult, error) {
+	var (
+		r       RateLimitResult
+		allowed int
+		retry   int64
+	)
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return r, err
+	}
+	if _, err := redis.Scan(values, &allowed, &r.Remaining, &retry); err != nil {
+		return r, err
+	}
+	r.Allowed = allowed == 1
+	r.RetryAfter = time.Duration(retry) * time.Microsecond
+	return r, nil
+}
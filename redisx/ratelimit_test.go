@@ -0,0 +1,77 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+// mockPool returns a pool that reuses the mock connection c.
+func mockPool(c redis.Conn) *redis.Pool {
+	return &redis.Pool{Dial: func() (redis.Conn, error) { return c, nil }, MaxIdle: 1}
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("EVALSHA", redistest.Any(), 1, "rl", 10, 60000000).Reply([]interface{}{int64(1), int64(9), int64(0)})
+	c.Expect("EVALSHA", redistest.Any(), 1, "rl", 10, 60000000).Reply([]interface{}{int64(0), int64(0), int64(1500000)})
+
+	var l redisx.RateLimiter = &redisx.TokenBucketLimiter{Pool: mockPool(c)}
+	r, err := l.Allow(context.Background(), "rl", 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (redisx.RateLimitResult{Allowed: true, Remaining: 9}); r != expected {
+		t.Errorf("Allow() = %+v, want %+v", r, expected)
+	}
+	r, err = l.Allow(context.Background(), "rl", 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (redisx.RateLimitResult{RetryAfter: 1500 * time.Millisecond}); r != expected {
+		t.Errorf("Allow() = %+v, want %+v", r, expected)
+	}
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	c.Expect("EVALSHA", redistest.Any(), 1, "rl", 2, 1000000, redistest.Any()).Error(redis.Error("NOSCRIPT No matching script."))
+	c.Expect("EVAL", redistest.Any(), 1, "rl", 2, 1000000, redistest.Any()).Reply([]interface{}{int64(1), int64(1), int64(0)})
+
+	var l redisx.RateLimiter = &redisx.SlidingWindowLimiter{Pool: mockPool(c)}
+	r, err := l.Allow(context.Background(), "rl", 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (redisx.RateLimitResult{Allowed: true, Remaining: 1}); r != expected {
+		t.Errorf("Allow() = %+v, want %+v", r, expected)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Allow(ctx, "rl", 2, time.Second); err != context.Canceled {
+		t.Errorf("Allow() with canceled context returned %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errSchedulerConfig = errors.New("redigo: Scheduler requires Pool, Name and Queue")
+
+// rescheduleScript sets the run time of job ARGV[2] in KEYS[1] to ARGV[1] if
+// the job is scheduled.
+var rescheduleScript = redis.NewScript(1, `
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+  return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// moveDueScript moves up to ARGV[2] jobs with a run time at or before
+// ARGV[1] from the schedule KEYS[1] and the bodies KEYS[2] to the queue
+// KEYS[3]. The queue message ID is derived from the job ID and run time.
+var moveDueScript = redis.NewScript(3, `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, id in ipairs(ids) do
+  local body = redis.call('HGET', KEYS[2], id)
+  redis.call('ZREM', KEYS[1], id)
+  redis.call('HDEL', KEYS[2], id)
+  if body then
+    local mid = string.sub(redis.sha1hex(id .. ':' .. ARGV[1]), 1, 32)
+    redis.call('LPUSH', KEYS[3], mid .. body)
+  end
+end
+return #ids
+`)
+
+// Scheduler stores jobs to run at a later time. A job is moved to the ready
+// Queue when its run time arrives. Consumers receive the job body with the
+// Queue Dequeue method.
+//
+// The job IDs are stored in a sorted set at Name scored by run time and the
+// job bodies are stored in a hash at Name + ":jobs". In Redis Cluster, use
+// the same hash tag in Name and the queue name so that the keys are stored
+// on the same node.
+//
+// Run times are computed with the clock of the application. Skew between the
+// clocks of the schedulers delays or advances jobs by the skew.
+type Scheduler struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Name is the key of the schedule.
+	Name string
+
+	// Queue is the queue that receives jobs when the jobs are due.
+	Queue *Queue
+
+	// Interval is the time between polls in the Run method. The default is
+	// one second.
+	Interval time.Duration
+
+	// BatchSize is the maximum number of jobs moved to the queue in one
+	// round trip. The default is 100.
+	BatchSize int
+
+	// ErrorHandler is an optional function called with errors from the
+	// server in the Run method.
+	ErrorHandler func(err error)
+}
+
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// Schedule schedules a job to run at runAt. A job scheduled with the ID of
+// a pending job replaces the pending job.
+func (s *Scheduler) Schedule(ctx context.Context, id string, body []byte, runAt time.Time) error {
+	c := s.Pool.Get()
+	defer c.Close()
+	c.Send("MULTI")
+	c.Send("HSET", s.Name+":jobs", id, body)
+	c.Send("ZADD", s.Name, unixMillis(runAt), id)
+	_, err := redis.DoContext(c, ctx, "EXEC")
+	return err
+}
+
+// Cancel removes a pending job. Cancel returns ErrNotFound if the job is
+// not pending.
+func (s *Scheduler) Cancel(ctx context.Context, id string) error {
+	c := s.Pool.Get()
+	defer c.Close()
+	c.Send("MULTI")
+	c.Send("ZREM", s.Name, id)
+	c.Send("HDEL", s.Name+":jobs", id)
+	values, err := redis.Ints(redis.DoContext(c, ctx, "EXEC"))
+	if err != nil {
+		return err
+	}
+	if values[0] == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Reschedule changes the run time of a pending job. Reschedule returns
+// ErrNotFound if the job is not pending.
+func (s *Scheduler) Reschedule(ctx context.Context, id string, runAt time.Time) error {
+	c := s.Pool.Get()
+	defer c.Close()
+	ok, err := redis.Bool(rescheduleScript.DoContext(c, ctx, s.Name, unixMillis(runAt), id))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MoveDue moves up to max due jobs to the queue and returns the number of
+// jobs moved. The move is atomic, so concurrent schedulers do not deliver a
+// job twice.
+func (s *Scheduler) MoveDue(ctx context.Context, max int) (int, error) {
+	if s.Pool == nil || s.Name == "" || s.Queue == nil {
+		return 0, errSchedulerConfig
+	}
+	c := s.Pool.Get()
+	defer c.Close()
+	return redis.Int(moveDueScript.DoContext(c, ctx, s.Name, s.Name+":jobs", s.Queue.Name, unixMillis(time.Now()), max))
+}
+
+// Run moves due jobs to the queue at the configured interval until the
+// context is done. Run returns the context's error or an error for invalid
+// configuration. Errors from the server are reported to ErrorHandler.
+func (s *Scheduler) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	batch := s.BatchSize
+	if batch <= 0 {
+		batch = 100
+	}
+	for {
+		n, err := s.MoveDue(ctx, batch)
+		switch {
+		case err == errSchedulerConfig:
+			return err
+		case err != nil:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if s.ErrorHandler != nil {
+				s.ErrorHandler(err)
+			}
+		case n == batch:
+			// More jobs may be due.
+			continue
+		}
+		if !sleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestScheduler(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+
+	runAt := time.Unix(1500000000, 0)
+	c.Expect("MULTI").Reply("OK")
+	c.Expect("HSET", "s:jobs", "j1", "body").Reply("QUEUED")
+	c.Expect("ZADD", "s", 1500000000000, "j1").Reply("QUEUED")
+	c.Expect("EXEC").Reply([]interface{}{int64(1), int64(1)})
+	c.Expect("EVALSHA", redistest.Any(), 1, "s", 1500000060000, "j1").Reply(int64(1))
+	c.Expect("EVALSHA", redistest.Any(), 1, "s", 1500000060000, "j2").Reply(int64(0))
+	c.Expect("EVALSHA", redistest.Any(), 3, "s", "s:jobs", "q", redistest.Any(), 10).Reply(int64(1))
+	c.Expect("MULTI").Reply("OK")
+	c.Expect("ZREM", "s", "j1").Reply("QUEUED")
+	c.Expect("HDEL", "s:jobs", "j1").Reply("QUEUED")
+	c.Expect("EXEC").Reply([]interface{}{int64(0), int64(0)})
+
+	p := mockPool(c)
+	s := &redisx.Scheduler{Pool: p, Name: "s", Queue: &redisx.Queue{Pool: p, Name: "q"}}
+	ctx := context.Background()
+	if err := s.Schedule(ctx, "j1", []byte("body"), runAt); err != nil {
+		t.Fatalf("Schedule() returned %v", err)
+	}
+	if err := s.Reschedule(ctx, "j1", runAt.Add(time.Minute)); err != nil {
+		t.Errorf("Reschedule(j1) returned %v", err)
+	}
+	if err := s.Reschedule(ctx, "j2", runAt.Add(time.Minute)); err != redisx.ErrNotFound {
+		t.Errorf("Reschedule(j2) returned %v, want ErrNotFound", err)
+	}
+	if n, err := s.MoveDue(ctx, 10); n != 1 || err != nil {
+		t.Errorf("MoveDue() = %d, %v, want 1, nil", n, err)
+	}
+	if err := s.Cancel(ctx, "j1"); err != redisx.ErrNotFound {
+		t.Errorf("Cancel(j1) returned %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errSemaphoreConfig = errors.New("redigo: Semaphore requires Pool, Key, Limit and TTL")
+
+// ErrSemaphoreFull is returned by the Semaphore TryAcquire method when all
+// permits are held.
+var ErrSemaphoreFull = errors.New("redigo: semaphore full")
+
+// ErrLeaseExpired is returned by the Lease Refresh and Release methods when
+// the lease expired.
+var ErrLeaseExpired = errors.New("redigo: semaphore lease expired")
+
+// acquireSemaphoreScript acquires a permit for holder ARGV[3] in the sorted
+// set of holders KEYS[1] scored by the time of the last refresh. Holders not
+// refreshed in ARGV[2] milliseconds are removed. When ARGV[4] is "1", the
+// holder waits its turn in the queue KEYS[2] scored by arrival time. Waiters
+// that stop retrying are removed from the queue using the last attempt times
+// in KEYS[3]. Effects replication is enabled because Redis versions before 5
+// reject writes after TIME otherwise.
+var acquireSemaphoreScript = redis.NewScript(3, `
+redis.replicate_commands()
+local limit = tonumber(ARGV[1])
+local ttl = tonumber(ARGV[2])
+local id = ARGV[3]
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - ttl)
+local free = limit - redis.call('ZCARD', KEYS[1])
+if ARGV[4] ~= '1' then
+  if free <= 0 then
+    return 0
+  end
+  redis.call('ZADD', KEYS[1], now, id)
+  redis.call('PEXPIRE', KEYS[1], ttl)
+  return 1
+end
+local stale = redis.call('ZRANGEBYSCORE', KEYS[3], '-inf', now - ttl)
+for _, w in ipairs(stale) do
+  redis.call('ZREM', KEYS[2], w)
+  redis.call('ZREM', KEYS[3], w)
+end
+if not redis.call('ZSCORE', KEYS[2], id) then
+  redis.call('ZADD', KEYS[2], now, id)
+end
+redis.call('ZADD', KEYS[3], now, id)
+redis.call('PEXPIRE', KEYS[2], ttl)
+redis.call('PEXPIRE', KEYS[3], ttl)
+if free <= 0 or redis.call('ZRANK', KEYS[2], id) >= free then
+  return 0
+end
+redis.call('ZREM', KEYS[2], id)
+redis.call('ZREM', KEYS[3], id)
+redis.call('ZADD', KEYS[1], now, id)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return 1
+`)
+
+// refreshSemaphoreScript resets the lease time of holder ARGV[2] in KEYS[1]
+// if the holder's lease has not expired.
+var refreshSemaphoreScript = redis.NewScript(1, `
+redis.replicate_commands()
+local ttl = tonumber(ARGV[1])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - ttl)
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+  return 0
+end
+redis.call('ZADD', KEYS[1], now, ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ttl)
+return 1
+`)
+
+// Semaphore is a distributed counting semaphore that allows up to Limit
+// concurrent holders. A holder obtains a Lease from the semaphore. Leases
+// expire after TTL unless refreshed, so permits held by a crashed process
+// are returned to the semaphore.
+//
+// The holders are stored in a sorted set at Key. A fair semaphore also
+// stores the waiting holders in sorted sets at Key + ":queue" and Key +
+// ":waiters". In Redis Cluster, use a hash tag in Key so that the keys are
+// stored on the same node.
+type Semaphore struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Key is the key of the semaphore.
+	Key string
+
+	// Limit is the maximum number of concurrent holders.
+	Limit int64
+
+	// TTL is the time after which a lease expires if it is not refreshed.
+	TTL time.Duration
+
+	// Fair specifies whether the Acquire method grants permits in the order
+	// of the first attempt. An unfair semaphore grants a free permit to the
+	// next attempt, so a waiter can be starved by other waiters.
+	Fair bool
+
+	// RetryDelay is the time between attempts in the Acquire method. The
+	// default is 50 milliseconds.
+	RetryDelay time.Duration
+}
+
+// Lease is a permit held on a semaphore.
+type Lease struct {
+	s  *Semaphore
+	id string
+}
+
+func (s *Semaphore) check() error {
+	if s.Pool == nil || s.Key == "" || s.Limit <= 0 || s.TTL <= 0 {
+		return errSemaphoreConfig
+	}
+	return nil
+}
+
+func (s *Semaphore) acquire(ctx context.Context, id string) (bool, error) {
+	c := s.Pool.Get()
+	defer c.Close()
+	fair := "0"
+	if s.Fair {
+		fair = "1"
+	}
+	return redis.Bool(acquireSemaphoreScript.DoContext(c, ctx,
+		s.Key, s.Key+":queue", s.Key+":waiters",
+		s.Limit, int64(s.TTL/time.Millisecond), id, fair))
+}
+
+// remove removes the holder id from the semaphore.
+func (s *Semaphore) remove(id string) (bool, error) {
+	c := s.Pool.Get()
+	defer c.Close()
+	c.Send("MULTI")
+	c.Send("ZREM", s.Key, id)
+	c.Send("ZREM", s.Key+":queue", id)
+	c.Send("ZREM", s.Key+":waiters", id)
+	values, err := redis.Ints(c.Do("EXEC"))
+	if err != nil {
+		return false, err
+	}
+	return values[0] == 1, nil
+}
+
+// TryAcquire attempts to acquire a permit. TryAcquire returns
+// ErrSemaphoreFull if all permits are held or, for a fair semaphore,
+// permits are promised to earlier waiters.
+func (s *Semaphore) TryAcquire(ctx context.Context) (*Lease, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.acquire(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if s.Fair {
+			s.remove(id)
+		}
+		return nil, ErrSemaphoreFull
+	}
+	return &Lease{s: s, id: id}, nil
+}
+
+// Acquire acquires a permit, retrying until a permit is acquired or the
+// context is done.
+func (s *Semaphore) Acquire(ctx context.Context) (*Lease, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	delay := s.RetryDelay
+	if delay <= 0 {
+		delay = 50 * time.Millisecond
+	}
+	for {
+		ok, err := s.acquire(ctx, id)
+		if err != nil {
+			s.remove(id)
+			return nil, err
+		}
+		if ok {
+			return &Lease{s: s, id: id}, nil
+		}
+		if !sleepContext(ctx, delay) {
+			if s.Fair {
+				s.remove(id)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Refresh resets the expiration of the lease to the semaphore's TTL.
+func (l *Lease) Refresh(ctx context.Context) error {
+	c := l.s.Pool.Get()
+	defer c.Close()
+	ok, err := redis.Bool(refreshSemaphoreScript.DoContext(c, ctx, l.s.Key, int64(l.s.TTL/time.Millisecond), l.id))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLeaseExpired
+	}
+	return nil
+}
+
+// Release returns the permit to the semaphore.
+func (l *Lease) Release() error {
+	ok, err := l.s.remove(l.id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLeaseExpired
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func expectSemaphoreRemove(c *redistest.Conn, removed int64) {
+	c.Expect("MULTI").Reply("OK")
+	c.Expect("ZREM", "sem", redistest.Any()).Reply("QUEUED")
+	c.Expect("ZREM", "sem:queue", redistest.Any()).Reply("QUEUED")
+	c.Expect("ZREM", "sem:waiters", redistest.Any()).Reply("QUEUED")
+	c.Expect("EXEC").Reply([]interface{}{removed, int64(0), int64(0)})
+}
+
+func TestSemaphore(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+	acquire := func(fair string) *redistest.Expectation {
+		return c.Expect("EVALSHA", redistest.Any(), 3, "sem", "sem:queue", "sem:waiters", 2, 10000, redistest.Any(), fair)
+	}
+	acquire("0").Reply(int64(1))
+	acquire("0").Reply(int64(0))
+	c.Expect("EVALSHA", redistest.Any(), 1, "sem", 10000, redistest.Any()).Reply(int64(1))
+	expectSemaphoreRemove(c, 1)
+	c.Expect("EVALSHA", redistest.Any(), 1, "sem", 10000, redistest.Any()).Reply(int64(0))
+	expectSemaphoreRemove(c, 0)
+
+	s := &redisx.Semaphore{Pool: mockPool(c), Key: "sem", Limit: 2, TTL: 10 * time.Second}
+	ctx := context.Background()
+	l, err := s.TryAcquire(ctx)
+	if err != nil {
+		t.Fatalf("TryAcquire() returned %v", err)
+	}
+	if _, err := s.TryAcquire(ctx); err != redisx.ErrSemaphoreFull {
+		t.Errorf("TryAcquire() returned %v, want ErrSemaphoreFull", err)
+	}
+	if err := l.Refresh(ctx); err != nil {
+		t.Errorf("Refresh() returned %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Errorf("Release() returned %v", err)
+	}
+	if err := l.Refresh(ctx); err != redisx.ErrLeaseExpired {
+		t.Errorf("Refresh() returned %v, want ErrLeaseExpired", err)
+	}
+	if err := l.Release(); err != redisx.ErrLeaseExpired {
+		t.Errorf("Release() returned %v, want ErrLeaseExpired", err)
+	}
+}
+
+func TestFairSemaphoreAcquire(t *testing.T) {
+	c := redistest.NewConn(t)
+	defer c.Verify()
+
+	// The waiter keeps its place in the queue between attempts.
+	var id string
+	sameID := redistest.MatchFunc("same id", func(arg string) bool {
+		if id == "" {
+			id = arg
+		}
+		return arg == id
+	})
+	c.Expect("EVALSHA", redistest.Any(), 3, "sem", "sem:queue", "sem:waiters", 1, 10000, sameID, "1").Reply(int64(0)).Times(2)
+	c.Expect("EVALSHA", redistest.Any(), 3, "sem", "sem:queue", "sem:waiters", 1, 10000, sameID, "1").Reply(int64(1))
+
+	s := &redisx.Semaphore{Pool: mockPool(c), Key: "sem", Limit: 1, TTL: 10 * time.Second, Fair: true, RetryDelay: time.Millisecond}
+	if _, err := s.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire() returned %v", err)
+	}
+
+	// An abandoned attempt is removed from the queue.
+	c.Expect("EVALSHA", redistest.Any(), 3, "sem", "sem:queue", "sem:waiters", 1, 10000, redistest.Any(), "1").Reply(int64(0))
+	expectSemaphoreRemove(c, 0)
+	if _, err := s.TryAcquire(context.Background()); err != redisx.ErrSemaphoreFull {
+		t.Errorf("TryAcquire() returned %v, want ErrSemaphoreFull", err)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import "context"
+
+// Shutdown gracefully closes the Subscriber. Shutdown unsubscribes from all
+// channels and patterns and continues to deliver the messages received
+// before the server confirms the unsubscribe. Shutdown then closes the
+// connection and the message channel. Messages buffered in the message
+// channel remain available to the application after Shutdown returns.
+//
+// If the context is done before the messages are delivered, then Shutdown
+// closes the Subscriber and returns the context's error.
+func (s *Subscriber) Shutdown(ctx context.Context) error {
+	s.drainOnce.Do(func() { close(s.draining) })
+	sent, err := s.ResilientPubSub.unsubscribeAll()
+	if err != nil || !sent {
+		return s.Close()
+	}
+
+	select {
+	case <-s.done:
+		return s.Close()
+	case <-ctx.Done():
+		s.Close()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestSubscriberShutdown(t *testing.T) {
+	pc, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	s := redisx.NewSubscriber(&redisx.ResilientPubSub{Dial: dialPubSub}, redisx.SubscriberBufferSize(10))
+	s.Subscribe("c1")
+	s.PSubscribe("p*")
+	waitSubscribers(t, pc, "c1", 1)
+
+	for i := 0; i < 3; i++ {
+		pc.Do("PUBLISH", "c1", i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() returned %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		expectMessage(t, s, redisx.Message{Channel: "c1", Data: []byte(strconv.Itoa(i))})
+	}
+	if _, ok := <-s.Messages(); ok {
+		t.Fatal("message channel not closed")
+	}
+}
@@ -0,0 +1,647 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errStreamIteratorClosed = errors.New("redigo: stream iterator closed")
+
+var errStreamConsumerConfig = errors.New("redigo: StreamConsumer requires Pool, Stream, Group, Consumer and Handler")
+
+// StreamConsumer reads entries from a stream as a member of a consumer
+// group and calls a handler for each entry. Entries are acknowledged with
+// XACK when the handler returns nil. Entries for which the handler returns
+// an error remain pending in the group.
+type StreamConsumer struct {
+
+	// Pool is the connection pool. The consumer holds one connection for
+	// reading and uses other connections for acknowledgements.
+	Pool *redis.Pool
+
+	// Stream, Group and Consumer specify the stream key, the consumer group
+	// name and the name of the consumer in the group.
+	Stream   string
+	Group    string
+	Consumer string
+
+	// StartID is the ID passed to XGROUP CREATE when the group does not
+	// exist. The default is "$", which delivers new entries only. Use "0"
+	// to deliver all entries in the stream.
+	StartID string
+
+	// Handler is called for each entry.
+	Handler func(ctx context.Context, entry redis.StreamEntry) error
+
+	// Concurrency is the number of goroutines calling Handler. The default
+	// is 1.
+	Concurrency int
+
+	// Count is the maximum number of entries read or claimed by each
+	// command. The default is 10.
+	Count int
+
+	// Block is the time that XREADGROUP waits for entries. The consumer
+	// checks for context cancellation between reads. The default is one
+	// second.
+	Block time.Duration
+
+	// ErrorHandler is an optional function called with errors from the
+	// server and the handler.
+	ErrorHandler func(err error)
+
+	// ClaimMinIdle enables recovery of entries delivered to consumers that
+	// crashed. If ClaimMinIdle is greater than zero, then the consumer
+	// periodically claims entries that are pending in the group for at least
+	// ClaimMinIdle and calls the handler for the entries. Entries for which
+	// the handler returned an error are also claimed again. ClaimMinIdle
+	// should be greater than the time taken by the handler.
+	ClaimMinIdle time.Duration
+
+	// ClaimInterval is the time between claims. The default is
+	// ClaimMinIdle.
+	ClaimInterval time.Duration
+
+	// OnClaim is an optional function called with the number of entries
+	// claimed by each claim.
+	OnClaim func(count int)
+
+	// DeadLetterStream and MaxDeliveries specify a dead-letter policy for
+	// claimed entries. If both fields are set, then a claimed entry that
+	// was delivered more than MaxDeliveries times is added to the
+	// DeadLetterStream stream and acknowledged instead of being passed to
+	// the handler. The dead-letter entry has the fields of the original
+	// entry and the fields dlq-stream, dlq-id, dlq-group, dlq-consumer and
+	// dlq-deliveries.
+	DeadLetterStream string
+	MaxDeliveries    int
+}
+
+// CreateGroup creates the consumer group if the group does not exist. The
+// stream is created if it does not exist.
+func (sc *StreamConsumer) CreateGroup() error {
+	c := sc.Pool.Get()
+	defer c.Close()
+	startID := sc.StartID
+	if startID == "" {
+		startID = "$"
+	}
+	_, err := c.Do("XGROUP", "CREATE", sc.Stream, sc.Group, startID, "MKSTREAM")
+	if redis.ErrorCode(err) == "BUSYGROUP" {
+		return nil
+	}
+	return err
+}
+
+// Run creates the consumer group if needed, then reads entries and calls
+// the handler until the context is done. Run waits for running handlers to
+// return and then returns the context's error.
+func (sc *StreamConsumer) Run(ctx context.Context) error {
+	if sc.Pool == nil || sc.Stream == "" || sc.Group == "" || sc.Consumer == "" || sc.Handler == nil {
+		return errStreamConsumerConfig
+	}
+	if err := sc.CreateGroup(); err != nil {
+		return err
+	}
+
+	concurrency := sc.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	entries := make(chan redis.StreamEntry)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range entries {
+				sc.handle(ctx, entry)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(entries)
+
+	var c redis.Conn
+	defer func() {
+		if c != nil {
+			c.Close()
+		}
+	}()
+
+	var (
+		nextClaim   time.Time
+		claimCursor = "0-0"
+	)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if c == nil {
+			c = sc.Pool.Get()
+		}
+		var (
+			batch []redis.StreamEntry
+			err   error
+		)
+		if sc.ClaimMinIdle > 0 && !time.Now().Before(nextClaim) {
+			batch, claimCursor, err = sc.claim(c, claimCursor)
+			if err == nil && sc.DeadLetterStream != "" && sc.MaxDeliveries > 0 {
+				batch, err = sc.deadLetter(c, batch)
+			}
+			if err == nil {
+				if sc.OnClaim != nil {
+					sc.OnClaim(len(batch))
+				}
+				// Continue immediately when more entries may be pending.
+				if claimCursor == "0-0" {
+					interval := sc.ClaimInterval
+					if interval <= 0 {
+						interval = sc.ClaimMinIdle
+					}
+					nextClaim = time.Now().Add(interval)
+				}
+			}
+		} else {
+			batch, err = sc.read(c)
+		}
+		if err != nil {
+			sc.reportError(err)
+			if c.Err() != nil {
+				c.Close()
+				c = nil
+			}
+			if !sleepContext(ctx, time.Second) {
+				return ctx.Err()
+			}
+			continue
+		}
+		for _, entry := range batch {
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+				// Undelivered entries remain pending in the group.
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// read reads a batch of new entries for the consumer.
+func (sc *StreamConsumer) read(c redis.Conn) ([]redis.StreamEntry, error) {
+	block := sc.Block
+	if block <= 0 {
+		block = time.Second
+	}
+	reply, err := c.Do("XREADGROUP", "GROUP", sc.Group, sc.Consumer,
+		"COUNT", sc.count(), "BLOCK", int64(block/time.Millisecond),
+		"STREAMS", sc.Stream, ">")
+	return readGroupEntries(reply, err)
+}
+
+// claim claims idle pending entries for the consumer using XAUTOCLAIM,
+// starting at cursor. The function returns the claimed entries and the cursor
+// for the next call. If the server does not support XAUTOCLAIM, then the
+// function uses XPENDING and XCLAIM.
+func (sc *StreamConsumer) claim(c redis.Conn, cursor string) ([]redis.StreamEntry, string, error) {
+	minIdle := int64(sc.ClaimMinIdle / time.Millisecond)
+	reply, err := c.Do("XAUTOCLAIM", sc.Stream, sc.Group, sc.Consumer, minIdle, cursor, "COUNT", sc.count())
+	if e, ok := err.(redis.Error); ok && strings.Contains(e.Message(), "unknown command") {
+		entries, err := sc.claimPending(c)
+		return entries, "0-0", err
+	}
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return nil, cursor, err
+	}
+	if len(values) < 2 {
+		return nil, cursor, errors.New("redigo: unexpected XAUTOCLAIM reply")
+	}
+	next, err := redis.String(values[0], nil)
+	if err != nil {
+		return nil, cursor, err
+	}
+	items, err := redis.Values(values[1], nil)
+	if err != nil {
+		return nil, cursor, err
+	}
+	// Redis 6.2 returns nil for entries deleted from the stream.
+	live := items[:0]
+	for _, item := range items {
+		if item != nil {
+			live = append(live, item)
+		}
+	}
+	entries, err := redis.StreamEntries(live, nil)
+	return entries, next, err
+}
+
+// claimPending claims idle pending entries using XPENDING and XCLAIM.
+func (sc *StreamConsumer) claimPending(c redis.Conn) ([]redis.StreamEntry, error) {
+	pending, err := redis.Values(c.Do("XPENDING", sc.Stream, sc.Group, "-", "+", sc.count()))
+	if err != nil {
+		return nil, err
+	}
+	args := redis.Args{sc.Stream, sc.Group, sc.Consumer, int64(sc.ClaimMinIdle / time.Millisecond)}
+	n := len(args)
+	for _, p := range pending {
+		var (
+			id       string
+			consumer string
+			idle     int64
+		)
+		fields, err := redis.Values(p, nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := redis.Scan(fields, &id, &consumer, &idle); err != nil {
+			return nil, err
+		}
+		if time.Duration(idle)*time.Millisecond >= sc.ClaimMinIdle {
+			args = append(args, id)
+		}
+	}
+	if len(args) == n {
+		return nil, nil
+	}
+	return redis.StreamEntries(c.Do("XCLAIM", args...))
+}
+
+// deadLetter moves the claimed entries that exceed the delivery limit to the
+// dead-letter stream and returns the remaining entries.
+func (sc *StreamConsumer) deadLetter(c redis.Conn, entries []redis.StreamEntry) ([]redis.StreamEntry, error) {
+	if len(entries) == 0 {
+		return entries, nil
+	}
+	// Query the delivery count of each claimed entry. A range query could
+	// return other entries pending for the consumer in place of claimed
+	// entries.
+	for _, entry := range entries {
+		c.Send("XPENDING", sc.Stream, sc.Group, entry.ID, entry.ID, 1, sc.Consumer)
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+	deliveries := make(map[string]int64, len(entries))
+	for range entries {
+		pending, err := redis.Values(c.Receive())
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pending {
+			fields, err := redis.Values(p, nil)
+			if err != nil {
+				return nil, err
+			}
+			var (
+				id       string
+				consumer string
+				idle     int64
+				count    int64
+			)
+			if _, err := redis.Scan(fields, &id, &consumer, &idle, &count); err != nil {
+				return nil, err
+			}
+			deliveries[id] = count
+		}
+	}
+
+	live := entries[:0]
+	for _, entry := range entries {
+		n := deliveries[entry.ID]
+		if n <= int64(sc.MaxDeliveries) {
+			live = append(live, entry)
+			continue
+		}
+		// Acknowledge the entry only after it is stored in the dead-letter
+		// stream. If XACK fails, the entry is dead-lettered again on the
+		// next claim.
+		args := redis.Args{sc.DeadLetterStream, "*"}.AddFlat(entry.Fields)
+		args = args.Add("dlq-stream", sc.Stream, "dlq-id", entry.ID, "dlq-group", sc.Group,
+			"dlq-consumer", sc.Consumer, "dlq-deliveries", n)
+		if _, err := c.Do("XADD", args...); err != nil {
+			return nil, err
+		}
+		if _, err := c.Do("XACK", sc.Stream, sc.Group, entry.ID); err != nil {
+			return nil, err
+		}
+	}
+	return live, nil
+}
+
+// count returns the maximum number of entries to read or claim with one
+// command.
+func (sc *StreamConsumer) count() int {
+	if sc.Count <= 0 {
+		return 10
+	}
+	return sc.Count
+}
+
+// handle calls the handler for entry and acknowledges the entry on
+// success.
+func (sc *StreamConsumer) handle(ctx context.Context, entry redis.StreamEntry) {
+	if err := sc.Handler(ctx, entry); err != nil {
+		sc.reportError(err)
+		return
+	}
+	c := sc.Pool.Get()
+	defer c.Close()
+	if _, err := c.Do("XACK", sc.Stream, sc.Group, entry.ID); err != nil {
+		sc.reportError(err)
+	}
+}
+
+func (sc *StreamConsumer) reportError(err error) {
+	if sc.ErrorHandler != nil {
+		sc.ErrorHandler(err)
+	}
+}
+
+// readGroupEntries converts the reply to XREAD or XREADGROUP for a single
+// stream to a slice of entries. A nil reply is returned when the command
+// times out.
+func readGroupEntries(reply interface{}, err error) ([]redis.StreamEntry, error) {
+	messages, err := readMessages(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]redis.StreamEntry, len(messages))
+	for i, m := range messages {
+		entries[i] = m.StreamEntry
+	}
+	return entries, nil
+}
+
+// readMessages is like redis.StreamMessages except that a nil reply is
+// returned when the command times out.
+func readMessages(reply interface{}, err error) ([]redis.StreamMessage, error) {
+	messages, err := redis.StreamMessages(reply, err)
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return messages, err
+}
+
+// StreamIterator reads entries from one or more streams using XREAD BLOCK.
+// The iterator records the ID of the last entry returned from each stream
+// and continues from that ID when it reconnects after a network error.
+//
+//  it := redisx.NewStreamIterator(pool, map[string]string{"events": "$"})
+//  defer it.Close()
+//  for it.Next(ctx) {
+//      stream, entry := it.Entry()
+//      // process entry
+//  }
+//  if err := it.Err(); err != nil && err != context.Canceled {
+//      // handle error
+//  }
+type StreamIterator struct {
+	// Count is the maximum number of entries read by each XREAD command.
+	// The default is 10.
+	Count int
+
+	// Block is the time that XREAD waits for entries. The iterator checks
+	// for context cancellation between reads. The default is one second.
+	Block time.Duration
+
+	// ErrorHandler is an optional function called with errors that cause
+	// the iterator to reconnect.
+	ErrorHandler func(err error)
+
+	pool    *redis.Pool
+	c       redis.Conn
+	streams []string
+	ids     map[string]string
+	batch   []redis.StreamMessage
+	message redis.StreamMessage
+	err     error
+}
+
+// NewStreamIterator returns an iterator over the streams in the map keys.
+// The map values are the IDs to start reading after. Use "$" to read new
+// entries only. The "$" ID is used until the first entry arrives from the
+// stream, so entries added while the iterator reconnects before then are
+// not returned.
+func NewStreamIterator(p *redis.Pool, streams map[string]string) *StreamIterator {
+	it := &StreamIterator{pool: p, ids: make(map[string]string, len(streams))}
+	for stream, id := range streams {
+		it.streams = append(it.streams, stream)
+		it.ids[stream] = id
+	}
+	sort.Strings(it.streams)
+	return it
+}
+
+// Next advances the iterator to the next entry. Next blocks until an entry
+// arrives, the context is done or the server returns an error. Next returns
+// false when the iteration stops. Use the Err method to get the reason.
+func (it *StreamIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	for {
+		if len(it.batch) > 0 {
+			it.message = it.batch[0]
+			it.batch = it.batch[1:]
+			it.ids[it.message.Stream] = it.message.ID
+			return true
+		}
+		if err := ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		if it.c == nil {
+			it.c = it.pool.Get()
+		}
+		batch, err := it.read()
+		if err != nil {
+			if it.c.Err() == nil {
+				// The server rejected the command. Retrying will not help.
+				it.err = err
+				return false
+			}
+			if it.ErrorHandler != nil {
+				it.ErrorHandler(err)
+			}
+			it.c.Close()
+			it.c = nil
+			if !sleepContext(ctx, time.Second) {
+				it.err = ctx.Err()
+				return false
+			}
+			continue
+		}
+		it.batch = batch
+	}
+}
+
+func (it *StreamIterator) read() ([]redis.StreamMessage, error) {
+	count := it.Count
+	if count <= 0 {
+		count = 10
+	}
+	block := it.Block
+	if block <= 0 {
+		block = time.Second
+	}
+	args := redis.Args{"COUNT", count, "BLOCK", int64(block / time.Millisecond), "STREAMS"}
+	args = args.AddFlat(it.streams)
+	for _, stream := range it.streams {
+		args = append(args, it.ids[stream])
+	}
+	return readMessages(it.c.Do("XREAD", args...))
+}
+
+// Entry returns the stream name and the entry at the current position of
+// the iterator.
+func (it *StreamIterator) Entry() (string, redis.StreamEntry) {
+	return it.message.Stream, it.message.StreamEntry
+}
+
+// LastID returns the ID of the last entry returned from stream or the start
+// ID if no entry was returned from stream.
+func (it *StreamIterator) LastID(stream string) string {
+	return it.ids[stream]
+}
+
+// Err returns the error that stopped the iteration.
+func (it *StreamIterator) Err() error {
+	return it.err
+}
+
+// Close releases the connection held by the iterator.
+func (it *StreamIterator) Close() error {
+	if it.err == nil {
+		it.err = errStreamIteratorClosed
+	}
+	if it.c == nil {
+		return nil
+	}
+	err := it.c.Close()
+	it.c = nil
+	return err
+}
+
+// sleepContext waits for d or until the context is done. The function
+// returns false if the context is done.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+var errStreamFanInConfig = errors.New("redigo: StreamFanIn requires Pool and Streams")
+
+// StreamFanIn reads entries from many streams using a bounded number of
+// connections and merges the entries into a single channel. Entries from
+// the same stream are delivered in order. There is no ordering between
+// entries from different streams.
+type StreamFanIn struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Streams maps stream keys to the IDs to start reading after. See
+	// NewStreamIterator for the meaning of the "$" ID.
+	Streams map[string]string
+
+	// MaxConns is the maximum number of connections used to read the
+	// streams. The streams are divided into MaxConns chunks and each chunk
+	// is read with a single XREAD command. The default is 1.
+	MaxConns int
+
+	// Count, Block and ErrorHandler are passed to the stream iterator for
+	// each chunk.
+	Count        int
+	Block        time.Duration
+	ErrorHandler func(err error)
+}
+
+// Run reads entries and sends the entries to messages until the context is
+// done or an iterator stops with an error. Run returns the first error.
+func (f *StreamFanIn) Run(ctx context.Context, messages chan<- redis.StreamMessage) error {
+	if f.Pool == nil || len(f.Streams) == 0 {
+		return errStreamFanInConfig
+	}
+	streams := make([]string, 0, len(f.Streams))
+	for stream := range f.Streams {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+
+	n := f.MaxConns
+	if n <= 0 {
+		n = 1
+	}
+	if n > len(streams) {
+		n = len(streams)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		// Assign streams to chunks round robin.
+		chunk := make(map[string]string)
+		for j := i; j < len(streams); j += n {
+			chunk[streams[j]] = f.Streams[streams[j]]
+		}
+		go func() {
+			errs <- f.read(ctx, chunk, messages)
+		}()
+	}
+
+	var err error
+	for i := 0; i < n; i++ {
+		if e := <-errs; err == nil {
+			err = e
+			cancel()
+		}
+	}
+	return err
+}
+
+// read reads entries from the streams in chunk using one iterator.
+func (f *StreamFanIn) read(ctx context.Context, chunk map[string]string, messages chan<- redis.StreamMessage) error {
+	it := NewStreamIterator(f.Pool, chunk)
+	it.Count = f.Count
+	it.Block = f.Block
+	it.ErrorHandler = f.ErrorHandler
+	defer it.Close()
+	for it.Next(ctx) {
+		stream, entry := it.Entry()
+		select {
+		case messages <- redis.StreamMessage{Stream: stream, StreamEntry: entry}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,434 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+// dialTestDB dials the test database. Unlike redistest.Dial, the function
+// does not require the database to be empty.
+func dialTestDB() (redis.Conn, error) {
+	return redis.Dial("tcp", ":6379", redis.DialDatabase(9))
+}
+
+func TestStreamConsumer(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	var (
+		mu       sync.Mutex
+		received []string
+		done     = make(chan struct{})
+	)
+	sc := &redisx.StreamConsumer{
+		Pool:        p,
+		Stream:      "s",
+		Group:       "g",
+		Consumer:    "c1",
+		StartID:     "0",
+		Concurrency: 2,
+		Block:       10 * time.Millisecond,
+		Handler: func(ctx context.Context, entry redis.StreamEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, entry.Fields["n"])
+			if len(received) == 4 {
+				close(done)
+			}
+			if entry.Fields["n"] == "3" {
+				return errors.New("handler failed")
+			}
+			return nil
+		},
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := c.Do("XADD", "s", "*", "n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- sc.Run(ctx) }()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for entries")
+	}
+	cancel()
+	if err := <-runErr; err != context.Canceled {
+		t.Errorf("Run() returned %v, want %v", err, context.Canceled)
+	}
+
+	sort.Strings(received)
+	if len(received) != 4 || received[0] != "0" || received[3] != "3" {
+		t.Errorf("received %v, want [0 1 2 3]", received)
+	}
+
+	// The entry that failed remains pending.
+	pending, err := redis.Values(c.Do("XPENDING", "s", "g"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := redis.Int(pending[0], nil); n != 1 {
+		t.Errorf("pending = %d, want 1", n)
+	}
+
+	// Creating an existing group is not an error.
+	if err := sc.CreateGroup(); err != nil {
+		t.Errorf("CreateGroup() returned %v", err)
+	}
+}
+
+func TestStreamConsumerClaim(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	// Deliver entries to a consumer that never acknowledges them.
+	for i := 0; i < 3; i++ {
+		if _, err := c.Do("XADD", "s", "*", "n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Do("XGROUP", "CREATE", "s", "g", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do("XREADGROUP", "GROUP", "g", "crashed", "STREAMS", "s", ">"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var (
+		mu       sync.Mutex
+		received int
+		claimed  int
+		done     = make(chan struct{})
+	)
+	sc := &redisx.StreamConsumer{
+		Pool:         p,
+		Stream:       "s",
+		Group:        "g",
+		Consumer:     "c1",
+		Block:        10 * time.Millisecond,
+		ClaimMinIdle: 10 * time.Millisecond,
+		OnClaim: func(count int) {
+			mu.Lock()
+			claimed += count
+			mu.Unlock()
+		},
+		Handler: func(ctx context.Context, entry redis.StreamEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received++
+			if received == 3 {
+				close(done)
+			}
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- sc.Run(ctx) }()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for claimed entries")
+	}
+	cancel()
+	<-runErr
+
+	if claimed != 3 {
+		t.Errorf("claimed %d, want 3", claimed)
+	}
+}
+
+func TestStreamConsumerDeadLetter(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	if _, err := c.Do("XADD", "s", "1-0", "n", "poison"); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	sc := &redisx.StreamConsumer{
+		Pool:             p,
+		Stream:           "s",
+		Group:            "g",
+		Consumer:         "c1",
+		StartID:          "0",
+		Block:            5 * time.Millisecond,
+		ClaimMinIdle:     5 * time.Millisecond,
+		DeadLetterStream: "dlq",
+		MaxDeliveries:    2,
+		Handler: func(ctx context.Context, entry redis.StreamEntry) error {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+			return errors.New("handler failed")
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- sc.Run(ctx) }()
+
+	var entries []redis.StreamEntry
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		entries, err = redis.StreamEntries(c.Do("XRANGE", "dlq", "-", "+"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) > 0 {
+			break
+		}
+	}
+	cancel()
+	<-runErr
+
+	if len(entries) != 1 {
+		t.Fatalf("dead-letter stream has %d entries, want 1", len(entries))
+	}
+	fields := entries[0].Fields
+	if fields["n"] != "poison" || fields["dlq-id"] != "1-0" || fields["dlq-stream"] != "s" || fields["dlq-deliveries"] != "3" {
+		t.Errorf("dead-letter fields = %v", fields)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+	pending, err := redis.Values(c.Do("XPENDING", "s", "g"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := redis.Int(pending[0], nil); n != 0 {
+		t.Errorf("pending = %d, want 0", n)
+	}
+}
+
+func TestStreamConsumerDeadLetterError(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	if _, err := c.Do("XADD", "s", "1-0", "n", "poison"); err != nil {
+		t.Fatal(err)
+	}
+	// XADD to the dead-letter stream fails with WRONGTYPE.
+	if _, err := c.Do("SET", "dlq", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 100)
+	sc := &redisx.StreamConsumer{
+		Pool:             p,
+		Stream:           "s",
+		Group:            "g",
+		Consumer:         "c1",
+		StartID:          "0",
+		Block:            5 * time.Millisecond,
+		ClaimMinIdle:     5 * time.Millisecond,
+		DeadLetterStream: "dlq",
+		MaxDeliveries:    1,
+		Handler: func(ctx context.Context, entry redis.StreamEntry) error {
+			return errors.New("handler failed")
+		},
+		ErrorHandler: func(err error) {
+			if _, ok := err.(redis.Error); ok {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- sc.Run(ctx) }()
+
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Error("dead-letter error not reported")
+	}
+	cancel()
+	<-runErr
+
+	pending, err := redis.Values(c.Do("XPENDING", "s", "g"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := redis.Int(pending[0], nil); n != 1 {
+		t.Errorf("pending = %d, want 1", n)
+	}
+}
+
+func TestStreamIterator(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	for _, args := range [][]interface{}{
+		{"a", "1-0", "n", "1"},
+		{"b", "1-0", "n", "2"},
+		{"a", "2-0", "n", "3"},
+	} {
+		if _, err := c.Do("XADD", args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	it := redisx.NewStreamIterator(p, map[string]string{"a": "0", "b": "0"})
+	it.Block = 10 * time.Millisecond
+	defer it.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var received []string
+	for len(received) < 3 && it.Next(ctx) {
+		stream, entry := it.Entry()
+		received = append(received, stream+":"+entry.ID+":"+entry.Fields["n"])
+	}
+	sort.Strings(received)
+	expected := []string{"a:1-0:1", "a:2-0:3", "b:1-0:2"}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("received %v, want %v", received, expected)
+	}
+	if id := it.LastID("a"); id != "2-0" {
+		t.Errorf("LastID(a) = %q, want 2-0", id)
+	}
+
+	added := make(chan struct{})
+	go func() {
+		defer close(added)
+		time.Sleep(20 * time.Millisecond)
+		c.Do("XADD", "b", "3-0", "n", "4")
+	}()
+	ok := it.Next(ctx)
+	<-added
+	if !ok {
+		t.Fatalf("Next() returned false, err = %v", it.Err())
+	}
+	if stream, entry := it.Entry(); stream != "b" || entry.ID != "3-0" {
+		t.Errorf("Entry() = %s, %s, want b, 3-0", stream, entry.ID)
+	}
+
+	cancel()
+	if it.Next(ctx) {
+		t.Fatal("Next() returned true after cancel")
+	}
+	if it.Err() != context.Canceled {
+		t.Errorf("Err() = %v, want %v", it.Err(), context.Canceled)
+	}
+}
+
+func TestStreamFanIn(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 3}
+	defer p.Close()
+
+	streams := map[string]string{}
+	for _, stream := range []string{"a", "b", "c", "d", "e"} {
+		streams[stream] = "0"
+		for i := 1; i <= 2; i++ {
+			if _, err := c.Do("XADD", stream, i, "n", i); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	f := &redisx.StreamFanIn{
+		Pool:     p,
+		Streams:  streams,
+		MaxConns: 2,
+		Block:    10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan redis.StreamMessage)
+	runErr := make(chan error, 1)
+	go func() { runErr <- f.Run(ctx, messages) }()
+
+	lastID := map[string]string{}
+	for i := 0; i < 10; i++ {
+		select {
+		case m := <-messages:
+			if m.ID <= lastID[m.Stream] {
+				t.Errorf("stream %s: got %s after %s", m.Stream, m.ID, lastID[m.Stream])
+			}
+			lastID[m.Stream] = m.ID
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for messages")
+		}
+	}
+	cancel()
+	if err := <-runErr; err != context.Canceled {
+		t.Errorf("Run() returned %v, want %v", err, context.Canceled)
+	}
+	if len(lastID) != 5 {
+		t.Errorf("received messages from %d streams, want 5", len(lastID))
+	}
+}
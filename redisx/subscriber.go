@@ -0,0 +1,279 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Message represents a message delivered by a Subscriber.
+type Message struct {
+
+	// The matched pattern or "" if the message was sent to a subscribed
+	// channel.
+	Pattern string
+
+	// The originating channel.
+	Channel string
+
+	// The message data.
+	Data []byte
+}
+
+// SubscriberOption specifies an option for a Subscriber.
+type SubscriberOption struct {
+	f func(*subscriberOptions)
+}
+
+type subscriberOptions struct {
+	bufferSize int
+	overflow   OverflowPolicy
+}
+
+// OverflowPolicy specifies how a Subscriber handles a message when the
+// message channel is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for the application to receive from the message
+	// channel. Receiving from the server stops while the Subscriber waits.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the message that does not fit in the
+	// channel.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest message in the channel to make
+	// room for the new message.
+	OverflowDropOldest
+)
+
+// SubscriberBufferSize specifies the capacity of the message channel. The
+// channel is unbuffered if this option is not specified.
+func SubscriberBufferSize(n int) SubscriberOption {
+	return SubscriberOption{func(so *subscriberOptions) {
+		so.bufferSize = n
+	}}
+}
+
+// SubscriberOverflow specifies the policy for handling messages when the
+// message channel is full. The default policy is OverflowBlock. The drop
+// policies bound the memory used by a slow application and should be used
+// with a buffered channel.
+func SubscriberOverflow(policy OverflowPolicy) SubscriberOption {
+	return SubscriberOption{func(so *subscriberOptions) {
+		so.overflow = policy
+	}}
+}
+
+const (
+	minRetryDelay = 100 * time.Millisecond
+	maxRetryDelay = 5 * time.Second
+)
+
+// Subscriber delivers the messages received by a ResilientPubSub on a Go
+// channel. A goroutine started by NewSubscriber receives from the
+// ResilientPubSub until the Subscriber is closed.
+//
+// Use the methods of the embedded ResilientPubSub to manage subscriptions.
+// Do not call the ResilientPubSub Receive method directly.
+type Subscriber struct {
+	// dropped is accessed atomically and is the first field for 64 bit
+	// alignment on 32 bit platforms.
+	dropped uint64
+
+	*ResilientPubSub
+
+	overflow OverflowPolicy
+	messages chan Message
+	errors   chan error
+
+	closeOnce sync.Once
+	closing   chan struct{}
+	done      chan struct{}
+
+	drainOnce sync.Once
+	draining  chan struct{}
+}
+
+// NewSubscriber returns a Subscriber for ps and starts receiving messages.
+func NewSubscriber(ps *ResilientPubSub, options ...SubscriberOption) *Subscriber {
+	var so subscriberOptions
+	for _, option := range options {
+		option.f(&so)
+	}
+	s := &Subscriber{
+		ResilientPubSub: ps,
+		overflow:        so.overflow,
+		messages:        make(chan Message, so.bufferSize),
+		errors:          make(chan error, 1),
+		closing:         make(chan struct{}),
+		done:            make(chan struct{}),
+		draining:        make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Messages returns the channel on which messages are delivered. The channel
+// is closed when the Subscriber is closed.
+func (s *Subscriber) Messages() <-chan Message {
+	return s.messages
+}
+
+// Dropped returns the number of messages discarded by the overflow policy.
+func (s *Subscriber) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Stats returns statistics for the Subscriber.
+func (s *Subscriber) Stats() PubSubStats {
+	stats := s.ResilientPubSub.Stats()
+	stats.Dropped = s.Dropped()
+	return stats
+}
+
+// Errors returns the channel on which receive and dial errors are
+// delivered. When the connection is re-established, the error that broke
+// the previous connection is delivered. Errors are discarded if the
+// application does not keep up with them. The channel is closed when the
+// Subscriber is closed.
+func (s *Subscriber) Errors() <-chan error {
+	return s.errors
+}
+
+// Close closes the ResilientPubSub and waits for the receiving goroutine to
+// exit.
+func (s *Subscriber) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closing)
+		err = s.ResilientPubSub.Close()
+	})
+	<-s.done
+	return err
+}
+
+func (s *Subscriber) run() {
+	defer func() {
+		close(s.messages)
+		close(s.errors)
+		close(s.done)
+	}()
+
+	var delay time.Duration
+	for {
+		switch v := s.ResilientPubSub.Receive().(type) {
+		case redis.Message:
+			delay = 0
+			if !s.deliver(Message{Channel: v.Channel, Data: v.Data}) {
+				return
+			}
+		case redis.PMessage:
+			delay = 0
+			if !s.deliver(Message{Pattern: v.Pattern, Channel: v.Channel, Data: v.Data}) {
+				return
+			}
+		case redis.Subscription:
+			if v.Count == 0 && s.isDraining() {
+				// All subscriptions are confirmed removed. Messages
+				// received before this point are delivered.
+				return
+			}
+		case Reconnect:
+			if s.isDraining() {
+				return
+			}
+			delay = 0
+			s.reportError(v.Err)
+		case error:
+			if v == errPubSubClosed {
+				return
+			}
+			s.reportError(v)
+
+			// Back off before the next Receive dials again.
+			if delay == 0 {
+				delay = minRetryDelay
+			} else if delay *= 2; delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-s.closing:
+				t.Stop()
+				return
+			}
+		}
+	}
+}
+
+func (s *Subscriber) isDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver sends m to the message channel using the overflow policy. It
+// returns false if the Subscriber is closed before the message is delivered.
+func (s *Subscriber) deliver(m Message) bool {
+	switch s.overflow {
+	case OverflowDropNewest:
+		select {
+		case s.messages <- m:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+		return true
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.messages <- m:
+				return true
+			default:
+			}
+			select {
+			case <-s.messages:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+				// The channel is unbuffered and the application is not
+				// waiting. Discard the message.
+				atomic.AddUint64(&s.dropped, 1)
+				return true
+			}
+		}
+	}
+	select {
+	case s.messages <- m:
+		return true
+	case <-s.closing:
+		return false
+	}
+}
+
+func (s *Subscriber) reportError(err error) {
+	select {
+	case s.errors <- err:
+	default:
+	}
+}
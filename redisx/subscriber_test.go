@@ -0,0 +1,114 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+// waitSubscribers waits for the channel to have n subscribers.
+func waitSubscribers(t *testing.T, c redis.Conn, channel string, n int) {
+	for i := 0; i < 100; i++ {
+		v, err := redis.Values(c.Do("PUBSUB", "NUMSUB", channel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m, _ := redis.Int(v[1], nil); m == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for %d subscribers to %s", n, channel)
+}
+
+func expectMessage(t *testing.T, s *redisx.Subscriber, expected redisx.Message) {
+	select {
+	case m := <-s.Messages():
+		if !reflect.DeepEqual(m, expected) {
+			t.Fatalf("message = %v, want %v", m, expected)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for message %v", expected)
+	}
+}
+
+func TestSubscriber(t *testing.T) {
+	pc, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	s := redisx.NewSubscriber(&redisx.ResilientPubSub{Dial: dialPubSub}, redisx.SubscriberBufferSize(10))
+	s.Subscribe("c1")
+	s.PSubscribe("p*")
+	waitSubscribers(t, pc, "c1", 1)
+
+	pc.Do("PUBLISH", "c1", "hello")
+	expectMessage(t, s, redisx.Message{Channel: "c1", Data: []byte("hello")})
+	pc.Do("PUBLISH", "p1", "world")
+	expectMessage(t, s, redisx.Message{Pattern: "p*", Channel: "p1", Data: []byte("world")})
+
+	s.Close()
+	if _, ok := <-s.Messages(); ok {
+		t.Fatal("message channel not closed")
+	}
+}
+
+func TestSubscriberOverflow(t *testing.T) {
+	pc, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	for _, tt := range []struct {
+		policy   redisx.OverflowPolicy
+		expected []string
+	}{
+		{redisx.OverflowDropNewest, []string{"0", "1"}},
+		{redisx.OverflowDropOldest, []string{"3", "4"}},
+	} {
+		s := redisx.NewSubscriber(&redisx.ResilientPubSub{Dial: dialPubSub},
+			redisx.SubscriberBufferSize(2), redisx.SubscriberOverflow(tt.policy))
+		s.Subscribe("c1")
+		waitSubscribers(t, pc, "c1", 1)
+
+		for i := 0; i < 5; i++ {
+			pc.Do("PUBLISH", "c1", i)
+		}
+		for i := 0; i < 100 && s.Dropped() < 3; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := s.Dropped(); n != 3 {
+			t.Errorf("policy %d: Dropped() = %d, want 3", tt.policy, n)
+		}
+		for _, data := range tt.expected {
+			expectMessage(t, s, redisx.Message{Channel: "c1", Data: []byte(data)})
+		}
+		expected := redisx.PubSubStats{Received: 5, Dropped: 3, Channels: 1}
+		if stats := s.Stats(); stats != expected {
+			t.Errorf("policy %d: Stats() = %+v, want %+v", tt.policy, stats, expected)
+		}
+		s.Close()
+		waitSubscribers(t, pc, "c1", 0)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SubscriptionManager coalesces frequent subscription changes on a
+// ResilientPubSub into batched SUBSCRIBE, UNSUBSCRIBE, PSUBSCRIBE and
+// PUNSUBSCRIBE commands.
+//
+// Changes are collected for the manager's delay and then sent to the server.
+// A subscribe followed by an unsubscribe of the same name within the delay
+// cancels out and no command is sent. The methods of SubscriptionManager are
+// safe for concurrent use.
+type SubscriptionManager struct {
+	ps    *ResilientPubSub
+	delay time.Duration
+
+	mu         sync.Mutex
+	subscribed map[subscriptionKey]bool
+	pending    map[subscriptionKey]bool
+	timer      *time.Timer
+}
+
+type subscriptionKey struct {
+	name    string
+	pattern bool
+}
+
+// NewSubscriptionManager returns a manager that sends changes to ps after
+// the delay. Use a Subscriber's embedded ResilientPubSub to manage the
+// subscriptions of a Subscriber.
+func NewSubscriptionManager(ps *ResilientPubSub, delay time.Duration) *SubscriptionManager {
+	return &SubscriptionManager{
+		ps:         ps,
+		delay:      delay,
+		subscribed: make(map[subscriptionKey]bool),
+		pending:    make(map[subscriptionKey]bool),
+	}
+}
+
+// Subscribe adds the channels to the subscriptions.
+func (m *SubscriptionManager) Subscribe(channel ...string) {
+	m.change(channel, false, true)
+}
+
+// Unsubscribe removes the channels from the subscriptions.
+func (m *SubscriptionManager) Unsubscribe(channel ...string) {
+	m.change(channel, false, false)
+}
+
+// PSubscribe adds the patterns to the subscriptions.
+func (m *SubscriptionManager) PSubscribe(pattern ...string) {
+	m.change(pattern, true, true)
+}
+
+// PUnsubscribe removes the patterns from the subscriptions.
+func (m *SubscriptionManager) PUnsubscribe(pattern ...string) {
+	m.change(pattern, true, false)
+}
+
+// Channels returns the sorted names of the subscribed channels, including
+// changes that are not sent to the server yet.
+func (m *SubscriptionManager) Channels() []string {
+	return m.names(false)
+}
+
+// Patterns returns the sorted subscribed patterns, including changes that
+// are not sent to the server yet.
+func (m *SubscriptionManager) Patterns() []string {
+	return m.names(true)
+}
+
+// Flush sends pending changes to the server immediately.
+func (m *SubscriptionManager) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	return m.flush()
+}
+
+func (m *SubscriptionManager) change(names []string, pattern bool, subscribe bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range names {
+		m.pending[subscriptionKey{name, pattern}] = subscribe
+	}
+	if m.timer == nil && len(m.pending) > 0 {
+		m.timer = time.AfterFunc(m.delay, m.timerFlush)
+	}
+}
+
+func (m *SubscriptionManager) timerFlush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timer = nil
+	// Errors are not reported here. The ResilientPubSub records the change
+	// before sending the command and replays the subscriptions when the
+	// connection is re-established.
+	m.flush()
+}
+
+// flush sends the pending changes. The caller must hold m.mu.
+func (m *SubscriptionManager) flush() error {
+	var subs, unsubs, psubs, punsubs []string
+	for k, subscribe := range m.pending {
+		if m.subscribed[k] == subscribe {
+			continue
+		}
+		switch {
+		case subscribe && !k.pattern:
+			subs = append(subs, k.name)
+		case subscribe && k.pattern:
+			psubs = append(psubs, k.name)
+		case !k.pattern:
+			unsubs = append(unsubs, k.name)
+		default:
+			punsubs = append(punsubs, k.name)
+		}
+		if subscribe {
+			m.subscribed[k] = true
+		} else {
+			delete(m.subscribed, k)
+		}
+	}
+	m.pending = make(map[subscriptionKey]bool)
+
+	var err error
+	setErr := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	// Guard against empty lists because an empty unsubscribe removes all
+	// subscriptions.
+	if len(unsubs) > 0 {
+		setErr(m.ps.Unsubscribe(unsubs...))
+	}
+	if len(punsubs) > 0 {
+		setErr(m.ps.PUnsubscribe(punsubs...))
+	}
+	if len(subs) > 0 {
+		setErr(m.ps.Subscribe(subs...))
+	}
+	if len(psubs) > 0 {
+		setErr(m.ps.PSubscribe(psubs...))
+	}
+	return err
+}
+
+func (m *SubscriptionManager) names(pattern bool) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for k := range m.subscribed {
+		if k.pattern == pattern {
+			if subscribe, ok := m.pending[k]; ok && !subscribe {
+				continue
+			}
+			names = append(names, k.name)
+		}
+	}
+	for k, subscribe := range m.pending {
+		if k.pattern == pattern && subscribe && !m.subscribed[k] {
+			names = append(names, k.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package redisx_test
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSubscriptionManager(t *testing.T) {
+	pc, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer pc.Close()
+
+	var buf lockedBuffer
+	logger := log.New(&buf, "", 0)
+	ps := &redisx.ResilientPubSub{Dial: func() (redis.Conn, error) {
+		c, err := dialPubSub()
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewLoggingConn(c, logger, ""), nil
+	}}
+	s := redisx.NewSubscriber(ps)
+	defer s.Close()
+
+	m := redisx.NewSubscriptionManager(s.ResilientPubSub, 20*time.Millisecond)
+	m.Subscribe("a")
+	m.Subscribe("b", "c")
+	m.Unsubscribe("c")
+	m.PSubscribe("p*")
+	if channels := m.Channels(); !reflect.DeepEqual(channels, []string{"a", "b"}) {
+		t.Errorf("Channels() = %v, want [a b]", channels)
+	}
+
+	waitSubscribers(t, pc, "a", 1)
+	waitSubscribers(t, pc, "b", 1)
+
+	m.Unsubscribe("a")
+	m.PUnsubscribe("p*")
+	if err := m.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	waitSubscribers(t, pc, "a", 0)
+	if patterns := m.Patterns(); patterns != nil {
+		t.Errorf("Patterns() = %v, want nil", patterns)
+	}
+
+	log := buf.String()
+	for cmd, expected := range map[string]int{"SUBSCRIBE": 1, "UNSUBSCRIBE": 1, "PSUBSCRIBE": 1, "PUNSUBSCRIBE": 1} {
+		if n := strings.Count(log, "Send("+cmd+","); n != expected {
+			t.Errorf("sent %s %d times, want %d\n%s", cmd, n, expected, log)
+		}
+	}
+	if strings.Contains(log, `"c"`) {
+		t.Errorf("canceled subscription to c sent to server\n%s", log)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errStreamTrimmerConfig = errors.New("redigo: StreamTrimmer requires Pool, Streams and one of MaxLen or MaxAge")
+
+// StreamTrimmer enforces a retention policy on a set of streams by running
+// XTRIM at an interval.
+type StreamTrimmer struct {
+	// Pool is the connection pool.
+	Pool *redis.Pool
+
+	// Streams is the list of stream keys to trim.
+	Streams []string
+
+	// MaxLen is the maximum number of entries to keep in each stream. If
+	// MaxLen is zero, then streams are not trimmed by length.
+	MaxLen int64
+
+	// MaxAge is the maximum age of entries to keep in each stream. The age
+	// of an entry is computed from the time in the entry ID. If MaxAge is
+	// zero, then streams are not trimmed by age.
+	MaxAge time.Duration
+
+	// Exact specifies that streams are trimmed exactly. By default, streams
+	// are trimmed with the ~ modifier, which lets the server trim whole
+	// nodes only. Approximate trimming is much more efficient.
+	Exact bool
+
+	// Interval is the time between trims. The default is one minute.
+	Interval time.Duration
+
+	// OnTrim is an optional function called after each XTRIM command with
+	// the stream key and the number of entries removed from the stream.
+	OnTrim func(stream string, count int64)
+
+	// ErrorHandler is an optional function called with errors from the
+	// server.
+	ErrorHandler func(err error)
+}
+
+// Trim trims each stream once and returns the total number of entries
+// removed. Trim continues with the remaining streams when trimming a stream
+// fails and returns the first error.
+func (st *StreamTrimmer) Trim() (int64, error) {
+	if st.Pool == nil || len(st.Streams) == 0 || (st.MaxLen <= 0 && st.MaxAge <= 0) {
+		return 0, errStreamTrimmerConfig
+	}
+	c := st.Pool.Get()
+	defer c.Close()
+
+	var (
+		total    int64
+		firstErr error
+	)
+	for _, stream := range st.Streams {
+		n, err := st.trim(c, stream)
+		total += n
+		if err != nil {
+			if st.ErrorHandler != nil {
+				st.ErrorHandler(err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return total, firstErr
+}
+
+// trim applies the MAXLEN and MINID policies to stream and returns the
+// number of entries removed.
+func (st *StreamTrimmer) trim(c redis.Conn, stream string) (int64, error) {
+	var total int64
+	if st.MaxLen > 0 {
+		n, err := st.xtrim(c, stream, "MAXLEN", st.MaxLen)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	if st.MaxAge > 0 {
+		ms := time.Now().Add(-st.MaxAge).UnixNano() / int64(time.Millisecond)
+		n, err := st.xtrim(c, stream, "MINID", strconv.FormatInt(ms, 10))
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (st *StreamTrimmer) xtrim(c redis.Conn, stream, strategy string, threshold interface{}) (int64, error) {
+	args := redis.Args{stream, strategy}
+	if !st.Exact {
+		args = append(args, "~")
+	}
+	n, err := redis.Int64(c.Do("XTRIM", append(args, threshold)...))
+	if err != nil {
+		return 0, err
+	}
+	if st.OnTrim != nil {
+		st.OnTrim(stream, n)
+	}
+	return n, nil
+}
+
+// Run trims the streams at the configured interval until the context is
+// done. Run returns the context's error or an error for invalid
+// configuration. Errors from the server are reported to ErrorHandler.
+func (st *StreamTrimmer) Run(ctx context.Context) error {
+	interval := st.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	for {
+		if _, err := st.Trim(); err == errStreamTrimmerConfig {
+			return err
+		}
+		if !sleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
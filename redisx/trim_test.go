@@ -0,0 +1,78 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// +build go1.7
+
+package redisx_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/internal/redistest"
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/redisx"
+)
+
+func TestStreamTrimmer(t *testing.T) {
+	c, err := redistest.Dial()
+	if err != nil {
+		t.Fatalf("error connection to database, %v", err)
+	}
+	defer c.Close()
+
+	p := &redis.Pool{Dial: dialTestDB, MaxIdle: 2}
+	defer p.Close()
+
+	old := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	for i := int64(0); i < 5; i++ {
+		if _, err := c.Do("XADD", "a", old+i, "n", i); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Do("XADD", "b", "*", "n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trimmed := map[string]int64{}
+	st := &redisx.StreamTrimmer{
+		Pool:    p,
+		Streams: []string{"a", "b"},
+		MaxLen:  3,
+		MaxAge:  time.Minute,
+		Exact:   true,
+		OnTrim: func(stream string, count int64) {
+			trimmed[stream] += count
+		},
+	}
+	n, err := st.Trim()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 7 || trimmed["a"] != 5 || trimmed["b"] != 2 {
+		t.Errorf("Trim() = %d, trimmed = %v, want 7, map[a:5 b:2]", n, trimmed)
+	}
+
+	if _, err := (&redisx.StreamTrimmer{Pool: p}).Trim(); err == nil {
+		t.Error("Trim() with missing config returned nil error")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	st.Interval = 5 * time.Millisecond
+	if err := st.Run(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Run() returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
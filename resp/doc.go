@@ -0,0 +1,36 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package resp encodes commands and decodes replies in the Redis
+// serialization protocol (RESP).
+//
+// The package uses the connection implementation in the redis package to
+// read and write the protocol. Commands are encoded and replies are decoded
+// exactly as they are on a connection returned from redis.Dial. Proxies,
+// test servers and fuzzers can use the package to speak the protocol without
+// reimplementing it.
+//
+// Because commands are arrays of bulk strings, a Reader can also decode the
+// commands sent by a client:
+//
+//  r := resp.NewReader(netConn)
+//  for {
+//      v, err := r.ReadReply()
+//      if err != nil {
+//          return err
+//      }
+//      args, err := redis.ByteSlices(v, nil)
+//      ...
+//  }
+package resp // import "github.com/garyburd/redigo/resp"
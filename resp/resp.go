@@ -0,0 +1,135 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package resp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Writer encodes commands to an io.Writer.
+type Writer struct {
+	c redis.Conn
+}
+
+// NewWriter returns a Writer that encodes commands to w. The commands are
+// buffered; call Flush to write the buffered data to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{c: redis.NewConn(&pipe{w: w}, 0, 0)}
+}
+
+// WriteCommand encodes the command to the buffer. Arguments are converted
+// as described in the redis package documentation. After an error is
+// returned, all subsequent calls return an error.
+func (w *Writer) WriteCommand(commandName string, args ...interface{}) error {
+	return w.c.Send(commandName, args...)
+}
+
+// Flush writes the buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	return w.c.Flush()
+}
+
+// WriteCommand encodes a command to w.
+func WriteCommand(w io.Writer, commandName string, args ...interface{}) error {
+	cw := NewWriter(w)
+	if err := cw.WriteCommand(commandName, args...); err != nil {
+		return err
+	}
+	return cw.Flush()
+}
+
+// Reader decodes replies from an io.Reader.
+type Reader struct {
+	c redis.Conn
+}
+
+// NewReader returns a Reader that decodes replies from r. Replies are
+// returned using the representation described in the redis package
+// documentation.
+func NewReader(r io.Reader) *Reader {
+	return newReader(r)
+}
+
+// NewNativeReader returns a Reader that decodes RESP3 replies from r to
+// native Go types as described in redis.DialNativeTypes.
+func NewNativeReader(r io.Reader) *Reader {
+	return newReader(r, redis.DialNativeTypes(true))
+}
+
+func newReader(r io.Reader, options ...redis.DialOption) *Reader {
+	p := &pipe{r: r}
+	options = append(options, redis.DialNetDial(func(network, addr string) (net.Conn, error) {
+		return p, nil
+	}))
+	c, err := redis.Dial("resp", "", options...)
+	if err != nil {
+		// Dial does not execute commands with these options and the
+		// dial function does not fail.
+		panic(err)
+	}
+	return &Reader{c: c}
+}
+
+// ReadReply decodes the next reply. Error replies are returned as a
+// redis.Error value with a nil error. The returned error is not nil when
+// the reply cannot be read or is malformed. After an error is returned, all
+// subsequent calls return an error.
+func (r *Reader) ReadReply() (interface{}, error) {
+	reply, err := r.c.Receive()
+	if e, ok := err.(redis.Error); ok {
+		return e, nil
+	}
+	return reply, err
+}
+
+var errNotSupported = errors.New("resp: operation not supported")
+
+// pipe adapts an io.Reader or io.Writer to the net.Conn interface expected
+// by the redis package.
+type pipe struct {
+	r io.Reader
+	w io.Writer
+}
+
+func (p *pipe) Read(b []byte) (int, error) {
+	if p.r == nil {
+		return 0, errNotSupported
+	}
+	return p.r.Read(b)
+}
+
+func (p *pipe) Write(b []byte) (int, error) {
+	if p.w == nil {
+		return 0, errNotSupported
+	}
+	return p.w.Write(b)
+}
+
+func (p *pipe) Close() error                       { return nil }
+func (p *pipe) LocalAddr() net.Addr                { return addr{} }
+func (p *pipe) RemoteAddr() net.Addr               { return addr{} }
+func (p *pipe) SetDeadline(t time.Time) error      { return nil }
+func (p *pipe) SetReadDeadline(t time.Time) error  { return nil }
+func (p *pipe) SetWriteDeadline(t time.Time) error { return nil }
+
+type addr struct{}
+
+func (addr) Network() string { return "resp" }
+func (addr) String() string  { return "resp" }
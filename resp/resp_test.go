@@ -0,0 +1,152 @@
+// Copyright 2017 Gary Burd
+//
+// Licensed under the Apache License, Version 2.0 (the "License"): you may
+// not use this file except in compliance with the License. You may obtain
+// a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package resp_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/garyburd/redigo/resp"
+)
+
+var writeCommandTests = []struct {
+	args     []interface{}
+	expected string
+}{
+	{
+		[]interface{}{"SET", "key", "value"},
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n",
+	},
+	{
+		[]interface{}{"SET", "key", []byte("value")},
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nvalue\r\n",
+	},
+	{
+		[]interface{}{"SET", "key", int64(-1234)},
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\n-1234\r\n",
+	},
+	{
+		[]interface{}{"SET", "key", 1.5},
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$3\r\n1.5\r\n",
+	},
+	{
+		[]interface{}{"SET", "key", true},
+		"*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$1\r\n1\r\n",
+	},
+	{
+		[]interface{}{"ECHO", nil},
+		"*2\r\n$4\r\nECHO\r\n$0\r\n\r\n",
+	},
+}
+
+func TestWriteCommand(t *testing.T) {
+	for _, tt := range writeCommandTests {
+		var buf bytes.Buffer
+		if err := resp.WriteCommand(&buf, tt.args[0].(string), tt.args[1:]...); err != nil {
+			t.Errorf("WriteCommand(%v) returned error %v", tt.args, err)
+			continue
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("WriteCommand(%v) = %q, want %q", tt.args, buf.String(), tt.expected)
+		}
+	}
+}
+
+func TestWriterBuffers(t *testing.T) {
+	var buf bytes.Buffer
+	w := resp.NewWriter(&buf)
+	w.WriteCommand("PING")
+	w.WriteCommand("PING")
+	if buf.Len() != 0 {
+		t.Fatalf("buffer written before Flush: %q", buf.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "*1\r\n$4\r\nPING\r\n*1\r\n$4\r\nPING\r\n"; buf.String() != expected {
+		t.Fatalf("got %q, want %q", buf.String(), expected)
+	}
+}
+
+var readReplyTests = []struct {
+	native   bool
+	reply    string
+	expected interface{}
+}{
+	{false, "+OK\r\n", "OK"},
+	{false, "+status\r\n", "status"},
+	{false, "-ERR failed\r\n", redis.Error("ERR failed")},
+	{false, ":1234\r\n", int64(1234)},
+	{false, "$5\r\nhello\r\n", []byte("hello")},
+	{false, "$-1\r\n", nil},
+	{false, "*2\r\n$1\r\na\r\n:1\r\n", []interface{}{[]byte("a"), int64(1)}},
+	{false, "*-1\r\n", nil},
+	{false, "_\r\n", nil},
+	{false, "#t\r\n", int64(1)},
+	{true, "#t\r\n", true},
+	{false, ",1.5\r\n", []byte("1.5")},
+	{true, ",1.5\r\n", 1.5},
+	{false, "%1\r\n+k\r\n:1\r\n", []interface{}{"k", int64(1)}},
+	{true, "%1\r\n+k\r\n:1\r\n", map[string]interface{}{"k": int64(1)}},
+	{false, "=7\r\ntxt:abc\r\n", []byte("abc")},
+	{false, "!4\r\nERR!\r\n", redis.Error("ERR!")},
+}
+
+func TestReadReply(t *testing.T) {
+	for _, tt := range readReplyTests {
+		r := resp.NewReader(strings.NewReader(tt.reply))
+		if tt.native {
+			r = resp.NewNativeReader(strings.NewReader(tt.reply))
+		}
+		actual, err := r.ReadReply()
+		if err != nil {
+			t.Errorf("ReadReply(%q) returned error %v", tt.reply, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("ReadReply(%q) = %#v, want %#v", tt.reply, actual, tt.expected)
+		}
+	}
+}
+
+func TestReadReplyError(t *testing.T) {
+	for _, reply := range []string{"", "x\r\n", "$5\r\nab\r\n", ":12a\r\n", "+OK\n"} {
+		r := resp.NewReader(strings.NewReader(reply))
+		if _, err := r.ReadReply(); err == nil {
+			t.Errorf("ReadReply(%q) did not return error", reply)
+		}
+	}
+}
+
+func TestReadCommand(t *testing.T) {
+	var buf bytes.Buffer
+	if err := resp.WriteCommand(&buf, "SET", "key", 42); err != nil {
+		t.Fatal(err)
+	}
+	v, err := resp.NewReader(&buf).ReadReply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := redis.Strings(v, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"SET", "key", "42"}; !reflect.DeepEqual(args, expected) {
+		t.Fatalf("got %v, want %v", args, expected)
+	}
+}